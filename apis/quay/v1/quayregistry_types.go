@@ -19,16 +19,21 @@ package v1
 import (
 	"fmt"
 	"os"
+	"slices"
 	"strings"
 
+	"github.com/Masterminds/semver/v3"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 	"k8s.io/utils/ptr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	quaycontext "github.com/quay/quay-operator/pkg/context"
+	"github.com/quay/quay-operator/pkg/image"
 )
 
 // QuayVersion represents a quay version as a string. Normally this is set using semantic
@@ -39,8 +44,12 @@ type QuayVersion string
 // variable. This can be used to identify a version upgrade.
 var QuayVersionCurrent QuayVersion = QuayVersion(os.Getenv("QUAY_VERSION"))
 
+// QuayUpgradeMaxMinorVersions is the number of minor versions of Quay a registry can be
+// upgraded across at once.
+const QuayUpgradeMaxMinorVersions = 3
+
 // ComponentKind holds a component type, e.g. "clair", "postgres", etc.
-// +kubebuilder:validation:Enum=quay;postgres;clair;clairpostgres;redis;horizontalpodautoscaler;objectstorage;route;mirror;monitoring;tls
+// +kubebuilder:validation:Enum=quay;postgres;clair;clairpostgres;redis;horizontalpodautoscaler;objectstorage;route;mirror;monitoring;tls;ingress;gateway;builder
 type ComponentKind string
 
 // Follow a list of constants representing all supported components.
@@ -56,6 +65,9 @@ const (
 	ComponentMirror        ComponentKind = "mirror"
 	ComponentMonitoring    ComponentKind = "monitoring"
 	ComponentTLS           ComponentKind = "tls"
+	ComponentIngress       ComponentKind = "ingress"
+	ComponentGateway       ComponentKind = "gateway"
+	ComponentBuilder       ComponentKind = "builder"
 )
 
 // AllComponents holds a list of all supported components.
@@ -71,6 +83,9 @@ var AllComponents = []ComponentKind{
 	ComponentMonitoring,
 	ComponentTLS,
 	ComponentClairPostgres,
+	ComponentIngress,
+	ComponentGateway,
+	ComponentBuilder,
 }
 
 var requiredComponents = []ComponentKind{
@@ -115,6 +130,9 @@ var supportsReplicasOverride = []ComponentKind{
 	ComponentClair,
 	ComponentMirror,
 	ComponentQuay,
+	ComponentRedis,
+	ComponentPostgres,
+	ComponentClairPostgres,
 }
 
 var supportsAffinityOverride = []ComponentKind{
@@ -128,6 +146,141 @@ var supportsSecurityContextOverride = []ComponentKind{
 	ComponentMirror,
 }
 
+var supportsIngressClassNameOverride = []ComponentKind{
+	ComponentIngress,
+}
+
+var supportsParentRefOverride = []ComponentKind{
+	ComponentGateway,
+}
+
+var supportsIssuerRefOverride = []ComponentKind{
+	ComponentTLS,
+}
+
+var supportsTLSOverride = []ComponentKind{
+	ComponentPostgres,
+	ComponentClairPostgres,
+}
+
+var supportsHighAvailabilityOverride = []ComponentKind{
+	ComponentRedis,
+}
+
+var supportsBackendOverride = []ComponentKind{
+	ComponentPostgres,
+	ComponentClairPostgres,
+}
+
+var supportsBackupOverride = []ComponentKind{
+	ComponentPostgres,
+	ComponentClairPostgres,
+}
+
+var supportsSnapshotOverride = []ComponentKind{
+	ComponentPostgres,
+	ComponentClairPostgres,
+}
+
+var supportsDataSourceOverride = []ComponentKind{
+	ComponentPostgres,
+	ComponentClairPostgres,
+}
+
+var supportsSchedulingOverride = []ComponentKind{
+	ComponentQuay,
+	ComponentClair,
+	ComponentMirror,
+	ComponentPostgres,
+	ComponentClairPostgres,
+	ComponentRedis,
+}
+
+var supportsPodDisruptionBudgetOverride = []ComponentKind{
+	ComponentQuay,
+	ComponentClair,
+	ComponentMirror,
+}
+
+var supportsImageOverride = []ComponentKind{
+	ComponentQuay,
+	ComponentClair,
+	ComponentRedis,
+	ComponentPostgres,
+	ComponentClairPostgres,
+}
+
+// DatabaseBackend is the kind of workload rendered for a managed database.
+// +kubebuilder:validation:Enum=deployment;cloudnativepg
+type DatabaseBackend string
+
+const (
+	// DatabaseBackendDeployment runs the database as a single replica Deployment.
+	DatabaseBackendDeployment DatabaseBackend = "deployment"
+	// DatabaseBackendCloudNativePG runs the database as a CloudNativePG Cluster, with
+	// standby instances and automated failover.
+	DatabaseBackendCloudNativePG DatabaseBackend = "cloudnativepg"
+)
+
+// DefaultDatabaseInstances is the number of instances, a primary and two standbys, of a
+// managed database run by CloudNativePG.
+const DefaultDatabaseInstances int32 = 3
+
+// BackupDestination is where the logical backups of a managed database are written.
+// +kubebuilder:validation:Enum=persistentvolume;objectstorage
+type BackupDestination string
+
+const (
+	// BackupDestinationPersistentVolume writes the backups to a PersistentVolumeClaim
+	// rendered along with the backup CronJob.
+	BackupDestinationPersistentVolume BackupDestination = "persistentvolume"
+	// BackupDestinationObjectStorage uploads the backups to the bucket of the managed
+	// objectstorage component.
+	BackupDestinationObjectStorage BackupDestination = "objectstorage"
+)
+
+// DefaultBackupRetention is the number of backups of a managed database kept by default.
+const DefaultBackupRetention int32 = 7
+
+// VolumeSnapshotGroup is the API group of the CSI VolumeSnapshots taken of, and restored
+// into, the volumes of the managed databases.
+const VolumeSnapshotGroup = "snapshot.storage.k8s.io"
+
+// PostgresVersion is a major version of Postgres on the upgrade path of a managed database.
+type PostgresVersion struct {
+	// Major is the major version of Postgres.
+	Major int32
+	// Image is the default image running this version.
+	Image string
+	// Volume is the name, without the registry prefix, of the volume holding the data of the
+	// database on this version.
+	Volume string
+}
+
+// postgresUpgradePaths declares, oldest first, the major versions of Postgres each managed
+// database is upgraded through. The last one is the version the database runs, the images
+// and volumes of the database components must match it. Databases found on an older version
+// are upgraded one version at a time.
+var postgresUpgradePaths = map[ComponentKind][]PostgresVersion{
+	ComponentPostgres: {
+		{Major: 10, Image: "centos/postgresql-10-centos7", Volume: "quay-database"},
+		{Major: 13, Image: "quay.io/sclorg/postgresql-13-c9s", Volume: "quay-postgres-13"},
+	},
+	ComponentClairPostgres: {
+		{Major: 10, Image: "centos/postgresql-10-centos7", Volume: "clair-postgres"},
+		{Major: 13, Image: "quay.io/sclorg/postgresql-13-c9s", Volume: "clair-postgres-13"},
+		{Major: 15, Image: "quay.io/sclorg/postgresql-15-c9s", Volume: "clair-postgres-15"},
+	},
+}
+
+// MinRedisHighAvailabilityReplicas is the smallest number of redis replicas, each running a
+// sentinel, able to elect a new master when one of them is lost.
+const MinRedisHighAvailabilityReplicas int32 = 3
+
+// HPAMinReplicas is the minimum number of replicas of the quay, clair and mirror components
+// set in the HorizontalPodAutoscalers rendered for them.
+const HPAMinReplicas int32 = 2
+
 const (
 	ManagedKeysName             = "quay-registry-managed-secret-keys"
 	QuayConfigTLSSecretName     = "quay-config-tls"
@@ -138,6 +291,9 @@ const (
 	ClusterTrustedCAName        = "cluster-trusted-ca"
 	TLSSecretHashAnnotation     = "quay.redhat.com/tls-secret-hash"
 	TLSSecretLabel              = "quay.redhat.com/tls-secret"
+	OperatorTLSSecretName       = "quay-operator-tls"
+	OperatorCAConfigMapName     = "quay-operator-ca"
+	DatabaseTLSSecretName       = "postgres-tls"
 )
 
 // QuayRegistrySpec defines the desired state of QuayRegistry.
@@ -147,6 +303,113 @@ type QuayRegistrySpec struct {
 	ConfigBundleSecret string `json:"configBundleSecret,omitempty"`
 	// Components declare how the Operator should handle backing Quay services.
 	Components []Component `json:"components,omitempty"`
+	// PostgresUpgrade configures how the managed databases are upgraded to a new major
+	// version of Postgres.
+	PostgresUpgrade *PostgresUpgradePolicy `json:"postgresUpgrade,omitempty"`
+	// UpgradeStrategy is how Quay is upgraded to a new version, defaults to Recreate. With
+	// Recreate Quay is scaled down while the database migrations run, with ReadOnly the
+	// previous version keeps serving pulls in read-only mode until the migrations finish.
+	// +kubebuilder:validation:Enum=Recreate;ReadOnly
+	UpgradeStrategy UpgradeStrategy `json:"upgradeStrategy,omitempty"`
+	// UpgradeRollback configures how an upgrade of Quay is rolled back when its database
+	// migrations fail.
+	UpgradeRollback *UpgradeRollbackPolicy `json:"upgradeRollback,omitempty"`
+	// Maintenance puts the registry under maintenance, Quay is rolled into the provided
+	// mode and the mirror workers are paused until it is removed.
+	Maintenance *Maintenance `json:"maintenance,omitempty"`
+	// JobOverrides overrides the Jobs run by the Operator to upgrade Quay and the managed
+	// databases, each Job is overridden at most once.
+	JobOverrides []JobOverride `json:"jobOverrides,omitempty"`
+}
+
+// JobOverride describes configuration overrides for one of the upgrade Jobs run by the
+// Operator. The database migrations of large registries need far more memory and time than
+// the Quay pods do.
+type JobOverride struct {
+	// Name is the name of the overridden Job.
+	// +kubebuilder:validation:Enum=quay-app-upgrade;quay-postgres-upgrade;clair-postgres-upgrade
+	Name string `json:"name"`
+	// Resources are the resources of the containers of the Job.
+	Resources *Resources `json:"resources,omitempty"`
+	// NodeSelector constrains the nodes the pods of the Job are scheduled on.
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+	// Affinity constrains the nodes the pods of the Job are scheduled on.
+	Affinity *corev1.Affinity `json:"affinity,omitempty"`
+	// Tolerations allow the pods of the Job to be scheduled on tainted nodes.
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+	// ActiveDeadlineSeconds is the time the Job may run for before it is failed.
+	// +kubebuilder:validation:Minimum=1
+	ActiveDeadlineSeconds *int64 `json:"activeDeadlineSeconds,omitempty"`
+	// BackoffLimit is the number of times the pods of the Job are retried before it is
+	// failed.
+	// +kubebuilder:validation:Minimum=0
+	BackoffLimit *int32 `json:"backoffLimit,omitempty"`
+	// TTLSecondsAfterFinished is the time after which the finished Job is deleted. A Job
+	// deleted before the Operator saw it finish is reported as missing.
+	// +kubebuilder:validation:Minimum=0
+	TTLSecondsAfterFinished *int32 `json:"ttlSecondsAfterFinished,omitempty"`
+}
+
+// MaintenanceMode is the mode Quay runs in while the registry is under maintenance.
+type MaintenanceMode string
+
+const (
+	MaintenanceModeReadOnly MaintenanceMode = "ReadOnly"
+)
+
+// Maintenance describes a planned maintenance of the registry.
+type Maintenance struct {
+	// Mode is the mode Quay runs in during the maintenance. In ReadOnly mode pulls keep
+	// being served while pushes, and any other write, fail.
+	// +kubebuilder:validation:Enum=ReadOnly
+	Mode MaintenanceMode `json:"mode"`
+	// Reason describes the maintenance, it is reported by the Maintenance condition.
+	Reason string `json:"reason,omitempty"`
+}
+
+// UpgradeStrategy is how Quay is upgraded to a new version.
+type UpgradeStrategy string
+
+const (
+	UpgradeStrategyRecreate UpgradeStrategy = "Recreate"
+	UpgradeStrategyReadOnly UpgradeStrategy = "ReadOnly"
+)
+
+// UpgradeRollbackPolicy describes how an upgrade of Quay is rolled back when its database
+// migrations fail. Only the database managed by a postgres Deployment can be rolled back.
+type UpgradeRollbackPolicy struct {
+	// OnMigrationFailure dumps the managed database before the migrations of an upgrade run.
+	// If they fail the dump is restored and the previous version of Quay is run again.
+	OnMigrationFailure bool `json:"onMigrationFailure,omitempty"`
+	// RetryVersion is the version of Quay, as reported in status.upgradeRollback, whose
+	// rolled back upgrade is attempted again.
+	RetryVersion QuayVersion `json:"retryVersion,omitempty"`
+}
+
+// PreUpgradeBackupMethod is how a managed database is backed up before it is upgraded to a
+// new major version of Postgres.
+type PreUpgradeBackupMethod string
+
+const (
+	PreUpgradeBackupNone     PreUpgradeBackupMethod = "none"
+	PreUpgradeBackupSnapshot PreUpgradeBackupMethod = "snapshot"
+	PreUpgradeBackupDump     PreUpgradeBackupMethod = "dump"
+)
+
+// PostgresUpgradePolicy describes how the managed databases are upgraded to a new major
+// version of Postgres.
+type PostgresUpgradePolicy struct {
+	// Backup is how a database is backed up before it is upgraded, defaults to none. The
+	// upgrade does not start until the backup succeeds.
+	// +kubebuilder:validation:Enum=none;snapshot;dump
+	Backup PreUpgradeBackupMethod `json:"backup,omitempty"`
+	// VolumeSnapshotClassName is the VolumeSnapshotClass of the snapshots taken with the
+	// snapshot method, defaults to the default class of the cluster.
+	VolumeSnapshotClassName string `json:"volumeSnapshotClassName,omitempty"`
+	// Acknowledged lists the pre-upgrade backups, as reported in the status, of upgrades
+	// verified to have succeeded. Acknowledged backups are deleted along with the volume of
+	// the previous version of the database.
+	Acknowledged []string `json:"acknowledged,omitempty"`
 }
 
 // Component describes how the Operator should handle a backing Quay service.
@@ -182,6 +445,107 @@ type Override struct {
 	Annotations     map[string]string       `json:"annotations,omitempty"`
 	Resources       *Resources              `json:"resources,omitempty"`
 	SecurityContext *corev1.SecurityContext `json:"securityContext,omitempty"`
+	// IngressClassName is the name of the IngressClass used by the rendered Ingress objects.
+	IngressClassName *string `json:"ingressClassName,omitempty"`
+	// ParentRef references the Gateway the rendered Gateway API routes are attached to.
+	ParentRef *GatewayParentReference `json:"parentRef,omitempty"`
+	// IssuerRef references the cert-manager issuer used to request the TLS certificate.
+	IssuerRef *CertificateIssuerReference `json:"issuerRef,omitempty"`
+	// TLS enables TLS, with certificates issued by the Operator, for the connections to the
+	// managed database.
+	TLS *bool `json:"tls,omitempty"`
+	// HighAvailability deploys Redis as a StatefulSet with one Sentinel per replica, Quay
+	// connects to the current master through the sentinels.
+	HighAvailability *bool `json:"highAvailability,omitempty"`
+	// Backend selects how the managed database is deployed, defaults to a Deployment.
+	Backend *DatabaseBackend `json:"backend,omitempty"`
+	// Backup schedules logical backups of the managed database.
+	Backup *DatabaseBackup `json:"backup,omitempty"`
+	// Snapshot configures CSI VolumeSnapshots of the volume of the managed database.
+	Snapshot *DatabaseSnapshot `json:"snapshot,omitempty"`
+	// DataSource is the VolumeSnapshot the volume of the managed database is created from.
+	// It is only used when the volume is first created.
+	DataSource *corev1.TypedLocalObjectReference `json:"dataSource,omitempty"`
+	// Image is the reference, by tag or digest, of the image run by the component. It takes
+	// precedence over the image set through the environment of the Operator.
+	Image string `json:"image,omitempty"`
+	// ImagePullSecrets are the secrets the image of the component is pulled with.
+	ImagePullSecrets []corev1.LocalObjectReference `json:"imagePullSecrets,omitempty"`
+	// Tolerations allow the pods of the component to be scheduled on tainted nodes.
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+	// NodeSelector constrains the nodes the pods of the component are scheduled on.
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+	// TopologySpreadConstraints describe how the pods of the component are spread across
+	// topology domains. Constraints without a labelSelector select the pods of the component.
+	TopologySpreadConstraints []corev1.TopologySpreadConstraint `json:"topologySpreadConstraints,omitempty"`
+	// PriorityClassName is the name of the PriorityClass of the pods of the component.
+	// +kubebuilder:validation:MinLength=1
+	PriorityClassName *string `json:"priorityClassName,omitempty"`
+	// PodDisruptionBudget sizes the PodDisruptionBudget of the pods of the component.
+	PodDisruptionBudget *DisruptionBudget `json:"podDisruptionBudget,omitempty"`
+}
+
+// DisruptionBudget sizes the PodDisruptionBudget of a component, either by the number of pods
+// that must remain available or by the number of pods that may be evicted.
+// +kubebuilder:validation:XValidation:rule="has(self.minAvailable) != has(self.maxUnavailable)",message="exactly one of minAvailable or maxUnavailable must be set"
+type DisruptionBudget struct {
+	// MinAvailable is the number, or percentage, of pods that must remain available.
+	// +kubebuilder:validation:XIntOrString
+	MinAvailable *intstr.IntOrString `json:"minAvailable,omitempty"`
+	// MaxUnavailable is the number, or percentage, of pods that may be evicted.
+	// +kubebuilder:validation:XIntOrString
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
+}
+
+// DatabaseBackup schedules logical backups, taken with pg_dump, of a managed database.
+type DatabaseBackup struct {
+	// Schedule is the cron schedule on which backups are taken.
+	Schedule string `json:"schedule"`
+	// Retention is the number of backups kept, older ones are removed. Defaults to 7.
+	// +kubebuilder:validation:Minimum=1
+	Retention *int32 `json:"retention,omitempty"`
+	// Destination is where the backups are written, defaults to a persistent volume.
+	Destination BackupDestination `json:"destination,omitempty"`
+}
+
+// DatabaseSnapshot configures CSI VolumeSnapshots of the volume of a managed database. The
+// database is checkpointed before each snapshot is taken.
+type DatabaseSnapshot struct {
+	// Schedule is the cron schedule on which snapshots are taken. Without a schedule
+	// snapshots are only taken on demand.
+	Schedule string `json:"schedule,omitempty"`
+	// Retention is the number of snapshots kept, older ones are removed. Defaults to 7.
+	// +kubebuilder:validation:Minimum=1
+	Retention *int32 `json:"retention,omitempty"`
+	// VolumeSnapshotClassName is the VolumeSnapshotClass of the snapshots, defaults to the
+	// default class of the cluster.
+	VolumeSnapshotClassName string `json:"volumeSnapshotClassName,omitempty"`
+}
+
+// CertificateIssuerReference identifies the cert-manager Issuer or ClusterIssuer used to
+// issue the certificate served by Quay.
+type CertificateIssuerReference struct {
+	// Name is the name of the issuer.
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+	// Kind is the kind of the issuer, either Issuer or ClusterIssuer.
+	// +kubebuilder:validation:Enum=Issuer;ClusterIssuer
+	// +kubebuilder:default=Issuer
+	Kind string `json:"kind,omitempty"`
+	// Group is the API group of the issuer, defaults to cert-manager.io.
+	Group string `json:"group,omitempty"`
+}
+
+// GatewayParentReference identifies a Gateway, and optionally one of its listeners, the
+// Gateway API routes are attached to.
+type GatewayParentReference struct {
+	// Name is the name of the Gateway.
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+	// Namespace is the namespace of the Gateway, defaults to the QuayRegistry namespace.
+	Namespace string `json:"namespace,omitempty"`
+	// SectionName is the name of the Gateway listener the routes are attached to.
+	SectionName string `json:"sectionName,omitempty"`
 }
 
 // Resources describes the resource limits and requests for a component.
@@ -197,6 +561,7 @@ type ConditionType string
 const (
 	ConditionTypeAvailable      ConditionType = "Available"
 	ConditionTypeRolloutBlocked ConditionType = "RolloutBlocked"
+	ConditionTypeMaintenance    ConditionType = "Maintenance"
 	ConditionComponentsCreated  ConditionType = "ComponentsCreated"
 	ComponentQuayReady          ConditionType = "ComponentQuayReady"
 	ComponentPostgresReady      ConditionType = "ComponentPostgresReady"
@@ -209,6 +574,12 @@ const (
 	ComponentMirrorReady        ConditionType = "ComponentMirrorReady"
 	ComponentMonitoringReady    ConditionType = "ComponentMonitoringReady"
 	ComponentTLSReady           ConditionType = "ComponentTLSReady"
+	ComponentIngressReady       ConditionType = "ComponentIngressReady"
+	ComponentGatewayReady       ConditionType = "ComponentGatewayReady"
+	ComponentBuilderReady       ConditionType = "ComponentBuilderReady"
+
+	ComponentPostgresBackupReady      ConditionType = "ComponentPostgresBackupReady"
+	ComponentClairPostgresBackupReady ConditionType = "ComponentClairPostgresBackupReady"
 )
 
 type ConditionReason string
@@ -218,22 +589,43 @@ type ConditionReason string
 const (
 	ConditionReasonComponentNotReady    ConditionReason = "ComponentNotReady"
 	ConditionReasonComponentReady       ConditionReason = "ComponentReady"
+	ConditionReasonCertificateExpiring  ConditionReason = "CertificateExpiring"
 	ConditionReasonComponentUnmanaged   ConditionReason = "ComponentNotManaged"
 	ConditionReasonHealthChecksPassing  ConditionReason = "HealthChecksPassing"
 	ConditionReasonMigrationsInProgress ConditionReason = "MigrationsInProgress"
 	ConditionReasonMigrationsFailed     ConditionReason = "MigrationsFailed"
 	ConditionReasonMigrationsJobMissing ConditionReason = "MigrationsJobMissing"
 
+	ConditionReasonReadOnlyRolloutInProgress ConditionReason = "ReadOnlyRolloutInProgress"
+
 	ConditionReasonPostgresUpgradeInProgress ConditionReason = "PostgresUpgradeInProgress"
 	ConditionReasonPostgresUpgradeFailed     ConditionReason = "PostgresUpgradeFailed"
 	ConditionReasonPostgresUpgradeJobMissing ConditionReason = "PostgresUpgradeJobMissing"
 
+	ConditionReasonBackupSucceeded     ConditionReason = "BackupSucceeded"
+	ConditionReasonBackupFailed        ConditionReason = "BackupFailed"
+	ConditionReasonBackupPending       ConditionReason = "BackupPending"
+	ConditionReasonBackupNotConfigured ConditionReason = "BackupNotConfigured"
+
+	ConditionReasonRestoreInProgress ConditionReason = "RestoreInProgress"
+
+	ConditionReasonUpgradeRollingBack    ConditionReason = "UpgradeRollingBack"
+	ConditionReasonUpgradeRolledBack     ConditionReason = "UpgradeRolledBack"
+	ConditionReasonUpgradeRollbackFailed ConditionReason = "UpgradeRollbackFailed"
+
+	ConditionReasonMaintenanceRolloutInProgress ConditionReason = "MaintenanceRolloutInProgress"
+	ConditionReasonMaintenanceReadOnly          ConditionReason = "MaintenanceReadOnly"
+
 	ConditionReasonComponentsCreationSuccess             ConditionReason = "ComponentsCreationSuccess"
 	ConditionReasonUpgradeUnsupported                    ConditionReason = "UpgradeUnsupported"
 	ConditionReasonComponentCreationFailed               ConditionReason = "ComponentCreationFailed"
 	ConditionReasonRouteComponentDependencyError         ConditionReason = "RouteComponentDependencyError"
 	ConditionReasonObjectStorageComponentDependencyError ConditionReason = "ObjectStorageComponentDependencyError"
 	ConditionReasonMonitoringComponentDependencyError    ConditionReason = "MonitoringComponentDependencyError"
+	ConditionReasonGatewayComponentDependencyError       ConditionReason = "GatewayComponentDependencyError"
+	ConditionReasonBuilderComponentDependencyError       ConditionReason = "BuilderComponentDependencyError"
+	ConditionReasonTLSComponentDependencyError           ConditionReason = "TLSComponentDependencyError"
+	ConditionReasonDatabaseComponentDependencyError      ConditionReason = "DatabaseComponentDependencyError"
 	ConditionReasonConfigInvalid                         ConditionReason = "ConfigInvalid"
 	ConditionReasonComponentOverrideInvalid              ConditionReason = "ComponentOverrideInvalid"
 	ConditionReasonPVCPending                            ConditionReason = "PVCPending"
@@ -257,14 +649,150 @@ type Condition struct {
 type QuayRegistryStatus struct {
 	// CurrentVersion is the actual version of Quay that is actively deployed.
 	CurrentVersion QuayVersion `json:"currentVersion,omitempty"`
+	// UpgradePreflightVersion is the version of Quay the registry last passed the upgrade
+	// preflight checks for.
+	UpgradePreflightVersion QuayVersion `json:"upgradePreflightVersion,omitempty"`
 	// RegistryEndpoint is the external access point for the Quay registry.
 	RegistryEndpoint string `json:"registryEndpoint,omitempty"`
+	// TLSCertificateExpiry is the expiration time of the certificate served for the registry
+	// endpoint. It is only reported when the certificate is available to the Operator.
+	TLSCertificateExpiry *metav1.Time `json:"tlsCertificateExpiry,omitempty"`
 	// LastUpdate is the timestamp when the Operator last processed this instance.
 	LastUpdate string `json:"lastUpdated,omitempty"`
 	// Conditions represent the conditions that a QuayRegistry can have.
 	Conditions []Condition `json:"conditions,omitempty"`
 	// ObservedGeneration is the most recent generation observed by the controller.
 	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Components holds the resolved set of components, including the ones not declared
+	// in spec.components and therefore defaulted by the Operator.
+	Components []ComponentStatus `json:"components,omitempty"`
+	// PostgresUpgradeBackups holds the backups taken of the managed databases before they
+	// were upgraded. They are kept until acknowledged in spec.postgresUpgrade.
+	PostgresUpgradeBackups []PostgresUpgradeBackup `json:"postgresUpgradeBackups,omitempty"`
+	// DatabaseVersions holds the major version of Postgres of the data of the managed
+	// databases, as read from their volumes.
+	DatabaseVersions []DatabaseVersion `json:"databaseVersions,omitempty"`
+	// PostgresUpgrades records the steps taken to upgrade the managed databases along their
+	// upgrade path.
+	PostgresUpgrades []PostgresUpgradeStep `json:"postgresUpgrades,omitempty"`
+	// UpgradeRollback records the dump taken of the managed database before the migrations
+	// of the last upgrade of Quay, and the rollback to it if they failed.
+	UpgradeRollback *UpgradeRollbackStatus `json:"upgradeRollback,omitempty"`
+}
+
+// UpgradeRollbackPhase is the stage the rollback of an upgrade of Quay is in.
+type UpgradeRollbackPhase string
+
+// Below follow the phases of the rollback of an upgrade. Ready means the dump has been taken
+// and the migrations may run, RolledBack and Failed are final.
+const (
+	UpgradeRollbackPhaseReady       UpgradeRollbackPhase = "Ready"
+	UpgradeRollbackPhaseRollingBack UpgradeRollbackPhase = "RollingBack"
+	UpgradeRollbackPhaseRolledBack  UpgradeRollbackPhase = "RolledBack"
+	UpgradeRollbackPhaseFailed      UpgradeRollbackPhase = "Failed"
+)
+
+// UpgradeRollbackStatus is the point an upgrade of Quay is rolled back to.
+type UpgradeRollbackStatus struct {
+	// Version is the version of Quay upgraded from.
+	Version QuayVersion `json:"version"`
+	// Image is the image of Quay of the version upgraded from.
+	Image string `json:"image"`
+	// TargetVersion is the version of Quay upgraded to.
+	TargetVersion QuayVersion `json:"targetVersion"`
+	// Backup is the PersistentVolumeClaim holding the dump of the database.
+	Backup string `json:"backup"`
+	// Phase is the stage the rollback is in.
+	Phase UpgradeRollbackPhase `json:"phase"`
+	// Message describes why the migrations failed.
+	Message string `json:"message,omitempty"`
+	// CompletionTime is when the rollback completed or failed.
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+	// Generation is the generation of the QuayRegistry when the rollback completed or failed.
+	// It is retried on the next change of the spec naming it in upgradeRollback.retryVersion.
+	Generation int64 `json:"generation,omitempty"`
+}
+
+// DatabaseVersion is the major version of Postgres of the data of a managed database.
+type DatabaseVersion struct {
+	// Component is the database component.
+	Component ComponentKind `json:"component"`
+	// Version is the major version of Postgres, as read from the PG_VERSION of the volume.
+	Version int32 `json:"version"`
+	// PersistentVolumeClaim is the volume holding the data.
+	PersistentVolumeClaim string `json:"persistentVolumeClaim"`
+}
+
+// PostgresUpgradePhase is the stage a step of a database upgrade is in.
+type PostgresUpgradePhase string
+
+const (
+	PostgresUpgradePhaseRunning   PostgresUpgradePhase = "Running"
+	PostgresUpgradePhaseSucceeded PostgresUpgradePhase = "Succeeded"
+	PostgresUpgradePhaseFailed    PostgresUpgradePhase = "Failed"
+)
+
+// PostgresUpgradeStep is an upgrade of a managed database from a major version of Postgres
+// to the next one on its upgrade path.
+type PostgresUpgradeStep struct {
+	// Component is the database component upgraded.
+	Component ComponentKind `json:"component"`
+	// From is the major version of Postgres upgraded from.
+	From int32 `json:"from"`
+	// To is the major version of Postgres upgraded to.
+	To int32 `json:"to"`
+	// Phase is the stage the step is in.
+	Phase PostgresUpgradePhase `json:"phase"`
+	// Message describes why the step failed.
+	Message string `json:"message,omitempty"`
+	// StartTime is when the step started.
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	// CompletionTime is when the step succeeded or failed.
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+}
+
+// PostgresUpgradeBackup is a backup taken of a managed database before it was upgraded to a
+// new major version of Postgres.
+type PostgresUpgradeBackup struct {
+	// Component is the database component the backup was taken of.
+	Component ComponentKind `json:"component"`
+	// Method is how the backup was taken.
+	Method PreUpgradeBackupMethod `json:"method"`
+	// Name is the name of the VolumeSnapshot, or of the PersistentVolumeClaim holding the
+	// dump, of the database.
+	Name string `json:"name"`
+	// Version is the major version of Postgres of the database when it was backed up.
+	Version int32 `json:"version"`
+	// TargetVersion is the major version of Postgres the database was being upgraded to.
+	TargetVersion int32 `json:"targetVersion"`
+	// PersistentVolumeClaim is the volume of the database before the upgrade.
+	PersistentVolumeClaim string `json:"persistentVolumeClaim,omitempty"`
+	// CompletionTime is when the backup completed.
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+}
+
+type ComponentStatusReason string
+
+// Follow a list of reasons explaining how the management state of a component has been
+// resolved. These are reported through the QuayRegistry .status.components slice.
+const (
+	ComponentStatusReasonUserDefined    ComponentStatusReason = "UserDefined"
+	ComponentStatusReasonAlwaysManaged  ComponentStatusReason = "AlwaysManaged"
+	ComponentStatusReasonDefaulted      ComponentStatusReason = "Defaulted"
+	ComponentStatusReasonAPIUnavailable ComponentStatusReason = "APIUnavailable"
+	ComponentStatusReasonConfigProvided ComponentStatusReason = "ConfigProvided"
+)
+
+// ComponentStatus describes how the Operator resolved the management state of a component.
+type ComponentStatus struct {
+	// Kind is the unique name of this type of component.
+	Kind ComponentKind `json:"kind"`
+	// Managed indicates whether or not the Operator is responsible for the lifecycle of this component.
+	Managed bool `json:"managed"`
+	// Reason is a machine readable explanation of why the component is (un)managed.
+	Reason ComponentStatusReason `json:"reason,omitempty"`
+	// Message is a human readable explanation of why the component is (un)managed.
+	Message string `json:"message,omitempty"`
 }
 
 // GetCondition retrieves the condition with the matching type from the given list.
@@ -356,6 +884,173 @@ func PostgresUpgradeRunning(quay *QuayRegistry) bool {
 	return created.Reason == ConditionReasonPostgresUpgradeInProgress || created.Reason == ConditionReasonPostgresUpgradeFailed
 }
 
+// UpgradePreflightNeeded returns true if the provided QuayRegistry is about to be upgraded to
+// the current version of Quay and has not passed the upgrade preflight checks for it yet.
+func UpgradePreflightNeeded(quay *QuayRegistry) bool {
+	current := quay.Status.CurrentVersion
+	if current == "" || current == QuayVersionCurrent {
+		return false
+	}
+	return quay.Status.UpgradePreflightVersion != QuayVersionCurrent
+}
+
+// ReadOnlyUpgradeEnabled returns true if the provided QuayRegistry is being upgraded to the
+// current version of Quay with the ReadOnly strategy. New registries have no previous version
+// to keep serving and are always created with the Recreate strategy.
+func ReadOnlyUpgradeEnabled(quay *QuayRegistry) bool {
+	current := quay.Status.CurrentVersion
+	if current == "" || current == QuayVersionCurrent {
+		return false
+	}
+	return quay.Spec.UpgradeStrategy == UpgradeStrategyReadOnly
+}
+
+// UpgradeRollbackEnabled returns true if the provided QuayRegistry is being upgraded to the
+// current version of Quay and asks for the upgrade to be rolled back if its migrations fail.
+func UpgradeRollbackEnabled(quay *QuayRegistry) bool {
+	current := quay.Status.CurrentVersion
+	if current == "" || current == QuayVersionCurrent {
+		return false
+	}
+
+	policy := quay.Spec.UpgradeRollback
+	if policy == nil || !policy.OnMigrationFailure {
+		return false
+	}
+
+	components := ResolvedComponents(quay)
+	return ComponentIsManaged(components, ComponentPostgres) &&
+		!DatabaseClusterEnabled(quay, ComponentPostgres)
+}
+
+// UpgradeRollbackFor returns the rollback point of the upgrade of the provided QuayRegistry to
+// the current version of Quay reported in the status. Returns nil if there is none.
+func UpgradeRollbackFor(quay *QuayRegistry) *UpgradeRollbackStatus {
+	rollback := quay.Status.UpgradeRollback
+	if rollback == nil || rollback.TargetVersion != QuayVersionCurrent {
+		return nil
+	}
+	if rollback.Version != quay.Status.CurrentVersion {
+		return nil
+	}
+	return rollback
+}
+
+// UpgradeRollbackRetried returns true if the user asked for the finished rollback of the
+// upgrade of the provided QuayRegistry to be retried since it completed or failed.
+func UpgradeRollbackRetried(quay *QuayRegistry) bool {
+	rollback := UpgradeRollbackFor(quay)
+	if rollback == nil || quay.Spec.UpgradeRollback == nil {
+		return false
+	}
+
+	finished := rollback.Phase == UpgradeRollbackPhaseRolledBack ||
+		rollback.Phase == UpgradeRollbackPhaseFailed
+	return finished && quay.Generation != rollback.Generation &&
+		quay.Spec.UpgradeRollback.RetryVersion == rollback.TargetVersion
+}
+
+// UpgradeRollbackPointNeeded returns true if the managed database of the provided QuayRegistry
+// must be dumped before the migrations of its upgrade run. A rolled back upgrade is not
+// attempted again, and so needs no new dump, until the user asks for it to be retried.
+func UpgradeRollbackPointNeeded(quay *QuayRegistry) bool {
+	if !UpgradeRollbackEnabled(quay) {
+		return false
+	}
+
+	rollback := UpgradeRollbackFor(quay)
+	if rollback == nil {
+		return true
+	}
+	return rollback.Phase == UpgradeRollbackPhaseRolledBack && UpgradeRollbackRetried(quay)
+}
+
+// UpgradeRolledBack returns the rollback point the provided QuayRegistry has been rolled back
+// to, it keeps running the previous version of Quay until the upgrade is retried. Returns nil
+// if its upgrade has not been rolled back.
+func UpgradeRolledBack(quay *QuayRegistry) *UpgradeRollbackStatus {
+	if !UpgradeRollbackEnabled(quay) || UpgradeRollbackRetried(quay) {
+		return nil
+	}
+
+	rollback := UpgradeRollbackFor(quay)
+	if rollback == nil || rollback.Phase != UpgradeRollbackPhaseRolledBack {
+		return nil
+	}
+	return rollback
+}
+
+// UpgradeRollbackFailed returns true if the database of the provided QuayRegistry could not be
+// restored to roll back its failed upgrade. The registry is held until the rollback is retried.
+func UpgradeRollbackFailed(quay *QuayRegistry) bool {
+	if !UpgradeRollbackEnabled(quay) {
+		return false
+	}
+
+	rollback := UpgradeRollbackFor(quay)
+	return rollback != nil && rollback.Phase == UpgradeRollbackPhaseFailed
+}
+
+// UpgradeRollbackRunning returns true if the status of the provided QuayRegistry indicates
+// the managed database is being restored to roll back a failed upgrade.
+func UpgradeRollbackRunning(quay *QuayRegistry) bool {
+	created := GetCondition(quay.Status.Conditions, ConditionComponentsCreated)
+	if created == nil {
+		return false
+	}
+	return created.Reason == ConditionReasonUpgradeRollingBack
+}
+
+// UpgradeRollbackNameFor returns the name of the PersistentVolumeClaim, and of the Job writing
+// it, holding the dump the upgrades of the provided QuayRegistry are rolled back to.
+func UpgradeRollbackNameFor(quay *QuayRegistry) string {
+	return quay.GetName() + "-quay-database-rollback"
+}
+
+// MaintenanceEnabled returns true if the provided QuayRegistry is under maintenance, with Quay
+// running in read-only mode.
+func MaintenanceEnabled(quay *QuayRegistry) bool {
+	return quay.Spec.Maintenance != nil && quay.Spec.Maintenance.Mode == MaintenanceModeReadOnly
+}
+
+// ValidateQuayUpgrade returns an error if a registry running the provided version of Quay can
+// not be upgraded to the other provided version. Downgrades, upgrades to another major version
+// and upgrades across more than QuayUpgradeMaxMinorVersions minor versions are not supported.
+// Versions that are not semantic versions, as used by development builds, are not checked.
+func ValidateQuayUpgrade(from, to QuayVersion) error {
+	fromVersion, err := semver.NewVersion(string(from))
+	if err != nil {
+		return nil
+	}
+	toVersion, err := semver.NewVersion(string(to))
+	if err != nil {
+		return nil
+	}
+
+	switch {
+	case toVersion.LessThan(fromVersion):
+		return fmt.Errorf("downgrading quay from %s to %s is not supported", from, to)
+	case toVersion.Major() != fromVersion.Major():
+		return fmt.Errorf("upgrading quay from %s to %s is not supported", from, to)
+	case toVersion.Minor()-fromVersion.Minor() > QuayUpgradeMaxMinorVersions:
+		return fmt.Errorf(
+			"upgrading quay from %s to %s skips more than %d minor versions",
+			from, to, QuayUpgradeMaxMinorVersions,
+		)
+	}
+	return nil
+}
+
+// RestoreRunning returns true if the status for provided QuayRegistry indicates that its
+// managed database is being restored by a QuayRegistryRestore.
+func RestoreRunning(quay *QuayRegistry) bool {
+	created := GetCondition(quay.Status.Conditions, ConditionComponentsCreated)
+	if created == nil {
+		return false
+	}
+	return created.Reason == ConditionReasonRestoreInProgress
+}
+
 // FlaggedForDeletion returns a boolean indicating if provided QuayRegistry object has
 // been flagged for deletion.
 func FlaggedForDeletion(quay *QuayRegistry) bool {
@@ -372,7 +1067,11 @@ func NeedsBundleSecret(quay *QuayRegistry) bool {
 // being Managed AND containing a custom user config provided through the config bundle
 // secret.
 func ComponentSupportsConfigWhenManaged(cmp Component) bool {
-	return cmp.Kind == ComponentRoute || cmp.Kind == ComponentMirror || cmp.Kind == ComponentRedis
+	return cmp.Kind == ComponentRoute ||
+		cmp.Kind == ComponentMirror ||
+		cmp.Kind == ComponentRedis ||
+		cmp.Kind == ComponentIngress ||
+		cmp.Kind == ComponentGateway
 }
 
 func EnsureComponents(components []Component) []Component {
@@ -425,6 +1124,47 @@ func GetTLSSecretRef(components []Component) *corev1.LocalObjectReference {
 	return nil
 }
 
+// TLSIssuedByCertManager returns true if the TLS component is managed and its certificate
+// is requested from a cert-manager issuer.
+func TLSIssuedByCertManager(quay *QuayRegistry) bool {
+	return ComponentIsManaged(quay.Spec.Components, ComponentTLS) &&
+		GetIssuerRefOverrideForComponent(quay, ComponentTLS) != nil
+}
+
+// TLSIssuedByOperator returns true if the TLS component is managed on a cluster without the
+// Route API and no issuer is referenced. In this case there are no router wildcard certs and
+// the operator issues the certificate from its own CA.
+func TLSIssuedByOperator(ctx *quaycontext.QuayRegistryContext, quay *QuayRegistry) bool {
+	return ComponentIsManaged(quay.Spec.Components, ComponentTLS) &&
+		!TLSIssuedByCertManager(quay) && !ctx.SupportsRoutes
+}
+
+// QuayServesTLS returns true if the Quay pods terminate TLS themselves. This is the case when
+// the TLS component is unmanaged or when its certificate is issued by cert-manager or by the
+// operator, otherwise TLS is terminated at the edge using the cluster wildcard certs.
+func QuayServesTLS(ctx *quaycontext.QuayRegistryContext, quay *QuayRegistry) bool {
+	return !ComponentIsManaged(quay.Spec.Components, ComponentTLS) ||
+		TLSIssuedByCertManager(quay) || TLSIssuedByOperator(ctx, quay)
+}
+
+// OperatorTLSSecretNameFor returns the name of the `Secret` in which the CA and the serving
+// certificate issued by the operator are stored.
+func OperatorTLSSecretNameFor(quay *QuayRegistry) string {
+	return strings.Join([]string{quay.GetName(), OperatorTLSSecretName}, "-")
+}
+
+// OperatorCAConfigMapNameFor returns the name of the `ConfigMap` in which the CA generated by
+// the operator is published.
+func OperatorCAConfigMapNameFor(quay *QuayRegistry) string {
+	return strings.Join([]string{quay.GetName(), OperatorCAConfigMapName}, "-")
+}
+
+// CertManagerTLSSecretName returns the name of the cert-manager Certificate rendered for the
+// provided QuayRegistry, the issued certificate is stored in a secret with the same name.
+func CertManagerTLSSecretName(quay *QuayRegistry) string {
+	return quay.GetName() + "-quay-app-tls"
+}
+
 func ComponentIsExplicitlyDefined(components []Component, name ComponentKind) bool {
 	for _, c := range components {
 		if c.Kind == name {
@@ -445,26 +1185,43 @@ func RequiredComponent(component ComponentKind) bool {
 }
 
 // EnsureDefaultComponents adds any `Components` which are missing from `Spec.Components`.
-// Returns an error if a component was declared as managed but is not supported in the current
-// k8s cluster.
+// The resolved set of components, along with the reason each one is (un)managed, is kept
+// in `Status.Components`. This function only changes the in memory object, the defaults
+// are never written back into the user provided spec. Returns an error if a component was
+// declared as managed but is not supported in the current k8s cluster.
 func EnsureDefaultComponents(ctx *quaycontext.QuayRegistryContext, quay *QuayRegistry) error {
 	if quay.Spec.Components == nil {
 		quay.Spec.Components = []Component{}
 	}
 
 	type check struct {
-		check func() bool
-		msg   string
+		check  func() bool
+		msg    string
+		reason ComponentStatusReason
+	}
+
+	// certificates issued by cert-manager do not depend on the router wildcard certs. when
+	// the Route API is not available a managed tls component is served with certificates
+	// issued by the operator, tls is only managed by default if the Route API is available.
+	tlscheck := check{
+		check: func() bool {
+			return ctx.SupportsRoutes || ComponentIsManaged(quay.Spec.Components, ComponentTLS)
+		},
+		msg: "Route API not available",
 	}
+	if GetIssuerRefOverrideForComponent(quay, ComponentTLS) != nil {
+		tlscheck = check{
+			check: func() bool { return ctx.SupportsCertManager },
+			msg:   "cert-manager API not available",
+		}
+	}
+
 	checks := map[ComponentKind]check{
 		ComponentRoute: {
 			check: func() bool { return ctx.SupportsRoutes },
 			msg:   "Route API not available",
 		},
-		ComponentTLS: {
-			check: func() bool { return ctx.SupportsRoutes },
-			msg:   "Route API not available",
-		},
+		ComponentTLS: tlscheck,
 		ComponentObjectStorage: {
 			check: func() bool { return ctx.SupportsObjectStorage },
 			msg:   "ObjectStorage API not available",
@@ -473,14 +1230,41 @@ func EnsureDefaultComponents(ctx *quaycontext.QuayRegistryContext, quay *QuayReg
 			check: func() bool { return ctx.SupportsMonitoring },
 			msg:   "Prometheus API not available",
 		},
+		ComponentGateway: {
+			check: func() bool { return ctx.SupportsGatewayAPI },
+			msg:   "Gateway API not available",
+		},
 	}
 
 	componentManaged := map[ComponentKind]check{
 		ComponentTLS: {
-			check: func() bool { return ctx.TLSCert == nil && ctx.TLSKey == nil },
+			check:  func() bool { return ctx.TLSCert == nil && ctx.TLSKey == nil },
+			msg:    "custom TLS certificate provided",
+			reason: ComponentStatusReasonConfigProvided,
+		},
+		// ingress is opt-in, on OpenShift clusters external access is provided
+		// by the route component.
+		ComponentIngress: {
+			check:  func() bool { return false },
+			msg:    "ingress must be explicitly enabled",
+			reason: ComponentStatusReasonDefaulted,
+		},
+		// gateway is opt-in as the routes must be attached to a user provided Gateway.
+		ComponentGateway: {
+			check:  func() bool { return false },
+			msg:    "gateway must be explicitly enabled",
+			reason: ComponentStatusReasonDefaulted,
+		},
+		// builder is opt-in as builds run in a dedicated namespace created by the
+		// operator.
+		ComponentBuilder: {
+			check:  func() bool { return false },
+			msg:    "builder must be explicitly enabled",
+			reason: ComponentStatusReasonDefaulted,
 		},
 	}
 
+	statuses := []ComponentStatus{}
 	for _, cmp := range AllComponents {
 		ccheck, checkexists := checks[cmp]
 		if checkexists {
@@ -502,13 +1286,24 @@ func EnsureDefaultComponents(ctx *quaycontext.QuayRegistryContext, quay *QuayReg
 				continue
 			}
 
+			status := ComponentStatus{
+				Kind:    cmp,
+				Managed: declaredComponent.Managed,
+				Reason:  ComponentStatusReasonUserDefined,
+				Message: "declared in spec.components",
+			}
+
 			// we disregard whatever the user has defined for Quay component, this
 			// is a component that can't be unmanaged so if user sets it to unmanaged
 			// we are going to roll it back to managed.
 			if declaredComponent.Kind == ComponentQuay {
 				quay.Spec.Components[i].Managed = true
+				status.Managed = true
+				status.Reason = ComponentStatusReasonAlwaysManaged
+				status.Message = "quay component is always managed"
 			}
 
+			statuses = append(statuses, status)
 			found = true
 			break
 		}
@@ -518,112 +1313,328 @@ func EnsureDefaultComponents(ctx *quaycontext.QuayRegistryContext, quay *QuayReg
 
 		// the component management status is set to true if the check for the component
 		// has passed.
-		managed := !checkexists || ccheck.check()
-		if _, ok := componentManaged[cmp]; ok {
-			managed = managed && componentManaged[cmp].check()
+		status := ComponentStatus{
+			Kind:    cmp,
+			Managed: true,
+			Reason:  ComponentStatusReasonDefaulted,
+			Message: "defaulted to managed",
+		}
+		if cmp == ComponentQuay {
+			status.Reason = ComponentStatusReasonAlwaysManaged
+			status.Message = "quay component is always managed"
+		}
+
+		if checkexists && !ccheck.check() {
+			status.Managed = false
+			status.Reason = ComponentStatusReasonAPIUnavailable
+			status.Message = ccheck.msg
+		} else if mcheck, ok := componentManaged[cmp]; ok && !mcheck.check() {
+			status.Managed = false
+			status.Reason = mcheck.reason
+			status.Message = mcheck.msg
 		}
 
 		quay.Spec.Components = append(
 			quay.Spec.Components,
 			Component{
 				Kind:    cmp,
-				Managed: managed,
+				Managed: status.Managed,
 			},
 		)
+		statuses = append(statuses, status)
 	}
 
+	quay.Status.Components = statuses
 	return nil
 }
 
-// hasAffinity checks if any anti/affinity option has been changed
-// returns true if something has changed, false otherwise
-func hasAffinity(component Component) bool {
-	overrideAffinity := component.Overrides.Affinity
-	return overrideAffinity != nil
-}
-
-// ValidateOverrides validates that the overrides set for each component are valid.
-func ValidateOverrides(quay *QuayRegistry) error {
-	for _, component := range quay.Spec.Components {
-
-		// No overrides provided
-		if component.Overrides == nil {
+// ResolvedComponents returns the components declared in the spec of provided QuayRegistry
+// followed by the ones defaulted by the Operator, as published in `Status.Components`.
+func ResolvedComponents(quay *QuayRegistry) []Component {
+	components := append([]Component{}, quay.Spec.Components...)
+	for _, status := range quay.Status.Components {
+		if ComponentIsExplicitlyDefined(components, status.Kind) {
 			continue
 		}
 
-		hasaffinity := hasAffinity(component)
-		hasvolume := component.Overrides.VolumeSize != nil
-		hasstorageclass := component.Overrides.StorageClassName != nil
-		hasreplicas := component.Overrides.Replicas != nil
-		hasresources := component.Overrides.Resources != nil
-		hassecuritycontext := component.Overrides.SecurityContext != nil
-		hasenvvar := len(component.Overrides.Env) > 0
-		hasoverride := hasaffinity || hasvolume || hasstorageclass || hasenvvar || hasreplicas || hasresources || hassecuritycontext
-
-		if hasoverride && !ComponentIsManaged(quay.Spec.Components, component.Kind) {
-			return fmt.Errorf("cannot set overrides on unmanaged %s", component.Kind)
-		}
-
-		if hasreplicas && ComponentIsManaged(quay.Spec.Components, ComponentHPA) {
-			// with managed HPA we only accept zero as an override for the number
-			// of replicas. we can't compete with HPA except when scaling down.
-			if *component.Overrides.Replicas != 0 {
-				return fmt.Errorf("cannot override replicas with managed HPA")
-			}
-		}
-
-		// Check that component supports override
-		if hasaffinity && !ComponentSupportsOverride(component.Kind, "affinity") {
-			return fmt.Errorf(
-				"component %s does not support affinity overrides",
-				component.Kind,
-			)
-		}
+		components = append(
+			components,
+			Component{
+				Kind:    status.Kind,
+				Managed: status.Managed,
+			},
+		)
+	}
+	return components
+}
 
-		if hasvolume && !ComponentSupportsOverride(component.Kind, "volumeSize") {
-			return fmt.Errorf(
-				"component %s does not support volumeSize overrides",
-				component.Kind,
+// setOverrides returns the names, as understood by ComponentSupportsOverride, of the
+// overrides populated in provided Override. Labels and annotations are supported by all
+// components and are therefore not returned.
+func setOverrides(overrides *Override) []string {
+	var names []string
+	if overrides.Affinity != nil {
+		names = append(names, "affinity")
+	}
+	if overrides.VolumeSize != nil {
+		names = append(names, "volumeSize")
+	}
+	if overrides.StorageClassName != nil {
+		names = append(names, "storageClassName")
+	}
+	if len(overrides.Env) > 0 {
+		names = append(names, "env")
+	}
+	if overrides.Replicas != nil {
+		names = append(names, "replicas")
+	}
+	if overrides.Resources != nil {
+		names = append(names, "resources")
+	}
+	if overrides.SecurityContext != nil {
+		names = append(names, "securityContext")
+	}
+	if overrides.IngressClassName != nil {
+		names = append(names, "ingressClassName")
+	}
+	if overrides.ParentRef != nil {
+		names = append(names, "parentRef")
+	}
+	if overrides.IssuerRef != nil {
+		names = append(names, "issuerRef")
+	}
+	if overrides.TLS != nil {
+		names = append(names, "tls")
+	}
+	if overrides.HighAvailability != nil {
+		names = append(names, "highAvailability")
+	}
+	if overrides.Backend != nil {
+		names = append(names, "backend")
+	}
+	if overrides.Backup != nil {
+		names = append(names, "backup")
+	}
+	if overrides.Snapshot != nil {
+		names = append(names, "snapshot")
+	}
+	if overrides.DataSource != nil {
+		names = append(names, "dataSource")
+	}
+	if overrides.Image != "" {
+		names = append(names, "image")
+	}
+	if len(overrides.ImagePullSecrets) > 0 {
+		names = append(names, "imagePullSecrets")
+	}
+	if len(overrides.Tolerations) > 0 {
+		names = append(names, "tolerations")
+	}
+	if len(overrides.NodeSelector) > 0 {
+		names = append(names, "nodeSelector")
+	}
+	if len(overrides.TopologySpreadConstraints) > 0 {
+		names = append(names, "topologySpreadConstraints")
+	}
+	if overrides.PriorityClassName != nil {
+		names = append(names, "priorityClassName")
+	}
+	if overrides.PodDisruptionBudget != nil {
+		names = append(names, "podDisruptionBudget")
+	}
+	return names
+}
+
+// ValidateOverrides validates that the overrides set for each component are valid, along with
+// the policies and job overrides set on the provided QuayRegistry. Returns a list of field
+// level errors, the same checks are run by the reconciler and by the admission webhook.
+func ValidateOverrides(quay *QuayRegistry) field.ErrorList {
+	var errs field.ErrorList
+
+	specPath := field.NewPath("spec")
+	componentsPath := specPath.Child("components")
+
+	// when HPA is not declared it is going to be defaulted to managed.
+	managedhpa := !ComponentIsExplicitlyDefined(quay.Spec.Components, ComponentHPA) ||
+		ComponentIsManaged(quay.Spec.Components, ComponentHPA)
+
+	// routes rendered by the gateway component can't be attached without a Gateway.
+	for i, component := range quay.Spec.Components {
+		if component.Kind != ComponentGateway || !component.Managed {
+			continue
+		}
+		if component.Overrides == nil || component.Overrides.ParentRef == nil {
+			errs = append(
+				errs,
+				field.Required(
+					componentsPath.Index(i).Child("overrides", "parentRef"),
+					"component gateway requires a parentRef override",
+				),
 			)
 		}
+	}
 
-		if hasstorageclass && !ComponentSupportsOverride(component.Kind, "storageClassName") {
-			return fmt.Errorf(
-				"component %s does not support storageClassName overrides",
-				component.Kind,
-			)
+	for i, component := range quay.Spec.Components {
+		overridesPath := componentsPath.Index(i).Child("overrides")
+
+		// No overrides provided
+		if component.Overrides == nil {
+			continue
 		}
 
-		if hasenvvar && !ComponentSupportsOverride(component.Kind, "env") {
-			return fmt.Errorf(
-				"component %s does not support env overrides",
-				component.Kind,
+		overrides := setOverrides(component.Overrides)
+		if len(overrides) > 0 && !ComponentIsManaged(quay.Spec.Components, component.Kind) {
+			errs = append(
+				errs,
+				field.Forbidden(
+					overridesPath,
+					fmt.Sprintf("cannot set overrides on unmanaged %s", component.Kind),
+				),
 			)
+			continue
 		}
 
-		if hasreplicas && !ComponentSupportsOverride(component.Kind, "replicas") {
-			return fmt.Errorf(
-				"component %s does not support replicas overrides",
-				component.Kind,
+		if err := validateDatabaseBackend(component); err != nil {
+			errs = append(
+				errs,
+				field.Invalid(overridesPath.Child("backend"), *component.Overrides.Backend, err.Error()),
+			)
+		}
+		if err := validateDatabaseBackup(quay, component); err != nil {
+			errs = append(
+				errs,
+				field.Invalid(overridesPath.Child("backup"), component.Overrides.Backup.Schedule, err.Error()),
+			)
+		}
+		if err := validateDatabaseSnapshot(quay, component); err != nil {
+			errs = append(
+				errs,
+				field.Invalid(overridesPath.Child("snapshot"), component.Overrides.Snapshot.Schedule, err.Error()),
+			)
+		}
+		if err := validateDataSource(quay, component); err != nil {
+			errs = append(
+				errs,
+				field.Invalid(overridesPath.Child("dataSource"), component.Overrides.DataSource.Name, err.Error()),
+			)
+		}
+		if err := validateImage(component); err != nil {
+			errs = append(
+				errs,
+				field.Invalid(overridesPath.Child("image"), component.Overrides.Image, err.Error()),
+			)
+		}
+		if err := validateTolerations(component); err != nil {
+			errs = append(
+				errs,
+				field.Invalid(overridesPath.Child("tolerations"), component.Overrides.Tolerations, err.Error()),
+			)
+		}
+		if err := validateTopologySpreadConstraints(component); err != nil {
+			errs = append(
+				errs,
+				field.Invalid(
+					overridesPath.Child("topologySpreadConstraints"),
+					component.Overrides.TopologySpreadConstraints,
+					err.Error(),
+				),
+			)
+		}
+		if err := validatePodDisruptionBudget(quay, component); err != nil {
+			errs = append(
+				errs,
+				field.Invalid(
+					overridesPath.Child("podDisruptionBudget"),
+					component.Overrides.PodDisruptionBudget,
+					err.Error(),
+				),
 			)
 		}
 
-		if hasresources && !ComponentSupportsOverride(component.Kind, "resources") {
-			return fmt.Errorf(
-				"component %s does not support resources overrides",
-				component.Kind,
+		replicas := component.Overrides.Replicas
+		isdb := component.Kind == ComponentPostgres || component.Kind == ComponentClairPostgres
+		if replicas != nil && component.Kind == ComponentRedis {
+			// redis is not scaled by HPA, replicas only make sense with sentinel.
+			if err := validateRedisReplicas(component.Overrides); err != nil {
+				errs = append(
+					errs,
+					field.Invalid(overridesPath.Child("replicas"), *replicas, err.Error()),
+				)
+			}
+		} else if replicas != nil && isdb {
+			// database instances can only be scaled by cloudnativepg.
+			if err := validateDatabaseReplicas(component); err != nil {
+				errs = append(
+					errs,
+					field.Invalid(overridesPath.Child("replicas"), *replicas, err.Error()),
+				)
+			}
+		} else if replicas != nil && *replicas != 0 && managedhpa {
+			// with managed HPA we only accept zero as an override for the number
+			// of replicas. we can't compete with HPA except when scaling down.
+			errs = append(
+				errs,
+				field.Invalid(
+					overridesPath.Child("replicas"),
+					*replicas,
+					"cannot override replicas with managed HPA",
+				),
 			)
 		}
 
-		if hassecuritycontext && !ComponentSupportsOverride(component.Kind, "securityContext") {
-			return fmt.Errorf(
-				"component %s does not support securityContext overrides",
-				component.Kind,
+		// Check that component supports override
+		for _, override := range overrides {
+			if ComponentSupportsOverride(component.Kind, override) {
+				continue
+			}
+			errs = append(
+				errs,
+				field.Forbidden(
+					overridesPath.Child(override),
+					fmt.Sprintf("component %s does not support %s overrides", component.Kind, override),
+				),
 			)
 		}
 	}
 
-	return nil
+	if err := validatePostgresUpgradePolicy(quay); err != nil {
+		errs = append(
+			errs,
+			field.Invalid(
+				specPath.Child("postgresUpgrade", "volumeSnapshotClassName"),
+				quay.Spec.PostgresUpgrade.VolumeSnapshotClassName,
+				err.Error(),
+			),
+		)
+	}
+
+	seenjobs := map[string]bool{}
+	for i, job := range quay.Spec.JobOverrides {
+		jobPath := specPath.Child("jobOverrides").Index(i).Child("name")
+		if !slices.Contains(overridableJobs, job.Name) {
+			errs = append(errs, field.NotSupported(jobPath, job.Name, overridableJobs))
+			continue
+		}
+
+		if seenjobs[job.Name] {
+			errs = append(errs, field.Duplicate(jobPath, job.Name))
+			continue
+		}
+		seenjobs[job.Name] = true
+	}
+
+	if err := validateUpgradeRollbackPolicy(quay); err != nil {
+		errs = append(
+			errs,
+			field.Invalid(
+				specPath.Child("upgradeRollback", "onMigrationFailure"),
+				quay.Spec.UpgradeRollback.OnMigrationFailure,
+				err.Error(),
+			),
+		)
+	}
+
+	return errs
 }
 
 // EnsureRegistryEndpoint sets the `status.registryEndpoint` field and returns `ok` if it was
@@ -638,6 +1649,9 @@ func EnsureRegistryEndpoint(
 
 	if serverHostname, ok := config["SERVER_HOSTNAME"]; ok {
 		quay.Status.RegistryEndpoint = "https://" + serverHostname.(string)
+	} else if exposed := ComponentIsManaged(quay.Spec.Components, ComponentIngress) ||
+		ComponentIsManaged(quay.Spec.Components, ComponentGateway); exposed && qctx.ServerHostname != "" {
+		quay.Status.RegistryEndpoint = "https://" + qctx.ServerHostname
 	} else if qctx.SupportsRoutes {
 		quay.Status.RegistryEndpoint = fmt.Sprintf(
 			"https://%s-quay-%s.%s",
@@ -750,6 +1764,12 @@ func FieldGroupNameFor(cmp ComponentKind) (string, error) {
 		return "DistributedStorage", nil
 	case ComponentRoute:
 		return "HostSettings", nil
+	case ComponentIngress:
+		return "HostSettings", nil
+	case ComponentGateway:
+		return "HostSettings", nil
+	case ComponentBuilder:
+		return "BuildManager", nil
 	case ComponentMirror:
 		return "RepoMirror", nil
 	case ComponentHPA:
@@ -778,7 +1798,8 @@ func FieldGroupNamesForManagedComponents(quay *QuayRegistry) ([]string, error) {
 			return nil, err
 		}
 
-		if len(fgn) == 0 {
+		// route, ingress and gateway share the same field group.
+		if len(fgn) == 0 || slices.Contains(fgns, fgn) {
 			continue
 		}
 
@@ -806,6 +1827,30 @@ func ComponentSupportsOverride(component ComponentKind, override string) bool {
 		components = supportsResourceOverrides
 	case "securityContext":
 		components = supportsSecurityContextOverride
+	case "ingressClassName":
+		components = supportsIngressClassNameOverride
+	case "parentRef":
+		components = supportsParentRefOverride
+	case "issuerRef":
+		components = supportsIssuerRefOverride
+	case "tls":
+		components = supportsTLSOverride
+	case "highAvailability":
+		components = supportsHighAvailabilityOverride
+	case "backend":
+		components = supportsBackendOverride
+	case "backup":
+		components = supportsBackupOverride
+	case "snapshot":
+		components = supportsSnapshotOverride
+	case "dataSource":
+		components = supportsDataSourceOverride
+	case "image", "imagePullSecrets":
+		components = supportsImageOverride
+	case "tolerations", "nodeSelector", "topologySpreadConstraints", "priorityClassName":
+		components = supportsSchedulingOverride
+	case "podDisruptionBudget":
+		components = supportsPodDisruptionBudgetOverride
 	}
 
 	for _, cmp := range components {
@@ -820,6 +1865,24 @@ func init() {
 	SchemeBuilder.Register(&QuayRegistry{}, &QuayRegistryList{})
 }
 
+// overridableJobs are the names of the Jobs whose spec can be overridden.
+var overridableJobs = []string{
+	QuayUpgradeJobName,
+	PostgresUpgradeJobName,
+	ClairPostgresUpgradeJobName,
+}
+
+// GetJobOverride returns the overrides set by the user for the Job with the provided name,
+// nil if the Job is not overridden.
+func GetJobOverride(quay *QuayRegistry, name string) *JobOverride {
+	for i := range quay.Spec.JobOverrides {
+		if quay.Spec.JobOverrides[i].Name == name {
+			return &quay.Spec.JobOverrides[i]
+		}
+	}
+	return nil
+}
+
 // GetReplicasOverrideForComponent returns the overrides set by the user for the provided
 // component. Returns nil if not set.
 func GetReplicasOverrideForComponent(quay *QuayRegistry, kind ComponentKind) *int32 {
@@ -894,6 +1957,776 @@ func GetSecurityContextOverrideForComponent(quay *QuayRegistry, kind ComponentKi
 	return nil
 }
 
+// GetIngressClassNameOverrideForComponent returns the IngressClass override for a given
+// component kind, nil is returned if not set.
+func GetIngressClassNameOverrideForComponent(quay *QuayRegistry, kind ComponentKind) *string {
+	for _, cmp := range quay.Spec.Components {
+		if cmp.Kind != kind {
+			continue
+		}
+		if cmp.Overrides == nil {
+			return nil
+		}
+		return cmp.Overrides.IngressClassName
+	}
+	return nil
+}
+
+// GetParentRefOverrideForComponent returns the Gateway reference override for a given
+// component kind, nil is returned if not set.
+func GetParentRefOverrideForComponent(
+	quay *QuayRegistry, kind ComponentKind,
+) *GatewayParentReference {
+	for _, cmp := range quay.Spec.Components {
+		if cmp.Kind != kind {
+			continue
+		}
+		if cmp.Overrides == nil {
+			return nil
+		}
+		return cmp.Overrides.ParentRef
+	}
+	return nil
+}
+
+// GetIssuerRefOverrideForComponent returns the cert-manager issuer reference override for a
+// given component kind, nil is returned if not set.
+func GetIssuerRefOverrideForComponent(
+	quay *QuayRegistry, kind ComponentKind,
+) *CertificateIssuerReference {
+	for _, cmp := range quay.Spec.Components {
+		if cmp.Kind != kind {
+			continue
+		}
+		if cmp.Overrides == nil {
+			return nil
+		}
+		return cmp.Overrides.IssuerRef
+	}
+	return nil
+}
+
+// DatabaseTLSEnabled returns true if the provided database component is managed and TLS has
+// been enabled for it through the tls override.
+func DatabaseTLSEnabled(quay *QuayRegistry, kind ComponentKind) bool {
+	for _, cmp := range quay.Spec.Components {
+		if cmp.Kind != kind {
+			continue
+		}
+		if !cmp.Managed || cmp.Overrides == nil || cmp.Overrides.TLS == nil {
+			return false
+		}
+		return *cmp.Overrides.TLS
+	}
+	return false
+}
+
+// RedisHighAvailabilityEnabled returns true if redis is managed and deployed with sentinel
+// through the highAvailability override.
+func RedisHighAvailabilityEnabled(quay *QuayRegistry) bool {
+	for _, cmp := range quay.Spec.Components {
+		if cmp.Kind != ComponentRedis {
+			continue
+		}
+		if !cmp.Managed || cmp.Overrides == nil || cmp.Overrides.HighAvailability == nil {
+			return false
+		}
+		return *cmp.Overrides.HighAvailability
+	}
+	return false
+}
+
+// RedisReplicasFor returns the number of redis replicas deployed with high availability,
+// defaults to the minimum number of replicas.
+func RedisReplicasFor(quay *QuayRegistry) int32 {
+	for _, cmp := range quay.Spec.Components {
+		if cmp.Kind != ComponentRedis || cmp.Overrides == nil {
+			continue
+		}
+		if cmp.Overrides.Replicas != nil {
+			return *cmp.Overrides.Replicas
+		}
+	}
+	return MinRedisHighAvailabilityReplicas
+}
+
+// RedisSentinelQuorumFor returns the number of sentinels that need to agree a redis master
+// is down before a failover starts, a majority of the replicas.
+func RedisSentinelQuorumFor(quay *QuayRegistry) int32 {
+	return RedisReplicasFor(quay)/2 + 1
+}
+
+// validateRedisReplicas verifies a replicas override set for redis is only used with high
+// availability and leaves enough sentinels to reach a quorum.
+func validateRedisReplicas(overrides *Override) error {
+	if overrides.HighAvailability == nil || !*overrides.HighAvailability {
+		return fmt.Errorf("redis replicas can only be overridden with highAvailability")
+	}
+	if *overrides.Replicas < MinRedisHighAvailabilityReplicas {
+		return fmt.Errorf(
+			"redis requires at least %d replicas with highAvailability",
+			MinRedisHighAvailabilityReplicas,
+		)
+	}
+	return nil
+}
+
+// DatabaseClusterEnabled returns true if the provided database component is managed and run
+// by CloudNativePG through the backend override.
+func DatabaseClusterEnabled(quay *QuayRegistry, kind ComponentKind) bool {
+	for _, cmp := range quay.Spec.Components {
+		if cmp.Kind != kind {
+			continue
+		}
+		if !cmp.Managed || cmp.Overrides == nil || cmp.Overrides.Backend == nil {
+			return false
+		}
+		return *cmp.Overrides.Backend == DatabaseBackendCloudNativePG
+	}
+	return false
+}
+
+// DatabaseInstancesFor returns the number of instances of the provided database component
+// run by CloudNativePG.
+func DatabaseInstancesFor(quay *QuayRegistry, kind ComponentKind) int32 {
+	for _, cmp := range quay.Spec.Components {
+		if cmp.Kind != kind || cmp.Overrides == nil {
+			continue
+		}
+		if cmp.Overrides.Replicas != nil {
+			return *cmp.Overrides.Replicas
+		}
+	}
+	return DefaultDatabaseInstances
+}
+
+// DatabaseClusterNameFor returns the name of the CloudNativePG Cluster rendered for the
+// provided database component, it matches the name of the Deployment it replaces.
+func DatabaseClusterNameFor(quay *QuayRegistry, kind ComponentKind) string {
+	if kind == ComponentClairPostgres {
+		return quay.GetName() + "-clair-postgres"
+	}
+	return quay.GetName() + "-quay-database"
+}
+
+// DatabaseClusterAppSecretNameFor returns the name of the Secret holding the credentials of
+// the application user, generated by CloudNativePG for the provided database component.
+func DatabaseClusterAppSecretNameFor(quay *QuayRegistry, kind ComponentKind) string {
+	return DatabaseClusterNameFor(quay, kind) + "-app"
+}
+
+// validateDatabaseBackend verifies the overrides set along with the cloudnativepg backend are
+// supported by it.
+func validateDatabaseBackend(cmp Component) error {
+	backend := cmp.Overrides.Backend
+	if backend == nil || *backend != DatabaseBackendCloudNativePG {
+		return nil
+	}
+	if cmp.Overrides.TLS != nil && *cmp.Overrides.TLS {
+		return fmt.Errorf("component %s does not support tls overrides with cloudnativepg", cmp.Kind)
+	}
+	if len(cmp.Overrides.Env) > 0 {
+		return fmt.Errorf("component %s does not support env overrides with cloudnativepg", cmp.Kind)
+	}
+	return nil
+}
+
+// validateDatabaseReplicas verifies a replicas override set for a database is only used with
+// the cloudnativepg backend.
+func validateDatabaseReplicas(cmp Component) error {
+	backend := cmp.Overrides.Backend
+	if backend == nil || *backend != DatabaseBackendCloudNativePG {
+		return fmt.Errorf("%s replicas can only be overridden with the cloudnativepg backend", cmp.Kind)
+	}
+	if *cmp.Overrides.Replicas < 1 {
+		return fmt.Errorf("%s requires at least one instance", cmp.Kind)
+	}
+	return nil
+}
+
+// DatabaseBackupFor returns the backup configured for the provided managed database component,
+// nil if backups are not enabled.
+func DatabaseBackupFor(quay *QuayRegistry, kind ComponentKind) *DatabaseBackup {
+	for _, cmp := range quay.Spec.Components {
+		if cmp.Kind != kind {
+			continue
+		}
+		if !cmp.Managed || cmp.Overrides == nil {
+			return nil
+		}
+		return cmp.Overrides.Backup
+	}
+	return nil
+}
+
+// DatabaseBackupRetentionFor returns the number of backups kept for the provided database
+// component.
+func DatabaseBackupRetentionFor(quay *QuayRegistry, kind ComponentKind) int32 {
+	backup := DatabaseBackupFor(quay, kind)
+	if backup == nil || backup.Retention == nil {
+		return DefaultBackupRetention
+	}
+	return *backup.Retention
+}
+
+// DatabaseBackupDestinationFor returns where the backups of the provided database component
+// are written.
+func DatabaseBackupDestinationFor(quay *QuayRegistry, kind ComponentKind) BackupDestination {
+	backup := DatabaseBackupFor(quay, kind)
+	if backup == nil || backup.Destination == "" {
+		return BackupDestinationPersistentVolume
+	}
+	return backup.Destination
+}
+
+// DatabaseBackupNameFor returns the name of the CronJob, and of its volume, taking backups of
+// the provided database component.
+func DatabaseBackupNameFor(quay *QuayRegistry, kind ComponentKind) string {
+	if kind == ComponentClairPostgres {
+		return quay.GetName() + "-clair-postgres-backup"
+	}
+	return quay.GetName() + "-quay-database-backup"
+}
+
+// validateDatabaseBackup verifies the backup override of a database component. Databases run
+// by cloudnativepg are not backed up by the operator.
+func validateDatabaseBackup(quay *QuayRegistry, cmp Component) error {
+	backup := cmp.Overrides.Backup
+	if backup == nil {
+		return nil
+	}
+
+	if DatabaseClusterEnabled(quay, cmp.Kind) {
+		return fmt.Errorf("component %s does not support backup overrides with cloudnativepg", cmp.Kind)
+	}
+
+	if !validSchedule(backup.Schedule) {
+		return fmt.Errorf("invalid %s backup schedule %q", cmp.Kind, backup.Schedule)
+	}
+
+	if backup.Retention != nil && *backup.Retention < 1 {
+		return fmt.Errorf("%s backup retention must keep at least one backup", cmp.Kind)
+	}
+
+	if backup.Destination != BackupDestinationObjectStorage {
+		return nil
+	}
+	for _, other := range quay.Spec.Components {
+		if other.Kind == ComponentObjectStorage && !other.Managed {
+			return fmt.Errorf(
+				"%s backups to objectstorage require a managed objectstorage", cmp.Kind,
+			)
+		}
+	}
+	return nil
+}
+
+// validSchedule returns true if the provided string is a cron schedule, or one of the
+// predefined @ schedules, as accepted by CronJobs.
+func validSchedule(schedule string) bool {
+	fields := strings.Fields(schedule)
+	return len(fields) == 5 || (len(fields) == 1 && strings.HasPrefix(fields[0], "@"))
+}
+
+// DatabaseSnapshotFor returns the snapshot configuration of the provided managed database
+// component, nil if snapshots are not enabled.
+func DatabaseSnapshotFor(quay *QuayRegistry, kind ComponentKind) *DatabaseSnapshot {
+	for _, cmp := range quay.Spec.Components {
+		if cmp.Kind != kind {
+			continue
+		}
+		if !cmp.Managed || cmp.Overrides == nil {
+			return nil
+		}
+		return cmp.Overrides.Snapshot
+	}
+	return nil
+}
+
+// DatabaseSnapshotRetentionFor returns the number of snapshots kept for the provided database
+// component.
+func DatabaseSnapshotRetentionFor(quay *QuayRegistry, kind ComponentKind) int32 {
+	snapshot := DatabaseSnapshotFor(quay, kind)
+	if snapshot == nil || snapshot.Retention == nil {
+		return DefaultBackupRetention
+	}
+	return *snapshot.Retention
+}
+
+// DatabaseSnapshotNameFor returns the name of the CronJob taking snapshots of the volume of
+// the provided database component. Snapshots are named after it.
+func DatabaseSnapshotNameFor(quay *QuayRegistry, kind ComponentKind) string {
+	if kind == ComponentClairPostgres {
+		return quay.GetName() + "-clair-postgres-snapshot"
+	}
+	return quay.GetName() + "-quay-database-snapshot"
+}
+
+// DatabaseVolumeNameFor returns the name of the PersistentVolumeClaim holding the data of the
+// provided database component.
+func DatabaseVolumeNameFor(quay *QuayRegistry, kind ComponentKind) string {
+	return PostgresVolumeNameFor(quay, TargetPostgresVersionFor(kind))
+}
+
+// PostgresUpgradePathFor returns, oldest first, the major versions of Postgres the provided
+// database component is upgraded through.
+func PostgresUpgradePathFor(kind ComponentKind) []PostgresVersion {
+	return postgresUpgradePaths[kind]
+}
+
+// TargetPostgresVersionFor returns the major version of Postgres the provided database
+// component runs.
+func TargetPostgresVersionFor(kind ComponentKind) PostgresVersion {
+	path := postgresUpgradePaths[kind]
+	if len(path) == 0 {
+		return PostgresVersion{}
+	}
+	return path[len(path)-1]
+}
+
+// PostgresVersionFor returns the provided major version of Postgres from the upgrade path of
+// the provided database component. Returns false if it is not on the path.
+func PostgresVersionFor(kind ComponentKind, major int32) (PostgresVersion, bool) {
+	for _, version := range postgresUpgradePaths[kind] {
+		if version.Major == major {
+			return version, true
+		}
+	}
+	return PostgresVersion{}, false
+}
+
+// NextPostgresVersion returns the version the provided database component, on the provided
+// major version of Postgres, is upgraded to next. Returns false if it is on the last version
+// of its path, or not on the path at all.
+func NextPostgresVersion(kind ComponentKind, major int32) (PostgresVersion, bool) {
+	path := postgresUpgradePaths[kind]
+	for i, version := range path {
+		if version.Major == major && i+1 < len(path) {
+			return path[i+1], true
+		}
+	}
+	return PostgresVersion{}, false
+}
+
+// PostgresVolumeNameFor returns the name of the PersistentVolumeClaim holding the data of a
+// database on the provided version of Postgres.
+func PostgresVolumeNameFor(quay *QuayRegistry, version PostgresVersion) string {
+	return quay.GetName() + "-" + version.Volume
+}
+
+// DatabaseVersionFor returns the version of the data of the provided database component
+// reported in the status. Returns nil if it has not been read yet.
+func DatabaseVersionFor(quay *QuayRegistry, kind ComponentKind) *DatabaseVersion {
+	for i, version := range quay.Status.DatabaseVersions {
+		if version.Component == kind {
+			return &quay.Status.DatabaseVersions[i]
+		}
+	}
+	return nil
+}
+
+// SetDatabaseVersion records the version of the data of a database component in the status
+// of the provided QuayRegistry.
+func SetDatabaseVersion(quay *QuayRegistry, version DatabaseVersion) {
+	if current := DatabaseVersionFor(quay, version.Component); current != nil {
+		*current = version
+		return
+	}
+	quay.Status.DatabaseVersions = append(quay.Status.DatabaseVersions, version)
+}
+
+// PostgresUpgradeStepFor returns the last upgrade step taken by the provided database
+// component as reported in the status. Returns nil if there is none.
+func PostgresUpgradeStepFor(quay *QuayRegistry, kind ComponentKind) *PostgresUpgradeStep {
+	for i := len(quay.Status.PostgresUpgrades) - 1; i >= 0; i-- {
+		if quay.Status.PostgresUpgrades[i].Component == kind {
+			return &quay.Status.PostgresUpgrades[i]
+		}
+	}
+	return nil
+}
+
+// PostgresUpgradeVersionsFor returns the versions of Postgres, and the volume of the version
+// upgraded from, of the upgrade step the provided database component is going through. When
+// no step is running the upgrade from the version before the last one is returned.
+func PostgresUpgradeVersionsFor(
+	quay *QuayRegistry, kind ComponentKind,
+) (from PostgresVersion, to PostgresVersion, volume string) {
+	path := postgresUpgradePaths[kind]
+	if len(path) < 2 {
+		return
+	}
+	from, to = path[len(path)-2], path[len(path)-1]
+
+	step := PostgresUpgradeStepFor(quay, kind)
+	if step != nil && step.Phase != PostgresUpgradePhaseSucceeded {
+		if version, ok := PostgresVersionFor(kind, step.From); ok {
+			from = version
+		}
+		if version, ok := PostgresVersionFor(kind, step.To); ok {
+			to = version
+		}
+	}
+
+	volume = PostgresVolumeNameFor(quay, from)
+	if current := DatabaseVersionFor(quay, kind); current != nil && current.Version == from.Major {
+		volume = current.PersistentVolumeClaim
+	}
+	return from, to, volume
+}
+
+// GetDataSourceOverrideForComponent returns the data source the volume of the provided
+// database component is created from, with the API group defaulted. Returns nil if not set.
+func GetDataSourceOverrideForComponent(
+	quay *QuayRegistry, kind ComponentKind,
+) *corev1.TypedLocalObjectReference {
+	for _, cmp := range quay.Spec.Components {
+		if cmp.Kind != kind {
+			continue
+		}
+		if cmp.Overrides == nil || cmp.Overrides.DataSource == nil {
+			return nil
+		}
+		source := cmp.Overrides.DataSource.DeepCopy()
+		if source.APIGroup == nil {
+			group := VolumeSnapshotGroup
+			source.APIGroup = &group
+		}
+		return source
+	}
+	return nil
+}
+
+// validateDatabaseSnapshot verifies the snapshot override of a database component. The
+// volumes of databases run by CloudNativePG are managed by it.
+func validateDatabaseSnapshot(quay *QuayRegistry, cmp Component) error {
+	snapshot := cmp.Overrides.Snapshot
+	if snapshot == nil {
+		return nil
+	}
+
+	if DatabaseClusterEnabled(quay, cmp.Kind) {
+		return fmt.Errorf("component %s does not support snapshot overrides with cloudnativepg", cmp.Kind)
+	}
+
+	if snapshot.Schedule != "" && !validSchedule(snapshot.Schedule) {
+		return fmt.Errorf("invalid %s snapshot schedule %q", cmp.Kind, snapshot.Schedule)
+	}
+
+	if snapshot.Retention != nil && *snapshot.Retention < 1 {
+		return fmt.Errorf("%s snapshot retention must keep at least one snapshot", cmp.Kind)
+	}
+	return nil
+}
+
+// validateDataSource verifies the dataSource override of a database component references a
+// VolumeSnapshot.
+func validateDataSource(quay *QuayRegistry, cmp Component) error {
+	source := cmp.Overrides.DataSource
+	if source == nil {
+		return nil
+	}
+
+	if DatabaseClusterEnabled(quay, cmp.Kind) {
+		return fmt.Errorf("component %s does not support dataSource overrides with cloudnativepg", cmp.Kind)
+	}
+
+	if source.Kind != "VolumeSnapshot" || (source.APIGroup != nil && *source.APIGroup != VolumeSnapshotGroup) {
+		return fmt.Errorf("%s dataSource must reference a VolumeSnapshot", cmp.Kind)
+	}
+
+	if source.Name == "" {
+		return fmt.Errorf("%s dataSource must name a VolumeSnapshot", cmp.Kind)
+	}
+	return nil
+}
+
+// GetImageOverrideForComponent returns the reference of the image the provided component runs
+// as overridden by the user, an empty string if not set.
+func GetImageOverrideForComponent(quay *QuayRegistry, kind ComponentKind) string {
+	for _, cmp := range quay.Spec.Components {
+		if cmp.Kind != kind {
+			continue
+		}
+		if cmp.Overrides == nil {
+			return ""
+		}
+		return cmp.Overrides.Image
+	}
+	return ""
+}
+
+// GetImagePullSecretsOverrideForComponent returns the secrets the image of the provided
+// component is pulled with, nil if not set.
+func GetImagePullSecretsOverrideForComponent(
+	quay *QuayRegistry, kind ComponentKind,
+) []corev1.LocalObjectReference {
+	for _, cmp := range quay.Spec.Components {
+		if cmp.Kind != kind {
+			continue
+		}
+		if cmp.Overrides == nil {
+			return nil
+		}
+		return cmp.Overrides.ImagePullSecrets
+	}
+	return nil
+}
+
+// validateImage verifies the image override of a component is a valid reference by tag or
+// digest.
+func validateImage(cmp Component) error {
+	if cmp.Overrides.Image == "" {
+		return nil
+	}
+
+	ref, err := image.ParseReference(cmp.Overrides.Image)
+	if err != nil {
+		return fmt.Errorf("%s image override is invalid: %w", cmp.Kind, err)
+	}
+
+	if ref.Tag == "" && ref.Digest == "" {
+		return fmt.Errorf(
+			"%s image override must be referenced by tag or digest: %s", cmp.Kind, cmp.Overrides.Image,
+		)
+	}
+	return nil
+}
+
+// validateTolerations verifies the tolerations of a component would be accepted on its pods.
+func validateTolerations(cmp Component) error {
+	for _, toleration := range cmp.Overrides.Tolerations {
+		if toleration.Key == "" && toleration.Operator != corev1.TolerationOpExists {
+			return fmt.Errorf("%s tolerations without a key must use the Exists operator", cmp.Kind)
+		}
+		if toleration.Operator == corev1.TolerationOpExists && toleration.Value != "" {
+			return fmt.Errorf("%s tolerations using the Exists operator must not set a value", cmp.Kind)
+		}
+	}
+	return nil
+}
+
+// validateTopologySpreadConstraints verifies the topology spread constraints of a component
+// would be accepted on its pods.
+func validateTopologySpreadConstraints(cmp Component) error {
+	for _, constraint := range cmp.Overrides.TopologySpreadConstraints {
+		if constraint.MaxSkew < 1 {
+			return fmt.Errorf("%s topologySpreadConstraints maxSkew must be at least 1", cmp.Kind)
+		}
+		if constraint.TopologyKey == "" {
+			return fmt.Errorf("%s topologySpreadConstraints must set a topologyKey", cmp.Kind)
+		}
+	}
+	return nil
+}
+
+// ReplicasFor returns the number of replicas the provided component runs with, the minimum
+// number of replicas of its HorizontalPodAutoscaler when it is managed. Returns zero for an
+// unmanaged component or one scaled down.
+func ReplicasFor(quay *QuayRegistry, kind ComponentKind) int32 {
+	if !ComponentIsManaged(quay.Spec.Components, kind) {
+		return 0
+	}
+
+	// the mirror workers are paused while the registry is under maintenance.
+	if kind == ComponentMirror && MaintenanceEnabled(quay) {
+		return 0
+	}
+
+	replicas := GetReplicasOverrideForComponent(quay, kind)
+	if replicas != nil && *replicas == 0 {
+		return 0
+	}
+
+	if ComponentIsManaged(quay.Spec.Components, ComponentHPA) {
+		return HPAMinReplicas
+	}
+
+	// deployments without replicas run a single pod.
+	if replicas == nil {
+		return 1
+	}
+	return *replicas
+}
+
+// PodDisruptionBudgetFor returns how the PodDisruptionBudget of the provided component is
+// sized, nil if the component runs less than two replicas and is left without one. Unless
+// overridden a single pod may be evicted at a time.
+func PodDisruptionBudgetFor(quay *QuayRegistry, kind ComponentKind) *DisruptionBudget {
+	if !slices.Contains(supportsPodDisruptionBudgetOverride, kind) {
+		return nil
+	}
+
+	// a budget covering a single pod would block node drains.
+	replicas := ReplicasFor(quay, kind)
+	if replicas < 2 {
+		return nil
+	}
+
+	for _, cmp := range quay.Spec.Components {
+		if cmp.Kind == kind && cmp.Overrides != nil && cmp.Overrides.PodDisruptionBudget != nil {
+			return cmp.Overrides.PodDisruptionBudget
+		}
+	}
+	return &DisruptionBudget{MinAvailable: ptr.To(intstr.FromInt32(replicas - 1))}
+}
+
+// validatePodDisruptionBudget verifies the PodDisruptionBudget override of a component leaves
+// at least one of its pods to be evicted.
+func validatePodDisruptionBudget(quay *QuayRegistry, cmp Component) error {
+	budget := cmp.Overrides.PodDisruptionBudget
+	if budget == nil {
+		return nil
+	}
+
+	if (budget.MinAvailable == nil) == (budget.MaxUnavailable == nil) {
+		return fmt.Errorf(
+			"%s podDisruptionBudget must set exactly one of minAvailable or maxUnavailable",
+			cmp.Kind,
+		)
+	}
+
+	if budget.MaxUnavailable != nil {
+		// percentages are scaled to a hundred pods, any other number of replicas rounds
+		// a non zero percentage up to at least one pod.
+		unavailable, err := scaledDisruptionBudgetValue(budget.MaxUnavailable, 100)
+		if err != nil {
+			return fmt.Errorf("%s podDisruptionBudget maxUnavailable is invalid: %w", cmp.Kind, err)
+		}
+		if unavailable < 1 {
+			return fmt.Errorf(
+				"%s podDisruptionBudget maxUnavailable must allow a pod to be evicted", cmp.Kind,
+			)
+		}
+		return nil
+	}
+
+	replicas := ReplicasFor(quay, cmp.Kind)
+	available, err := scaledDisruptionBudgetValue(budget.MinAvailable, replicas)
+	if err != nil {
+		return fmt.Errorf("%s podDisruptionBudget minAvailable is invalid: %w", cmp.Kind, err)
+	}
+	if replicas > 1 && int32(available) >= replicas {
+		return fmt.Errorf(
+			"%s podDisruptionBudget minAvailable must be lower than its %d replicas",
+			cmp.Kind, replicas,
+		)
+	}
+	return nil
+}
+
+// scaledDisruptionBudgetValue returns the number of pods the provided value of a
+// PodDisruptionBudget amounts to for the provided number of replicas, percentages are
+// rounded up as done by the disruption controller.
+func scaledDisruptionBudgetValue(value *intstr.IntOrString, replicas int32) (int, error) {
+	scaled, err := intstr.GetScaledValueFromIntOrPercent(value, int(replicas), true)
+	if err != nil {
+		return 0, err
+	}
+	if scaled < 0 {
+		return 0, fmt.Errorf("must not be negative")
+	}
+	return scaled, nil
+}
+
+// PreUpgradeBackupMethodFor returns how the managed databases of the provided QuayRegistry
+// are backed up before being upgraded.
+func PreUpgradeBackupMethodFor(quay *QuayRegistry) PreUpgradeBackupMethod {
+	policy := quay.Spec.PostgresUpgrade
+	if policy == nil || policy.Backup == "" {
+		return PreUpgradeBackupNone
+	}
+	return policy.Backup
+}
+
+// PreUpgradeBackupNameFor returns the name of the backup taken of the provided database
+// component before it is upgraded. Both the VolumeSnapshot and the PersistentVolumeClaim
+// holding the dump are named after it.
+func PreUpgradeBackupNameFor(quay *QuayRegistry, kind ComponentKind) string {
+	if kind == ComponentClairPostgres {
+		return quay.GetName() + "-clair-postgres-pre-upgrade"
+	}
+	return quay.GetName() + "-quay-database-pre-upgrade"
+}
+
+// PreUpgradeBackupFor returns the pre-upgrade backup of the provided database component
+// reported in the status. Returns nil if there is none.
+func PreUpgradeBackupFor(quay *QuayRegistry, kind ComponentKind) *PostgresUpgradeBackup {
+	for i, backup := range quay.Status.PostgresUpgradeBackups {
+		if backup.Component == kind {
+			return &quay.Status.PostgresUpgradeBackups[i]
+		}
+	}
+	return nil
+}
+
+// PreUpgradeBackupAcknowledged returns true if the pre-upgrade backup with the provided name
+// has been acknowledged by the user.
+func PreUpgradeBackupAcknowledged(quay *QuayRegistry, name string) bool {
+	if quay.Spec.PostgresUpgrade == nil {
+		return false
+	}
+	for _, acknowledged := range quay.Spec.PostgresUpgrade.Acknowledged {
+		if acknowledged == name {
+			return true
+		}
+	}
+	return false
+}
+
+// validatePostgresUpgradePolicy verifies the upgrade policy of the managed databases.
+func validatePostgresUpgradePolicy(quay *QuayRegistry) error {
+	policy := quay.Spec.PostgresUpgrade
+	if policy == nil {
+		return nil
+	}
+
+	if policy.VolumeSnapshotClassName != "" && policy.Backup != PreUpgradeBackupSnapshot {
+		return fmt.Errorf("volumeSnapshotClassName is only used by the snapshot backup method")
+	}
+	return nil
+}
+
+// validateUpgradeRollbackPolicy verifies the upgrade rollback policy can be honoured, only
+// the database managed by a postgres Deployment is dumped and restored by the operator.
+func validateUpgradeRollbackPolicy(quay *QuayRegistry) error {
+	policy := quay.Spec.UpgradeRollback
+	if policy == nil || !policy.OnMigrationFailure {
+		return nil
+	}
+
+	unmanaged := ComponentIsExplicitlyDefined(quay.Spec.Components, ComponentPostgres) &&
+		!ComponentIsManaged(quay.Spec.Components, ComponentPostgres)
+	if unmanaged || DatabaseClusterEnabled(quay, ComponentPostgres) {
+		return fmt.Errorf("upgrades can only be rolled back with a managed postgres deployment")
+	}
+	return nil
+}
+
+// BuilderNamespaceFor returns the name of the namespace created by the operator in which the
+// builds of the provided QuayRegistry run.
+func BuilderNamespaceFor(quay *QuayRegistry) string {
+	return strings.Join([]string{quay.GetNamespace(), quay.GetName(), "builder"}, "-")
+}
+
+// BuilderServiceAccountNameFor returns the name of the `ServiceAccount` used by Quay to
+// schedule builds in the build namespace.
+func BuilderServiceAccountNameFor(quay *QuayRegistry) string {
+	return strings.Join([]string{quay.GetName(), "quay-builder"}, "-")
+}
+
+// BuilderTokenSecretNameFor returns the name of the `Secret` holding the token issued for the
+// builder `ServiceAccount`.
+func BuilderTokenSecretNameFor(quay *QuayRegistry) string {
+	return strings.Join([]string{quay.GetName(), "quay-builder-token"}, "-")
+}
+
+// DatabaseTLSSecretNameFor returns the name of the `Secret` in which the CA and the
+// certificates issued by the operator for the managed databases are stored.
+func DatabaseTLSSecretNameFor(quay *QuayRegistry) string {
+	return strings.Join([]string{quay.GetName(), DatabaseTLSSecretName}, "-")
+}
+
 // GetAffinityForComponent returns affinity overrides for the provided component
 // if they are present, nil otherwise
 func GetAffinityForComponent(quay *QuayRegistry, kind ComponentKind) (affinity *corev1.Affinity) {
@@ -912,6 +2745,68 @@ func GetAffinityForComponent(quay *QuayRegistry, kind ComponentKind) (affinity *
 	return
 }
 
+// GetTolerationsOverrideForComponent returns the tolerations of the pods of the provided
+// component, nil if not set.
+func GetTolerationsOverrideForComponent(quay *QuayRegistry, kind ComponentKind) []corev1.Toleration {
+	for _, cmp := range quay.Spec.Components {
+		if cmp.Kind != kind {
+			continue
+		}
+		if cmp.Overrides == nil {
+			return nil
+		}
+		return cmp.Overrides.Tolerations
+	}
+	return nil
+}
+
+// GetNodeSelectorOverrideForComponent returns the node selector of the pods of the provided
+// component, nil if not set.
+func GetNodeSelectorOverrideForComponent(quay *QuayRegistry, kind ComponentKind) map[string]string {
+	for _, cmp := range quay.Spec.Components {
+		if cmp.Kind != kind {
+			continue
+		}
+		if cmp.Overrides == nil {
+			return nil
+		}
+		return cmp.Overrides.NodeSelector
+	}
+	return nil
+}
+
+// GetTopologySpreadConstraintsOverrideForComponent returns the topology spread constraints of
+// the pods of the provided component, nil if not set.
+func GetTopologySpreadConstraintsOverrideForComponent(
+	quay *QuayRegistry, kind ComponentKind,
+) []corev1.TopologySpreadConstraint {
+	for _, cmp := range quay.Spec.Components {
+		if cmp.Kind != kind {
+			continue
+		}
+		if cmp.Overrides == nil {
+			return nil
+		}
+		return cmp.Overrides.TopologySpreadConstraints
+	}
+	return nil
+}
+
+// GetPriorityClassNameOverrideForComponent returns the PriorityClass of the pods of the
+// provided component, nil if not set.
+func GetPriorityClassNameOverrideForComponent(quay *QuayRegistry, kind ComponentKind) *string {
+	for _, cmp := range quay.Spec.Components {
+		if cmp.Kind != kind {
+			continue
+		}
+		if cmp.Overrides == nil {
+			return nil
+		}
+		return cmp.Overrides.PriorityClassName
+	}
+	return nil
+}
+
 // GetEnvOverrideForComponent return the environment variables overrides for the provided
 // component, nil is returned if not defined.
 func GetEnvOverrideForComponent(quay *QuayRegistry, kind ComponentKind) []corev1.EnvVar {
@@ -982,6 +2877,7 @@ func RemoveUnusedConditions(quay *QuayRegistry) {
 	validConditionTypes := []ConditionType{
 		ConditionTypeAvailable,
 		ConditionTypeRolloutBlocked,
+		ConditionTypeMaintenance,
 		ConditionComponentsCreated,
 		ComponentQuayReady,
 		ComponentPostgresReady,
@@ -994,6 +2890,11 @@ func RemoveUnusedConditions(quay *QuayRegistry) {
 		ComponentMirrorReady,
 		ComponentMonitoringReady,
 		ComponentTLSReady,
+		ComponentIngressReady,
+		ComponentGatewayReady,
+		ComponentBuilderReady,
+		ComponentPostgresBackupReady,
+		ComponentClairPostgresBackupReady,
 	}
 
 	newconds := []Condition{}
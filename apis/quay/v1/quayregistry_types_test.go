@@ -8,6 +8,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/utils/ptr"
 
 	quaycontext "github.com/quay/quay-operator/pkg/context"
@@ -56,6 +57,9 @@ var ensureDefaultComponentsTests = []struct {
 			{Kind: "horizontalpodautoscaler", Managed: true},
 			{Kind: "mirror", Managed: true},
 			{Kind: "monitoring", Managed: true},
+			{Kind: "ingress", Managed: false},
+			{Kind: "gateway", Managed: false},
+			{Kind: "builder", Managed: false},
 		},
 		nil,
 	},
@@ -167,8 +171,96 @@ var ensureDefaultComponentsTests = []struct {
 			{Kind: "horizontalpodautoscaler", Managed: true},
 			{Kind: "mirror", Managed: true},
 			{Kind: "monitoring", Managed: true},
+			{Kind: "ingress", Managed: false},
+			{Kind: "gateway", Managed: false},
+			{Kind: "builder", Managed: false},
 		},
-		errors.New("cannot use `tls` component when `Route` API not available or TLS cert/key pair is provided"),
+		nil,
+	},
+	{
+		"TLSCertManagerWithoutRoutes",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				Components: []Component{
+					{Kind: "quay", Managed: true},
+					{Kind: "postgres", Managed: true},
+					{Kind: "redis", Managed: true},
+					{Kind: "clair", Managed: true},
+					{Kind: "clairpostgres", Managed: true},
+					{Kind: "objectstorage", Managed: false},
+					{Kind: "route", Managed: false},
+					{
+						Kind:    "tls",
+						Managed: true,
+						Overrides: &Override{
+							IssuerRef: &CertificateIssuerReference{Name: "letsencrypt"},
+						},
+					},
+					{Kind: "horizontalpodautoscaler", Managed: true},
+					{Kind: "mirror", Managed: true},
+					{Kind: "monitoring", Managed: false},
+				},
+			},
+		},
+		quaycontext.QuayRegistryContext{
+			SupportsRoutes:      false,
+			SupportsCertManager: true,
+		},
+		[]Component{
+			{Kind: "quay", Managed: true},
+			{Kind: "postgres", Managed: true},
+			{Kind: "redis", Managed: true},
+			{Kind: "clair", Managed: true},
+			{Kind: "clairpostgres", Managed: true},
+			{Kind: "objectstorage", Managed: false},
+			{Kind: "route", Managed: false},
+			{
+				Kind:    "tls",
+				Managed: true,
+				Overrides: &Override{
+					IssuerRef: &CertificateIssuerReference{Name: "letsencrypt"},
+				},
+			},
+			{Kind: "horizontalpodautoscaler", Managed: true},
+			{Kind: "mirror", Managed: true},
+			{Kind: "monitoring", Managed: false},
+			{Kind: "ingress", Managed: false},
+			{Kind: "gateway", Managed: false},
+			{Kind: "builder", Managed: false},
+		},
+		nil,
+	},
+	{
+		"TLSCertManagerNotAvailable",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				Components: []Component{
+					{Kind: "quay", Managed: true},
+					{Kind: "postgres", Managed: true},
+					{Kind: "redis", Managed: true},
+					{Kind: "clair", Managed: true},
+					{Kind: "clairpostgres", Managed: true},
+					{Kind: "objectstorage", Managed: false},
+					{Kind: "route", Managed: false},
+					{
+						Kind:    "tls",
+						Managed: true,
+						Overrides: &Override{
+							IssuerRef: &CertificateIssuerReference{Name: "letsencrypt"},
+						},
+					},
+					{Kind: "horizontalpodautoscaler", Managed: true},
+					{Kind: "mirror", Managed: true},
+					{Kind: "monitoring", Managed: false},
+				},
+			},
+		},
+		quaycontext.QuayRegistryContext{
+			SupportsRoutes:      true,
+			SupportsCertManager: false,
+		},
+		nil,
+		errors.New("error validating component tls: cert-manager API not available"),
 	},
 	{
 		"AllComponentsOmitted",
@@ -188,6 +280,9 @@ var ensureDefaultComponentsTests = []struct {
 			{Kind: "horizontalpodautoscaler", Managed: true},
 			{Kind: "mirror", Managed: true},
 			{Kind: "monitoring", Managed: false},
+			{Kind: "ingress", Managed: false},
+			{Kind: "gateway", Managed: false},
+			{Kind: "builder", Managed: false},
 		},
 		nil,
 	},
@@ -213,6 +308,9 @@ var ensureDefaultComponentsTests = []struct {
 			{Kind: "horizontalpodautoscaler", Managed: true},
 			{Kind: "mirror", Managed: true},
 			{Kind: "monitoring", Managed: true},
+			{Kind: "ingress", Managed: false},
+			{Kind: "gateway", Managed: false},
+			{Kind: "builder", Managed: false},
 		},
 		nil,
 	},
@@ -240,6 +338,9 @@ var ensureDefaultComponentsTests = []struct {
 			{Kind: "horizontalpodautoscaler", Managed: true},
 			{Kind: "mirror", Managed: true},
 			{Kind: "monitoring", Managed: false},
+			{Kind: "ingress", Managed: false},
+			{Kind: "gateway", Managed: false},
+			{Kind: "builder", Managed: false},
 		},
 		nil,
 	},
@@ -271,6 +372,9 @@ var ensureDefaultComponentsTests = []struct {
 			{Kind: "horizontalpodautoscaler", Managed: true},
 			{Kind: "mirror", Managed: true},
 			{Kind: "monitoring", Managed: true},
+			{Kind: "ingress", Managed: false},
+			{Kind: "gateway", Managed: false},
+			{Kind: "builder", Managed: false},
 		},
 		nil,
 	},
@@ -304,6 +408,9 @@ var ensureDefaultComponentsTests = []struct {
 			{Kind: "horizontalpodautoscaler", Managed: true},
 			{Kind: "mirror", Managed: true},
 			{Kind: "monitoring", Managed: true},
+			{Kind: "ingress", Managed: false},
+			{Kind: "gateway", Managed: false},
+			{Kind: "builder", Managed: false},
 		},
 		nil,
 	},
@@ -441,7 +548,257 @@ var validateOverridesTests = []struct {
 				},
 			},
 		},
-		errors.New("component redis does not support replicas overrides"),
+		errors.New("redis replicas can only be overridden with highAvailability"),
+	},
+	{
+		"RedisHighAvailabilityReplicasOverride",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				Components: []Component{
+					{Kind: "redis", Managed: true, Overrides: &Override{HighAvailability: ptr.To(true), Replicas: ptr.To[int32](5)}},
+					{Kind: "horizontalpodautoscaler", Managed: true},
+				},
+			},
+		},
+		nil,
+	},
+	{
+		"RedisHighAvailabilityTooFewReplicas",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				Components: []Component{
+					{Kind: "redis", Managed: true, Overrides: &Override{HighAvailability: ptr.To(true), Replicas: ptr.To[int32](2)}},
+				},
+			},
+		},
+		errors.New("redis requires at least 3 replicas with highAvailability"),
+	},
+	{
+		"DatabaseClusterReplicasOverride",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				Components: []Component{
+					{Kind: "postgres", Managed: true, Overrides: &Override{Backend: ptr.To(DatabaseBackendCloudNativePG), Replicas: ptr.To[int32](2)}},
+					{Kind: "clairpostgres", Managed: true, Overrides: &Override{Backend: ptr.To(DatabaseBackendCloudNativePG)}},
+					{Kind: "horizontalpodautoscaler", Managed: true},
+				},
+			},
+		},
+		nil,
+	},
+	{
+		"DatabaseReplicasWithoutCluster",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				Components: []Component{
+					{Kind: "postgres", Managed: true, Overrides: &Override{Replicas: ptr.To[int32](3)}},
+				},
+			},
+		},
+		errors.New("postgres replicas can only be overridden with the cloudnativepg backend"),
+	},
+	{
+		"DatabaseClusterTLSOverride",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				Components: []Component{
+					{Kind: "clairpostgres", Managed: true, Overrides: &Override{Backend: ptr.To(DatabaseBackendCloudNativePG), TLS: ptr.To(true)}},
+				},
+			},
+		},
+		errors.New("component clairpostgres does not support tls overrides with cloudnativepg"),
+	},
+	{
+		"DatabaseBackupOverride",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				Components: []Component{
+					{Kind: "postgres", Managed: true, Overrides: &Override{Backup: &DatabaseBackup{Schedule: "0 2 * * *", Retention: ptr.To[int32](3)}}},
+					{Kind: "clairpostgres", Managed: true, Overrides: &Override{Backup: &DatabaseBackup{Schedule: "@daily", Destination: BackupDestinationObjectStorage}}},
+					{Kind: "objectstorage", Managed: true},
+				},
+			},
+		},
+		nil,
+	},
+	{
+		"DatabaseBackupInvalidSchedule",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				Components: []Component{
+					{Kind: "postgres", Managed: true, Overrides: &Override{Backup: &DatabaseBackup{Schedule: "0 2 * *"}}},
+				},
+			},
+		},
+		errors.New(`invalid postgres backup schedule "0 2 * *"`),
+	},
+	{
+		"DatabaseBackupNoRetention",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				Components: []Component{
+					{Kind: "postgres", Managed: true, Overrides: &Override{Backup: &DatabaseBackup{Schedule: "@daily", Retention: ptr.To[int32](0)}}},
+				},
+			},
+		},
+		errors.New("postgres backup retention must keep at least one backup"),
+	},
+	{
+		"DatabaseBackupUnmanagedObjectStorage",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				Components: []Component{
+					{Kind: "postgres", Managed: true, Overrides: &Override{Backup: &DatabaseBackup{Schedule: "@daily", Destination: BackupDestinationObjectStorage}}},
+					{Kind: "objectstorage", Managed: false},
+				},
+			},
+		},
+		errors.New("postgres backups to objectstorage require a managed objectstorage"),
+	},
+	{
+		"DatabaseClusterBackupOverride",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				Components: []Component{
+					{Kind: "postgres", Managed: true, Overrides: &Override{Backend: ptr.To(DatabaseBackendCloudNativePG), Backup: &DatabaseBackup{Schedule: "@daily"}}},
+				},
+			},
+		},
+		errors.New("component postgres does not support backup overrides with cloudnativepg"),
+	},
+	{
+		"InvalidBackupOverride",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				Components: []Component{
+					{Kind: "redis", Managed: true, Overrides: &Override{Backup: &DatabaseBackup{Schedule: "@daily"}}},
+				},
+			},
+		},
+		errors.New("component redis does not support backup overrides"),
+	},
+	{
+		"DatabaseSnapshotOverride",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				Components: []Component{
+					{Kind: "postgres", Managed: true, Overrides: &Override{Snapshot: &DatabaseSnapshot{Schedule: "0 3 * * *", VolumeSnapshotClassName: "csi-snapclass"}}},
+					{Kind: "clairpostgres", Managed: true, Overrides: &Override{Snapshot: &DatabaseSnapshot{}}},
+				},
+			},
+		},
+		nil,
+	},
+	{
+		"DatabaseSnapshotInvalidSchedule",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				Components: []Component{
+					{Kind: "postgres", Managed: true, Overrides: &Override{Snapshot: &DatabaseSnapshot{Schedule: "daily"}}},
+				},
+			},
+		},
+		errors.New(`invalid postgres snapshot schedule "daily"`),
+	},
+	{
+		"DatabaseSnapshotNoRetention",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				Components: []Component{
+					{Kind: "clairpostgres", Managed: true, Overrides: &Override{Snapshot: &DatabaseSnapshot{Retention: ptr.To[int32](0)}}},
+				},
+			},
+		},
+		errors.New("clairpostgres snapshot retention must keep at least one snapshot"),
+	},
+	{
+		"DatabaseClusterSnapshotOverride",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				Components: []Component{
+					{Kind: "postgres", Managed: true, Overrides: &Override{Backend: ptr.To(DatabaseBackendCloudNativePG), Snapshot: &DatabaseSnapshot{}}},
+				},
+			},
+		},
+		errors.New("component postgres does not support snapshot overrides with cloudnativepg"),
+	},
+	{
+		"InvalidSnapshotOverride",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				Components: []Component{
+					{Kind: "redis", Managed: true, Overrides: &Override{Snapshot: &DatabaseSnapshot{}}},
+				},
+			},
+		},
+		errors.New("component redis does not support snapshot overrides"),
+	},
+	{
+		"DataSourceOverride",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				Components: []Component{
+					{Kind: "postgres", Managed: true, Overrides: &Override{DataSource: &corev1.TypedLocalObjectReference{APIGroup: ptr.To(VolumeSnapshotGroup), Kind: "VolumeSnapshot", Name: "production"}}},
+					{Kind: "clairpostgres", Managed: true, Overrides: &Override{DataSource: &corev1.TypedLocalObjectReference{Kind: "VolumeSnapshot", Name: "production-clair"}}},
+				},
+			},
+		},
+		nil,
+	},
+	{
+		"DataSourceNotASnapshot",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				Components: []Component{
+					{Kind: "postgres", Managed: true, Overrides: &Override{DataSource: &corev1.TypedLocalObjectReference{Kind: "PersistentVolumeClaim", Name: "production"}}},
+				},
+			},
+		},
+		errors.New("postgres dataSource must reference a VolumeSnapshot"),
+	},
+	{
+		"DataSourceClusterOverride",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				Components: []Component{
+					{Kind: "clairpostgres", Managed: true, Overrides: &Override{Backend: ptr.To(DatabaseBackendCloudNativePG), DataSource: &corev1.TypedLocalObjectReference{Kind: "VolumeSnapshot", Name: "production"}}},
+				},
+			},
+		},
+		errors.New("component clairpostgres does not support dataSource overrides with cloudnativepg"),
+	},
+	{
+		"InvalidDataSourceOverride",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				Components: []Component{
+					{Kind: "quay", Managed: true, Overrides: &Override{DataSource: &corev1.TypedLocalObjectReference{Kind: "VolumeSnapshot", Name: "production"}}},
+				},
+			},
+		},
+		errors.New("component quay does not support dataSource overrides"),
+	},
+	{
+		"InvalidBackendOverride",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				Components: []Component{
+					{Kind: "redis", Managed: true, Overrides: &Override{Backend: ptr.To(DatabaseBackendCloudNativePG)}},
+				},
+			},
+		},
+		errors.New("component redis does not support backend overrides"),
+	},
+	{
+		"InvalidHighAvailabilityOverride",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				Components: []Component{
+					{Kind: "postgres", Managed: true, Overrides: &Override{HighAvailability: ptr.To(true)}},
+				},
+			},
+		},
+		errors.New("component postgres does not support highAvailability overrides"),
 	},
 	{
 		"InvalidEnvVarOverride",
@@ -602,6 +959,218 @@ var validateOverridesTests = []struct {
 		},
 		errors.New("component redis does not support securityContext overrides"),
 	},
+	{
+		"ValidSchedulingOverrides",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				Components: []Component{
+					{
+						Kind:    "postgres",
+						Managed: true,
+						Overrides: &Override{
+							Tolerations: []corev1.Toleration{
+								{Key: "dedicated", Operator: corev1.TolerationOpEqual, Value: "registry"},
+							},
+							NodeSelector: map[string]string{"dedicated": "registry"},
+							TopologySpreadConstraints: []corev1.TopologySpreadConstraint{
+								{MaxSkew: 1, TopologyKey: "kubernetes.io/hostname", WhenUnsatisfiable: corev1.DoNotSchedule},
+							},
+							PriorityClassName: ptr.To("registry-critical"),
+						},
+					},
+					{
+						Kind:    "redis",
+						Managed: true,
+						Overrides: &Override{
+							Tolerations: []corev1.Toleration{{Operator: corev1.TolerationOpExists}},
+						},
+					},
+				},
+			},
+		},
+		nil,
+	},
+	{
+		"InvalidSchedulingOverrideOnRoute",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				Components: []Component{
+					{Kind: "route", Managed: true, Overrides: &Override{NodeSelector: map[string]string{"a": "b"}}},
+				},
+			},
+		},
+		errors.New("component route does not support nodeSelector overrides"),
+	},
+	{
+		"InvalidTolerationOverride",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				Components: []Component{
+					{
+						Kind:    "clair",
+						Managed: true,
+						Overrides: &Override{
+							Tolerations: []corev1.Toleration{
+								{Key: "dedicated", Operator: corev1.TolerationOpExists, Value: "registry"},
+							},
+						},
+					},
+				},
+			},
+		},
+		errors.New("clair tolerations using the Exists operator must not set a value"),
+	},
+	{
+		"InvalidTopologySpreadConstraintOverride",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				Components: []Component{
+					{
+						Kind:    "mirror",
+						Managed: true,
+						Overrides: &Override{
+							TopologySpreadConstraints: []corev1.TopologySpreadConstraint{
+								{MaxSkew: 1, WhenUnsatisfiable: corev1.ScheduleAnyway},
+							},
+						},
+					},
+				},
+			},
+		},
+		errors.New("mirror topologySpreadConstraints must set a topologyKey"),
+	},
+	{
+		"ValidPodDisruptionBudgetOverrides",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				Components: []Component{
+					{Kind: "horizontalpodautoscaler", Managed: true},
+					{
+						Kind:    "quay",
+						Managed: true,
+						Overrides: &Override{
+							PodDisruptionBudget: &DisruptionBudget{
+								MinAvailable: ptr.To(intstr.FromString("50%")),
+							},
+						},
+					},
+					{
+						Kind:    "clair",
+						Managed: true,
+						Overrides: &Override{
+							PodDisruptionBudget: &DisruptionBudget{
+								MaxUnavailable: ptr.To(intstr.FromInt32(2)),
+							},
+						},
+					},
+				},
+			},
+		},
+		nil,
+	},
+	{
+		"InvalidPodDisruptionBudgetOverrideOnRedis",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				Components: []Component{
+					{
+						Kind:    "redis",
+						Managed: true,
+						Overrides: &Override{
+							PodDisruptionBudget: &DisruptionBudget{
+								MaxUnavailable: ptr.To(intstr.FromInt32(1)),
+							},
+						},
+					},
+				},
+			},
+		},
+		errors.New("component redis does not support podDisruptionBudget overrides"),
+	},
+	{
+		"InvalidPodDisruptionBudgetBothSet",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				Components: []Component{
+					{
+						Kind:    "mirror",
+						Managed: true,
+						Overrides: &Override{
+							PodDisruptionBudget: &DisruptionBudget{
+								MinAvailable:   ptr.To(intstr.FromInt32(1)),
+								MaxUnavailable: ptr.To(intstr.FromInt32(1)),
+							},
+						},
+					},
+				},
+			},
+		},
+		errors.New("mirror podDisruptionBudget must set exactly one of minAvailable or maxUnavailable"),
+	},
+	{
+		"InvalidPodDisruptionBudgetMinAvailable",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				Components: []Component{
+					{Kind: "horizontalpodautoscaler", Managed: false},
+					{
+						Kind:    "quay",
+						Managed: true,
+						Overrides: &Override{
+							Replicas: ptr.To[int32](3),
+							PodDisruptionBudget: &DisruptionBudget{
+								MinAvailable: ptr.To(intstr.FromString("100%")),
+							},
+						},
+					},
+				},
+			},
+		},
+		errors.New("quay podDisruptionBudget minAvailable must be lower than its 3 replicas"),
+	},
+	{
+		"InvalidPodDisruptionBudgetMaxUnavailable",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				Components: []Component{
+					{
+						Kind:    "clair",
+						Managed: true,
+						Overrides: &Override{
+							PodDisruptionBudget: &DisruptionBudget{
+								MaxUnavailable: ptr.To(intstr.FromString("0%")),
+							},
+						},
+					},
+				},
+			},
+		},
+		errors.New("clair podDisruptionBudget maxUnavailable must allow a pod to be evicted"),
+	},
+	{
+		"PostgresUpgradeDumpWithSnapshotClass",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				PostgresUpgrade: &PostgresUpgradePolicy{
+					Backup:                  PreUpgradeBackupDump,
+					VolumeSnapshotClassName: "csi-snapclass",
+				},
+			},
+		},
+		errors.New("volumeSnapshotClassName is only used by the snapshot backup method"),
+	},
+	{
+		"UpgradeRollbackWithUnmanagedPostgres",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				Components: []Component{
+					{Kind: "postgres", Managed: false},
+				},
+				UpgradeRollback: &UpgradeRollbackPolicy{OnMigrationFailure: true},
+			},
+		},
+		errors.New("upgrades can only be rolled back with a managed postgres deployment"),
+	},
 }
 
 func TestValidOverrides(t *testing.T) {
@@ -609,12 +1178,14 @@ func TestValidOverrides(t *testing.T) {
 
 	for _, test := range validateOverridesTests {
 		t.Run(test.name, func(t *testing.T) {
-			err := ValidateOverrides(&test.quay)
+			errs := ValidateOverrides(&test.quay)
 			if test.expectedErr != nil {
-				assert.NotNil(err, test.name)
-				assert.Equal(test.expectedErr, err)
+				assert.NotEmpty(errs, test.name)
+				if len(errs) > 0 {
+					assert.Equal(test.expectedErr.Error(), errs[0].Detail)
+				}
 			} else {
-				assert.Equal(test.expectedErr, err)
+				assert.Empty(errs)
 			}
 		})
 	}
@@ -818,3 +1389,245 @@ func resourcePtr(s string) *resource.Quantity {
 	q := resource.MustParse(s)
 	return &q
 }
+
+func TestEnsureDefaultComponentsStatus(t *testing.T) {
+	quay := QuayRegistry{
+		Spec: QuayRegistrySpec{
+			Components: []Component{
+				{Kind: "quay", Managed: false},
+				{Kind: "postgres", Managed: false},
+			},
+		},
+	}
+	ctx := quaycontext.QuayRegistryContext{
+		SupportsRoutes:     true,
+		SupportsMonitoring: false,
+		SupportsGatewayAPI: true,
+		TLSCert:            []byte("my-own-cert"),
+		TLSKey:             []byte("my-own-key"),
+	}
+
+	err := EnsureDefaultComponents(&ctx, &quay)
+	assert.NoError(t, err)
+
+	expected := map[ComponentKind]ComponentStatus{
+		ComponentQuay:          {Kind: "quay", Managed: true, Reason: ComponentStatusReasonAlwaysManaged, Message: "quay component is always managed"},
+		ComponentPostgres:      {Kind: "postgres", Managed: false, Reason: ComponentStatusReasonUserDefined, Message: "declared in spec.components"},
+		ComponentRedis:         {Kind: "redis", Managed: true, Reason: ComponentStatusReasonDefaulted, Message: "defaulted to managed"},
+		ComponentRoute:         {Kind: "route", Managed: true, Reason: ComponentStatusReasonDefaulted, Message: "defaulted to managed"},
+		ComponentTLS:           {Kind: "tls", Managed: false, Reason: ComponentStatusReasonConfigProvided, Message: "custom TLS certificate provided"},
+		ComponentObjectStorage: {Kind: "objectstorage", Managed: false, Reason: ComponentStatusReasonAPIUnavailable, Message: "ObjectStorage API not available"},
+		ComponentMonitoring:    {Kind: "monitoring", Managed: false, Reason: ComponentStatusReasonAPIUnavailable, Message: "Prometheus API not available"},
+		ComponentIngress:       {Kind: "ingress", Managed: false, Reason: ComponentStatusReasonDefaulted, Message: "ingress must be explicitly enabled"},
+		ComponentGateway:       {Kind: "gateway", Managed: false, Reason: ComponentStatusReasonDefaulted, Message: "gateway must be explicitly enabled"},
+		ComponentBuilder:       {Kind: "builder", Managed: false, Reason: ComponentStatusReasonDefaulted, Message: "builder must be explicitly enabled"},
+	}
+
+	assert.Len(t, quay.Status.Components, len(AllComponents))
+	for _, status := range quay.Status.Components {
+		exp, ok := expected[status.Kind]
+		if !ok {
+			continue
+		}
+		assert.Equal(t, exp, status)
+	}
+}
+
+func TestResolvedComponents(t *testing.T) {
+	quay := QuayRegistry{
+		Spec: QuayRegistrySpec{
+			Components: []Component{
+				{Kind: "clair", Managed: true, Overrides: &Override{Replicas: ptr.To[int32](0)}},
+			},
+		},
+		Status: QuayRegistryStatus{
+			Components: []ComponentStatus{
+				{Kind: "clair", Managed: false, Reason: ComponentStatusReasonUserDefined},
+				{Kind: "route", Managed: false, Reason: ComponentStatusReasonAPIUnavailable},
+			},
+		},
+	}
+
+	assert.Equal(
+		t,
+		[]Component{
+			{Kind: "clair", Managed: true, Overrides: &Override{Replicas: ptr.To[int32](0)}},
+			{Kind: "route", Managed: false},
+		},
+		ResolvedComponents(&quay),
+	)
+	assert.Len(t, quay.Spec.Components, 1)
+}
+
+func TestPostgresUpgradePath(t *testing.T) {
+	for _, kind := range []ComponentKind{ComponentPostgres, ComponentClairPostgres} {
+		path := PostgresUpgradePathFor(kind)
+		assert.GreaterOrEqual(t, len(path), 2, string(kind))
+		for i := 1; i < len(path); i++ {
+			assert.Greater(t, path[i].Major, path[i-1].Major, string(kind))
+		}
+	}
+
+	next, ok := NextPostgresVersion(ComponentClairPostgres, 10)
+	assert.True(t, ok)
+	assert.Equal(t, int32(13), next.Major)
+
+	next, ok = NextPostgresVersion(ComponentClairPostgres, 13)
+	assert.True(t, ok)
+	assert.Equal(t, int32(15), next.Major)
+
+	_, ok = NextPostgresVersion(ComponentClairPostgres, 15)
+	assert.False(t, ok)
+
+	_, ok = NextPostgresVersion(ComponentPostgres, 9)
+	assert.False(t, ok)
+
+	_, ok = PostgresVersionFor(ComponentPostgres, 15)
+	assert.False(t, ok)
+}
+
+func TestPostgresUpgradeVersionsFor(t *testing.T) {
+	quay := &QuayRegistry{ObjectMeta: metav1.ObjectMeta{Name: "test"}}
+	assert.Equal(t, "test-quay-postgres-13", DatabaseVolumeNameFor(quay, ComponentPostgres))
+	assert.Equal(t, "test-clair-postgres-15", DatabaseVolumeNameFor(quay, ComponentClairPostgres))
+
+	// without a step running the last step of the path is assumed.
+	from, to, volume := PostgresUpgradeVersionsFor(quay, ComponentClairPostgres)
+	assert.Equal(t, int32(13), from.Major)
+	assert.Equal(t, int32(15), to.Major)
+	assert.Equal(t, "test-clair-postgres-13", volume)
+
+	quay.Status.PostgresUpgrades = []PostgresUpgradeStep{
+		{Component: ComponentClairPostgres, From: 10, To: 13, Phase: PostgresUpgradePhaseRunning},
+	}
+	quay.Status.DatabaseVersions = []DatabaseVersion{
+		{Component: ComponentClairPostgres, Version: 10, PersistentVolumeClaim: "restored"},
+	}
+	from, to, volume = PostgresUpgradeVersionsFor(quay, ComponentClairPostgres)
+	assert.Equal(t, int32(10), from.Major)
+	assert.Equal(t, int32(13), to.Major)
+	assert.Equal(t, "restored", volume)
+
+	SetDatabaseVersion(quay, DatabaseVersion{
+		Component: ComponentClairPostgres, Version: 13, PersistentVolumeClaim: "test-clair-postgres-13",
+	})
+	assert.Len(t, quay.Status.DatabaseVersions, 1)
+	assert.Equal(t, int32(13), DatabaseVersionFor(quay, ComponentClairPostgres).Version)
+	assert.Nil(t, DatabaseVersionFor(quay, ComponentPostgres))
+}
+
+func TestValidateQuayUpgrade(t *testing.T) {
+	for _, tt := range []struct {
+		from      QuayVersion
+		to        QuayVersion
+		expectErr bool
+	}{
+		{from: "3.10.0", to: "3.10.2"},
+		{from: "v3.9.4", to: "v3.12.0"},
+		{from: "dev", to: "3.12.0"},
+		{from: "3.12.0", to: "dev"},
+		{from: "3.12.1", to: "3.12.0", expectErr: true},
+		{from: "3.8.0", to: "3.12.0", expectErr: true},
+		{from: "3.15.0", to: "4.0.0", expectErr: true},
+	} {
+		err := ValidateQuayUpgrade(tt.from, tt.to)
+		assert.Equal(t, tt.expectErr, err != nil, "%s to %s: %v", tt.from, tt.to, err)
+	}
+}
+
+func TestUpgradePreflightNeeded(t *testing.T) {
+	current := QuayVersionCurrent
+	QuayVersionCurrent = "3.12.0"
+	t.Cleanup(func() { QuayVersionCurrent = current })
+
+	quay := &QuayRegistry{}
+	assert.False(t, UpgradePreflightNeeded(quay), "new registries are not upgraded")
+
+	quay.Status.CurrentVersion = "3.12.0"
+	assert.False(t, UpgradePreflightNeeded(quay))
+
+	quay.Status.CurrentVersion = "3.11.0"
+	assert.True(t, UpgradePreflightNeeded(quay))
+
+	quay.Status.UpgradePreflightVersion = "3.12.0"
+	assert.False(t, UpgradePreflightNeeded(quay))
+}
+
+func TestReadOnlyUpgradeEnabled(t *testing.T) {
+	current := QuayVersionCurrent
+	QuayVersionCurrent = "3.12.0"
+	t.Cleanup(func() { QuayVersionCurrent = current })
+
+	quay := &QuayRegistry{Spec: QuayRegistrySpec{UpgradeStrategy: UpgradeStrategyReadOnly}}
+	assert.False(t, ReadOnlyUpgradeEnabled(quay), "new registries are not upgraded")
+
+	quay.Status.CurrentVersion = "3.12.0"
+	assert.False(t, ReadOnlyUpgradeEnabled(quay))
+
+	quay.Status.CurrentVersion = "3.11.0"
+	assert.True(t, ReadOnlyUpgradeEnabled(quay))
+
+	quay.Spec.UpgradeStrategy = UpgradeStrategyRecreate
+	assert.False(t, ReadOnlyUpgradeEnabled(quay))
+}
+
+func TestMaintenanceEnabled(t *testing.T) {
+	quay := &QuayRegistry{}
+	assert.False(t, MaintenanceEnabled(quay))
+
+	quay.Spec.Maintenance = &Maintenance{Mode: MaintenanceModeReadOnly}
+	assert.True(t, MaintenanceEnabled(quay))
+}
+
+func TestUpgradeRollback(t *testing.T) {
+	current := QuayVersionCurrent
+	QuayVersionCurrent = "3.12.0"
+	t.Cleanup(func() { QuayVersionCurrent = current })
+
+	quay := &QuayRegistry{
+		ObjectMeta: metav1.ObjectMeta{Generation: 1},
+		Spec: QuayRegistrySpec{
+			Components:      []Component{{Kind: ComponentPostgres, Managed: true}},
+			UpgradeRollback: &UpgradeRollbackPolicy{OnMigrationFailure: true},
+		},
+	}
+	assert.False(t, UpgradeRollbackEnabled(quay), "new registries are not upgraded")
+
+	quay.Status.CurrentVersion = "3.11.0"
+	assert.True(t, UpgradeRollbackEnabled(quay))
+	assert.True(t, UpgradeRollbackPointNeeded(quay))
+
+	quay.Status.UpgradeRollback = &UpgradeRollbackStatus{
+		Version:       "3.10.0",
+		TargetVersion: "3.11.0",
+		Phase:         UpgradeRollbackPhaseReady,
+	}
+	assert.True(t, UpgradeRollbackPointNeeded(quay), "rollback point of a previous upgrade")
+
+	quay.Status.UpgradeRollback = &UpgradeRollbackStatus{
+		Version:       "3.11.0",
+		Image:         "quay.io/projectquay/quay:3.11.0",
+		TargetVersion: "3.12.0",
+		Phase:         UpgradeRollbackPhaseRolledBack,
+		Generation:    1,
+	}
+	assert.False(t, UpgradeRollbackPointNeeded(quay))
+	assert.Equal(t, quay.Status.UpgradeRollback, UpgradeRolledBack(quay))
+
+	quay.Spec.UpgradeRollback.RetryVersion = "3.12.0"
+	assert.False(t, UpgradeRollbackRetried(quay), "retry version set before the rollback")
+
+	quay.Generation = 2
+	assert.True(t, UpgradeRollbackRetried(quay))
+	assert.True(t, UpgradeRollbackPointNeeded(quay))
+	assert.Nil(t, UpgradeRolledBack(quay))
+
+	quay.Generation = 1
+	quay.Status.UpgradeRollback.Phase = UpgradeRollbackPhaseFailed
+	assert.True(t, UpgradeRollbackFailed(quay))
+	assert.Nil(t, UpgradeRolledBack(quay))
+
+	quay.Spec.Components = []Component{{Kind: ComponentPostgres, Managed: false}}
+	assert.False(t, UpgradeRollbackEnabled(quay), "unmanaged databases are not rolled back")
+	assert.False(t, UpgradeRollbackFailed(quay))
+}
@@ -0,0 +1,223 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// +kubebuilder:webhook:path=/validate-quay-redhat-com-v1-quayregistry,mutating=false,failurePolicy=fail,sideEffects=None,groups=quay.redhat.com,resources=quayregistries,verbs=create;update,versions=v1,name=vquayregistry.kb.io,admissionReviewVersions=v1
+
+// QuayRegistryValidator is an admission validator for QuayRegistry objects. It runs the
+// same component and override checks the reconciler runs so invalid objects are refused
+// by the API server instead of being reported later through a RolloutBlocked condition.
+type QuayRegistryValidator struct{}
+
+// SetupWebhookWithManager registers the QuayRegistry validating webhook within the
+// manager's webhook server.
+func (r *QuayRegistry) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		WithValidator(&QuayRegistryValidator{}).
+		Complete()
+}
+
+// ValidateCreate validates a QuayRegistry being created.
+func (v *QuayRegistryValidator) ValidateCreate(
+	ctx context.Context, obj runtime.Object,
+) (admission.Warnings, error) {
+	quay, ok := obj.(*QuayRegistry)
+	if !ok {
+		return nil, fmt.Errorf("expected a QuayRegistry but got %T", obj)
+	}
+	return validate(quay)
+}
+
+// ValidateUpdate validates a QuayRegistry being updated. Objects flagged for deletion, and
+// updates that do not change the spec, are always accepted so we never block the changes the
+// operator does to its own finalizer.
+func (v *QuayRegistryValidator) ValidateUpdate(
+	ctx context.Context, oldObj, newObj runtime.Object,
+) (admission.Warnings, error) {
+	quay, ok := newObj.(*QuayRegistry)
+	if !ok {
+		return nil, fmt.Errorf("expected a QuayRegistry but got %T", newObj)
+	}
+
+	if FlaggedForDeletion(quay) {
+		return nil, nil
+	}
+
+	// updates leaving the spec untouched, as the ones of the finalizer or of the status done
+	// by the operator, are accepted even if the object would not pass the current rules.
+	if old, ok := oldObj.(*QuayRegistry); ok && equality.Semantic.DeepEqual(old.Spec, quay.Spec) {
+		return nil, nil
+	}
+	return validate(quay)
+}
+
+// ValidateDelete accepts all deletions.
+func (v *QuayRegistryValidator) ValidateDelete(
+	ctx context.Context, obj runtime.Object,
+) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validate runs ValidateQuayRegistry and converts the result into an Invalid status error
+// as expected by the API server.
+func validate(quay *QuayRegistry) (admission.Warnings, error) {
+	warns, errs := ValidateQuayRegistry(quay)
+	if len(errs) == 0 {
+		return warns, nil
+	}
+
+	return warns, apierrors.NewInvalid(
+		GroupVersion.WithKind("QuayRegistry").GroupKind(), quay.GetName(), errs,
+	)
+}
+
+// ValidateQuayRegistry validates the components declared in provided QuayRegistry. Besides
+// the checks done by ValidateOverrides this also covers duplicated or unknown components,
+// required components without configuration and the rules enforced through CEL on the CRD.
+// Returns a list of warnings and a list of field level errors.
+func ValidateQuayRegistry(quay *QuayRegistry) ([]string, field.ErrorList) {
+	var warns []string
+	var errs field.ErrorList
+
+	specPath := field.NewPath("spec")
+	componentsPath := specPath.Child("components")
+
+	malformed := false
+	seen := map[ComponentKind]bool{}
+	for i, cmp := range quay.Spec.Components {
+		cmpPath := componentsPath.Index(i)
+
+		if !knownComponent(cmp.Kind) {
+			errs = append(
+				errs,
+				field.NotSupported(cmpPath.Child("kind"), cmp.Kind, componentNames()),
+			)
+			malformed = true
+			continue
+		}
+
+		if seen[cmp.Kind] {
+			errs = append(errs, field.Duplicate(cmpPath.Child("kind"), cmp.Kind))
+			malformed = true
+			continue
+		}
+		seen[cmp.Kind] = true
+
+		// quay can't be unmanaged, the reconciler silently flips it back to managed.
+		if cmp.Kind == ComponentQuay && !cmp.Managed {
+			warns = append(
+				warns,
+				fmt.Sprintf("%s: component quay is always managed", cmpPath.Child("managed")),
+			)
+		}
+
+		if cmp.SecretRef != nil {
+			if cmp.Managed {
+				errs = append(
+					errs,
+					field.Forbidden(
+						cmpPath.Child("secretRef"),
+						"secretRef cannot be set on a managed component",
+					),
+				)
+			} else if cmp.SecretRef.Name == "" {
+				errs = append(
+					errs,
+					field.Required(
+						cmpPath.Child("secretRef", "name"),
+						"secretRef.name must not be empty",
+					),
+				)
+			}
+		}
+
+		if !cmp.Managed && cmp.Kind != ComponentQuay && missingConfigFor(quay, cmp) {
+			errs = append(
+				errs,
+				field.Required(
+					specPath.Child("configBundleSecret"),
+					fmt.Sprintf(
+						"required component %s marked as unmanaged, its "+
+							"configuration must be provided",
+						cmp.Kind,
+					),
+				),
+			)
+		}
+	}
+
+	// the overrides can't be told apart when components are unknown or duplicated.
+	if malformed {
+		return warns, errs
+	}
+	return warns, append(errs, ValidateOverrides(quay)...)
+}
+
+// missingConfigFor returns true if provided unmanaged component is required but there is
+// no way the user could have provided its configuration. The content of the config bundle
+// secret is verified later on by the reconciler.
+func missingConfigFor(quay *QuayRegistry, cmp Component) bool {
+	if quay.Spec.ConfigBundleSecret != "" {
+		return false
+	}
+
+	// the certificate for an unmanaged tls may come from a dedicated secret.
+	if cmp.Kind == ComponentTLS && cmp.SecretRef != nil {
+		return false
+	}
+
+	// clair can't be rendered without a database configuration.
+	if cmp.Kind == ComponentClairPostgres {
+		managedclair := !ComponentIsExplicitlyDefined(quay.Spec.Components, ComponentClair) ||
+			ComponentIsManaged(quay.Spec.Components, ComponentClair)
+		return managedclair
+	}
+
+	return RequiredComponent(cmp.Kind)
+}
+
+// knownComponent returns true if provided kind is one of the supported components.
+func knownComponent(kind ComponentKind) bool {
+	for _, cmp := range AllComponents {
+		if cmp == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// componentNames returns the names of all supported components.
+func componentNames() []string {
+	names := make([]string, 0, len(AllComponents))
+	for _, cmp := range AllComponents {
+		names = append(names, string(cmp))
+	}
+	return names
+}
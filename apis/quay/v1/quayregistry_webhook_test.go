@@ -0,0 +1,628 @@
+package v1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+)
+
+var validateQuayRegistryTests = []struct {
+	name     string
+	quay     QuayRegistry
+	warnings []string
+	fields   []string
+}{
+	{
+		"NoComponents",
+		QuayRegistry{},
+		nil,
+		nil,
+	},
+	{
+		"ValidOverrides",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				Components: []Component{
+					{Kind: "quay", Managed: true, Overrides: &Override{Env: []corev1.EnvVar{{Name: "foo", Value: "bar"}}}},
+					{Kind: "postgres", Managed: true, Overrides: &Override{VolumeSize: resourcePtr("50Gi")}},
+					{Kind: "clair", Managed: true, Overrides: &Override{Replicas: ptr.To[int32](0)}},
+				},
+			},
+		},
+		nil,
+		nil,
+	},
+	{
+		"UnknownComponent",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				Components: []Component{
+					{Kind: "postgres", Managed: true},
+					{Kind: "foo", Managed: true},
+				},
+			},
+		},
+		nil,
+		[]string{"spec.components[1].kind"},
+	},
+	{
+		"DuplicatedComponent",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				Components: []Component{
+					{Kind: "postgres", Managed: true},
+					{Kind: "postgres", Managed: false},
+				},
+			},
+		},
+		nil,
+		[]string{"spec.components[1].kind"},
+	},
+	{
+		"UnmanagedQuay",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				Components: []Component{
+					{Kind: "quay", Managed: false},
+				},
+			},
+		},
+		[]string{"spec.components[0].managed: component quay is always managed"},
+		nil,
+	},
+	{
+		"UnsupportedOverrides",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				Components: []Component{
+					{Kind: "horizontalpodautoscaler", Managed: false},
+					{Kind: "tls", Managed: true, Overrides: &Override{VolumeSize: &resource.Quantity{}}},
+					{
+						Kind:    "redis",
+						Managed: true,
+						Overrides: &Override{
+							Replicas:        ptr.To[int32](3),
+							SecurityContext: &corev1.SecurityContext{RunAsNonRoot: ptr.To(true)},
+						},
+					},
+				},
+			},
+		},
+		nil,
+		[]string{
+			"spec.components[1].overrides.volumeSize",
+			"spec.components[2].overrides.replicas",
+			"spec.components[2].overrides.securityContext",
+		},
+	},
+	{
+		"IngressClassNameOverride",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				Components: []Component{
+					{Kind: "ingress", Managed: true, Overrides: &Override{IngressClassName: ptr.To("nginx")}},
+					{Kind: "quay", Managed: true, Overrides: &Override{IngressClassName: ptr.To("nginx")}},
+				},
+			},
+		},
+		nil,
+		[]string{"spec.components[1].overrides.ingressClassName"},
+	},
+	{
+		"ManagedGatewayWithoutParentRef",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				Components: []Component{
+					{Kind: "gateway", Managed: true},
+				},
+			},
+		},
+		nil,
+		[]string{"spec.components[0].overrides.parentRef"},
+	},
+	{
+		"ParentRefOverride",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				Components: []Component{
+					{Kind: "gateway", Managed: true, Overrides: &Override{ParentRef: &GatewayParentReference{Name: "gw"}}},
+					{Kind: "ingress", Managed: true, Overrides: &Override{ParentRef: &GatewayParentReference{Name: "gw"}}},
+				},
+			},
+		},
+		nil,
+		[]string{"spec.components[1].overrides.parentRef"},
+	},
+	{
+		"IssuerRefOverride",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				Components: []Component{
+					{Kind: "tls", Managed: true, Overrides: &Override{IssuerRef: &CertificateIssuerReference{Name: "ca"}}},
+					{Kind: "route", Managed: true, Overrides: &Override{IssuerRef: &CertificateIssuerReference{Name: "ca"}}},
+				},
+			},
+		},
+		nil,
+		[]string{"spec.components[1].overrides.issuerRef"},
+	},
+	{
+		"TLSOverride",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				Components: []Component{
+					{Kind: "postgres", Managed: true, Overrides: &Override{TLS: ptr.To(true)}},
+					{Kind: "clairpostgres", Managed: true, Overrides: &Override{TLS: ptr.To(true)}},
+					{Kind: "redis", Managed: true, Overrides: &Override{TLS: ptr.To(true)}},
+				},
+			},
+		},
+		nil,
+		[]string{"spec.components[2].overrides.tls"},
+	},
+	{
+		"RedisHighAvailability",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				Components: []Component{
+					{Kind: "redis", Managed: true, Overrides: &Override{HighAvailability: ptr.To(true), Replicas: ptr.To[int32](3)}},
+				},
+			},
+		},
+		nil,
+		nil,
+	},
+	{
+		"RedisReplicasWithoutHighAvailability",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				Components: []Component{
+					{Kind: "postgres", Managed: true, Overrides: &Override{HighAvailability: ptr.To(true)}},
+					{Kind: "redis", Managed: true, Overrides: &Override{Replicas: ptr.To[int32](3)}},
+				},
+			},
+		},
+		nil,
+		[]string{"spec.components[0].overrides.highAvailability", "spec.components[1].overrides.replicas"},
+	},
+	{
+		"DatabaseCluster",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				Components: []Component{
+					{Kind: "postgres", Managed: true, Overrides: &Override{Backend: ptr.To(DatabaseBackendCloudNativePG), Replicas: ptr.To[int32](3)}},
+				},
+			},
+		},
+		nil,
+		nil,
+	},
+	{
+		"DatabaseClusterInvalidOverrides",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				Components: []Component{
+					{Kind: "postgres", Managed: true, Overrides: &Override{Backend: ptr.To(DatabaseBackendCloudNativePG), TLS: ptr.To(true)}},
+					{Kind: "clairpostgres", Managed: true, Overrides: &Override{Replicas: ptr.To[int32](3)}},
+				},
+			},
+		},
+		nil,
+		[]string{"spec.components[0].overrides.backend", "spec.components[1].overrides.replicas"},
+	},
+	{
+		"DatabaseBackupInvalidOverrides",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				ConfigBundleSecret: "config-bundle",
+				Components: []Component{
+					{Kind: "postgres", Managed: true, Overrides: &Override{Backup: &DatabaseBackup{Schedule: "daily"}}},
+					{Kind: "clairpostgres", Managed: true, Overrides: &Override{Backup: &DatabaseBackup{Schedule: "@daily", Destination: BackupDestinationObjectStorage}}},
+					{Kind: "objectstorage", Managed: false},
+				},
+			},
+		},
+		nil,
+		[]string{"spec.components[0].overrides.backup", "spec.components[1].overrides.backup"},
+	},
+	{
+		"DatabaseSnapshotInvalidOverrides",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				Components: []Component{
+					{Kind: "postgres", Managed: true, Overrides: &Override{Snapshot: &DatabaseSnapshot{Schedule: "daily"}}},
+					{Kind: "clairpostgres", Managed: true, Overrides: &Override{DataSource: &corev1.TypedLocalObjectReference{Kind: "PersistentVolumeClaim", Name: "production"}}},
+				},
+			},
+		},
+		nil,
+		[]string{"spec.components[0].overrides.snapshot", "spec.components[1].overrides.dataSource"},
+	},
+	{
+		"PostgresUpgradeSnapshotBackup",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				PostgresUpgrade: &PostgresUpgradePolicy{
+					Backup:                  PreUpgradeBackupSnapshot,
+					VolumeSnapshotClassName: "csi-snapclass",
+				},
+			},
+		},
+		nil,
+		nil,
+	},
+	{
+		"PostgresUpgradeDumpWithSnapshotClass",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				PostgresUpgrade: &PostgresUpgradePolicy{
+					Backup:                  PreUpgradeBackupDump,
+					VolumeSnapshotClassName: "csi-snapclass",
+				},
+			},
+		},
+		nil,
+		[]string{"spec.postgresUpgrade.volumeSnapshotClassName"},
+	},
+	{
+		"UpgradeRollbackWithUnmanagedPostgres",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				ConfigBundleSecret: "config",
+				Components: []Component{
+					{Kind: "postgres", Managed: false},
+				},
+				UpgradeRollback: &UpgradeRollbackPolicy{OnMigrationFailure: true},
+			},
+		},
+		nil,
+		[]string{"spec.upgradeRollback.onMigrationFailure"},
+	},
+	{
+		"ImageOverride",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				Components: []Component{
+					{
+						Kind:    "quay",
+						Managed: true,
+						Overrides: &Override{
+							Image:            "mirror.local:5000/projectquay/quay:3.14.0-hotfix",
+							ImagePullSecrets: []corev1.LocalObjectReference{{Name: "pull-secret"}},
+						},
+					},
+				},
+			},
+		},
+		nil,
+		nil,
+	},
+	{
+		"ImageOverrideWithoutTag",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				Components: []Component{
+					{Kind: "clair", Managed: true, Overrides: &Override{Image: "mirror.local:5000/clair"}},
+				},
+			},
+		},
+		nil,
+		[]string{"spec.components[0].overrides.image"},
+	},
+	{
+		"InvalidImageOverride",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				Components: []Component{
+					{Kind: "redis", Managed: true, Overrides: &Override{Image: "quay.io/Redis:7"}},
+				},
+			},
+		},
+		nil,
+		[]string{"spec.components[0].overrides.image"},
+	},
+	{
+		"ImageOverrideUnsupported",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				Components: []Component{
+					{Kind: "mirror", Managed: true, Overrides: &Override{Image: "quay.io/projectquay/quay:3.14.0"}},
+				},
+			},
+		},
+		nil,
+		[]string{"spec.components[0].overrides.image"},
+	},
+	{
+		"SchedulingOverrides",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				Components: []Component{
+					{
+						Kind:    "clairpostgres",
+						Managed: true,
+						Overrides: &Override{
+							Tolerations:       []corev1.Toleration{{Key: "dedicated", Operator: corev1.TolerationOpExists}},
+							NodeSelector:      map[string]string{"dedicated": "registry"},
+							PriorityClassName: ptr.To("registry-critical"),
+						},
+					},
+				},
+			},
+		},
+		nil,
+		nil,
+	},
+	{
+		"InvalidSchedulingOverrides",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				Components: []Component{
+					{
+						Kind:    "quay",
+						Managed: true,
+						Overrides: &Override{
+							Tolerations: []corev1.Toleration{{Operator: corev1.TolerationOpEqual, Value: "registry"}},
+							TopologySpreadConstraints: []corev1.TopologySpreadConstraint{
+								{TopologyKey: "kubernetes.io/hostname"},
+							},
+						},
+					},
+				},
+			},
+		},
+		nil,
+		[]string{
+			"spec.components[0].overrides.tolerations",
+			"spec.components[0].overrides.topologySpreadConstraints",
+		},
+	},
+	{
+		"InvalidPodDisruptionBudget",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				Components: []Component{
+					{
+						Kind:    "quay",
+						Managed: true,
+						Overrides: &Override{
+							PodDisruptionBudget: &DisruptionBudget{
+								MinAvailable: ptr.To(intstr.FromString("half")),
+							},
+						},
+					},
+				},
+			},
+		},
+		nil,
+		[]string{"spec.components[0].overrides.podDisruptionBudget"},
+	},
+	{
+		"JobOverrides",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				JobOverrides: []JobOverride{
+					{Name: "quay-app-upgrade", BackoffLimit: ptr.To[int32](2)},
+					{Name: "clair-postgres-upgrade", ActiveDeadlineSeconds: ptr.To[int64](3600)},
+				},
+			},
+		},
+		nil,
+		nil,
+	},
+	{
+		"UnknownJobOverride",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				JobOverrides: []JobOverride{{Name: "quay-app"}},
+			},
+		},
+		nil,
+		[]string{"spec.jobOverrides[0].name"},
+	},
+	{
+		"DuplicatedJobOverride",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				JobOverrides: []JobOverride{
+					{Name: "quay-app-upgrade"},
+					{Name: "quay-app-upgrade"},
+				},
+			},
+		},
+		nil,
+		[]string{"spec.jobOverrides[1].name"},
+	},
+	{
+		"ReplicasWithManagedHPA",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				Components: []Component{
+					{Kind: "quay", Managed: true, Overrides: &Override{Replicas: ptr.To[int32](3)}},
+				},
+			},
+		},
+		nil,
+		[]string{"spec.components[0].overrides.replicas"},
+	},
+	{
+		"ReplicasWithUnmanagedHPA",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				Components: []Component{
+					{Kind: "horizontalpodautoscaler", Managed: false},
+					{Kind: "quay", Managed: true, Overrides: &Override{Replicas: ptr.To[int32](3)}},
+				},
+			},
+		},
+		nil,
+		nil,
+	},
+	{
+		"OverridesOnUnmanagedComponent",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				ConfigBundleSecret: "config-bundle",
+				Components: []Component{
+					{Kind: "clair", Managed: false, Overrides: &Override{Env: []corev1.EnvVar{{Name: "FOO", Value: "bar"}}}},
+				},
+			},
+		},
+		nil,
+		[]string{"spec.components[0].overrides"},
+	},
+	{
+		"SecretRefOnManagedComponent",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				Components: []Component{
+					{Kind: "tls", Managed: true, SecretRef: &corev1.LocalObjectReference{Name: "tls"}},
+				},
+			},
+		},
+		nil,
+		[]string{"spec.components[0].secretRef"},
+	},
+	{
+		"EmptySecretRefName",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				Components: []Component{
+					{Kind: "tls", Managed: false, SecretRef: &corev1.LocalObjectReference{}},
+				},
+			},
+		},
+		nil,
+		[]string{"spec.components[0].secretRef.name"},
+	},
+	{
+		"UnmanagedTLSWithSecretRef",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				Components: []Component{
+					{Kind: "tls", Managed: false, SecretRef: &corev1.LocalObjectReference{Name: "tls"}},
+				},
+			},
+		},
+		nil,
+		nil,
+	},
+	{
+		"UnmanagedRequiredComponentWithoutConfig",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				Components: []Component{
+					{Kind: "postgres", Managed: false},
+				},
+			},
+		},
+		nil,
+		[]string{"spec.configBundleSecret"},
+	},
+	{
+		"UnmanagedRequiredComponentWithConfig",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				ConfigBundleSecret: "config-bundle",
+				Components: []Component{
+					{Kind: "postgres", Managed: false},
+				},
+			},
+		},
+		nil,
+		nil,
+	},
+	{
+		"UnmanagedOptionalComponentWithoutConfig",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				Components: []Component{
+					{Kind: "mirror", Managed: false},
+				},
+			},
+		},
+		nil,
+		nil,
+	},
+	{
+		"UnmanagedClairPostgresWithManagedClair",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				Components: []Component{
+					{Kind: "clairpostgres", Managed: false},
+				},
+			},
+		},
+		nil,
+		[]string{"spec.configBundleSecret"},
+	},
+	{
+		"UnmanagedClairPostgresWithUnmanagedClair",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				Components: []Component{
+					{Kind: "clair", Managed: false},
+					{Kind: "clairpostgres", Managed: false},
+				},
+			},
+		},
+		nil,
+		nil,
+	},
+}
+
+func TestValidateQuayRegistry(t *testing.T) {
+	for _, test := range validateQuayRegistryTests {
+		t.Run(test.name, func(t *testing.T) {
+			warns, errs := ValidateQuayRegistry(&test.quay)
+			assert.Equal(t, test.warnings, warns)
+
+			var fields []string
+			for _, err := range errs {
+				fields = append(fields, err.Field)
+			}
+			assert.Equal(t, test.fields, fields)
+		})
+	}
+}
+
+func TestQuayRegistryValidator(t *testing.T) {
+	invalid := &QuayRegistry{
+		ObjectMeta: metav1.ObjectMeta{Name: "registry"},
+		Spec: QuayRegistrySpec{
+			Components: []Component{
+				{Kind: "postgres", Managed: false},
+			},
+		},
+	}
+
+	validator := &QuayRegistryValidator{}
+	ctx := context.Background()
+
+	_, err := validator.ValidateCreate(ctx, invalid)
+	assert.True(t, apierrors.IsInvalid(err), "expected invalid error, got %v", err)
+
+	_, err = validator.ValidateUpdate(ctx, &QuayRegistry{}, invalid)
+	assert.True(t, apierrors.IsInvalid(err), "expected invalid error, got %v", err)
+
+	// the operator updating the finalizers of a registry does not change its spec.
+	finalized := invalid.DeepCopy()
+	finalized.Finalizers = []string{"quay-operator/finalizer"}
+	_, err = validator.ValidateUpdate(ctx, invalid, finalized)
+	assert.NoError(t, err)
+
+	deleting := invalid.DeepCopy()
+	deleting.DeletionTimestamp = ptr.To(metav1.Now())
+	_, err = validator.ValidateUpdate(ctx, invalid, deleting)
+	assert.NoError(t, err)
+
+	_, err = validator.ValidateDelete(ctx, invalid)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,164 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// QuayRegistryRestoreSpec defines the desired state of QuayRegistryRestore.
+type QuayRegistryRestoreSpec struct {
+	// QuayRegistry is the name of the QuayRegistry, in the same namespace, whose managed
+	// database is restored.
+	// +kubebuilder:validation:MinLength=1
+	QuayRegistry string `json:"quayRegistry"`
+	// Backup is the backup artifact restored into the managed database.
+	Backup RestoreBackup `json:"backup"`
+}
+
+// RestoreBackup references a dump taken by the scheduled backups of the managed database.
+type RestoreBackup struct {
+	// Name is the file name of the dump, e.g. quay-database-20240501T020000Z.dump.
+	// +kubebuilder:validation:Pattern=`^[^/]+$`
+	Name string `json:"name"`
+	// Destination is where the dump was written by the scheduled backups, defaults to
+	// persistentvolume.
+	// +kubebuilder:validation:Enum=persistentvolume;objectstorage
+	Destination BackupDestination `json:"destination,omitempty"`
+	// PersistentVolumeClaim holding the dump when restoring from a persistentvolume,
+	// defaults to the volume written by the scheduled backups of the database.
+	PersistentVolumeClaim string `json:"persistentVolumeClaim,omitempty"`
+}
+
+// RestorePhase is the stage a QuayRegistryRestore is in.
+type RestorePhase string
+
+// Below follow the phases a QuayRegistryRestore goes through. Completed and Failed are final.
+const (
+	RestorePhasePending     RestorePhase = "Pending"
+	RestorePhaseScalingDown RestorePhase = "ScalingDown"
+	RestorePhaseRestoring   RestorePhase = "Restoring"
+	RestorePhaseMigrating   RestorePhase = "Migrating"
+	RestorePhaseCompleted   RestorePhase = "Completed"
+	RestorePhaseFailed      RestorePhase = "Failed"
+)
+
+// QuayRegistryRestoreStatus defines the observed state of QuayRegistryRestore.
+type QuayRegistryRestoreStatus struct {
+	// Phase is the stage the restore is in.
+	Phase RestorePhase `json:"phase,omitempty"`
+	// Message is a human readable description of the progress, or of the failure, of the
+	// restore.
+	Message string `json:"message,omitempty"`
+	// StartTime is when the registry was scaled down for the restore.
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	// CompletionTime is when the restore completed or failed.
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+	// ScaledDown holds the deployments scaled down for the restore along with the number
+	// of replicas they are scaled back to.
+	ScaledDown []ScaledDeployment `json:"scaledDown,omitempty"`
+}
+
+// ScaledDeployment is a deployment scaled down while the database is restored.
+type ScaledDeployment struct {
+	// Name is the name of the deployment.
+	Name string `json:"name"`
+	// Replicas is the number of replicas the deployment had before the restore.
+	Replicas int32 `json:"replicas"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Registry",type=string,JSONPath=`.spec.quayRegistry`
+// +kubebuilder:printcolumn:name="Backup",type=string,JSONPath=`.spec.backup.name`
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// QuayRegistryRestore is the Schema for the quayregistryrestores API.
+type QuayRegistryRestore struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   QuayRegistryRestoreSpec   `json:"spec,omitempty"`
+	Status QuayRegistryRestoreStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// QuayRegistryRestoreList contains a list of QuayRegistryRestore.
+type QuayRegistryRestoreList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []QuayRegistryRestore `json:"items"`
+}
+
+// RestoreFinished returns true if the provided QuayRegistryRestore has completed or failed.
+func RestoreFinished(restore *QuayRegistryRestore) bool {
+	phase := restore.Status.Phase
+	return phase == RestorePhaseCompleted || phase == RestorePhaseFailed
+}
+
+// RestoreJobNameFor returns the name of the Job restoring the dump into the database.
+func RestoreJobNameFor(restore *QuayRegistryRestore) string {
+	return restore.GetName() + "-pg-restore"
+}
+
+// RestoreDestinationFor returns where the dump restored by the provided QuayRegistryRestore
+// is read from.
+func RestoreDestinationFor(restore *QuayRegistryRestore) BackupDestination {
+	if restore.Spec.Backup.Destination == "" {
+		return BackupDestinationPersistentVolume
+	}
+	return restore.Spec.Backup.Destination
+}
+
+// RestoreVolumeFor returns the PersistentVolumeClaim holding the dump restored by the
+// provided QuayRegistryRestore.
+func RestoreVolumeFor(quay *QuayRegistry, restore *QuayRegistryRestore) string {
+	if restore.Spec.Backup.PersistentVolumeClaim != "" {
+		return restore.Spec.Backup.PersistentVolumeClaim
+	}
+	return DatabaseBackupNameFor(quay, ComponentPostgres)
+}
+
+// ValidateRestore verifies the provided QuayRegistry can be restored by the provided
+// QuayRegistryRestore. Only the database managed by a postgres Deployment is restored.
+func ValidateRestore(quay *QuayRegistry, restore *QuayRegistryRestore) error {
+	components := ResolvedComponents(quay)
+	if !ComponentIsManaged(components, ComponentPostgres) {
+		return fmt.Errorf("QuayRegistry %s does not have a managed postgres", quay.GetName())
+	}
+
+	if DatabaseClusterEnabled(quay, ComponentPostgres) {
+		return fmt.Errorf("restores are not supported with the cloudnativepg backend")
+	}
+
+	if RestoreDestinationFor(restore) != BackupDestinationObjectStorage {
+		return nil
+	}
+
+	if !ComponentIsManaged(components, ComponentObjectStorage) {
+		return fmt.Errorf(
+			"QuayRegistry %s does not have a managed objectstorage", quay.GetName(),
+		)
+	}
+	return nil
+}
+
+func init() {
+	SchemeBuilder.Register(&QuayRegistryRestore{}, &QuayRegistryRestoreList{})
+}
@@ -0,0 +1,93 @@
+package v1
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+)
+
+func TestValidateRestore(t *testing.T) {
+	for _, tt := range []struct {
+		name       string
+		components []Component
+		backup     RestoreBackup
+		expected   error
+	}{
+		{
+			name: "persistentvolume",
+			components: []Component{
+				{Kind: ComponentPostgres, Managed: true},
+			},
+			backup: RestoreBackup{Name: "backup.dump"},
+		},
+		{
+			name: "objectstorage",
+			components: []Component{
+				{Kind: ComponentPostgres, Managed: true},
+				{Kind: ComponentObjectStorage, Managed: true},
+			},
+			backup: RestoreBackup{
+				Name:        "backup.dump",
+				Destination: BackupDestinationObjectStorage,
+			},
+		},
+		{
+			name: "unmanaged postgres",
+			components: []Component{
+				{Kind: ComponentPostgres, Managed: false},
+			},
+			backup:   RestoreBackup{Name: "backup.dump"},
+			expected: errors.New("QuayRegistry registry does not have a managed postgres"),
+		},
+		{
+			name: "cloudnativepg",
+			components: []Component{
+				{
+					Kind:    ComponentPostgres,
+					Managed: true,
+					Overrides: &Override{
+						Backend: ptr.To(DatabaseBackendCloudNativePG),
+					},
+				},
+			},
+			backup:   RestoreBackup{Name: "backup.dump"},
+			expected: errors.New("restores are not supported with the cloudnativepg backend"),
+		},
+		{
+			name: "unmanaged objectstorage",
+			components: []Component{
+				{Kind: ComponentPostgres, Managed: true},
+				{Kind: ComponentObjectStorage, Managed: false},
+			},
+			backup: RestoreBackup{
+				Name:        "backup.dump",
+				Destination: BackupDestinationObjectStorage,
+			},
+			expected: errors.New("QuayRegistry registry does not have a managed objectstorage"),
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			quay := &QuayRegistry{
+				ObjectMeta: metav1.ObjectMeta{Name: "registry"},
+				Spec:       QuayRegistrySpec{Components: tt.components},
+			}
+			restore := &QuayRegistryRestore{
+				Spec: QuayRegistryRestoreSpec{QuayRegistry: "registry", Backup: tt.backup},
+			}
+			assert.Equal(t, tt.expected, ValidateRestore(quay, restore))
+		})
+	}
+}
+
+func TestRestoreVolumeFor(t *testing.T) {
+	quay := &QuayRegistry{ObjectMeta: metav1.ObjectMeta{Name: "registry"}}
+
+	restore := &QuayRegistryRestore{}
+	assert.Equal(t, "registry-quay-database-backup", RestoreVolumeFor(quay, restore))
+
+	restore.Spec.Backup.PersistentVolumeClaim = "imported-backups"
+	assert.Equal(t, "imported-backups", RestoreVolumeFor(quay, restore))
+}
@@ -9,7 +9,7 @@ import (
 	"encoding/pem"
 	err "errors"
 	"fmt"
-	"slices"
+	"net/url"
 	"strings"
 
 	routev1 "github.com/openshift/api/route/v1"
@@ -21,6 +21,7 @@ import (
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/yaml"
 
@@ -49,6 +50,13 @@ const (
 	clairDbName   = "CLAIR_DB_NAME"
 )
 
+// databaseClusterGVK is the kind of the CloudNativePG clusters running the managed databases.
+var databaseClusterGVK = schema.GroupVersionKind{
+	Group:   "postgresql.cnpg.io",
+	Version: "v1",
+	Kind:    "Cluster",
+}
+
 // checkDeprecatedManagedKeys populates the provided quay context with information we
 // persist between Reconcile calls. This function uses the old secret (<=3.6.4) and not
 // the new one (>=3.7.0).
@@ -215,9 +223,118 @@ func (r *QuayRegistryReconciler) checkExternalTLSSecret(
 		)
 	}
 
-	secretName := secretRef.Name
-	namespace := quay.GetNamespace()
+	return r.loadTLSSecret(ctx, qctx, quay.GetNamespace(), secretRef.Name)
+}
+
+// checkCertManagerTLS reads the TLS cert/key issued by cert-manager when the TLS component
+// references an issuer. The Certificate is created during the rollout so a missing secret is
+// not an error, once issued the secret watch triggers a new reconcile. Populates TLSCert,
+// TLSKey, and TLSSecretHash on the context.
+func (r *QuayRegistryReconciler) checkCertManagerTLS(
+	ctx context.Context, qctx *quaycontext.QuayRegistryContext,
+	quay *v1.QuayRegistry, bundle *corev1.Secret,
+) error {
+	if !v1.TLSIssuedByCertManager(quay) {
+		return nil
+	}
+
+	if _, hasCert := bundle.Data["ssl.cert"]; hasCert {
+		return fmt.Errorf(
+			"tls component issuerRef and ssl.cert in configBundleSecret are mutually exclusive",
+		)
+	}
+	if _, hasKey := bundle.Data["ssl.key"]; hasKey {
+		return fmt.Errorf(
+			"tls component issuerRef and ssl.key in configBundleSecret are mutually exclusive",
+		)
+	}
+
+	secretName := v1.CertManagerTLSSecretName(quay)
+	if err := r.loadTLSSecret(ctx, qctx, quay.GetNamespace(), secretName); err != nil {
+		if errors.IsNotFound(err) {
+			r.Log.Info("certificate not yet issued by cert-manager", "secret", secretName)
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+// checkOperatorTLS populates the provided QuayRegistryContext with the CA and the serving
+// certificate previously issued by the operator. They are generated during the inflate
+// process if the secret does not exist yet, this function is a no-op if TLS is not issued
+// by the operator.
+func (r *QuayRegistryReconciler) checkOperatorTLS(
+	ctx context.Context, qctx *quaycontext.QuayRegistryContext, quay *v1.QuayRegistry,
+) error {
+	if !v1.TLSIssuedByOperator(qctx, quay) {
+		return nil
+	}
 
+	nsn := types.NamespacedName{
+		Name:      v1.OperatorTLSSecretNameFor(quay),
+		Namespace: quay.Namespace,
+	}
+
+	var secret corev1.Secret
+	if err := r.Get(ctx, nsn, &secret); err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	qctx.OperatorCACert = secret.Data["ca.crt"]
+	qctx.OperatorCAKey = secret.Data["ca.key"]
+	qctx.TLSCert = secret.Data["tls.crt"]
+	qctx.TLSKey = secret.Data["tls.key"]
+	return nil
+}
+
+// checkDatabaseTLS populates the provided QuayRegistryContext with the CA and the certificates
+// previously issued by the operator for the managed databases. They are generated during the
+// inflate process if the secret does not exist yet, this function is a no-op if TLS is not
+// enabled for any of the managed databases.
+func (r *QuayRegistryReconciler) checkDatabaseTLS(
+	ctx context.Context, qctx *quaycontext.QuayRegistryContext, quay *v1.QuayRegistry,
+) error {
+	if !v1.DatabaseTLSEnabled(quay, v1.ComponentPostgres) &&
+		!v1.DatabaseTLSEnabled(quay, v1.ComponentClairPostgres) {
+		return nil
+	}
+
+	nsn := types.NamespacedName{
+		Name:      v1.DatabaseTLSSecretNameFor(quay),
+		Namespace: quay.Namespace,
+	}
+
+	var secret corev1.Secret
+	if err := r.Get(ctx, nsn, &secret); err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	qctx.DatabaseCACert = secret.Data["ca.crt"]
+	qctx.DatabaseCAKey = secret.Data["ca.key"]
+	qctx.DatabaseServerCert = secret.Data["postgres.crt"]
+	qctx.DatabaseServerKey = secret.Data["postgres.key"]
+	qctx.DatabaseClientCert = secret.Data["postgres-client.crt"]
+	qctx.DatabaseClientKey = secret.Data["postgres-client.key"]
+	qctx.ClairDatabaseServerCert = secret.Data["clair-postgres.crt"]
+	qctx.ClairDatabaseServerKey = secret.Data["clair-postgres.key"]
+	qctx.ClairDatabaseClientCert = secret.Data["clair-postgres-client.crt"]
+	qctx.ClairDatabaseClientKey = secret.Data["clair-postgres-client.key"]
+	return nil
+}
+
+// loadTLSSecret fetches and validates the TLS secret with the provided name, labels it so the
+// cache informer picks it up for reactive watches and populates TLSCert, TLSKey, and
+// TLSSecretHash on the context.
+func (r *QuayRegistryReconciler) loadTLSSecret(
+	ctx context.Context, qctx *quaycontext.QuayRegistryContext, namespace, secretName string,
+) error {
 	tlsCert, tlsKey, secret, err := quaytls.FetchAndValidate(ctx, r.Client, namespace, secretName)
 	if err != nil {
 		return err
@@ -231,16 +348,14 @@ func (r *QuayRegistryReconciler) checkExternalTLSSecret(
 		}
 		secret.Labels[v1.TLSSecretLabel] = "true"
 		if err := r.Patch(ctx, secret, patch); err != nil {
-			return fmt.Errorf("unable to label external TLS secret %q: %w", secretName, err)
+			return fmt.Errorf("unable to label TLS secret %q: %w", secretName, err)
 		}
 	}
 
 	qctx.TLSCert = tlsCert
 	qctx.TLSKey = tlsKey
 
-	hash := sha256.Sum256(slices.Concat(tlsCert, tlsKey))
-	hashStr := hex.EncodeToString(hash[:])
-	qctx.TLSSecretHash = hashStr[len(hashStr)-8:]
+	qctx.TLSSecretHash = quaytls.Hash(tlsCert, tlsKey)
 	return nil
 }
 
@@ -431,7 +546,8 @@ func (r *QuayRegistryReconciler) checkObjectBucketClaimsAvailable(
 // checkManagedDatabaseReady checks whether managed database deployments are
 // available. When a database component is unmanaged we cannot verify its
 // readiness so we optimistically mark it as initialized. When managed, the
-// corresponding deployment must have at least one available replica.
+// corresponding deployment must have at least one available replica. Databases
+// run by CloudNativePG must have a ready instance and their credentials issued.
 func (r *QuayRegistryReconciler) checkManagedDatabaseReady(
 	ctx context.Context, qctx *quaycontext.QuayRegistryContext, quay *v1.QuayRegistry,
 ) {
@@ -443,6 +559,9 @@ func (r *QuayRegistryReconciler) checkManagedDatabaseReady(
 				"component", component, "deployment", suffix)
 			return true
 		}
+		if v1.DatabaseClusterEnabled(quay, component) {
+			return r.checkDatabaseClusterReady(ctx, qctx, quay, component)
+		}
 		name := fmt.Sprintf("%s-%s", quay.GetName(), suffix)
 		var dep appsv1.Deployment
 		if err := r.Get(ctx, types.NamespacedName{
@@ -469,6 +588,215 @@ func (r *QuayRegistryReconciler) checkManagedDatabaseReady(
 	qctx.ClairDatabaseInitialized = checkDeployment(v1.ComponentClairPostgres, "clair-postgres")
 }
 
+// checkReadOnlyUpgrade populates the provided QuayContext with the image of the previous
+// version of Quay when the registry is upgraded with the read-only strategy, and whether it
+// has been rolled into read-only mode already. The previous version is only known while the
+// Quay deployment still runs it.
+func (r *QuayRegistryReconciler) checkReadOnlyUpgrade(
+	ctx context.Context, qctx *quaycontext.QuayRegistryContext, quay *v1.QuayRegistry,
+) error {
+	if !v1.ReadOnlyUpgradeEnabled(quay) {
+		return nil
+	}
+
+	var dep appsv1.Deployment
+	if err := r.Get(ctx, types.NamespacedName{
+		Name:      fmt.Sprintf("%s-quay-app", quay.GetName()),
+		Namespace: quay.GetNamespace(),
+	}, &dep); err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	qctx.PreviousQuayImage = quayImageOf(&dep, quay.Status.CurrentVersion)
+	if qctx.PreviousQuayImage == "" {
+		return nil
+	}
+
+	readonly := false
+	for _, volume := range dep.Spec.Template.Spec.Volumes {
+		if volume.Name != "config" || volume.Projected == nil {
+			continue
+		}
+		for _, source := range volume.Projected.Sources {
+			prefix := fmt.Sprintf("%s-quay-config-secret-readonly-", quay.GetName())
+			if source.Secret != nil && strings.HasPrefix(source.Secret.Name, prefix) {
+				readonly = true
+			}
+		}
+	}
+
+	// the migrations only start once no pod runs the previous config anymore, and once the
+	// mirror workers, which are not run in read-only mode, are stopped.
+	rolledout := dep.Status.ObservedGeneration >= dep.Generation &&
+		dep.Status.UpdatedReplicas == dep.Status.Replicas
+	if !readonly || !rolledout {
+		return nil
+	}
+
+	paused, err := r.pauseMirror(ctx, quay)
+	if err != nil {
+		return err
+	}
+	qctx.QuayReadOnly = paused
+	return nil
+}
+
+// pauseMirror scales the mirror workers of the provided QuayRegistry down to zero replicas.
+// Returns true once none of them runs anymore.
+func (r *QuayRegistryReconciler) pauseMirror(ctx context.Context, quay *v1.QuayRegistry) (bool, error) {
+	var dep appsv1.Deployment
+	if err := r.Get(ctx, types.NamespacedName{
+		Name:      fmt.Sprintf("%s-quay-mirror", quay.GetName()),
+		Namespace: quay.GetNamespace(),
+	}, &dep); err != nil {
+		if errors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	}
+
+	if dep.Spec.Replicas == nil || *dep.Spec.Replicas != 0 {
+		patch := client.MergeFrom(dep.DeepCopy())
+		dep.Spec.Replicas = ptr.To[int32](0)
+		if err := r.Patch(ctx, &dep, patch); err != nil {
+			return false, fmt.Errorf("unable to scale mirror workers down: %w", err)
+		}
+	}
+	return dep.Status.Replicas == 0, nil
+}
+
+// quayImageOf returns the image the provided Quay deployment runs the provided version of Quay
+// with. Returns an empty string if it runs another version.
+func quayImageOf(dep *appsv1.Deployment, version v1.QuayVersion) string {
+	for _, container := range dep.Spec.Template.Spec.Containers {
+		if container.Name != "quay-app" {
+			continue
+		}
+		for _, env := range container.Env {
+			if env.Name == "QUAY_VERSION" && env.Value == string(version) {
+				return container.Image
+			}
+		}
+	}
+	return ""
+}
+
+// checkDatabaseClusterReady returns true if the CloudNativePG cluster of the provided
+// database component has a ready instance and the credentials for it have been read.
+func (r *QuayRegistryReconciler) checkDatabaseClusterReady(
+	ctx context.Context,
+	qctx *quaycontext.QuayRegistryContext,
+	quay *v1.QuayRegistry,
+	component v1.ComponentKind,
+) bool {
+	log := r.Log.WithValues("QuayRegistry", quay.GetName())
+
+	name := v1.DatabaseClusterNameFor(quay, component)
+	var cluster unstructured.Unstructured
+	cluster.SetGroupVersionKind(databaseClusterGVK)
+	if err := r.Get(ctx, types.NamespacedName{
+		Name:      name,
+		Namespace: quay.GetNamespace(),
+	}, &cluster); err != nil {
+		log.Info("database cluster not found, not initialized",
+			"component", component, "cluster", name)
+		return false
+	}
+
+	readyInstances, _, _ := unstructured.NestedInt64(cluster.Object, "status", "readyInstances")
+	hasCredentials := qctx.DbUri != ""
+	if component == v1.ComponentClairPostgres {
+		hasCredentials = qctx.ClairDbPassword != ""
+	}
+
+	ready := readyInstances > 0 && hasCredentials
+	log.Info("database cluster status",
+		"component", component,
+		"cluster", name,
+		"readyInstances", readyInstances,
+		"credentials", hasCredentials,
+		"initialized", ready,
+	)
+	return ready
+}
+
+// checkDatabaseClusterCredentials reads the credentials generated by CloudNativePG for the
+// databases it runs into the provided context, these take precedence over the persisted
+// managed keys. The credentials are left empty until the clusters have been bootstrapped.
+func (r *QuayRegistryReconciler) checkDatabaseClusterCredentials(
+	ctx context.Context, qctx *quaycontext.QuayRegistryContext, quay *v1.QuayRegistry,
+) error {
+	appSecret := func(component v1.ComponentKind) (*corev1.Secret, error) {
+		nsn := types.NamespacedName{
+			Name:      v1.DatabaseClusterAppSecretNameFor(quay, component),
+			Namespace: quay.GetNamespace(),
+		}
+
+		var secret corev1.Secret
+		if err := r.Get(ctx, nsn, &secret); err != nil {
+			if errors.IsNotFound(err) {
+				return nil, nil
+			}
+			return nil, err
+		}
+		return &secret, nil
+	}
+
+	if v1.DatabaseClusterEnabled(quay, v1.ComponentPostgres) {
+		secret, err := appSecret(v1.ComponentPostgres)
+		if err != nil {
+			return err
+		}
+
+		qctx.DbUri = ""
+		if secret != nil {
+			uri, err := databaseClusterURI(string(secret.Data["uri"]))
+			if err != nil {
+				return err
+			}
+			qctx.DbUri = uri
+		}
+	}
+
+	if v1.DatabaseClusterEnabled(quay, v1.ComponentClairPostgres) {
+		secret, err := appSecret(v1.ComponentClairPostgres)
+		if err != nil {
+			return err
+		}
+
+		qctx.ClairDbUser = ""
+		qctx.ClairDbPassword = ""
+		qctx.ClairDbName = ""
+		if secret != nil {
+			qctx.ClairDbUser = string(secret.Data["username"])
+			qctx.ClairDbPassword = string(secret.Data["password"])
+			qctx.ClairDbName = string(secret.Data["dbname"])
+		}
+	}
+	return nil
+}
+
+// databaseClusterURI returns the provided CloudNativePG connection uri requiring TLS, the
+// clusters only accept encrypted connections from outside of their pods.
+func databaseClusterURI(uri string) (string, error) {
+	if uri == "" {
+		return "", nil
+	}
+
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		return "", fmt.Errorf("unable to parse database cluster uri: %w", err)
+	}
+
+	query := parsed.Query()
+	query.Set("sslmode", "require")
+	parsed.RawQuery = query.Encode()
+	return parsed.String(), nil
+}
+
 // checkBuildManagerAvailable verifies if the config bundle contains an entry pointing to the
 // buildman host. If it contains then sets it properly in the provided QuayRegistryContext
 func (r *QuayRegistryReconciler) checkBuildManagerAvailable(
@@ -486,6 +814,61 @@ func (r *QuayRegistryReconciler) checkBuildManagerAvailable(
 	return nil
 }
 
+// checkBuilderAvailable prepares the build namespace for the managed builder component and
+// reads the token issued for the builder service account into the provided context. If the
+// config bundle does not point to a build manager host one is derived from the cluster
+// hostname, the token is only available after the builder objects have been created.
+func (r *QuayRegistryReconciler) checkBuilderAvailable(
+	ctx context.Context, qctx *quaycontext.QuayRegistryContext, quay *v1.QuayRegistry,
+) error {
+	// NOTE: the build namespace is created by the operator, this requires cluster wide
+	// permissions.
+	if r.WatchNamespace != "" {
+		return fmt.Errorf("builds are not supported when not running in all-namespaces mode")
+	}
+
+	if !v1.ComponentIsManaged(quay.Spec.Components, v1.ComponentRedis) {
+		return fmt.Errorf("builds are orchestrated through the managed redis component")
+	}
+
+	// the build manager orchestrator connects to a single redis host.
+	if v1.RedisHighAvailabilityEnabled(quay) {
+		return fmt.Errorf("builds are not supported with a highly available redis")
+	}
+
+	if qctx.BuildManagerHostname == "" {
+		if qctx.ClusterHostname == "" {
+			return fmt.Errorf("`BUILDMAN_HOSTNAME` is not set")
+		}
+		qctx.BuildManagerHostname = fmt.Sprintf(
+			"%s-quay-builder-%s.%s:443",
+			quay.GetName(),
+			quay.GetNamespace(),
+			qctx.ClusterHostname,
+		)
+	}
+
+	if err := r.ensureBuilderNamespace(ctx, quay); err != nil {
+		return fmt.Errorf("unable to create build namespace: %w", err)
+	}
+
+	nsn := types.NamespacedName{
+		Namespace: v1.BuilderNamespaceFor(quay),
+		Name:      v1.BuilderTokenSecretNameFor(quay),
+	}
+
+	var secret corev1.Secret
+	if err := r.Get(ctx, nsn, &secret); err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	qctx.BuilderServiceAccountToken = string(secret.Data[corev1.ServiceAccountTokenKey])
+	return nil
+}
+
 // Validates if the monitoring component can be run. We assume that we are
 // running in an Openshift environment with cluster monitoring enabled for our
 // monitoring component to work
@@ -536,6 +919,69 @@ func (r *QuayRegistryReconciler) checkMonitoringAvailable(
 	return nil
 }
 
+// checkGatewayAPIAvailable verifies if the Gateway API routes can be listed in the namespace
+// of the provided QuayRegistry, flags the context accordingly.
+func (r *QuayRegistryReconciler) checkGatewayAPIAvailable(
+	ctx context.Context, qctx *quaycontext.QuayRegistryContext, quay *v1.QuayRegistry,
+) error {
+	var routes unstructured.UnstructuredList
+	routes.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   "gateway.networking.k8s.io",
+		Version: "v1",
+		Kind:    "HTTPRouteList",
+	})
+	if err := r.List(ctx, &routes, client.InNamespace(quay.GetNamespace())); err != nil {
+		r.Log.Info("Unable to find HTTPRoute CRD. Gateway component disabled")
+		return err
+	}
+
+	r.Log.Info("cluster supports Gateway API")
+	qctx.SupportsGatewayAPI = true
+	return nil
+}
+
+// checkCertManagerAvailable verifies if the cert-manager API is available in the cluster, if
+// it is the context is updated to reflect this.
+func (r *QuayRegistryReconciler) checkCertManagerAvailable(
+	ctx context.Context, qctx *quaycontext.QuayRegistryContext, quay *v1.QuayRegistry,
+) error {
+	var certs unstructured.UnstructuredList
+	certs.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   "cert-manager.io",
+		Version: "v1",
+		Kind:    "CertificateList",
+	})
+	if err := r.List(ctx, &certs, client.InNamespace(quay.GetNamespace())); err != nil {
+		r.Log.Info("Unable to find Certificate CRD. cert-manager TLS disabled")
+		return err
+	}
+
+	r.Log.Info("cluster supports cert-manager API")
+	qctx.SupportsCertManager = true
+	return nil
+}
+
+// checkCloudNativePGAvailable verifies if the CloudNativePG API is available in the cluster,
+// if it is the context is updated to reflect this.
+func (r *QuayRegistryReconciler) checkCloudNativePGAvailable(
+	ctx context.Context, qctx *quaycontext.QuayRegistryContext, quay *v1.QuayRegistry,
+) error {
+	var clusters unstructured.UnstructuredList
+	clusters.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   databaseClusterGVK.Group,
+		Version: databaseClusterGVK.Version,
+		Kind:    "ClusterList",
+	})
+	if err := r.List(ctx, &clusters, client.InNamespace(quay.GetNamespace())); err != nil {
+		r.Log.Info("Unable to find CloudNativePG Cluster CRD. cloudnativepg backend disabled")
+		return err
+	}
+
+	r.Log.Info("cluster supports CloudNativePG API")
+	qctx.SupportsCloudNativePG = true
+	return nil
+}
+
 // checkPostgresVersion returns the image name used by the currently deployed postgres version
 func (r *QuayRegistryReconciler) checkNeedsPostgresUpgradeForComponent(
 	ctx context.Context, qctx *quaycontext.QuayRegistryContext, quay *v1.QuayRegistry, component v1.ComponentKind,
@@ -569,32 +1015,42 @@ func (r *QuayRegistryReconciler) checkNeedsPostgresUpgradeForComponent(
 		return nil, true
 	}
 
-	deployedImageName := postgresDeployment.Spec.Template.Spec.Containers[0].Image
-	r.Log.Info(fmt.Sprintf("%s deployment found", component), "image", deployedImageName)
-
-	expectedImage, err := kustomize.ComponentImageFor(component)
-	if err != nil {
-		r.Log.Error(err, "failed to get postgres image")
+	// the version is read from the data of the database, not from the image it runs, as a
+	// database may be several versions behind the end of its upgrade path.
+	version, err := r.checkDatabaseVersion(ctx, quay, component, postgresDeployment)
+	if err != nil || version == nil {
+		return err, false
 	}
+	current := *version
 
-	expectedName := expectedImage.NewName
-	if expectedName == "" {
-		expectedName = expectedImage.Name
+	next, ok := v1.NextPostgresVersion(component, current.Version)
+	if !ok {
+		r.Log.Info(fmt.Sprintf("%s does not need to perform an upgrade", component))
+		return nil, true
 	}
 
-	currentName := extractImageName(deployedImageName)
+	r.Log.Info(
+		fmt.Sprintf("%s needs to perform an upgrade, marking in context", component),
+		"from", current.Version,
+		"to", next.Major,
+	)
+	*info.upgradeField = true
 
-	// Extract repository names by finding the last component after splitting by '/'
-	// This handles cases where repository names contain slashes (e.g., "quay.io/org/repo")
-	currentRepoName := currentName[strings.LastIndex(currentName, "/")+1:]
-	expectedRepoName := expectedName[strings.LastIndex(expectedName, "/")+1:]
+	if err := r.checkPostgresUpgradeVolume(ctx, quay, component, current, next); err != nil {
+		return err, false
+	}
 
-	if currentRepoName != expectedRepoName {
-		r.Log.Info(fmt.Sprintf("%s needs to perform an upgrade, marking in context", component))
-		*info.upgradeField = true
-	} else {
-		r.Log.Info(fmt.Sprintf("%s does not need to perform an upgrade", component))
-		return nil, true
+	if err := r.startPostgresUpgradeStep(ctx, quay, component, current.Version, next.Major); err != nil {
+		return err, false
+	}
+
+	// dumps are taken from the running database, before it is scaled down.
+	method := v1.PreUpgradeBackupMethodFor(quay)
+	if method == v1.PreUpgradeBackupDump {
+		done, err := r.checkPreUpgradeBackup(ctx, quay, component, current)
+		if err != nil || !done {
+			return err, false
+		}
 	}
 
 	// at this point we have determined that these postgres deployments need to be upgraded and can set them to 0 replicas
@@ -632,19 +1088,13 @@ func (r *QuayRegistryReconciler) checkNeedsPostgresUpgradeForComponent(
 		return nil, false
 	}
 
-	return nil, true
-}
-
-func extractImageName(imageName string) string {
-	parts := strings.Split(imageName, "@")
-	if len(parts) > 1 {
-		return parts[0]
+	// snapshots are taken of the volume once the database has been shut down.
+	if method == v1.PreUpgradeBackupSnapshot {
+		done, err := r.checkPreUpgradeBackup(ctx, quay, component, current)
+		return err, done
 	}
-	parts = strings.Split(imageName, ":")
-	if len(parts) > 1 {
-		return parts[0]
-	}
-	return imageName
+
+	return nil, true
 }
 
 // Taken from https://stackoverflow.com/questions/46735347/how-can-i-fetch-a-certificate-from-a-url
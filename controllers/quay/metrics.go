@@ -0,0 +1,21 @@
+package controllers
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// tlsCertificateExpirySeconds tracks, per quay registry, the number of seconds left before the
+// certificate served for the registry endpoint expires. Registries whose certificate is not
+// available to the operator (e.g. when using the cluster wildcard certs) are not reported.
+var tlsCertificateExpirySeconds = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "quay_operator_tls_certificate_expiry_seconds",
+		Help: "Seconds until the certificate served for a QuayRegistry expires.",
+	},
+	[]string{"namespace", "name"},
+)
+
+func init() {
+	metrics.Registry.MustRegister(tlsCertificateExpirySeconds)
+}
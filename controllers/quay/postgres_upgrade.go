@@ -0,0 +1,690 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	v1 "github.com/quay/quay-operator/apis/quay/v1"
+	quaycontext "github.com/quay/quay-operator/pkg/context"
+	"github.com/quay/quay-operator/pkg/kustomize"
+)
+
+// defaultPostgresVolumeSize is the size of the volumes of the managed databases when no
+// volumeSize override is set.
+const defaultPostgresVolumeSize = "50Gi"
+
+// preUpgradeDumpFile is the name of the dump written into the volume of a pre-upgrade dump.
+// It can be restored with a QuayRegistryRestore referencing that volume.
+const preUpgradeDumpFile = "pre-upgrade.dump"
+
+// preUpgradeDumpScript dumps the database with the pg_dump of the version being upgraded.
+const preUpgradeDumpScript = `set -euo pipefail
+
+echo "dumping ${PGDATABASE} into ${BACKUP_FILE}"
+pg_dump --format=custom --file="/backups/${BACKUP_FILE}.partial"
+mv "/backups/${BACKUP_FILE}.partial" "/backups/${BACKUP_FILE}"
+`
+
+// postgresVersionScript writes the major version of Postgres of the data in the volume to the
+// termination message of the container. Nothing is written when the volume holds no data.
+const postgresVersionScript = `cat /var/lib/pgsql/data/userdata/PG_VERSION > /dev/termination-log 2>/dev/null || true`
+
+var volumeSnapshotGVK = schema.GroupVersionKind{
+	Group:   v1.VolumeSnapshotGroup,
+	Version: "v1",
+	Kind:    "VolumeSnapshot",
+}
+
+// checkDatabaseVersion returns the major version of Postgres of the data of the provided
+// database component along with the volume holding it. The version is read once from the
+// PG_VERSION of the volumes of the versions on the upgrade path, newest first, and kept in the
+// status afterwards. Databases without data on any of them are on the last version of the
+// path. Returns nil while the volumes are being read.
+func (r *QuayRegistryReconciler) checkDatabaseVersion(
+	ctx context.Context,
+	quay *v1.QuayRegistry,
+	component v1.ComponentKind,
+	deployment *appsv1.Deployment,
+) (*v1.DatabaseVersion, error) {
+	if current := v1.DatabaseVersionFor(quay, component); current != nil {
+		return current, nil
+	}
+
+	target := v1.TargetPostgresVersionFor(component)
+	image, err := kustomize.PostgresImageFor(quay, component, target)
+	if err != nil {
+		return nil, err
+	}
+
+	found := v1.DatabaseVersion{
+		Component:             component,
+		Version:               target.Major,
+		PersistentVolumeClaim: v1.PostgresVolumeNameFor(quay, target),
+	}
+
+	path := v1.PostgresUpgradePathFor(component)
+	var probed []string
+	for i := len(path) - 1; i >= 0; i-- {
+		volume := v1.PostgresVolumeNameFor(quay, path[i])
+
+		var pvc corev1.PersistentVolumeClaim
+		if err := r.Get(ctx, types.NamespacedName{
+			Name:      volume,
+			Namespace: quay.GetNamespace(),
+		}, &pvc); err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+
+		probed = append(probed, postgresVersionJobNameFor(volume))
+		version, done, err := r.readPostgresVersion(ctx, quay, volume, image, deployment)
+		if err != nil || !done {
+			return nil, err
+		}
+		if version == "" {
+			continue
+		}
+
+		major, err := strconv.ParseInt(version, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("unexpected PG_VERSION %q in %s", version, volume)
+		}
+		if _, ok := v1.PostgresVersionFor(component, int32(major)); !ok {
+			return nil, fmt.Errorf(
+				"%w: %s holds data of unsupported postgres version %d",
+				errUpgradeUnsupported, volume, major,
+			)
+		}
+
+		found.Version = int32(major)
+		found.PersistentVolumeClaim = volume
+		break
+	}
+
+	r.Log.Info(
+		"read database version",
+		"component", component,
+		"version", found.Version,
+		"volume", found.PersistentVolumeClaim,
+	)
+	v1.SetDatabaseVersion(quay, found)
+	if err := r.updateStatus(ctx, quay); err != nil {
+		return nil, err
+	}
+
+	// the jobs are only deleted once the version is recorded so volumes without data are
+	// not read again.
+	for _, name := range probed {
+		job := &batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: quay.GetNamespace()},
+		}
+		if err := r.Delete(
+			ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground),
+		); err != nil && !errors.IsNotFound(err) {
+			r.Log.Error(err, "unable to delete database version job", "job", name)
+		}
+	}
+	return v1.DatabaseVersionFor(quay, component), nil
+}
+
+// readPostgresVersion reads the PG_VERSION of the provided volume through a Job. Returns true
+// once the Job has completed, along with the version read, empty if the volume holds no data.
+func (r *QuayRegistryReconciler) readPostgresVersion(
+	ctx context.Context,
+	quay *v1.QuayRegistry,
+	volume string,
+	image string,
+	deployment *appsv1.Deployment,
+) (string, bool, error) {
+	name := postgresVersionJobNameFor(volume)
+
+	var job batchv1.Job
+	if err := r.Get(ctx, types.NamespacedName{
+		Name:      name,
+		Namespace: quay.GetNamespace(),
+	}, &job); err != nil {
+		if !errors.IsNotFound(err) {
+			return "", false, err
+		}
+
+		obj := v1.EnsureOwnerReference(
+			quay, postgresVersionJobFor(quay, name, volume, image, deployment),
+		)
+		return "", false, r.Create(ctx, obj)
+	}
+
+	if job.Status.Succeeded == 0 {
+		for _, cond := range job.Status.Conditions {
+			if cond.Type == batchv1.JobFailed && cond.Status == corev1.ConditionTrue {
+				return "", false, fmt.Errorf("job %s failed: %s", name, cond.Message)
+			}
+		}
+		return "", false, nil
+	}
+
+	var pods corev1.PodList
+	if err := r.List(
+		ctx,
+		&pods,
+		client.InNamespace(quay.GetNamespace()),
+		client.MatchingLabels{"job-name": name},
+	); err != nil {
+		return "", false, err
+	}
+
+	for _, pod := range pods.Items {
+		for _, status := range pod.Status.ContainerStatuses {
+			terminated := status.State.Terminated
+			if terminated != nil && terminated.ExitCode == 0 {
+				return strings.TrimSpace(terminated.Message), true, nil
+			}
+		}
+	}
+	return "", false, fmt.Errorf("unable to find the result of job %s", name)
+}
+
+// startPostgresUpgradeStep records in the status the upgrade of the provided database component
+// from and to the provided versions. A failed attempt of the same step is retried. The upgrade
+// job left by a previous step is deleted so the step gets a job of its own.
+func (r *QuayRegistryReconciler) startPostgresUpgradeStep(
+	ctx context.Context, quay *v1.QuayRegistry, component v1.ComponentKind, from, to int32,
+) error {
+	step := v1.PostgresUpgradeStepFor(quay, component)
+	same := step != nil && step.From == from && step.To == to
+	if same && step.Phase == v1.PostgresUpgradePhaseRunning {
+		return nil
+	}
+
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      postgresUpgradeJobNameFor(quay, component),
+			Namespace: quay.GetNamespace(),
+		},
+	}
+	if err := r.Delete(
+		ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground),
+	); err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+
+	r.Log.Info("starting postgres upgrade", "component", component, "from", from, "to", to)
+	now := metav1.Now()
+	if same && step.Phase == v1.PostgresUpgradePhaseFailed {
+		step.Phase = v1.PostgresUpgradePhaseRunning
+		step.Message = ""
+		step.StartTime = &now
+		step.CompletionTime = nil
+	} else {
+		quay.Status.PostgresUpgrades = append(
+			quay.Status.PostgresUpgrades,
+			v1.PostgresUpgradeStep{
+				Component: component,
+				From:      from,
+				To:        to,
+				Phase:     v1.PostgresUpgradePhaseRunning,
+				StartTime: &now,
+			},
+		)
+	}
+	return r.updateStatus(ctx, quay)
+}
+
+// checkPreUpgradeBackup takes the backup the upgrade policy of the provided QuayRegistry asks
+// for before the provided database component is upgraded from the provided version. Returns
+// true once the backup has completed and has been recorded in the status. A backup is taken
+// once per upgrade to the version at the end of the upgrade path, a backup of a previous
+// upgrade not yet acknowledged blocks the upgrade as it would otherwise be overwritten.
+func (r *QuayRegistryReconciler) checkPreUpgradeBackup(
+	ctx context.Context,
+	quay *v1.QuayRegistry,
+	component v1.ComponentKind,
+	from v1.DatabaseVersion,
+) (bool, error) {
+	method := v1.PreUpgradeBackupMethodFor(quay)
+	if method == v1.PreUpgradeBackupNone {
+		return true, nil
+	}
+
+	target := v1.TargetPostgresVersionFor(component)
+	if backup := v1.PreUpgradeBackupFor(quay, component); backup != nil {
+		if backup.TargetVersion == target.Major {
+			return true, nil
+		}
+		return false, fmt.Errorf(
+			"pre-upgrade backup %s of a previous upgrade has not been acknowledged", backup.Name,
+		)
+	}
+
+	version, ok := v1.PostgresVersionFor(component, from.Version)
+	if !ok {
+		return false, fmt.Errorf("unsupported %s version %d", component, from.Version)
+	}
+	image, err := kustomize.PostgresImageFor(quay, component, version)
+	if err != nil {
+		return false, err
+	}
+
+	var done bool
+	if method == v1.PreUpgradeBackupDump {
+		done, err = r.checkPreUpgradeDump(
+			ctx, quay, component, v1.PreUpgradeBackupNameFor(quay, component), image,
+			from.PersistentVolumeClaim,
+		)
+	} else {
+		done, err = r.checkPreUpgradeSnapshot(ctx, quay, component, from.PersistentVolumeClaim)
+	}
+	if err != nil {
+		return false, fmt.Errorf("pre-upgrade backup of %s failed: %w", component, err)
+	}
+	if !done {
+		r.Log.Info("waiting for pre-upgrade backup", "component", component, "method", method)
+		return false, nil
+	}
+
+	quay.Status.PostgresUpgradeBackups = append(
+		quay.Status.PostgresUpgradeBackups,
+		v1.PostgresUpgradeBackup{
+			Component:             component,
+			Method:                method,
+			Name:                  v1.PreUpgradeBackupNameFor(quay, component),
+			Version:               from.Version,
+			TargetVersion:         target.Major,
+			PersistentVolumeClaim: from.PersistentVolumeClaim,
+			CompletionTime:        ptr.To(metav1.Now()),
+		},
+	)
+	if err := r.updateStatus(ctx, quay); err != nil {
+		r.Log.Error(err, "unable to record pre-upgrade backup", "component", component)
+		return false, nil
+	}
+	return true, nil
+}
+
+// checkPreUpgradeDump dumps the database into a volume of its own, both the volume and the
+// Job writing it are given the provided name. The database must still be running. Returns true
+// once the dump has been written.
+func (r *QuayRegistryReconciler) checkPreUpgradeDump(
+	ctx context.Context,
+	quay *v1.QuayRegistry,
+	component v1.ComponentKind,
+	name string,
+	image string,
+	volume string,
+) (bool, error) {
+	nsn := types.NamespacedName{
+		Name:      name,
+		Namespace: quay.GetNamespace(),
+	}
+
+	var pvc corev1.PersistentVolumeClaim
+	if err := r.Get(ctx, nsn, &pvc); err != nil {
+		if !errors.IsNotFound(err) {
+			return false, err
+		}
+
+		var source corev1.PersistentVolumeClaim
+		if err := r.Get(ctx, types.NamespacedName{
+			Name:      volume,
+			Namespace: quay.GetNamespace(),
+		}, &source); err != nil {
+			return false, err
+		}
+
+		// the dump volume is not owned by the registry so it outlives it.
+		if err := r.Create(ctx, preUpgradeDumpVolumeFor(quay, nsn.Name, &source)); err != nil {
+			return false, err
+		}
+	}
+
+	var job batchv1.Job
+	if err := r.Get(ctx, nsn, &job); err != nil {
+		if !errors.IsNotFound(err) {
+			return false, err
+		}
+
+		obj := v1.EnsureOwnerReference(quay, preUpgradeDumpJobFor(quay, component, name, image))
+		return false, r.Create(ctx, obj)
+	}
+
+	if job.Status.Succeeded > 0 {
+		return true, nil
+	}
+
+	for _, cond := range job.Status.Conditions {
+		if cond.Type == batchv1.JobFailed && cond.Status == corev1.ConditionTrue {
+			return false, fmt.Errorf("job %s failed: %s", job.GetName(), cond.Message)
+		}
+	}
+	return false, nil
+}
+
+// checkPreUpgradeSnapshot takes a VolumeSnapshot of the volume of the database. The database
+// must have been shut down. Returns true once the snapshot is ready to be used.
+func (r *QuayRegistryReconciler) checkPreUpgradeSnapshot(
+	ctx context.Context,
+	quay *v1.QuayRegistry,
+	component v1.ComponentKind,
+	volume string,
+) (bool, error) {
+	name := v1.PreUpgradeBackupNameFor(quay, component)
+
+	var snapshot unstructured.Unstructured
+	snapshot.SetGroupVersionKind(volumeSnapshotGVK)
+	if err := r.Get(ctx, types.NamespacedName{
+		Name:      name,
+		Namespace: quay.GetNamespace(),
+	}, &snapshot); err != nil {
+		if !errors.IsNotFound(err) {
+			return false, err
+		}
+
+		// the snapshot is not owned by the registry so it outlives it.
+		return false, r.Create(ctx, preUpgradeSnapshotFor(quay, name, volume))
+	}
+
+	if msg, found, _ := unstructured.NestedString(
+		snapshot.Object, "status", "error", "message",
+	); found {
+		return false, fmt.Errorf("snapshot %s failed: %s", name, msg)
+	}
+
+	ready, _, _ := unstructured.NestedBool(snapshot.Object, "status", "readyToUse")
+	return ready, nil
+}
+
+// releasePreUpgradeBackups deletes the pre-upgrade backups acknowledged by the user along with
+// the volume the database used before it was upgraded. Backups of databases still being
+// upgraded are kept.
+func (r *QuayRegistryReconciler) releasePreUpgradeBackups(
+	ctx context.Context, qctx *quaycontext.QuayRegistryContext, quay *v1.QuayRegistry,
+) error {
+	upgrading := map[v1.ComponentKind]bool{
+		v1.ComponentPostgres:      qctx.NeedsPgUpgrade,
+		v1.ComponentClairPostgres: qctx.NeedsClairPgUpgrade,
+	}
+
+	var kept []v1.PostgresUpgradeBackup
+	for _, backup := range quay.Status.PostgresUpgradeBackups {
+		if upgrading[backup.Component] || !v1.PreUpgradeBackupAcknowledged(quay, backup.Name) {
+			kept = append(kept, backup)
+			continue
+		}
+
+		meta := metav1.ObjectMeta{Name: backup.Name, Namespace: quay.GetNamespace()}
+		objs := []client.Object{
+			&corev1.PersistentVolumeClaim{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      backup.PersistentVolumeClaim,
+					Namespace: quay.GetNamespace(),
+				},
+			},
+		}
+		if backup.Method == v1.PreUpgradeBackupDump {
+			objs = append(
+				objs,
+				&batchv1.Job{ObjectMeta: meta},
+				&corev1.PersistentVolumeClaim{ObjectMeta: meta},
+			)
+		} else {
+			snapshot := &unstructured.Unstructured{}
+			snapshot.SetGroupVersionKind(volumeSnapshotGVK)
+			snapshot.SetName(backup.Name)
+			snapshot.SetNamespace(quay.GetNamespace())
+			objs = append(objs, snapshot)
+		}
+
+		for _, obj := range objs {
+			if obj.GetName() == "" {
+				continue
+			}
+
+			if err := r.Delete(
+				ctx, obj, client.PropagationPolicy(metav1.DeletePropagationBackground),
+			); err != nil && !errors.IsNotFound(err) {
+				return fmt.Errorf("unable to delete %s: %w", obj.GetName(), err)
+			}
+		}
+		r.Log.Info("released acknowledged pre-upgrade backup", "backup", backup.Name)
+	}
+
+	if len(kept) == len(quay.Status.PostgresUpgradeBackups) {
+		return nil
+	}
+
+	quay.Status.PostgresUpgradeBackups = kept
+	return r.updateStatus(ctx, quay)
+}
+
+// databaseVolumeOf returns the name of the PersistentVolumeClaim mounted by the provided
+// database deployment.
+func databaseVolumeOf(deployment *appsv1.Deployment) string {
+	for _, volume := range deployment.Spec.Template.Spec.Volumes {
+		if volume.PersistentVolumeClaim != nil {
+			return volume.PersistentVolumeClaim.ClaimName
+		}
+	}
+	return ""
+}
+
+// postgresUpgradeJobNameFor returns the name of the Job upgrading the provided database
+// component.
+func postgresUpgradeJobNameFor(quay *v1.QuayRegistry, component v1.ComponentKind) string {
+	if component == v1.ComponentClairPostgres {
+		return fmt.Sprintf("%s-%s", quay.GetName(), v1.ClairPostgresUpgradeJobName)
+	}
+	return fmt.Sprintf("%s-%s", quay.GetName(), v1.PostgresUpgradeJobName)
+}
+
+// postgresVersionJobNameFor returns the name of the Job reading the PG_VERSION of the provided
+// volume.
+func postgresVersionJobNameFor(volume string) string {
+	return volume + "-pg-version"
+}
+
+// postgresVersionJobFor returns the Job reading the PG_VERSION of the provided volume. The
+// volume is mounted read only. When it is the one of the provided database deployment, and the
+// database is running, the Job runs next to it as the volume may not be attachable elsewhere.
+func postgresVersionJobFor(
+	quay *v1.QuayRegistry, name, volume, image string, deployment *appsv1.Deployment,
+) *batchv1.Job {
+	var affinity *corev1.Affinity
+	if databaseVolumeOf(deployment) == volume && deployment.Status.ReadyReplicas > 0 {
+		affinity = &corev1.Affinity{
+			PodAffinity: &corev1.PodAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: []corev1.PodAffinityTerm{
+					{
+						LabelSelector: deployment.Spec.Selector,
+						TopologyKey:   "kubernetes.io/hostname",
+					},
+				},
+			},
+		}
+	}
+
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: quay.GetNamespace(),
+			Labels: map[string]string{
+				kustomize.QuayRegistryNameLabel: quay.GetName(),
+			},
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: ptr.To[int32](3),
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					RestartPolicy:      corev1.RestartPolicyNever,
+					ServiceAccountName: deployment.Spec.Template.Spec.ServiceAccountName,
+					Affinity:           affinity,
+					Containers: []corev1.Container{
+						{
+							Name:    "pg-version",
+							Image:   image,
+							Command: []string{"/bin/bash", "-c", postgresVersionScript},
+							VolumeMounts: []corev1.VolumeMount{
+								{
+									Name:      "postgres-data",
+									MountPath: "/var/lib/pgsql/data",
+									ReadOnly:  true,
+								},
+							},
+						},
+					},
+					Volumes: []corev1.Volume{
+						{
+							Name: "postgres-data",
+							VolumeSource: corev1.VolumeSource{
+								PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+									ClaimName: volume,
+									ReadOnly:  true,
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// preUpgradeDumpVolumeFor returns the PersistentVolumeClaim holding the pre-upgrade dump of a
+// database. It is sized and classed after the volume of the database.
+func preUpgradeDumpVolumeFor(
+	quay *v1.QuayRegistry, name string, source *corev1.PersistentVolumeClaim,
+) *corev1.PersistentVolumeClaim {
+	size, ok := source.Spec.Resources.Requests[corev1.ResourceStorage]
+	if !ok {
+		size = resource.MustParse(defaultPostgresVolumeSize)
+	}
+
+	return &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: quay.GetNamespace(),
+			Labels: map[string]string{
+				kustomize.QuayRegistryNameLabel: quay.GetName(),
+			},
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes:      []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+			StorageClassName: source.Spec.StorageClassName,
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceStorage: size},
+			},
+		},
+	}
+}
+
+// preUpgradeDumpJobFor returns the Job dumping a database, into the volume with the same name,
+// before it is upgraded. The dump is taken as the superuser with the image the database
+// currently runs.
+func preUpgradeDumpJobFor(
+	quay *v1.QuayRegistry, component v1.ComponentKind, name, image string,
+) *batchv1.Job {
+	host := fmt.Sprintf("%s-quay-database", quay.GetName())
+	if component == v1.ComponentClairPostgres {
+		host = fmt.Sprintf("%s-clair-postgres", quay.GetName())
+	}
+
+	secret := corev1.LocalObjectReference{
+		Name: fmt.Sprintf("%s-%s-config-secret", quay.GetName(), component),
+	}
+
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: quay.GetNamespace(),
+			Labels: map[string]string{
+				kustomize.QuayRegistryNameLabel: quay.GetName(),
+			},
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: ptr.To[int32](1),
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					RestartPolicy:    corev1.RestartPolicyNever,
+					ImagePullSecrets: v1.GetImagePullSecretsOverrideForComponent(quay, component),
+					Containers: []corev1.Container{
+						{
+							Name:    "pg-dump",
+							Image:   image,
+							Command: []string{"/bin/bash", "-c", preUpgradeDumpScript},
+							Env: []corev1.EnvVar{
+								{Name: "BACKUP_FILE", Value: preUpgradeDumpFile},
+								{Name: "PGHOST", Value: host},
+								{Name: "PGUSER", Value: "postgres"},
+								{
+									Name: "PGPASSWORD",
+									ValueFrom: &corev1.EnvVarSource{
+										SecretKeyRef: &corev1.SecretKeySelector{
+											LocalObjectReference: secret,
+											Key:                  "database-root-password",
+										},
+									},
+								},
+								{
+									Name: "PGDATABASE",
+									ValueFrom: &corev1.EnvVarSource{
+										SecretKeyRef: &corev1.SecretKeySelector{
+											LocalObjectReference: secret,
+											Key:                  "database-name",
+										},
+									},
+								},
+							},
+							VolumeMounts: []corev1.VolumeMount{
+								{Name: "backups", MountPath: "/backups"},
+							},
+						},
+					},
+					Volumes: []corev1.Volume{
+						{
+							Name: "backups",
+							VolumeSource: corev1.VolumeSource{
+								PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+									ClaimName: name,
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// preUpgradeSnapshotFor returns the VolumeSnapshot of the volume of a database taken before
+// it is upgraded.
+func preUpgradeSnapshotFor(quay *v1.QuayRegistry, name, volume string) *unstructured.Unstructured {
+	spec := map[string]interface{}{
+		"source": map[string]interface{}{
+			"persistentVolumeClaimName": volume,
+		},
+	}
+	if class := quay.Spec.PostgresUpgrade.VolumeSnapshotClassName; class != "" {
+		spec["volumeSnapshotClassName"] = class
+	}
+
+	snapshot := &unstructured.Unstructured{Object: map[string]interface{}{"spec": spec}}
+	snapshot.SetGroupVersionKind(volumeSnapshotGVK)
+	snapshot.SetName(name)
+	snapshot.SetNamespace(quay.GetNamespace())
+	snapshot.SetLabels(map[string]string{kustomize.QuayRegistryNameLabel: quay.GetName()})
+	return snapshot
+}
@@ -0,0 +1,517 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	v1 "github.com/quay/quay-operator/apis/quay/v1"
+	quaycontext "github.com/quay/quay-operator/pkg/context"
+)
+
+func newPreUpgradeTestReconciler(t *testing.T, objs ...client.Object) *QuayRegistryReconciler {
+	t.Helper()
+
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := v1.AddToScheme(scheme); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	scheme.AddKnownTypeWithName(volumeSnapshotGVK, &unstructured.Unstructured{})
+	scheme.AddKnownTypeWithName(
+		volumeSnapshotGVK.GroupVersion().WithKind("VolumeSnapshotList"),
+		&unstructured.UnstructuredList{},
+	)
+
+	cli := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(objs...).
+		WithStatusSubresource(&v1.QuayRegistry{}).
+		Build()
+	return &QuayRegistryReconciler{
+		Client: cli,
+		Log:    logr.Discard(),
+		Scheme: scheme,
+	}
+}
+
+func newPreUpgradeTestObjects(method v1.PreUpgradeBackupMethod) (*v1.QuayRegistry, *appsv1.Deployment, *corev1.PersistentVolumeClaim) {
+	quay := &v1.QuayRegistry{
+		ObjectMeta: metav1.ObjectMeta{Name: "registry", Namespace: "ns"},
+		Spec: v1.QuayRegistrySpec{
+			PostgresUpgrade: &v1.PostgresUpgradePolicy{Backup: method},
+		},
+	}
+
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "registry-quay-database", Namespace: "ns"},
+		Spec: appsv1.DeploymentSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Name: "postgres", Image: "centos/postgresql-10-centos7:latest"},
+					},
+					Volumes: []corev1.Volume{
+						{
+							Name: "postgres-data",
+							VolumeSource: corev1.VolumeSource{
+								PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+									ClaimName: "registry-quay-database",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	pvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: "registry-quay-database", Namespace: "ns"},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceStorage: resource.MustParse("10Gi"),
+				},
+			},
+		},
+	}
+	return quay, deployment, pvc
+}
+
+func TestCheckPreUpgradeBackupDump(t *testing.T) {
+	ctx := context.Background()
+	quay, deployment, pvc := newPreUpgradeTestObjects(v1.PreUpgradeBackupDump)
+	r := newPreUpgradeTestReconciler(t, quay, deployment, pvc)
+	from := v1.DatabaseVersion{
+		Component:             v1.ComponentPostgres,
+		Version:               10,
+		PersistentVolumeClaim: "registry-quay-database",
+	}
+
+	done, err := r.checkPreUpgradeBackup(ctx, quay, v1.ComponentPostgres, from)
+	if err != nil || done {
+		t.Fatalf("expected the dump to be started, received %v, %v", done, err)
+	}
+
+	nsn := types.NamespacedName{Name: "registry-quay-database-pre-upgrade", Namespace: "ns"}
+	var volume corev1.PersistentVolumeClaim
+	if err := r.Get(ctx, nsn, &volume); err != nil {
+		t.Fatalf("expected dump volume to be created: %s", err)
+	}
+	if size := volume.Spec.Resources.Requests[corev1.ResourceStorage]; size.String() != "10Gi" {
+		t.Errorf("expected dump volume sized after the database, received %s", size.String())
+	}
+
+	var job batchv1.Job
+	if err := r.Get(ctx, nsn, &job); err != nil {
+		t.Fatalf("expected dump job to be created: %s", err)
+	}
+	if image := job.Spec.Template.Spec.Containers[0].Image; image != "centos/postgresql-10-centos7:latest" {
+		t.Errorf("expected dump taken with the image of the version upgraded from, received %s", image)
+	}
+
+	job.Status.Conditions = []batchv1.JobCondition{
+		{Type: batchv1.JobFailed, Status: corev1.ConditionTrue, Message: "BackoffLimitExceeded"},
+	}
+	if err := r.Status().Update(ctx, &job); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if _, err := r.checkPreUpgradeBackup(ctx, quay, v1.ComponentPostgres, from); err == nil {
+		t.Fatal("expected failed dump to block the upgrade")
+	}
+
+	job.Status.Conditions = nil
+	job.Status.Succeeded = 1
+	if err := r.Status().Update(ctx, &job); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	done, err = r.checkPreUpgradeBackup(ctx, quay, v1.ComponentPostgres, from)
+	if err != nil || !done {
+		t.Fatalf("expected the dump to be completed, received %v, %v", done, err)
+	}
+
+	var updated v1.QuayRegistry
+	if err := r.Get(ctx, types.NamespacedName{Name: "registry", Namespace: "ns"}, &updated); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	backup := v1.PreUpgradeBackupFor(&updated, v1.ComponentPostgres)
+	if backup == nil {
+		t.Fatal("expected backup to be recorded in the status")
+	}
+	if backup.Name != nsn.Name || backup.PersistentVolumeClaim != "registry-quay-database" ||
+		backup.Version != 10 || backup.TargetVersion != 13 {
+		t.Errorf("unexpected backup recorded: %+v", backup)
+	}
+
+	// the backup covers the whole upgrade path, it is not taken again.
+	done, err = r.checkPreUpgradeBackup(ctx, &updated, v1.ComponentPostgres, from)
+	if err != nil || !done {
+		t.Fatalf("expected the backup to cover the upgrade, received %v, %v", done, err)
+	}
+
+	// a later upgrade is blocked until the backup is acknowledged.
+	backup.TargetVersion = 10
+	_, err = r.checkPreUpgradeBackup(ctx, &updated, v1.ComponentPostgres, from)
+	if err == nil || !strings.Contains(err.Error(), "has not been acknowledged") {
+		t.Errorf("expected unacknowledged backup to block the upgrade, received %v", err)
+	}
+}
+
+func TestCheckPreUpgradeBackupSnapshot(t *testing.T) {
+	ctx := context.Background()
+	quay, deployment, pvc := newPreUpgradeTestObjects(v1.PreUpgradeBackupSnapshot)
+	quay.Spec.PostgresUpgrade.VolumeSnapshotClassName = "csi-snapclass"
+	quay.Spec.Components = []v1.Component{{Kind: v1.ComponentClair, Managed: false}}
+	r := newPreUpgradeTestReconciler(t, quay, deployment, pvc)
+	if err := v1.EnsureDefaultComponents(quaycontext.NewQuayRegistryContext(), quay); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	from := v1.DatabaseVersion{
+		Component:             v1.ComponentPostgres,
+		Version:               10,
+		PersistentVolumeClaim: "registry-quay-database",
+	}
+
+	done, err := r.checkPreUpgradeBackup(ctx, quay, v1.ComponentPostgres, from)
+	if err != nil || done {
+		t.Fatalf("expected the snapshot to be started, received %v, %v", done, err)
+	}
+
+	var snapshot unstructured.Unstructured
+	snapshot.SetGroupVersionKind(volumeSnapshotGVK)
+	nsn := types.NamespacedName{Name: "registry-quay-database-pre-upgrade", Namespace: "ns"}
+	if err := r.Get(ctx, nsn, &snapshot); err != nil {
+		t.Fatalf("expected snapshot to be created: %s", err)
+	}
+
+	source, _, _ := unstructured.NestedString(
+		snapshot.Object, "spec", "source", "persistentVolumeClaimName",
+	)
+	class, _, _ := unstructured.NestedString(snapshot.Object, "spec", "volumeSnapshotClassName")
+	if source != "registry-quay-database" || class != "csi-snapclass" {
+		t.Errorf("unexpected snapshot spec: %v", snapshot.Object["spec"])
+	}
+
+	if err := unstructured.SetNestedField(
+		snapshot.Object, "failed to take snapshot", "status", "error", "message",
+	); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := r.Update(ctx, &snapshot); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if _, err := r.checkPreUpgradeBackup(ctx, quay, v1.ComponentPostgres, from); err == nil {
+		t.Fatal("expected failed snapshot to block the upgrade")
+	}
+
+	unstructured.RemoveNestedField(snapshot.Object, "status", "error")
+	if err := unstructured.SetNestedField(snapshot.Object, true, "status", "readyToUse"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := r.Update(ctx, &snapshot); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	done, err = r.checkPreUpgradeBackup(ctx, quay, v1.ComponentPostgres, from)
+	if err != nil || !done {
+		t.Fatalf("expected the snapshot to be completed, received %v, %v", done, err)
+	}
+
+	backup := v1.PreUpgradeBackupFor(quay, v1.ComponentPostgres)
+	if backup == nil || backup.Method != v1.PreUpgradeBackupSnapshot {
+		t.Errorf("expected snapshot to be recorded in the status, received %+v", backup)
+	}
+	if !v1.ComponentIsManaged(quay.Spec.Components, v1.ComponentPostgres) {
+		t.Errorf("expected the defaulted components to be kept after recording the snapshot")
+	}
+}
+
+func TestReleasePreUpgradeBackups(t *testing.T) {
+	ctx := context.Background()
+	quay, _, pvc := newPreUpgradeTestObjects(v1.PreUpgradeBackupDump)
+	quay.Status.PostgresUpgradeBackups = []v1.PostgresUpgradeBackup{
+		{
+			Component:             v1.ComponentPostgres,
+			Method:                v1.PreUpgradeBackupDump,
+			Name:                  "registry-quay-database-pre-upgrade",
+			PersistentVolumeClaim: "registry-quay-database",
+		},
+		{
+			Component:             v1.ComponentClairPostgres,
+			Method:                v1.PreUpgradeBackupDump,
+			Name:                  "registry-clair-postgres-pre-upgrade",
+			PersistentVolumeClaim: "registry-clair-postgres",
+		},
+	}
+	dump := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: "registry-quay-database-pre-upgrade", Namespace: "ns"},
+	}
+	clairDump := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: "registry-clair-postgres-pre-upgrade", Namespace: "ns"},
+	}
+	quay.Spec.Components = []v1.Component{{Kind: v1.ComponentClair, Managed: true}}
+	r := newPreUpgradeTestReconciler(t, quay, pvc, dump, clairDump)
+
+	qctx := quaycontext.NewQuayRegistryContext()
+	if err := v1.EnsureDefaultComponents(qctx, quay); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := r.releasePreUpgradeBackups(ctx, qctx, quay); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(quay.Status.PostgresUpgradeBackups) != 2 {
+		t.Fatalf("expected unacknowledged backups to be kept")
+	}
+
+	quay.Spec.PostgresUpgrade.Acknowledged = []string{
+		"registry-quay-database-pre-upgrade", "registry-clair-postgres-pre-upgrade",
+	}
+	qctx.NeedsClairPgUpgrade = true
+	if err := r.releasePreUpgradeBackups(ctx, qctx, quay); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(quay.Status.PostgresUpgradeBackups) != 1 ||
+		quay.Status.PostgresUpgradeBackups[0].Component != v1.ComponentClairPostgres {
+		t.Errorf("expected only the backup of the upgraded database to be released, received %+v",
+			quay.Status.PostgresUpgradeBackups)
+	}
+	if !v1.ComponentIsManaged(quay.Spec.Components, v1.ComponentClairPostgres) {
+		t.Errorf("expected the defaulted components to be kept after releasing the backups")
+	}
+
+	for _, name := range []string{"registry-quay-database", "registry-quay-database-pre-upgrade"} {
+		var claim corev1.PersistentVolumeClaim
+		err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: "ns"}, &claim)
+		if !errors.IsNotFound(err) {
+			t.Errorf("expected %s to be deleted, received %v", name, err)
+		}
+	}
+
+	var claim corev1.PersistentVolumeClaim
+	nsn := types.NamespacedName{Name: "registry-clair-postgres-pre-upgrade", Namespace: "ns"}
+	if err := r.Get(ctx, nsn, &claim); err != nil {
+		t.Errorf("expected backup of database being upgraded to be kept: %s", err)
+	}
+}
+
+func TestCheckDatabaseVersion(t *testing.T) {
+	ctx := context.Background()
+	quay, deployment, pvc := newPreUpgradeTestObjects(v1.PreUpgradeBackupNone)
+	deployment.Spec.Selector = &metav1.LabelSelector{
+		MatchLabels: map[string]string{"quay-component": "postgres"},
+	}
+	deployment.Status.ReadyReplicas = 1
+	next := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: "registry-quay-postgres-13", Namespace: "ns"},
+	}
+	quay.Spec.Components = []v1.Component{{Kind: v1.ComponentClair, Managed: false}}
+	r := newPreUpgradeTestReconciler(t, quay, deployment, pvc, next)
+	if err := v1.EnsureDefaultComponents(quaycontext.NewQuayRegistryContext(), quay); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// completes the job reading the provided volume with the provided PG_VERSION.
+	complete := func(volume, version string) {
+		t.Helper()
+
+		var job batchv1.Job
+		nsn := types.NamespacedName{Name: volume + "-pg-version", Namespace: "ns"}
+		if err := r.Get(ctx, nsn, &job); err != nil {
+			t.Fatalf("expected job reading %s to be created: %s", volume, err)
+		}
+		job.Status.Succeeded = 1
+		if err := r.Status().Update(ctx, &job); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      nsn.Name + "-abcde",
+				Namespace: "ns",
+				Labels:    map[string]string{"job-name": nsn.Name},
+			},
+			Status: corev1.PodStatus{
+				ContainerStatuses: []corev1.ContainerStatus{
+					{
+						Name: "pg-version",
+						State: corev1.ContainerState{
+							Terminated: &corev1.ContainerStateTerminated{Message: version},
+						},
+					},
+				},
+			},
+		}
+		if err := r.Create(ctx, pod); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	// the volume of the last version is read first, it holds no data yet.
+	version, err := r.checkDatabaseVersion(ctx, quay, v1.ComponentPostgres, deployment)
+	if err != nil || version != nil {
+		t.Fatalf("expected the version to be read, received %v, %v", version, err)
+	}
+	complete("registry-quay-postgres-13", "")
+
+	version, err = r.checkDatabaseVersion(ctx, quay, v1.ComponentPostgres, deployment)
+	if err != nil || version != nil {
+		t.Fatalf("expected the version to be read, received %v, %v", version, err)
+	}
+
+	var job batchv1.Job
+	nsn := types.NamespacedName{Name: "registry-quay-database-pg-version", Namespace: "ns"}
+	if err := r.Get(ctx, nsn, &job); err != nil {
+		t.Fatalf("expected job reading the volume of the database to be created: %s", err)
+	}
+	if affinity := job.Spec.Template.Spec.Affinity; affinity == nil || affinity.PodAffinity == nil {
+		t.Errorf("expected job to run next to the database")
+	}
+	if !job.Spec.Template.Spec.Volumes[0].PersistentVolumeClaim.ReadOnly {
+		t.Errorf("expected volume to be mounted read only")
+	}
+	complete("registry-quay-database", "10\n")
+
+	version, err = r.checkDatabaseVersion(ctx, quay, v1.ComponentPostgres, deployment)
+	if err != nil || version == nil {
+		t.Fatalf("expected the version to be read, received %v, %v", version, err)
+	}
+	if version.Version != 10 || version.PersistentVolumeClaim != "registry-quay-database" {
+		t.Errorf("unexpected database version: %+v", version)
+	}
+	if !v1.ComponentIsManaged(quay.Spec.Components, v1.ComponentPostgres) {
+		t.Errorf("expected the defaulted components to be kept after recording the version")
+	}
+
+	for _, name := range []string{"registry-quay-postgres-13-pg-version", nsn.Name} {
+		err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: "ns"}, &job)
+		if !errors.IsNotFound(err) {
+			t.Errorf("expected job %s to be deleted, received %v", name, err)
+		}
+	}
+
+	// the version recorded in the status is not read again.
+	version, err = r.checkDatabaseVersion(ctx, quay, v1.ComponentPostgres, deployment)
+	if err != nil || version == nil || version.Version != 10 {
+		t.Errorf("expected the recorded version, received %v, %v", version, err)
+	}
+}
+
+func TestCheckDatabaseVersionUnsupported(t *testing.T) {
+	ctx := context.Background()
+	quay, deployment, pvc := newPreUpgradeTestObjects(v1.PreUpgradeBackupNone)
+	r := newPreUpgradeTestReconciler(t, quay, deployment, pvc)
+
+	if _, err := r.checkDatabaseVersion(ctx, quay, v1.ComponentPostgres, deployment); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	var job batchv1.Job
+	nsn := types.NamespacedName{Name: "registry-quay-database-pg-version", Namespace: "ns"}
+	if err := r.Get(ctx, nsn, &job); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if job.Spec.Template.Spec.Affinity != nil {
+		t.Errorf("expected no affinity without a running database")
+	}
+	job.Status.Succeeded = 1
+	if err := r.Status().Update(ctx, &job); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := r.Create(ctx, &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pg-version",
+			Namespace: "ns",
+			Labels:    map[string]string{"job-name": nsn.Name},
+		},
+		Status: corev1.PodStatus{
+			ContainerStatuses: []corev1.ContainerStatus{
+				{
+					State: corev1.ContainerState{
+						Terminated: &corev1.ContainerStateTerminated{Message: "9.6"},
+					},
+				},
+			},
+		},
+	}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if _, err := r.checkDatabaseVersion(ctx, quay, v1.ComponentPostgres, deployment); err == nil {
+		t.Error("expected a version not on the upgrade path to be refused")
+	}
+}
+
+func TestStartPostgresUpgradeStep(t *testing.T) {
+	ctx := context.Background()
+	quay, _, _ := newPreUpgradeTestObjects(v1.PreUpgradeBackupNone)
+	quay.Status.PostgresUpgrades = []v1.PostgresUpgradeStep{
+		{
+			Component: v1.ComponentClairPostgres,
+			From:      10,
+			To:        13,
+			Phase:     v1.PostgresUpgradePhaseSucceeded,
+		},
+	}
+	previous := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: "registry-clair-postgres-upgrade", Namespace: "ns"},
+		Status:     batchv1.JobStatus{Succeeded: 1},
+	}
+	quay.Spec.Components = []v1.Component{{Kind: v1.ComponentClair, Managed: true}}
+	r := newPreUpgradeTestReconciler(t, quay, previous)
+	if err := v1.EnsureDefaultComponents(quaycontext.NewQuayRegistryContext(), quay); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if err := r.startPostgresUpgradeStep(ctx, quay, v1.ComponentClairPostgres, 13, 15); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	var job batchv1.Job
+	nsn := types.NamespacedName{Name: previous.Name, Namespace: "ns"}
+	if err := r.Get(ctx, nsn, &job); !errors.IsNotFound(err) {
+		t.Errorf("expected the job of the previous step to be deleted, received %v", err)
+	}
+
+	step := v1.PostgresUpgradeStepFor(quay, v1.ComponentClairPostgres)
+	if len(quay.Status.PostgresUpgrades) != 2 || step.From != 13 || step.To != 15 ||
+		step.Phase != v1.PostgresUpgradePhaseRunning {
+		t.Fatalf("expected the step to be recorded, received %+v", quay.Status.PostgresUpgrades)
+	}
+	if !v1.ComponentIsManaged(quay.Spec.Components, v1.ComponentClairPostgres) {
+		t.Errorf("expected the defaulted components to be kept after recording the step")
+	}
+
+	// a failed step is retried rather than recorded again.
+	step.Phase = v1.PostgresUpgradePhaseFailed
+	step.Message = "BackoffLimitExceeded"
+	if err := r.startPostgresUpgradeStep(ctx, quay, v1.ComponentClairPostgres, 13, 15); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	step = v1.PostgresUpgradeStepFor(quay, v1.ComponentClairPostgres)
+	if len(quay.Status.PostgresUpgrades) != 2 || step.Phase != v1.PostgresUpgradePhaseRunning ||
+		step.Message != "" {
+		t.Errorf("expected the step to be retried, received %+v", quay.Status.PostgresUpgrades)
+	}
+}
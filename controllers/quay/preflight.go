@@ -0,0 +1,226 @@
+package controllers
+
+import (
+	"context"
+	goerrors "errors"
+	"fmt"
+	"strings"
+
+	"github.com/quay/quay/config-tool/pkg/lib/fieldgroups/database"
+	"github.com/quay/quay/config-tool/pkg/lib/fieldgroups/distributedstorage"
+	"github.com/quay/quay/config-tool/pkg/lib/shared"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/yaml"
+
+	v1 "github.com/quay/quay-operator/apis/quay/v1"
+	quaycontext "github.com/quay/quay-operator/pkg/context"
+)
+
+// errUpgradeUnsupported is wrapped by the errors of the upgrade preflight checks, the registry
+// is then blocked with the UpgradeUnsupported reason instead of being left half upgraded.
+var errUpgradeUnsupported = goerrors.New("upgrade unsupported")
+
+// checkUpgradePreflight verifies the provided QuayRegistry can be upgraded to the current
+// version of Quay before the upgrade scales it down: the version jump must be supported and
+// the database and the object storage must be usable with the current configuration.
+func (r *QuayRegistryReconciler) checkUpgradePreflight(
+	ctx context.Context,
+	qctx *quaycontext.QuayRegistryContext,
+	quay *v1.QuayRegistry,
+	bundle *corev1.Secret,
+) error {
+	if err := v1.ValidateQuayUpgrade(quay.Status.CurrentVersion, v1.QuayVersionCurrent); err != nil {
+		return fmt.Errorf("%w: %s", errUpgradeUnsupported, err)
+	}
+
+	var config map[string]interface{}
+	if err := yaml.Unmarshal(bundle.Data["config.yaml"], &config); err != nil {
+		return fmt.Errorf("%w: unable to parse config: %s", errUpgradeUnsupported, err)
+	}
+
+	// the certificates of the config bundle are trusted when connecting to the unmanaged
+	// database and object storage.
+	opts := shared.Options{Mode: "online", Certificates: map[string][]byte{}}
+	for key, value := range bundle.Data {
+		if key != "config.yaml" {
+			opts.Certificates[key] = value
+		}
+	}
+
+	if err := r.checkPreflightDatabase(ctx, qctx, quay, config, opts); err != nil {
+		return fmt.Errorf("%w: %s", errUpgradeUnsupported, err)
+	}
+
+	if err := checkPreflightObjectStorage(qctx, quay, config, opts); err != nil {
+		return fmt.Errorf("%w: %s", errUpgradeUnsupported, err)
+	}
+	return nil
+}
+
+// recordUpgradePreflight records in the status of the provided QuayRegistry that the preflight
+// checks passed for the current version of Quay so they are not run again.
+func (r *QuayRegistryReconciler) recordUpgradePreflight(ctx context.Context, quay *v1.QuayRegistry) error {
+	quay.Status.UpgradePreflightVersion = v1.QuayVersionCurrent
+	return r.updateStatus(ctx, quay)
+}
+
+// checkPreflightDatabase verifies the database of Quay is reachable and runs a supported
+// version. The unmanaged database is validated by connecting to it, the managed one must be
+// running on a version of its upgrade path.
+func (r *QuayRegistryReconciler) checkPreflightDatabase(
+	ctx context.Context,
+	qctx *quaycontext.QuayRegistryContext,
+	quay *v1.QuayRegistry,
+	config map[string]interface{},
+	opts shared.Options,
+) error {
+	if !v1.ComponentIsManaged(quay.Spec.Components, v1.ComponentPostgres) {
+		fg, err := database.NewDatabaseFieldGroup(config)
+		if err != nil {
+			return fmt.Errorf("unable to read database config: %w", err)
+		}
+		return validationErrors(fg.Validate(opts))
+	}
+
+	if v1.DatabaseClusterEnabled(quay, v1.ComponentPostgres) {
+		if !r.checkDatabaseClusterReady(ctx, qctx, quay, v1.ComponentPostgres) {
+			return fmt.Errorf("managed database cluster is not ready")
+		}
+		return nil
+	}
+
+	var dep appsv1.Deployment
+	if err := r.Get(ctx, types.NamespacedName{
+		Name:      fmt.Sprintf("%s-quay-database", quay.GetName()),
+		Namespace: quay.GetNamespace(),
+	}, &dep); err != nil {
+		return fmt.Errorf("unable to get managed database: %w", err)
+	}
+	if dep.Status.AvailableReplicas == 0 {
+		return fmt.Errorf("managed database is not running")
+	}
+
+	if current := v1.DatabaseVersionFor(quay, v1.ComponentPostgres); current != nil {
+		if _, ok := v1.PostgresVersionFor(v1.ComponentPostgres, current.Version); !ok {
+			return fmt.Errorf("managed database runs unsupported postgres %d", current.Version)
+		}
+	}
+	return nil
+}
+
+// checkPreflightObjectStorage verifies the credentials of the object storage. The unmanaged
+// storage is validated by connecting to it, the credentials of the managed one must have been
+// issued through its ObjectBucketClaim.
+func checkPreflightObjectStorage(
+	qctx *quaycontext.QuayRegistryContext,
+	quay *v1.QuayRegistry,
+	config map[string]interface{},
+	opts shared.Options,
+) error {
+	if v1.ComponentIsManaged(quay.Spec.Components, v1.ComponentObjectStorage) {
+		if !qctx.ObjectStorageInitialized || qctx.StorageAccessKey == "" {
+			return fmt.Errorf("managed object storage credentials have not been issued")
+		}
+		return nil
+	}
+
+	fg, err := distributedstorage.NewDistributedStorageFieldGroup(config)
+	if err != nil {
+		return fmt.Errorf("unable to read object storage config: %w", err)
+	}
+	return validationErrors(fg.Validate(opts))
+}
+
+// postgresUpgradeFailureReason returns the reason the registry is blocked with when checking
+// for an upgrade of its managed databases failed with the provided error.
+func postgresUpgradeFailureReason(err error) v1.ConditionReason {
+	if goerrors.Is(err, errUpgradeUnsupported) {
+		return v1.ConditionReasonUpgradeUnsupported
+	}
+	return v1.ConditionReasonPostgresUpgradeFailed
+}
+
+// checkPostgresUpgradeVolume verifies the volume the provided database component is upgraded
+// to can hold the data copied from the volume of the version it is upgraded from. As the data
+// may fill its volume the capacity of the volume upgraded from is required.
+func (r *QuayRegistryReconciler) checkPostgresUpgradeVolume(
+	ctx context.Context,
+	quay *v1.QuayRegistry,
+	component v1.ComponentKind,
+	from v1.DatabaseVersion,
+	to v1.PostgresVersion,
+) error {
+	var source corev1.PersistentVolumeClaim
+	if err := r.Get(ctx, types.NamespacedName{
+		Name:      from.PersistentVolumeClaim,
+		Namespace: quay.GetNamespace(),
+	}, &source); err != nil {
+		return err
+	}
+
+	required := volumeCapacityOf(&source)
+	if required == nil {
+		return nil
+	}
+
+	// the volume is created by the upgrade with the size of the volume of the component.
+	name := v1.PostgresVolumeNameFor(quay, to)
+	available := resource.MustParse(defaultPostgresVolumeSize)
+	if size := v1.GetVolumeSizeOverrideForComponent(quay, component); size != nil {
+		available = *size
+	}
+
+	var target corev1.PersistentVolumeClaim
+	if err := r.Get(ctx, types.NamespacedName{
+		Name:      name,
+		Namespace: quay.GetNamespace(),
+	}, &target); err == nil {
+		if capacity := volumeCapacityOf(&target); capacity != nil {
+			available = *capacity
+		}
+	} else if !errors.IsNotFound(err) {
+		return err
+	}
+
+	if available.Cmp(*required) < 0 {
+		return fmt.Errorf(
+			"%w: %s of %s can not hold the data of %s of %s, raise the volumeSize override of %s",
+			errUpgradeUnsupported,
+			name,
+			available.String(),
+			from.PersistentVolumeClaim,
+			required.String(),
+			component,
+		)
+	}
+	return nil
+}
+
+// volumeCapacityOf returns the capacity of the provided volume, or the size it was requested
+// with while it is not bound.
+func volumeCapacityOf(pvc *corev1.PersistentVolumeClaim) *resource.Quantity {
+	if capacity, ok := pvc.Status.Capacity[corev1.ResourceStorage]; ok {
+		return &capacity
+	}
+	if request, ok := pvc.Spec.Resources.Requests[corev1.ResourceStorage]; ok {
+		return &request
+	}
+	return nil
+}
+
+// validationErrors joins the messages of the provided config validation errors.
+func validationErrors(errs []shared.ValidationError) error {
+	if len(errs) == 0 {
+		return nil
+	}
+
+	msgs := make([]string, 0, len(errs))
+	for _, err := range errs {
+		msgs = append(msgs, err.Message)
+	}
+	return goerrors.New(strings.Join(msgs, ", "))
+}
@@ -0,0 +1,303 @@
+package controllers
+
+import (
+	"context"
+	goerrors "errors"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	v1 "github.com/quay/quay-operator/apis/quay/v1"
+	quaycontext "github.com/quay/quay-operator/pkg/context"
+)
+
+func TestCheckUpgradePreflight(t *testing.T) {
+	current := v1.QuayVersionCurrent
+	v1.QuayVersionCurrent = "3.12.0"
+	t.Cleanup(func() { v1.QuayVersionCurrent = current })
+
+	localStorage := []byte(`DISTRIBUTED_STORAGE_CONFIG:
+  default:
+    - LocalStorage
+    - storage_path: /datastorage/registry
+`)
+
+	database := func(available int32) *appsv1.Deployment {
+		return &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "registry-quay-database", Namespace: "ns"},
+			Status:     appsv1.DeploymentStatus{AvailableReplicas: available},
+		}
+	}
+
+	for _, tt := range []struct {
+		name       string
+		version    v1.QuayVersion
+		components []v1.Component
+		config     []byte
+		qctx       quaycontext.QuayRegistryContext
+		objs       []client.Object
+		expectErr  bool
+	}{
+		{
+			name:    "Supported",
+			version: "3.10.3",
+			components: []v1.Component{
+				{Kind: v1.ComponentPostgres, Managed: true},
+				{Kind: v1.ComponentObjectStorage, Managed: false},
+			},
+			config: localStorage,
+			objs:   []client.Object{database(1)},
+		},
+		{
+			name:    "DevelopmentVersion",
+			version: "dev",
+			components: []v1.Component{
+				{Kind: v1.ComponentPostgres, Managed: true},
+				{Kind: v1.ComponentObjectStorage, Managed: false},
+			},
+			config: localStorage,
+			objs:   []client.Object{database(1)},
+		},
+		{
+			name:      "Downgrade",
+			version:   "3.13.0",
+			config:    localStorage,
+			expectErr: true,
+		},
+		{
+			name:      "TooManyMinorVersions",
+			version:   "3.8.0",
+			config:    localStorage,
+			expectErr: true,
+		},
+		{
+			name:    "ManagedDatabaseNotRunning",
+			version: "3.11.0",
+			components: []v1.Component{
+				{Kind: v1.ComponentPostgres, Managed: true},
+				{Kind: v1.ComponentObjectStorage, Managed: false},
+			},
+			config:    localStorage,
+			objs:      []client.Object{database(0)},
+			expectErr: true,
+		},
+		{
+			name:    "UnmanagedDatabaseNotConfigured",
+			version: "3.11.0",
+			components: []v1.Component{
+				{Kind: v1.ComponentPostgres, Managed: false},
+				{Kind: v1.ComponentObjectStorage, Managed: false},
+			},
+			config:    localStorage,
+			expectErr: true,
+		},
+		{
+			name:    "ManagedObjectStorageWithoutCredentials",
+			version: "3.11.0",
+			components: []v1.Component{
+				{Kind: v1.ComponentPostgres, Managed: true},
+				{Kind: v1.ComponentObjectStorage, Managed: true},
+			},
+			objs:      []client.Object{database(1)},
+			expectErr: true,
+		},
+		{
+			name:    "ManagedObjectStorage",
+			version: "3.11.0",
+			components: []v1.Component{
+				{Kind: v1.ComponentPostgres, Managed: true},
+				{Kind: v1.ComponentObjectStorage, Managed: true},
+			},
+			qctx: quaycontext.QuayRegistryContext{
+				ObjectStorageInitialized: true,
+				StorageAccessKey:         "access",
+			},
+			objs: []client.Object{database(1)},
+		},
+		{
+			name:    "UnmanagedObjectStorageNotConfigured",
+			version: "3.11.0",
+			components: []v1.Component{
+				{Kind: v1.ComponentPostgres, Managed: true},
+				{Kind: v1.ComponentObjectStorage, Managed: false},
+			},
+			objs:      []client.Object{database(1)},
+			expectErr: true,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			quay := &v1.QuayRegistry{
+				ObjectMeta: metav1.ObjectMeta{Name: "registry", Namespace: "ns"},
+				Spec:       v1.QuayRegistrySpec{Components: tt.components},
+				Status:     v1.QuayRegistryStatus{CurrentVersion: tt.version},
+			}
+			bundle := &corev1.Secret{Data: map[string][]byte{"config.yaml": tt.config}}
+			r := newPreUpgradeTestReconciler(t, append(tt.objs, quay)...)
+
+			err := r.checkUpgradePreflight(context.Background(), &tt.qctx, quay, bundle)
+			if !tt.expectErr {
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				return
+			}
+			if !goerrors.Is(err, errUpgradeUnsupported) {
+				t.Errorf("expected the upgrade to be unsupported, received %v", err)
+			}
+		})
+	}
+}
+
+func TestRecordUpgradePreflightKeepsDefaults(t *testing.T) {
+	current := v1.QuayVersionCurrent
+	v1.QuayVersionCurrent = "3.12.0"
+	t.Cleanup(func() { v1.QuayVersionCurrent = current })
+
+	ctx := context.Background()
+	quay := &v1.QuayRegistry{
+		ObjectMeta: metav1.ObjectMeta{Name: "registry", Namespace: "ns"},
+		Spec: v1.QuayRegistrySpec{
+			Components: []v1.Component{{Kind: v1.ComponentObjectStorage, Managed: false}},
+		},
+		Status: v1.QuayRegistryStatus{CurrentVersion: "3.11.0"},
+	}
+	database := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "registry-quay-database", Namespace: "ns"},
+		Status:     appsv1.DeploymentStatus{AvailableReplicas: 1},
+	}
+	bundle := &corev1.Secret{
+		Data: map[string][]byte{
+			"config.yaml": []byte(`DISTRIBUTED_STORAGE_CONFIG:
+  default:
+    - LocalStorage
+    - storage_path: /datastorage/registry
+`),
+		},
+	}
+	r := newPreUpgradeTestReconciler(t, quay, database)
+
+	qctx := quaycontext.NewQuayRegistryContext()
+	updated := quay.DeepCopy()
+	if err := v1.EnsureDefaultComponents(qctx, updated); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !v1.UpgradePreflightNeeded(updated) {
+		t.Fatal("expected the upgrade preflight checks to be needed")
+	}
+	if err := r.checkUpgradePreflight(ctx, qctx, updated, bundle); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := r.recordUpgradePreflight(ctx, updated); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// the components defaulted in memory must survive the status write for the rest of the
+	// reconcile, and the registry must still be writable.
+	for _, cmp := range []v1.ComponentKind{v1.ComponentPostgres, v1.ComponentRedis, v1.ComponentClair} {
+		if !v1.ComponentIsManaged(updated.Spec.Components, cmp) {
+			t.Errorf("expected %s to remain managed after recording the preflight checks", cmp)
+		}
+	}
+	if err := r.updateStatus(ctx, updated); err != nil {
+		t.Errorf("unexpected error updating status again: %s", err)
+	}
+
+	var stored v1.QuayRegistry
+	if err := r.Get(ctx, client.ObjectKeyFromObject(quay), &stored); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if stored.Status.UpgradePreflightVersion != v1.QuayVersionCurrent {
+		t.Errorf(
+			"expected preflight version %s, received %s",
+			v1.QuayVersionCurrent, stored.Status.UpgradePreflightVersion,
+		)
+	}
+	if len(stored.Spec.Components) != 1 {
+		t.Errorf("expected the spec to be left untouched, received %v", stored.Spec.Components)
+	}
+}
+
+func TestCheckPostgresUpgradeVolume(t *testing.T) {
+	volume := func(name, size string, bound bool) *corev1.PersistentVolumeClaim {
+		pvc := &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ns"},
+			Spec: corev1.PersistentVolumeClaimSpec{
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("50Gi")},
+				},
+			},
+		}
+		if bound {
+			pvc.Status.Capacity = corev1.ResourceList{corev1.ResourceStorage: resource.MustParse(size)}
+		}
+		return pvc
+	}
+
+	from := v1.DatabaseVersion{
+		Component:             v1.ComponentPostgres,
+		Version:               10,
+		PersistentVolumeClaim: "registry-quay-database",
+	}
+	to, _ := v1.PostgresVersionFor(v1.ComponentPostgres, 13)
+
+	for _, tt := range []struct {
+		name      string
+		override  string
+		objs      []client.Object
+		expectErr bool
+	}{
+		{
+			name: "DefaultSizeLargeEnough",
+			objs: []client.Object{volume("registry-quay-database", "50Gi", true)},
+		},
+		{
+			name:      "DefaultSizeTooSmall",
+			objs:      []client.Object{volume("registry-quay-database", "100Gi", true)},
+			expectErr: true,
+		},
+		{
+			name:     "OverriddenSize",
+			override: "100Gi",
+			objs:     []client.Object{volume("registry-quay-database", "100Gi", true)},
+		},
+		{
+			name: "ExistingVolume",
+			objs: []client.Object{
+				volume("registry-quay-database", "100Gi", true),
+				volume("registry-quay-postgres-13", "150Gi", true),
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			quay := &v1.QuayRegistry{
+				ObjectMeta: metav1.ObjectMeta{Name: "registry", Namespace: "ns"},
+			}
+			if tt.override != "" {
+				size := resource.MustParse(tt.override)
+				quay.Spec.Components = []v1.Component{
+					{
+						Kind:      v1.ComponentPostgres,
+						Managed:   true,
+						Overrides: &v1.Override{VolumeSize: &size},
+					},
+				}
+			}
+			r := newPreUpgradeTestReconciler(t, tt.objs...)
+
+			err := r.checkPostgresUpgradeVolume(context.Background(), quay, v1.ComponentPostgres, from, to)
+			if !tt.expectErr {
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				return
+			}
+			if !goerrors.Is(err, errUpgradeUnsupported) {
+				t.Errorf("expected the upgrade to be unsupported, received %v", err)
+			}
+		})
+	}
+}
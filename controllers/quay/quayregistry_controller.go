@@ -34,6 +34,8 @@ import (
 	autoscalingv2beta2 "k8s.io/api/autoscaling/v2beta2"
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
@@ -41,6 +43,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -70,6 +73,7 @@ const (
 	quayDashboardJSONKey                  = "quay.json"
 	quayOperatorManagedLabelKey           = "quay-operator/managed-label"
 	quayOperatorFinalizer                 = "quay-operator/finalizer"
+	jobFailureReportedAnnotation          = "quay-operator/failure-reported"
 	grafanaDashboardConfigNamespace       = "openshift-config-managed"
 )
 
@@ -153,12 +157,23 @@ func (r *QuayRegistryReconciler) checkMigrationStatus(
 	}
 
 	if job.Status.Active == 1 {
+		// with the read-only upgrade strategy nothing but the migrations writes to the
+		// database, mirror workers scaled back up meanwhile are paused again.
+		if v1.ReadOnlyUpgradeEnabled(quay) {
+			if _, err := r.pauseMirror(ctx, quay); err != nil {
+				log.Error(err, "unable to keep mirror workers paused during migrations")
+			}
+		}
+
 		log.Info("Upgrade job running, requeueing reconcile...")
 		return r.Requeue, nil
 	}
 
 	if job.Status.Succeeded == 1 {
 		log.Info("Quay upgrade complete, updating `status.currentVersion`")
+		if v1.ReadOnlyUpgradeEnabled(quay) {
+			log.Info("rolling Quay forward from read-only mode")
+		}
 
 		condition := v1.Condition{
 			Type:               v1.ConditionComponentsCreated,
@@ -195,12 +210,32 @@ func (r *QuayRegistryReconciler) checkMigrationStatus(
 	// when the migration job fails due to misconfiguration, then the
 	// reconcile function should be allowed to proceed.
 	msg := "failed to run migrations"
+	failed := false
 	for _, cond := range job.Status.Conditions {
 		if cond.Type == batchv1.JobFailed && cond.Status == corev1.ConditionTrue {
 			msg = cond.Message
+			failed = true
 			break
 		}
 	}
+	// the pods of a job being retried are not reported, its log is only read once it gave up.
+	if failed {
+		msg = r.jobFailureMessage(ctx, quay, &job, msg, log)
+	}
+
+	// with the rollback policy the database is restored once the job gave up, the previous
+	// version of Quay is then run again.
+	rollback := v1.UpgradeRollbackFor(quay)
+	if failed && v1.UpgradeRollbackEnabled(quay) &&
+		rollback != nil && rollback.Phase == v1.UpgradeRollbackPhaseReady {
+		return r.startUpgradeRollback(ctx, quay, msg, log)
+	}
+
+	// with the read-only upgrade strategy the previous version is left serving pulls
+	// until the migrations succeed.
+	if v1.ReadOnlyUpgradeEnabled(quay) {
+		msg = fmt.Sprintf("%s, quay remains in read-only mode", msg)
+	}
 
 	if err := r.updateWithCondition(
 		ctx,
@@ -232,6 +267,11 @@ func (r *QuayRegistryReconciler) checkPostgresUpgradeStatus(
 	jobs := []string{postgresUpgradeJobName, clairPostgresUpgradeJobName}
 
 	for _, jobName := range jobs {
+		component := v1.ComponentPostgres
+		if jobName == clairPostgresUpgradeJobName {
+			component = v1.ComponentClairPostgres
+		}
+
 		nsn := types.NamespacedName{
 			Name:      jobName,
 			Namespace: quay.GetNamespace(),
@@ -254,6 +294,25 @@ func (r *QuayRegistryReconciler) checkPostgresUpgradeStatus(
 
 		if job.Status.Succeeded == 1 {
 			log.Info(fmt.Sprintf("%s upgrade complete", jobName))
+
+			// the database now runs on the version upgraded to, the volume of the version
+			// upgraded from is left behind.
+			_, to, fromVolume := v1.PostgresUpgradeVersionsFor(quay, component)
+			if step := v1.PostgresUpgradeStepFor(quay, component); step != nil &&
+				step.Phase == v1.PostgresUpgradePhaseRunning {
+				step.Phase = v1.PostgresUpgradePhaseSucceeded
+				step.CompletionTime = ptr.To(metav1.Now())
+				v1.SetDatabaseVersion(quay, v1.DatabaseVersion{
+					Component:             component,
+					Version:               to.Major,
+					PersistentVolumeClaim: v1.PostgresVolumeNameFor(quay, to),
+				})
+				if err := r.Status().Update(ctx, quay); err != nil {
+					log.Error(err, "could not record postgres upgrade step")
+					return r.Requeue, nil
+				}
+			}
+
 			var oldPostgresDeploymentName string
 			if jobName == clairPostgresUpgradeJobName {
 				oldPostgresDeploymentName = fmt.Sprintf("%s-%s", quay.GetName(), "clair-postgres-old")
@@ -316,12 +375,7 @@ func (r *QuayRegistryReconciler) checkPostgresUpgradeStatus(
 			}
 
 			// Remove owner reference from old pvc so user can delete when ready
-			var oldPostgresPVCName string
-			if jobName == clairPostgresUpgradeJobName {
-				oldPostgresPVCName = fmt.Sprintf("%s-%s", quay.GetName(), "clair-postgres")
-			} else {
-				oldPostgresPVCName = fmt.Sprintf("%s-%s", quay.GetName(), "quay-database")
-			}
+			oldPostgresPVCName := fromVolume
 			oldPostgresPVC := &corev1.PersistentVolumeClaim{}
 			if err := r.Get(
 				ctx,
@@ -338,6 +392,12 @@ func (r *QuayRegistryReconciler) checkPostgresUpgradeStatus(
 				log.Error(err, "could not remove owner reference from old postgres pvc")
 			}
 
+			// With a pre-upgrade backup the old PVC is kept until the backup is acknowledged
+			backup := v1.PreUpgradeBackupFor(quay, component)
+			if backup != nil && backup.PersistentVolumeClaim == oldPostgresPVCName {
+				continue
+			}
+
 			// If user did not set POSTGRES_UPGRADE_DELETE_BACKUP or POSTGRES_UPGRADE_DELETE_BACKUP=false, then we should not delete the old PVC
 			delBackup, exists := os.LookupEnv("POSTGRES_UPGRADE_DELETE_BACKUP")
 			if !exists || (exists && delBackup == "false") {
@@ -358,11 +418,18 @@ func (r *QuayRegistryReconciler) checkPostgresUpgradeStatus(
 		msg := "failed to run postgres upgrade"
 		for _, cond := range job.Status.Conditions {
 			if cond.Type == batchv1.JobFailed && cond.Status == corev1.ConditionTrue {
-				msg = cond.Message
+				msg = r.jobFailureMessage(ctx, quay, &job, cond.Message, log)
 				break
 			}
 		}
 
+		if step := v1.PostgresUpgradeStepFor(quay, component); step != nil &&
+			step.Phase == v1.PostgresUpgradePhaseRunning {
+			step.Phase = v1.PostgresUpgradePhaseFailed
+			step.Message = msg
+			step.CompletionTime = ptr.To(metav1.Now())
+		}
+
 		if err := r.updateWithCondition(
 			ctx,
 			quay,
@@ -521,18 +588,65 @@ func (r *QuayRegistryReconciler) quayAppDeploymentRolledOut(
 		deployment.Status.AvailableReplicas == desired, nil
 }
 
+// updateMaintenanceCondition reports whether the provided QuayRegistry is under maintenance
+// through the Maintenance condition, so a planned downtime can be told apart from an incident.
+// The condition is removed once the maintenance ends.
+func (r *QuayRegistryReconciler) updateMaintenanceCondition(
+	ctx context.Context, quay *v1.QuayRegistry,
+) error {
+	current := v1.GetCondition(quay.Status.Conditions, v1.ConditionTypeMaintenance)
+	if !v1.MaintenanceEnabled(quay) {
+		if current == nil {
+			return nil
+		}
+
+		quay.Status.Conditions = v1.RemoveCondition(quay.Status.Conditions, v1.ConditionTypeMaintenance)
+		r.EventRecorder.Event(quay, corev1.EventTypeNormal, "MaintenanceCompleted", "registry is out of maintenance")
+		return r.updateStatus(ctx, quay)
+	}
+
+	rolledOut, err := r.quayAppDeploymentRolledOut(ctx, quay)
+	if err != nil {
+		return err
+	}
+
+	reason := v1.ConditionReasonMaintenanceReadOnly
+	msg := "registry is in read-only mode for maintenance"
+	if quay.Spec.Maintenance.Reason != "" {
+		msg = fmt.Sprintf("%s: %s", msg, quay.Spec.Maintenance.Reason)
+	}
+	if !rolledOut {
+		reason = v1.ConditionReasonMaintenanceRolloutInProgress
+		msg = "rolling quay into read-only mode for maintenance"
+	}
+
+	if current != nil && current.Status == metav1.ConditionTrue &&
+		current.Reason == reason && current.Message == msg {
+		return nil
+	}
+
+	return r.updateWithCondition(ctx, quay, v1.ConditionTypeMaintenance, metav1.ConditionTrue, reason, msg)
+}
+
 // +kubebuilder:rbac:groups=quay.redhat.com,resources=quayregistries,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=quay.redhat.com,resources=quayregistries/status,verbs=get;update;patch
-// +kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=apps,resources=deployments;statefulsets,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups="",resources=pods;services;secrets;configmaps;serviceaccounts;persistentvolumeclaims;events,verbs=get;list;watch;create;update;patch;delete
-// +kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="",resources=pods/log,verbs=get
 // +kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=roles;rolebindings,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=route.openshift.io,resources=routes;routes/custom-host,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=autoscaling,resources=horizontalpodautoscalers,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=httproutes;grpcroutes;tlsroutes,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=cert-manager.io,resources=certificates,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=objectbucket.io,resources=objectbucketclaims,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=monitoring.coreos.com,resources=prometheusrules;servicemonitors,verbs=get;list;watch;create;update;patch;delete
-// +kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=policy,resources=poddisruptionbudgets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=postgresql.cnpg.io,resources=clusters,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=batch,resources=cronjobs;jobs,verbs=get;list;watch;create;update;patch;delete;deletecollection
 // +kubebuilder:rbac:groups=config.openshift.io,resources=apiservers,verbs=get
+// +kubebuilder:rbac:groups=snapshot.storage.k8s.io,resources=volumesnapshots,verbs=get;list;create;delete
 
 // Reconcile is called every time an update happens in a QuayRegistry object. It attempts to
 // create all needed objects to get a quay instance running.
@@ -559,10 +673,23 @@ func (r *QuayRegistryReconciler) Reconcile(ctx context.Context, req ctrl.Request
 		return r.manageQuayDeletion(ctx, updatedQuay, log)
 	}
 
+	if v1.RestoreRunning(updatedQuay) {
+		log.Info("database restore running, requeueing reconcile...")
+		return r.Requeue, nil
+	}
+
 	if v1.PostgresUpgradeRunning(updatedQuay) {
 		return r.checkPostgresUpgradeStatus(ctx, updatedQuay, log)
 	}
 
+	if v1.UpgradeRollbackRunning(updatedQuay) {
+		return r.checkUpgradeRollback(ctx, updatedQuay, log)
+	}
+
+	if v1.UpgradeRollbackFailed(updatedQuay) {
+		return r.retryUpgradeRollback(ctx, updatedQuay, log)
+	}
+
 	if v1.MigrationsRunning(updatedQuay) {
 		return r.checkMigrationStatus(ctx, updatedQuay, log)
 	}
@@ -596,6 +723,17 @@ func (r *QuayRegistryReconciler) Reconcile(ctx context.Context, req ctrl.Request
 		)
 	}
 
+	if err := r.checkCertManagerTLS(ctx, quayContext, updatedQuay, cbundle); err != nil {
+		return r.reconcileWithCondition(
+			ctx,
+			&quay,
+			v1.ConditionTypeRolloutBlocked,
+			metav1.ConditionTrue,
+			v1.ConditionReasonConfigInvalid,
+			fmt.Sprintf("cert-manager TLS secret error: %s", err),
+		)
+	}
+
 	r.checkManagedTLS(quayContext, cbundle)
 
 	if err := r.checkClusterCAHash(ctx, quayContext, updatedQuay); err != nil {
@@ -673,8 +811,151 @@ func (r *QuayRegistryReconciler) Reconcile(ctx context.Context, req ctrl.Request
 		)
 	}
 
-	// Populate the QuayContext with whether or not the QuayRegistry needs an upgrade
-	if v1.ComponentIsManaged(updatedQuay.Spec.Components, v1.ComponentPostgres) {
+	monmanaged := v1.ComponentIsManaged(updatedQuay.Spec.Components, v1.ComponentMonitoring)
+	if err := r.checkMonitoringAvailable(ctx, quayContext); err != nil && monmanaged {
+		return r.reconcileWithCondition(
+			ctx,
+			&quay,
+			v1.ConditionTypeRolloutBlocked,
+			metav1.ConditionTrue,
+			v1.ConditionReasonMonitoringComponentDependencyError,
+			fmt.Sprintf("could not check for monitoring support: %s", err),
+		)
+	}
+
+	gwmanaged := v1.ComponentIsManaged(updatedQuay.Spec.Components, v1.ComponentGateway)
+	if err := r.checkGatewayAPIAvailable(ctx, quayContext, updatedQuay); err != nil && gwmanaged {
+		return r.reconcileWithCondition(
+			ctx,
+			&quay,
+			v1.ConditionTypeRolloutBlocked,
+			metav1.ConditionTrue,
+			v1.ConditionReasonGatewayComponentDependencyError,
+			fmt.Sprintf("could not check for Gateway API support: %s", err),
+		)
+	}
+
+	cmissued := v1.TLSIssuedByCertManager(updatedQuay)
+	if err := r.checkCertManagerAvailable(ctx, quayContext, updatedQuay); err != nil && cmissued {
+		return r.reconcileWithCondition(
+			ctx,
+			&quay,
+			v1.ConditionTypeRolloutBlocked,
+			metav1.ConditionTrue,
+			v1.ConditionReasonTLSComponentDependencyError,
+			fmt.Sprintf("could not check for cert-manager support: %s", err),
+		)
+	}
+
+	if err := r.checkOperatorTLS(ctx, quayContext, updatedQuay); err != nil {
+		return r.reconcileWithCondition(
+			ctx,
+			&quay,
+			v1.ConditionTypeRolloutBlocked,
+			metav1.ConditionTrue,
+			v1.ConditionReasonConfigInvalid,
+			fmt.Sprintf("unable to read operator issued TLS certificate: %s", err),
+		)
+	}
+
+	if err := r.checkDatabaseTLS(ctx, quayContext, updatedQuay); err != nil {
+		return r.reconcileWithCondition(
+			ctx,
+			&quay,
+			v1.ConditionTypeRolloutBlocked,
+			metav1.ConditionTrue,
+			v1.ConditionReasonConfigInvalid,
+			fmt.Sprintf("unable to read database TLS certificates: %s", err),
+		)
+	}
+
+	// the defaults are resolved in memory on every reconcile and published through
+	// status.components, the user provided spec is never updated.
+	if err = v1.EnsureDefaultComponents(quayContext, updatedQuay); err != nil {
+		log.Error(err, "could not ensure default `spec.components`")
+		return r.Requeue, err
+	}
+
+	if errs := v1.ValidateOverrides(updatedQuay); len(errs) > 0 {
+		return r.reconcileWithCondition(
+			ctx,
+			&quay,
+			v1.ConditionTypeRolloutBlocked,
+			metav1.ConditionTrue,
+			v1.ConditionReasonComponentOverrideInvalid,
+			fmt.Sprintf("invalid overrides: %s", errs.ToAggregate()),
+		)
+	}
+
+	pgclusters := v1.DatabaseClusterEnabled(updatedQuay, v1.ComponentPostgres) ||
+		v1.DatabaseClusterEnabled(updatedQuay, v1.ComponentClairPostgres)
+	if err := r.checkCloudNativePGAvailable(ctx, quayContext, updatedQuay); err != nil && pgclusters {
+		return r.reconcileWithCondition(
+			ctx,
+			&quay,
+			v1.ConditionTypeRolloutBlocked,
+			metav1.ConditionTrue,
+			v1.ConditionReasonDatabaseComponentDependencyError,
+			fmt.Sprintf("could not check for CloudNativePG support: %s", err),
+		)
+	}
+
+	if err := r.checkDatabaseClusterCredentials(ctx, quayContext, updatedQuay); err != nil {
+		return r.reconcileWithCondition(
+			ctx,
+			&quay,
+			v1.ConditionTypeRolloutBlocked,
+			metav1.ConditionTrue,
+			v1.ConditionReasonConfigInvalid,
+			fmt.Sprintf("unable to read database cluster credentials: %s", err),
+		)
+	}
+
+	// the preflight checks run once per version of Quay, before anything is scaled down for
+	// the upgrade to it.
+	if v1.UpgradePreflightNeeded(updatedQuay) {
+		if err := r.checkUpgradePreflight(ctx, quayContext, updatedQuay, cbundle); err != nil {
+			return r.reconcileWithCondition(
+				ctx,
+				&quay,
+				v1.ConditionTypeRolloutBlocked,
+				metav1.ConditionTrue,
+				v1.ConditionReasonUpgradeUnsupported,
+				err.Error(),
+			)
+		}
+
+		log.Info("upgrade preflight checks passed", "version", v1.QuayVersionCurrent)
+		if err := r.recordUpgradePreflight(ctx, updatedQuay); err != nil {
+			log.Error(err, "could not record upgrade preflight checks")
+			return r.Requeue, nil
+		}
+	}
+
+	// with the rollback policy the managed database is dumped before anything is scaled
+	// down for the upgrade, the migrations are rolled back to the dump if they fail.
+	if v1.UpgradeRollbackPointNeeded(updatedQuay) {
+		done, err := r.checkUpgradeRollbackPoint(ctx, updatedQuay)
+		if err != nil {
+			return r.reconcileWithCondition(
+				ctx,
+				&quay,
+				v1.ConditionTypeRolloutBlocked,
+				metav1.ConditionTrue,
+				v1.ConditionReasonUpgradeRollbackFailed,
+				fmt.Sprintf("unable to dump database before upgrading: %s", err),
+			)
+		}
+		if !done {
+			log.Info("waiting for the database dump the upgrade is rolled back to")
+			return r.Requeue, nil
+		}
+	}
+
+	// Populate the QuayContext with whether or not the QuayRegistry needs an upgrade,
+	// the databases run by cloudnativepg are never upgraded by the operator.
+	pgmanaged := v1.ComponentIsManaged(updatedQuay.Spec.Components, v1.ComponentPostgres)
+	if pgmanaged && !v1.DatabaseClusterEnabled(updatedQuay, v1.ComponentPostgres) {
 		err, scaledDown := r.checkNeedsPostgresUpgradeForComponent(ctx, quayContext, updatedQuay, v1.ComponentPostgres)
 		if err != nil {
 			return r.reconcileWithCondition(
@@ -682,7 +963,7 @@ func (r *QuayRegistryReconciler) Reconcile(ctx context.Context, req ctrl.Request
 				&quay,
 				v1.ConditionTypeRolloutBlocked,
 				metav1.ConditionTrue,
-				v1.ConditionReasonPostgresUpgradeFailed,
+				postgresUpgradeFailureReason(err),
 				fmt.Sprintf("error checking for pg upgrade: %s", err),
 			)
 		}
@@ -692,7 +973,8 @@ func (r *QuayRegistryReconciler) Reconcile(ctx context.Context, req ctrl.Request
 	}
 
 	// Populate the QuayContext with whether or not the QuayRegistry needs an upgrade
-	if v1.ComponentIsManaged(updatedQuay.Spec.Components, v1.ComponentClairPostgres) {
+	clairpgmanaged := v1.ComponentIsManaged(updatedQuay.Spec.Components, v1.ComponentClairPostgres)
+	if clairpgmanaged && !v1.DatabaseClusterEnabled(updatedQuay, v1.ComponentClairPostgres) {
 		err, scaledDown := r.checkNeedsPostgresUpgradeForComponent(ctx, quayContext, updatedQuay, v1.ComponentClairPostgres)
 		if err != nil {
 			return r.reconcileWithCondition(
@@ -700,7 +982,7 @@ func (r *QuayRegistryReconciler) Reconcile(ctx context.Context, req ctrl.Request
 				&quay,
 				v1.ConditionTypeRolloutBlocked,
 				metav1.ConditionTrue,
-				v1.ConditionReasonPostgresUpgradeFailed,
+				postgresUpgradeFailureReason(err),
 				fmt.Sprintf("error checking for pg upgrade: %s", err),
 			)
 		}
@@ -709,8 +991,26 @@ func (r *QuayRegistryReconciler) Reconcile(ctx context.Context, req ctrl.Request
 		}
 	}
 
+	if err := r.releasePreUpgradeBackups(ctx, quayContext, updatedQuay); err != nil {
+		log.Error(err, "unable to release acknowledged pre-upgrade backups")
+	}
+
 	r.checkManagedDatabaseReady(ctx, quayContext, updatedQuay)
 
+	if err := r.checkReadOnlyUpgrade(ctx, quayContext, updatedQuay); err != nil {
+		log.Error(err, "unable to check read-only upgrade, upgrading with quay scaled down")
+		quayContext.PreviousQuayImage = ""
+		quayContext.QuayReadOnly = false
+	}
+
+	// a registry whose upgrade was rolled back keeps running the previous version of Quay
+	// until the upgrade is retried.
+	if rollback := v1.UpgradeRolledBack(updatedQuay); rollback != nil {
+		quayContext.PreviousQuayImage = rollback.Image
+		quayContext.QuayReadOnly = false
+		quayContext.QuayRolledBack = true
+	}
+
 	if err := r.checkBuildManagerAvailable(quayContext, cbundle); err != nil {
 		return r.reconcileWithCondition(
 			ctx,
@@ -722,53 +1022,30 @@ func (r *QuayRegistryReconciler) Reconcile(ctx context.Context, req ctrl.Request
 		)
 	}
 
-	if !v1.ComponentIsManaged(updatedQuay.Spec.Components, v1.ComponentTLS) {
-		if err := r.checkTLSSecurityProfile(ctx, quayContext, cbundle); err != nil {
+	if v1.ComponentIsManaged(updatedQuay.Spec.Components, v1.ComponentBuilder) {
+		if err := r.checkBuilderAvailable(ctx, quayContext, updatedQuay); err != nil {
 			return r.reconcileWithCondition(
 				ctx,
 				&quay,
 				v1.ConditionTypeRolloutBlocked,
 				metav1.ConditionTrue,
-				v1.ConditionReasonConfigInvalid,
-				fmt.Sprintf("unable to check TLS security profile: %s", err),
+				v1.ConditionReasonBuilderComponentDependencyError,
+				fmt.Sprintf("could not check for builder support: %s", err),
 			)
 		}
 	}
 
-	monmanaged := v1.ComponentIsManaged(updatedQuay.Spec.Components, v1.ComponentMonitoring)
-	if err := r.checkMonitoringAvailable(ctx, quayContext); err != nil && monmanaged {
-		return r.reconcileWithCondition(
-			ctx,
-			&quay,
-			v1.ConditionTypeRolloutBlocked,
-			metav1.ConditionTrue,
-			v1.ConditionReasonMonitoringComponentDependencyError,
-			fmt.Sprintf("could not check for monitoring support: %s", err),
-		)
-	}
-
-	if err = v1.EnsureDefaultComponents(quayContext, updatedQuay); err != nil {
-		log.Error(err, "could not ensure default `spec.components`")
-		return r.Requeue, err
-	}
-
-	if err := v1.ValidateOverrides(updatedQuay); err != nil {
-		return r.reconcileWithCondition(
-			ctx,
-			&quay,
-			v1.ConditionTypeRolloutBlocked,
-			metav1.ConditionTrue,
-			v1.ConditionReasonComponentOverrideInvalid,
-			fmt.Sprintf("invalid overrides: %s", err),
-		)
-	}
-
-	if !v1.ComponentsMatch(quay.Spec.Components, updatedQuay.Spec.Components) {
-		log.Info("updating QuayRegistry `spec.components` to include defaults")
-		if err = r.Update(ctx, updatedQuay); err != nil {
-			log.Error(err, "failed to update `spec.components` to include defaults")
+	if v1.QuayServesTLS(quayContext, updatedQuay) {
+		if err := r.checkTLSSecurityProfile(ctx, quayContext, cbundle); err != nil {
+			return r.reconcileWithCondition(
+				ctx,
+				&quay,
+				v1.ConditionTypeRolloutBlocked,
+				metav1.ConditionTrue,
+				v1.ConditionReasonConfigInvalid,
+				fmt.Sprintf("unable to check TLS security profile: %s", err),
+			)
 		}
-		return r.Requeue, nil
 	}
 
 	var usercfg map[string]interface{}
@@ -787,7 +1064,7 @@ func (r *QuayRegistryReconciler) Reconcile(ctx context.Context, req ctrl.Request
 		updatedQuay.Status.Conditions, v1.ConditionTypeRolloutBlocked,
 	)
 
-	if err := r.hasNecessaryConfig(quay, cbundle.Data); err != nil {
+	if err := r.hasNecessaryConfig(*updatedQuay, cbundle.Data); err != nil {
 		return r.reconcileWithCondition(
 			ctx,
 			&quay,
@@ -909,6 +1186,39 @@ func (r *QuayRegistryReconciler) Reconcile(ctx context.Context, req ctrl.Request
 		}
 	}
 
+	if err := r.cleanupRedisObjects(ctx, updatedQuay); err != nil {
+		return r.reconcileWithCondition(
+			ctx,
+			updatedQuay,
+			v1.ConditionTypeRolloutBlocked,
+			metav1.ConditionTrue,
+			v1.ConditionReasonComponentCreationFailed,
+			fmt.Sprintf("could not remove previous redis objects: %s", err),
+		)
+	}
+
+	if err := r.cleanupPodDisruptionBudgets(ctx, updatedQuay); err != nil {
+		return r.reconcileWithCondition(
+			ctx,
+			updatedQuay,
+			v1.ConditionTypeRolloutBlocked,
+			metav1.ConditionTrue,
+			v1.ConditionReasonComponentCreationFailed,
+			fmt.Sprintf("could not remove pod disruption budgets: %s", err),
+		)
+	}
+
+	if err := r.cleanupDatabaseBackups(ctx, updatedQuay); err != nil {
+		return r.reconcileWithCondition(
+			ctx,
+			updatedQuay,
+			v1.ConditionTypeRolloutBlocked,
+			metav1.ConditionTrue,
+			v1.ConditionReasonComponentCreationFailed,
+			fmt.Sprintf("could not remove database backup jobs: %s", err),
+		)
+	}
+
 	if quayContext.SupportsMonitoring {
 		if err := r.patchNamespaceForMonitoring(ctx, quay); err != nil {
 			return r.reconcileWithCondition(
@@ -976,6 +1286,11 @@ func (r *QuayRegistryReconciler) Reconcile(ctx context.Context, req ctrl.Request
 		return r.Requeue, nil
 	}
 
+	if err := r.updateMaintenanceCondition(ctx, updatedQuay); err != nil {
+		log.Error(err, "failed to update maintenance `conditions` of `QuayRegistry`")
+		return r.Requeue, nil
+	}
+
 	if osmanaged && !quayContext.ObjectStorageInitialized {
 		r.Log.Info("requeuing to populate values for managed component: `objectstorage`")
 		return r.Requeue, nil
@@ -988,7 +1303,7 @@ func (r *QuayRegistryReconciler) Reconcile(ctx context.Context, req ctrl.Request
 
 	upToDate := v1.EnsureRegistryEndpoint(quayContext, updatedQuay, usercfg)
 	if !upToDate {
-		if err = r.Status().Update(ctx, updatedQuay); err != nil {
+		if err = r.updateStatus(ctx, updatedQuay); err != nil {
 			log.Error(err, "failed to update `registryEndpoint` of `QuayRegistry`")
 			return r.Requeue, nil
 		}
@@ -1009,28 +1324,67 @@ func (r *QuayRegistryReconciler) Reconcile(ctx context.Context, req ctrl.Request
 		return r.Requeue, nil
 	}
 
+	if quayContext.QuayRolledBack {
+		msg := upgradeRolledBackMessage(v1.UpgradeRolledBack(updatedQuay))
+		created := v1.GetCondition(updatedQuay.Status.Conditions, v1.ConditionComponentsCreated)
+		if created == nil || created.Reason != v1.ConditionReasonUpgradeRolledBack ||
+			created.Message != msg {
+			if err := r.updateWithCondition(
+				ctx,
+				updatedQuay,
+				v1.ConditionComponentsCreated,
+				metav1.ConditionFalse,
+				v1.ConditionReasonUpgradeRolledBack,
+				msg,
+			); err != nil {
+				log.Error(err, "failed to update `conditions` of `QuayRegistry`")
+			}
+		}
+		return ctrl.Result{RequeueAfter: time.Minute}, nil
+	}
+
 	// if the version differ then it means that the operator was upgraded and we need
 	// to wait until the database upgrade job finishes. sets a condition here and
 	// returns.
+	// with the read-only upgrade strategy the migrations wait for quay to be rolled into
+	// read-only mode, the reconcile runs in its entirety until then.
+	if quayContext.PreviousQuayImage != "" && !quayContext.QuayReadOnly {
+		if err := r.updateWithCondition(
+			ctx,
+			updatedQuay,
+			v1.ConditionComponentsCreated,
+			metav1.ConditionFalse,
+			v1.ConditionReasonReadOnlyRolloutInProgress,
+			"rolling quay into read-only mode before running database migrations",
+		); err != nil {
+			log.Error(err, "failed to update `conditions` of `QuayRegistry`")
+		}
+		return r.Requeue, nil
+	}
+
 	if updatedQuay.Status.CurrentVersion != v1.QuayVersionCurrent {
+		msg := "running database migrations"
+		if quayContext.QuayReadOnly {
+			msg = "running database migrations, quay serves pulls in read-only mode"
+		}
+
 		if err := r.updateWithCondition(
 			ctx,
 			updatedQuay,
 			v1.ConditionComponentsCreated,
 			metav1.ConditionFalse,
 			v1.ConditionReasonMigrationsInProgress,
-			"running database migrations",
+			msg,
 		); err != nil {
 			log.Error(err, "failed to update `conditions` of `QuayRegistry`")
 		}
 		return r.Requeue, nil
 	}
 
-	if !controllerutil.ContainsFinalizer(updatedQuay, quayOperatorFinalizer) {
-		controllerutil.AddFinalizer(updatedQuay, quayOperatorFinalizer)
-		if err := r.Update(ctx, updatedQuay); err != nil {
-			return r.Requeue, err
-		}
+	// the finalizer is added to the registry as it was fetched, the components defaulted in
+	// memory must never make it into the spec.
+	if err := r.ensureFinalizer(ctx, &quay); err != nil {
+		return r.Requeue, err
 	}
 
 	// when we get to this point all objects were created as expected and we can safely
@@ -1038,6 +1392,18 @@ func (r *QuayRegistryReconciler) Reconcile(ctx context.Context, req ctrl.Request
 	return ctrl.Result{RequeueAfter: time.Minute}, nil
 }
 
+// ensureFinalizer adds the operator finalizer to the provided QuayRegistry. Only the finalizers
+// are patched, the rest of the object is left as the user provided it.
+func (r *QuayRegistryReconciler) ensureFinalizer(ctx context.Context, quay *v1.QuayRegistry) error {
+	if controllerutil.ContainsFinalizer(quay, quayOperatorFinalizer) {
+		return nil
+	}
+
+	patch := client.MergeFrom(quay.DeepCopy())
+	controllerutil.AddFinalizer(quay, quayOperatorFinalizer)
+	return r.Patch(ctx, quay, patch)
+}
+
 // hasNecessaryConfig checks every component has been provided with a proper configuration. For
 // instance if redis is unmanaged the user provided configuration must contain a custom redis
 // config otherwise we may render an invalid quay deployment.
@@ -1207,12 +1573,12 @@ func (r *QuayRegistryReconciler) createOrUpdateObject(
 	log.Info("creating/updating object")
 
 	// we set the owner in the object except when it belongs to a different namespace,
-	// on this case we have only the grafana dashboard that lives in another place.
+	// on this case we have the grafana dashboard and the objects in the build namespace.
 	obj = v1.EnsureOwnerReference(&quay, obj)
-	if isGrafanaConfigMap(obj) {
+	if isGrafanaConfigMap(obj) || obj.GetNamespace() != quay.GetNamespace() {
 		var err error
 		if obj, err = v1.RemoveOwnerReference(&quay, obj); err != nil {
-			log.Error(err, "could not remove `ownerReferences` from grafana config")
+			log.Error(err, "could not remove `ownerReferences` from object in another namespace")
 			return false, err
 		}
 	}
@@ -1272,6 +1638,20 @@ func (r *QuayRegistryReconciler) createOrUpdateObject(
 		return false, nil
 	}
 
+	// the data source of a volume can't be changed once it has been created, the one of the
+	// existing volume is kept so a dataSource override only applies to new volumes.
+	if pvc, ok := obj.(*corev1.PersistentVolumeClaim); ok {
+		var existing corev1.PersistentVolumeClaim
+		nsn := types.NamespacedName{Name: pvc.GetName(), Namespace: pvc.GetNamespace()}
+		if err := r.Get(ctx, nsn, &existing); err == nil {
+			pvc.Spec.DataSource = existing.Spec.DataSource
+			pvc.Spec.DataSourceRef = existing.Spec.DataSourceRef
+		} else if !errors.IsNotFound(err) {
+			log.Error(err, "failed to get existing volume")
+			return false, err
+		}
+	}
+
 	hpaGVK := schema.GroupVersionKind{Group: "autoscaling", Version: "v2", Kind: "HorizontalPodAutoscaler"}
 
 	opts := []client.PatchOption{
@@ -1323,7 +1703,20 @@ func (r *QuayRegistryReconciler) updateWithCondition(
 	}
 	r.EventRecorder.Event(quay, eventType, string(reason), msg)
 
-	return r.Status().Update(ctx, quay)
+	return r.updateStatus(ctx, quay)
+}
+
+// updateStatus writes the status of the provided QuayRegistry. The write is done from a copy as
+// the response would otherwise replace the components defaulted in memory with the ones of the
+// user provided spec, only the new resource version is kept.
+func (r *QuayRegistryReconciler) updateStatus(ctx context.Context, quay *v1.QuayRegistry) error {
+	updated := quay.DeepCopy()
+	if err := r.Status().Update(ctx, updated); err != nil {
+		return err
+	}
+
+	quay.SetResourceVersion(updated.GetResourceVersion())
+	return nil
 }
 
 func (r *QuayRegistryReconciler) cleanupPreviousSecrets(log logr.Logger, ctx context.Context, quay *v1.QuayRegistry, previousSecrets []corev1.Secret) error {
@@ -1403,6 +1796,7 @@ func (r *QuayRegistryReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		Owns(&corev1.Secret{}, genChanged).
 		Owns(&corev1.ConfigMap{}, genChanged).
 		Owns(&corev1.PersistentVolumeClaim{}, genChanged).
+		Owns(&networkingv1.Ingress{}, genChanged).
 		Watches(
 			&corev1.Secret{},
 			handler.EnqueueRequestsFromMapFunc(r.findQuayRegistriesForSecret),
@@ -1412,7 +1806,8 @@ func (r *QuayRegistryReconciler) SetupWithManager(mgr ctrl.Manager) error {
 }
 
 // findQuayRegistriesForSecret maps a Secret event to reconcile requests for QuayRegistries
-// that reference the Secret via spec.tls.secretRef.
+// that reference the Secret via spec.tls.secretRef or that consume it as the certificate
+// issued by cert-manager.
 func (r *QuayRegistryReconciler) findQuayRegistriesForSecret(
 	ctx context.Context, obj client.Object,
 ) []reconcile.Request {
@@ -1431,11 +1826,13 @@ func (r *QuayRegistryReconciler) findQuayRegistriesForSecret(
 
 	var requests []reconcile.Request
 	for _, reg := range registries.Items {
-		secretRef := v1.GetTLSSecretRef(reg.Spec.Components)
-		if secretRef == nil {
-			continue
+		name := ""
+		if secretRef := v1.GetTLSSecretRef(reg.Spec.Components); secretRef != nil {
+			name = secretRef.Name
+		} else if v1.TLSIssuedByCertManager(&reg) {
+			name = v1.CertManagerTLSSecretName(&reg)
 		}
-		if secretRef.Name != secret.GetName() {
+		if name != secret.GetName() {
 			continue
 		}
 		requests = append(requests, reconcile.Request{
@@ -1538,6 +1935,184 @@ func (r *QuayRegistryReconciler) cleanupNamespaceLabels(ctx context.Context, qua
 	return nil
 }
 
+// ensureBuilderNamespace creates the namespace in which the builds of the provided
+// QuayRegistry run. owner references can't cross namespaces, the namespace is removed when
+// the QuayRegistry is finalized.
+func (r *QuayRegistryReconciler) ensureBuilderNamespace(
+	ctx context.Context, quay *v1.QuayRegistry,
+) error {
+	nsn := types.NamespacedName{
+		Name: v1.BuilderNamespaceFor(quay),
+	}
+
+	var ns corev1.Namespace
+	if err := r.Get(ctx, nsn, &ns); err == nil || !errors.IsNotFound(err) {
+		return err
+	}
+
+	ns = corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: nsn.Name,
+			Labels: map[string]string{
+				kustomize.QuayRegistryNameLabel: quay.GetName(),
+				quayOperatorManagedLabelKey:     "true",
+			},
+		},
+	}
+	return r.Create(ctx, &ns)
+}
+
+// cleanupBuilderNamespace removes the build namespace of the provided QuayRegistry, only
+// namespaces created by the operator are removed.
+func (r *QuayRegistryReconciler) cleanupBuilderNamespace(
+	ctx context.Context, quay *v1.QuayRegistry,
+) error {
+	nsn := types.NamespacedName{
+		Name: v1.BuilderNamespaceFor(quay),
+	}
+
+	var ns corev1.Namespace
+	if err := r.Get(ctx, nsn, &ns); err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	if ns.Labels[kustomize.QuayRegistryNameLabel] != quay.GetName() ||
+		ns.Labels[quayOperatorManagedLabelKey] != "true" {
+		return nil
+	}
+	return r.Delete(ctx, &ns)
+}
+
+// cleanupRedisObjects removes the objects left behind by the previous redis deployment mode
+// when high availability is switched on or off. Only objects owned by the provided
+// QuayRegistry are removed.
+func (r *QuayRegistryReconciler) cleanupRedisObjects(
+	ctx context.Context, quay *v1.QuayRegistry,
+) error {
+	if !v1.ComponentIsManaged(quay.Spec.Components, v1.ComponentRedis) {
+		return nil
+	}
+
+	name := quay.GetName() + "-quay-redis"
+	meta := func(suffix string) metav1.ObjectMeta {
+		return metav1.ObjectMeta{Name: name + suffix, Namespace: quay.GetNamespace()}
+	}
+
+	stale := []client.Object{
+		&appsv1.StatefulSet{ObjectMeta: meta("")},
+		&policyv1.PodDisruptionBudget{ObjectMeta: meta("")},
+		&corev1.Service{ObjectMeta: meta("-headless")},
+		&corev1.Service{ObjectMeta: meta("-sentinel")},
+		&corev1.ConfigMap{ObjectMeta: meta("-ha-scripts")},
+	}
+	if v1.RedisHighAvailabilityEnabled(quay) {
+		stale = []client.Object{
+			&appsv1.Deployment{ObjectMeta: meta("")},
+			&corev1.Service{ObjectMeta: meta("")},
+		}
+	}
+
+	for _, obj := range stale {
+		if err := r.Get(ctx, client.ObjectKeyFromObject(obj), obj); err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return err
+		}
+
+		if !v1.Owns(*quay, obj) {
+			continue
+		}
+
+		if err := r.Delete(ctx, obj); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// disruptionBudgetSuffixes maps the components covered by a PodDisruptionBudget to the suffix
+// of its name.
+var disruptionBudgetSuffixes = map[v1.ComponentKind]string{
+	v1.ComponentQuay:   "quay-app",
+	v1.ComponentClair:  "clair-app",
+	v1.ComponentMirror: "quay-mirror",
+}
+
+// cleanupPodDisruptionBudgets removes the PodDisruptionBudgets of the components left without
+// one, e.g. scaled down to a single replica, as they would block node drains. Only budgets
+// owned by the provided QuayRegistry are removed.
+func (r *QuayRegistryReconciler) cleanupPodDisruptionBudgets(
+	ctx context.Context, quay *v1.QuayRegistry,
+) error {
+	for kind, suffix := range disruptionBudgetSuffixes {
+		if v1.PodDisruptionBudgetFor(quay, kind) != nil {
+			continue
+		}
+
+		pdb := &policyv1.PodDisruptionBudget{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      fmt.Sprintf("%s-%s", quay.GetName(), suffix),
+				Namespace: quay.GetNamespace(),
+			},
+		}
+		if err := r.Get(ctx, client.ObjectKeyFromObject(pdb), pdb); err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return err
+		}
+
+		if !v1.Owns(*quay, pdb) {
+			continue
+		}
+
+		if err := r.Delete(ctx, pdb); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// cleanupDatabaseBackups removes the CronJobs taking backups, or snapshots, of the databases
+// whose backup or snapshot override has been removed. Previous backups and snapshots are kept.
+func (r *QuayRegistryReconciler) cleanupDatabaseBackups(
+	ctx context.Context, quay *v1.QuayRegistry,
+) error {
+	for _, kind := range []v1.ComponentKind{v1.ComponentPostgres, v1.ComponentClairPostgres} {
+		var names []string
+		if v1.DatabaseBackupFor(quay, kind) == nil {
+			names = append(names, v1.DatabaseBackupNameFor(quay, kind))
+		}
+		if v1.DatabaseSnapshotFor(quay, kind) == nil {
+			names = append(names, v1.DatabaseSnapshotNameFor(quay, kind))
+		}
+
+		for _, name := range names {
+			var cj batchv1.CronJob
+			nsn := types.NamespacedName{Namespace: quay.GetNamespace(), Name: name}
+			if err := r.Get(ctx, nsn, &cj); err != nil {
+				if errors.IsNotFound(err) {
+					continue
+				}
+				return err
+			}
+
+			if !v1.Owns(*quay, &cj) {
+				continue
+			}
+
+			if err := r.Delete(ctx, &cj); err != nil && !errors.IsNotFound(err) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 func (r *QuayRegistryReconciler) cleanupGrafanaConfigMap(ctx context.Context, quay *v1.QuayRegistry) error {
 	var grafanaConfigMap corev1.ConfigMap
 	grafanaConfigMapName := types.NamespacedName{
@@ -1576,6 +2151,17 @@ func (r *QuayRegistryReconciler) finalizeQuay(ctx context.Context, quay *v1.Quay
 		r.Log.Info("successfully cleaned up grafana config map")
 	}
 
+	// NOTE: `controller-runtime` hangs rather than return "forbidden" error if insufficient RBAC permissions, so we use `WatchNamespace` to skip (https://github.com/kubernetes-sigs/controller-runtime/issues/550).
+	if r.WatchNamespace != "" {
+		r.Log.Info("not running in all-namespaces mode, skipping finalizer step: build namespace cleanup")
+	} else {
+		r.Log.Info("cleaning up build namespace")
+		if err := r.cleanupBuilderNamespace(ctx, quay); err != nil {
+			return err
+		}
+		r.Log.Info("successfully cleaned up build namespace")
+	}
+
 	return nil
 }
 
@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/hex"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -13,6 +14,7 @@ import (
 	appsv1 "k8s.io/api/apps/v1"
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
@@ -20,10 +22,13 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/cert"
+	"k8s.io/utils/ptr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
@@ -172,6 +177,80 @@ func TestCreateOrUpdateObject_Job(t *testing.T) {
 	}
 }
 
+func TestCreateOrUpdateObject_PersistentVolumeClaimDataSource(t *testing.T) {
+	source := &corev1.TypedLocalObjectReference{
+		APIGroup: ptr.To(v1.VolumeSnapshotGroup),
+		Kind:     "VolumeSnapshot",
+		Name:     "production",
+	}
+
+	for _, tt := range []struct {
+		name     string
+		existing *corev1.PersistentVolumeClaim
+		expected *corev1.TypedLocalObjectReference
+	}{
+		{
+			name:     "new volume",
+			expected: source,
+		},
+		{
+			name: "existing volume",
+			existing: &corev1.PersistentVolumeClaim{
+				ObjectMeta: metav1.ObjectMeta{Name: "registry-quay-postgres-13", Namespace: "ns"},
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var applied *corev1.PersistentVolumeClaim
+			builder := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithInterceptorFuncs(
+				interceptor.Funcs{
+					Patch: func(
+						ctx context.Context,
+						cli client.WithWatch,
+						obj client.Object,
+						patch client.Patch,
+						opts ...client.PatchOption,
+					) error {
+						applied = obj.(*corev1.PersistentVolumeClaim).DeepCopy()
+						return nil
+					},
+				},
+			)
+			if tt.existing != nil {
+				builder = builder.WithObjects(tt.existing)
+			}
+
+			reconciler := &QuayRegistryReconciler{
+				Client:        builder.Build(),
+				Log:           testLogger,
+				Scheme:        scheme.Scheme,
+				EventRecorder: testEventRecorder,
+			}
+
+			pvc := &corev1.PersistentVolumeClaim{
+				TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "PersistentVolumeClaim"},
+				ObjectMeta: metav1.ObjectMeta{Name: "registry-quay-postgres-13", Namespace: "ns"},
+				Spec:       corev1.PersistentVolumeClaimSpec{DataSource: source.DeepCopy()},
+			}
+			quay := v1.QuayRegistry{
+				ObjectMeta: metav1.ObjectMeta{Name: "registry", Namespace: "ns"},
+			}
+			if _, err := reconciler.createOrUpdateObject(
+				context.Background(), pvc, quay, testLogger,
+			); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if applied == nil {
+				t.Fatalf("expected volume to be applied")
+			}
+			if !reflect.DeepEqual(tt.expected, applied.Spec.DataSource) {
+				t.Errorf("expected data source %+v, received %+v", tt.expected, applied.Spec.DataSource)
+			}
+		})
+	}
+}
+
 func newQuayRegistry(name, namespace string) *v1.QuayRegistry {
 	quay := &v1.QuayRegistry{
 		TypeMeta: metav1.TypeMeta{
@@ -588,19 +667,11 @@ var _ = Describe("Reconciling a QuayRegistry", func() {
 			Expect(result.Requeue).To(BeFalse())
 		})
 
-		It("populates `spec.components` field with all default managed components", func() {
+		It("does not write the default components into `spec.components`", func() {
 			var updatedQuayRegistry v1.QuayRegistry
 
 			Expect(k8sClient.Get(context.Background(), quayRegistryName, &updatedQuayRegistry)).Should(Succeed())
-
-			expectedQuay := quayRegistry.DeepCopy()
-			err := v1.EnsureDefaultComponents(
-				&quaycontext.QuayRegistryContext{SupportsRoutes: false, SupportsObjectStorage: false, SupportsMonitoring: false},
-				expectedQuay,
-			)
-
-			Expect(err).NotTo(HaveOccurred())
-			Expect(v1.ComponentsMatch(updatedQuayRegistry.Spec.Components, expectedQuay.Spec.Components))
+			Expect(updatedQuayRegistry.Spec.Components).To(BeEmpty())
 		})
 	})
 })
@@ -942,6 +1013,355 @@ func Test_cleanupPreviousSecret(t *testing.T) {
 	}
 }
 
+func Test_cleanupRedisObjects(t *testing.T) {
+	owner := []metav1.OwnerReference{
+		{
+			APIVersion: v1.GroupVersion.String(),
+			Kind:       "QuayRegistry",
+			Name:       "registry",
+			UID:        "uid",
+		},
+	}
+	objmeta := func(name string, owned bool) metav1.ObjectMeta {
+		meta := metav1.ObjectMeta{Name: name, Namespace: "ns"}
+		if owned {
+			meta.OwnerReferences = owner
+		}
+		return meta
+	}
+
+	for _, tt := range []struct {
+		name    string
+		ha      bool
+		objs    []client.Object
+		removed []string
+		kept    []string
+	}{
+		{
+			name: "switch to high availability",
+			ha:   true,
+			objs: []client.Object{
+				&appsv1.Deployment{ObjectMeta: objmeta("registry-quay-redis", true)},
+				&corev1.Service{ObjectMeta: objmeta("registry-quay-redis", true)},
+				&appsv1.StatefulSet{ObjectMeta: objmeta("registry-quay-redis", true)},
+				&corev1.Service{ObjectMeta: objmeta("registry-quay-redis-sentinel", true)},
+			},
+			removed: []string{"Deployment", "Service/registry-quay-redis"},
+			kept:    []string{"StatefulSet", "Service/registry-quay-redis-sentinel"},
+		},
+		{
+			name: "switch to single replica",
+			objs: []client.Object{
+				&appsv1.Deployment{ObjectMeta: objmeta("registry-quay-redis", true)},
+				&corev1.Service{ObjectMeta: objmeta("registry-quay-redis", true)},
+				&appsv1.StatefulSet{ObjectMeta: objmeta("registry-quay-redis", true)},
+				&corev1.Service{ObjectMeta: objmeta("registry-quay-redis-sentinel", true)},
+				&corev1.Service{ObjectMeta: objmeta("registry-quay-redis-headless", false)},
+			},
+			removed: []string{"StatefulSet", "Service/registry-quay-redis-sentinel"},
+			kept: []string{
+				"Deployment",
+				"Service/registry-quay-redis",
+				"Service/registry-quay-redis-headless",
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			quay := &v1.QuayRegistry{
+				ObjectMeta: metav1.ObjectMeta{Name: "registry", Namespace: "ns", UID: "uid"},
+				Spec: v1.QuayRegistrySpec{
+					Components: []v1.Component{
+						{
+							Kind:      v1.ComponentRedis,
+							Managed:   true,
+							Overrides: &v1.Override{HighAvailability: &tt.ha},
+						},
+					},
+				},
+			}
+
+			cli := fake.NewClientBuilder().WithObjects(tt.objs...).Build()
+			reconciler := QuayRegistryReconciler{Client: cli}
+			if err := reconciler.cleanupRedisObjects(context.Background(), quay); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			exists := func(ref string) bool {
+				kind, name, _ := strings.Cut(ref, "/")
+				if name == "" {
+					name = "registry-quay-redis"
+				}
+
+				var obj client.Object
+				switch kind {
+				case "Deployment":
+					obj = &appsv1.Deployment{}
+				case "StatefulSet":
+					obj = &appsv1.StatefulSet{}
+				case "Service":
+					obj = &corev1.Service{}
+				}
+
+				nsn := types.NamespacedName{Namespace: "ns", Name: name}
+				err := cli.Get(context.Background(), nsn, obj)
+				if err != nil && !k8serrors.IsNotFound(err) {
+					t.Fatalf("unexpected error: %s", err)
+				}
+				return err == nil
+			}
+
+			for _, ref := range tt.removed {
+				if exists(ref) {
+					t.Errorf("expected %s to be removed", ref)
+				}
+			}
+			for _, ref := range tt.kept {
+				if !exists(ref) {
+					t.Errorf("expected %s to be kept", ref)
+				}
+			}
+		})
+	}
+}
+
+func Test_cleanupDatabaseBackups(t *testing.T) {
+	owner := []metav1.OwnerReference{
+		{
+			APIVersion: v1.GroupVersion.String(),
+			Kind:       "QuayRegistry",
+			Name:       "registry",
+			UID:        "uid",
+		},
+	}
+
+	quay := &v1.QuayRegistry{
+		ObjectMeta: metav1.ObjectMeta{Name: "registry", Namespace: "ns", UID: "uid"},
+		Spec: v1.QuayRegistrySpec{
+			Components: []v1.Component{
+				{
+					Kind:      v1.ComponentPostgres,
+					Managed:   true,
+					Overrides: &v1.Override{Backup: &v1.DatabaseBackup{Schedule: "@daily"}},
+				},
+				{Kind: v1.ComponentClairPostgres, Managed: true},
+			},
+		},
+	}
+
+	objs := []client.Object{
+		&batchv1.CronJob{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "registry-quay-database-backup", Namespace: "ns", OwnerReferences: owner,
+			},
+		},
+		&batchv1.CronJob{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "registry-clair-postgres-backup", Namespace: "ns", OwnerReferences: owner,
+			},
+		},
+		&batchv1.CronJob{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "registry-quay-database-snapshot", Namespace: "ns", OwnerReferences: owner,
+			},
+		},
+		&corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "registry-clair-postgres-backup", Namespace: "ns", OwnerReferences: owner,
+			},
+		},
+	}
+
+	cli := fake.NewClientBuilder().WithObjects(objs...).Build()
+	reconciler := QuayRegistryReconciler{Client: cli}
+	if err := reconciler.cleanupDatabaseBackups(context.Background(), quay); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for _, tt := range []struct {
+		obj    client.Object
+		name   string
+		exists bool
+	}{
+		{&batchv1.CronJob{}, "registry-quay-database-backup", true},
+		{&batchv1.CronJob{}, "registry-clair-postgres-backup", false},
+		{&batchv1.CronJob{}, "registry-quay-database-snapshot", false},
+		{&corev1.PersistentVolumeClaim{}, "registry-clair-postgres-backup", true},
+	} {
+		nsn := types.NamespacedName{Namespace: "ns", Name: tt.name}
+		err := cli.Get(context.Background(), nsn, tt.obj)
+		if err != nil && !k8serrors.IsNotFound(err) {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if exists := err == nil; exists != tt.exists {
+			t.Errorf("expected %T %s exists to be %v", tt.obj, tt.name, tt.exists)
+		}
+	}
+}
+
+func Test_cleanupPodDisruptionBudgets(t *testing.T) {
+	owner := []metav1.OwnerReference{
+		{
+			APIVersion: v1.GroupVersion.String(),
+			Kind:       "QuayRegistry",
+			Name:       "registry",
+			UID:        "uid",
+		},
+	}
+
+	quay := &v1.QuayRegistry{
+		ObjectMeta: metav1.ObjectMeta{Name: "registry", Namespace: "ns", UID: "uid"},
+		Spec: v1.QuayRegistrySpec{
+			Components: []v1.Component{
+				{Kind: v1.ComponentHPA, Managed: false},
+				{Kind: v1.ComponentQuay, Managed: true, Overrides: &v1.Override{Replicas: ptr.To[int32](3)}},
+				{Kind: v1.ComponentClair, Managed: true, Overrides: &v1.Override{Replicas: ptr.To[int32](1)}},
+				{Kind: v1.ComponentMirror, Managed: false},
+			},
+		},
+	}
+
+	objs := []client.Object{
+		&policyv1.PodDisruptionBudget{
+			ObjectMeta: metav1.ObjectMeta{Name: "registry-quay-app", Namespace: "ns", OwnerReferences: owner},
+		},
+		&policyv1.PodDisruptionBudget{
+			ObjectMeta: metav1.ObjectMeta{Name: "registry-clair-app", Namespace: "ns", OwnerReferences: owner},
+		},
+		&policyv1.PodDisruptionBudget{
+			ObjectMeta: metav1.ObjectMeta{Name: "registry-quay-mirror", Namespace: "ns"},
+		},
+	}
+
+	cli := fake.NewClientBuilder().WithObjects(objs...).Build()
+	reconciler := QuayRegistryReconciler{Client: cli}
+	if err := reconciler.cleanupPodDisruptionBudgets(context.Background(), quay); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for _, tt := range []struct {
+		name   string
+		exists bool
+	}{
+		{"registry-quay-app", true},
+		{"registry-clair-app", false},
+		// not owned by the registry.
+		{"registry-quay-mirror", true},
+	} {
+		nsn := types.NamespacedName{Namespace: "ns", Name: tt.name}
+		err := cli.Get(context.Background(), nsn, &policyv1.PodDisruptionBudget{})
+		if err != nil && !k8serrors.IsNotFound(err) {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if exists := err == nil; exists != tt.exists {
+			t.Errorf("expected pod disruption budget %s exists to be %v", tt.name, tt.exists)
+		}
+	}
+}
+
+func TestCheckMigrationStatusPausesMirror(t *testing.T) {
+	current := v1.QuayVersionCurrent
+	v1.QuayVersionCurrent = "3.15.0"
+	t.Cleanup(func() { v1.QuayVersionCurrent = current })
+
+	for _, tt := range []struct {
+		name     string
+		strategy v1.UpgradeStrategy
+		replicas int32
+	}{
+		{name: "read-only strategy", strategy: v1.UpgradeStrategyReadOnly, replicas: 0},
+		{name: "recreate strategy", strategy: v1.UpgradeStrategyRecreate, replicas: 2},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			quay := &v1.QuayRegistry{
+				ObjectMeta: metav1.ObjectMeta{Name: "registry", Namespace: "ns"},
+				Spec:       v1.QuayRegistrySpec{UpgradeStrategy: tt.strategy},
+				Status:     v1.QuayRegistryStatus{CurrentVersion: "3.14.0"},
+			}
+			job := &batchv1.Job{
+				ObjectMeta: metav1.ObjectMeta{Name: "registry-quay-app-upgrade", Namespace: "ns"},
+				Status:     batchv1.JobStatus{Active: 1},
+			}
+			// the mirror workers have been scaled back up while the migrations run.
+			mirror := &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "registry-quay-mirror", Namespace: "ns"},
+				Spec:       appsv1.DeploymentSpec{Replicas: ptr.To[int32](2)},
+			}
+			r := newPreUpgradeTestReconciler(t, quay, job, mirror)
+
+			if _, err := r.checkMigrationStatus(ctx, quay, r.Log); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			var dep appsv1.Deployment
+			if err := r.Get(ctx, client.ObjectKeyFromObject(mirror), &dep); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if *dep.Spec.Replicas != tt.replicas {
+				t.Errorf("expected %d mirror replicas, received %d", tt.replicas, *dep.Spec.Replicas)
+			}
+		})
+	}
+}
+
+func Test_ensureFinalizer(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		defaulted bool
+	}{
+		{name: "as fetched"},
+		{name: "defaulted in memory", defaulted: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			quay := &v1.QuayRegistry{
+				ObjectMeta: metav1.ObjectMeta{Name: "registry", Namespace: "ns"},
+			}
+			r := newPreUpgradeTestReconciler(t, quay)
+			r.EventRecorder = record.NewFakeRecorder(10)
+
+			// the reconcile resolves the defaults and writes the status before the
+			// finalizer is added.
+			updated := quay.DeepCopy()
+			if err := v1.EnsureDefaultComponents(quaycontext.NewQuayRegistryContext(), updated); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if err := r.updateWithCondition(
+				ctx,
+				updated,
+				v1.ConditionTypeRolloutBlocked,
+				metav1.ConditionFalse,
+				v1.ConditionReasonComponentsCreationSuccess,
+				"All objects created/updated successfully",
+			); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if len(updated.Spec.Components) == 0 {
+				t.Fatal("expected the defaulted components to be kept after the status write")
+			}
+
+			target := quay
+			if tt.defaulted {
+				target = updated
+			}
+			if err := r.ensureFinalizer(ctx, target); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			var stored v1.QuayRegistry
+			if err := r.Get(ctx, client.ObjectKeyFromObject(quay), &stored); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if !controllerutil.ContainsFinalizer(&stored, quayOperatorFinalizer) {
+				t.Errorf("expected finalizer to be added, received %v", stored.GetFinalizers())
+			}
+			if stored.Spec.Components != nil {
+				t.Errorf("expected spec components to be left unset, received %v", stored.Spec.Components)
+			}
+		})
+	}
+}
+
 func newTestOBC(name, namespace string) *unstructured.Unstructured {
 	obj := &unstructured.Unstructured{}
 	obj.SetGroupVersionKind(schema.GroupVersionKind{
@@ -1409,6 +1829,95 @@ func TestQuayAppDeploymentRolledOut(t *testing.T) {
 	}
 }
 
+func Test_updateMaintenanceCondition(t *testing.T) {
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-quay-app", Namespace: "default"},
+		Status:     appsv1.DeploymentStatus{UpdatedReplicas: 1, AvailableReplicas: 1},
+	}
+
+	for _, tt := range []struct {
+		name        string
+		maintenance *v1.Maintenance
+		conditions  []v1.Condition
+		objs        []client.Object
+		reason      v1.ConditionReason
+		message     string
+	}{
+		{
+			name: "no maintenance",
+		},
+		{
+			name: "maintenance ended",
+			conditions: []v1.Condition{
+				{
+					Type:   v1.ConditionTypeMaintenance,
+					Status: metav1.ConditionTrue,
+					Reason: v1.ConditionReasonMaintenanceReadOnly,
+				},
+			},
+		},
+		{
+			name: "rolling into read-only mode",
+			maintenance: &v1.Maintenance{
+				Mode: v1.MaintenanceModeReadOnly,
+			},
+			objs: []client.Object{
+				&appsv1.Deployment{
+					ObjectMeta: metav1.ObjectMeta{Name: "test-quay-app", Namespace: "default"},
+					Status:     appsv1.DeploymentStatus{UpdatedReplicas: 0, AvailableReplicas: 1},
+				},
+			},
+			reason:  v1.ConditionReasonMaintenanceRolloutInProgress,
+			message: "rolling quay into read-only mode for maintenance",
+		},
+		{
+			name: "read-only mode",
+			maintenance: &v1.Maintenance{
+				Mode:   v1.MaintenanceModeReadOnly,
+				Reason: "storage migration",
+			},
+			objs:    []client.Object{deployment},
+			reason:  v1.ConditionReasonMaintenanceReadOnly,
+			message: "registry is in read-only mode for maintenance: storage migration",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			quay := &v1.QuayRegistry{
+				ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+				Spec:       v1.QuayRegistrySpec{Maintenance: tt.maintenance},
+				Status:     v1.QuayRegistryStatus{Conditions: tt.conditions},
+			}
+
+			r := newPreUpgradeTestReconciler(t, append(tt.objs, quay)...)
+			r.EventRecorder = record.NewFakeRecorder(10)
+
+			if err := r.updateMaintenanceCondition(context.Background(), quay); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			var updated v1.QuayRegistry
+			if err := r.Get(context.Background(), client.ObjectKeyFromObject(quay), &updated); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			cond := v1.GetCondition(updated.Status.Conditions, v1.ConditionTypeMaintenance)
+			if tt.reason == "" {
+				if cond != nil {
+					t.Errorf("unexpected maintenance condition %+v", cond)
+				}
+				return
+			}
+
+			if cond == nil {
+				t.Fatal("expected maintenance condition")
+			}
+			if cond.Status != metav1.ConditionTrue || cond.Reason != tt.reason || cond.Message != tt.message {
+				t.Errorf("unexpected maintenance condition %+v", cond)
+			}
+		})
+	}
+}
+
 func newTypedSecret(name, namespace string) client.Object {
 	s := &corev1.Secret{
 		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
@@ -49,13 +49,19 @@ func (q *QuayRegistryStatusReconciler) Reconcile(
 		if errors.IsNotFound(err) {
 			// the QuayRegistry is no more, we can simply ignore it from now
 			// on, no need for a reschedule.
+			tlsCertificateExpirySeconds.DeleteLabelValues(req.Namespace, req.Name)
 			return ctrl.Result{}, nil
 		}
 		log.Error(err, "error getting QuayRegistry object")
 		return reschedule, nil
 	}
 
-	conds, err := cmpstatus.Evaluate(ctx, q.Client, reg)
+	// components not declared by the user are defaulted by the main reconciler and
+	// published through the status, we need to take them into account as well.
+	resolved := reg.DeepCopy()
+	resolved.Spec.Components = qv1.ResolvedComponents(&reg)
+
+	conds, err := cmpstatus.Evaluate(ctx, q.Client, *resolved)
 	if err != nil {
 		log.Error(err, "error retrieving QuayRegistry component conditions")
 		return reschedule, nil
@@ -64,6 +70,13 @@ func (q *QuayRegistryStatusReconciler) Reconcile(
 	// uses the list of updated conditions to overwrite the QuayRegistry conditions.
 	q.overwriteConditions(conds, &reg)
 
+	notAfter, err := cmpstatus.CertificateNotAfter(ctx, q.Client, *resolved)
+	if err != nil {
+		log.Error(err, "error retrieving QuayRegistry certificate expiration")
+		return reschedule, nil
+	}
+	q.setCertificateExpiry(notAfter, &reg)
+
 	if err := q.Client.Status().Update(ctx, &reg); err != nil {
 		if errors.IsConflict(err) {
 			log.Info("skipping status reconcile due to conflict, will retry")
@@ -132,3 +145,22 @@ func (q *QuayRegistryStatusReconciler) overwriteConditions(
 	// and not used anymore).
 	qv1.RemoveUnusedConditions(reg)
 }
+
+// setCertificateExpiry publishes the expiration time of the certificate served for the provided
+// QuayRegistry in its status and through the tls certificate expiry gauge. A nil notAfter means
+// the certificate is not available to the operator, the expiry is then cleared.
+func (q *QuayRegistryStatusReconciler) setCertificateExpiry(
+	notAfter *time.Time, reg *qv1.QuayRegistry,
+) {
+	if notAfter == nil {
+		reg.Status.TLSCertificateExpiry = nil
+		tlsCertificateExpirySeconds.DeleteLabelValues(reg.Namespace, reg.Name)
+		return
+	}
+
+	expiry := metav1.NewTime(*notAfter)
+	reg.Status.TLSCertificateExpiry = &expiry
+	tlsCertificateExpirySeconds.WithLabelValues(reg.Namespace, reg.Name).Set(
+		time.Until(*notAfter).Seconds(),
+	)
+}
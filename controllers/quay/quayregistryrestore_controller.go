@@ -0,0 +1,554 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	v1 "github.com/quay/quay-operator/apis/quay/v1"
+)
+
+// restoreScaledDeployments are the deployments, suffixed to the QuayRegistry name, holding
+// connections to the quay database. They are scaled down while the database is restored.
+var restoreScaledDeployments = []string{"quay-app", "quay-mirror", "clair-app"}
+
+// restoreScript restores a dump taken by the database backups. The dump is restored in a
+// single transaction so a failed restore leaves the database untouched.
+const restoreScript = `set -euo pipefail
+
+if [ "${BACKUP_DESTINATION}" == "objectstorage" ]; then
+    # the bucket may be served with a certificate issued by the cluster service CA.
+    cat /etc/pki/tls/certs/ca-bundle.crt /run/secrets/backup-ca/* > /tmp/ca.crt 2>/dev/null || true
+
+    echo "downloading ${BACKUP_FILE}"
+    curl --silent --show-error --fail \
+        --cacert /tmp/ca.crt \
+        --aws-sigv4 "aws:amz:${BUCKET_REGION:-us-east-1}:s3" \
+        --user "${AWS_ACCESS_KEY_ID}:${AWS_SECRET_ACCESS_KEY}" \
+        --output "/backups/${BACKUP_FILE}" \
+        "https://${BUCKET_HOST}:${BUCKET_PORT}/${BUCKET_NAME}/backups/quay-database/${BACKUP_FILE}"
+fi
+
+echo "restoring ${BACKUP_FILE}"
+pg_restore --clean --if-exists --no-owner --single-transaction --exit-on-error \
+    --dbname="${DATABASE_URI}" "/backups/${BACKUP_FILE}"
+`
+
+// QuayRegistryRestoreReconciler restores the managed database of a QuayRegistry from a dump
+// taken by the database backups. The registry is scaled down while the dump is restored and
+// the database migrations are run again before scaling it back up.
+type QuayRegistryRestoreReconciler struct {
+	client.Client
+	Log     logr.Logger
+	Scheme  *runtime.Scheme
+	Requeue ctrl.Result
+}
+
+// +kubebuilder:rbac:groups=quay.redhat.com,resources=quayregistryrestores,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=quay.redhat.com,resources=quayregistryrestores/status,verbs=get;update;patch
+
+// Reconcile moves a QuayRegistryRestore through its phases. The QuayRegistry is held, through
+// its ComponentsCreated condition, from the moment its deployments are scaled down until the
+// dump is restored so the QuayRegistry reconciler does not scale them back up.
+func (r *QuayRegistryRestoreReconciler) Reconcile(
+	ctx context.Context, req ctrl.Request,
+) (ctrl.Result, error) {
+	log := r.Log.WithValues("quayregistryrestore", req.NamespacedName)
+
+	var restore v1.QuayRegistryRestore
+	if err := r.Get(ctx, req.NamespacedName, &restore); err != nil {
+		if errors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		log.Error(err, "unable to retrieve QuayRegistryRestore")
+		return r.Requeue, nil
+	}
+
+	if v1.RestoreFinished(&restore) {
+		return ctrl.Result{}, nil
+	}
+
+	var quay v1.QuayRegistry
+	nsn := types.NamespacedName{
+		Namespace: restore.GetNamespace(),
+		Name:      restore.Spec.QuayRegistry,
+	}
+	if err := r.Get(ctx, nsn, &quay); err != nil {
+		if errors.IsNotFound(err) {
+			return r.finish(
+				ctx, &restore, nil, v1.RestorePhaseFailed,
+				fmt.Sprintf("QuayRegistry %s not found", nsn.Name), log,
+			)
+		}
+		log.Error(err, "unable to retrieve QuayRegistry")
+		return r.Requeue, nil
+	}
+
+	switch restore.Status.Phase {
+	case "", v1.RestorePhasePending:
+		return r.start(ctx, &restore, &quay, log)
+	case v1.RestorePhaseScalingDown:
+		return r.scaleDown(ctx, &restore, &quay, log)
+	case v1.RestorePhaseRestoring:
+		return r.checkRestoreJob(ctx, &restore, &quay, log)
+	case v1.RestorePhaseMigrating:
+		return r.checkMigrations(ctx, &restore, &quay, log)
+	}
+	return ctrl.Result{}, nil
+}
+
+// start holds the QuayRegistry for the restore once no other operation is running on it.
+func (r *QuayRegistryRestoreReconciler) start(
+	ctx context.Context,
+	restore *v1.QuayRegistryRestore,
+	quay *v1.QuayRegistry,
+	log logr.Logger,
+) (ctrl.Result, error) {
+	if err := v1.ValidateRestore(quay, restore); err != nil {
+		return r.finish(ctx, restore, nil, v1.RestorePhaseFailed, err.Error(), log)
+	}
+
+	if v1.RestoreRunning(quay) || v1.MigrationsRunning(quay) || v1.PostgresUpgradeRunning(quay) ||
+		v1.UpgradeRollbackRunning(quay) {
+		return r.updatePhase(
+			ctx, restore, v1.RestorePhasePending,
+			fmt.Sprintf("waiting for QuayRegistry %s to finish ongoing operations", quay.GetName()),
+			log,
+		)
+	}
+
+	log.Info("holding QuayRegistry for the restore", "quayregistry", quay.GetName())
+	quay.Status.Conditions = v1.SetCondition(
+		quay.Status.Conditions,
+		v1.Condition{
+			Type:               v1.ConditionComponentsCreated,
+			Status:             metav1.ConditionFalse,
+			Reason:             v1.ConditionReasonRestoreInProgress,
+			Message:            fmt.Sprintf("restoring database from QuayRegistryRestore %s", restore.GetName()),
+			LastUpdateTime:     metav1.Now(),
+			LastTransitionTime: metav1.Now(),
+		},
+	)
+	if err := r.Status().Update(ctx, quay); err != nil {
+		log.Error(err, "unable to hold QuayRegistry for the restore")
+		return r.Requeue, nil
+	}
+
+	now := metav1.Now()
+	restore.Status.StartTime = &now
+	return r.updatePhase(
+		ctx, restore, v1.RestorePhaseScalingDown, "scaling down registry deployments", log,
+	)
+}
+
+// scaleDown scales down the deployments connected to the database, recording their replicas
+// first so they can be scaled back up once the restore finishes.
+func (r *QuayRegistryRestoreReconciler) scaleDown(
+	ctx context.Context,
+	restore *v1.QuayRegistryRestore,
+	quay *v1.QuayRegistry,
+	log logr.Logger,
+) (ctrl.Result, error) {
+	var deployments []*appsv1.Deployment
+	recorded := false
+	for _, suffix := range restoreScaledDeployments {
+		var dep appsv1.Deployment
+		nsn := types.NamespacedName{
+			Namespace: quay.GetNamespace(),
+			Name:      fmt.Sprintf("%s-%s", quay.GetName(), suffix),
+		}
+		if err := r.Get(ctx, nsn, &dep); err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			log.Error(err, "unable to retrieve deployment", "deployment", nsn.Name)
+			return r.Requeue, nil
+		}
+		deployments = append(deployments, &dep)
+
+		replicas := ptr.Deref(dep.Spec.Replicas, 1)
+		if replicas == 0 || scaledDeploymentFor(restore, dep.GetName()) != nil {
+			continue
+		}
+		restore.Status.ScaledDown = append(
+			restore.Status.ScaledDown,
+			v1.ScaledDeployment{Name: dep.GetName(), Replicas: replicas},
+		)
+		recorded = true
+	}
+
+	// replicas are persisted before scaling down, otherwise they would be lost if we fail
+	// to update the status afterwards.
+	if recorded {
+		if err := r.Status().Update(ctx, restore); err != nil {
+			log.Error(err, "unable to record deployment replicas")
+		}
+		return r.Requeue, nil
+	}
+
+	running := false
+	for _, dep := range deployments {
+		if ptr.Deref(dep.Spec.Replicas, 1) != 0 {
+			log.Info("scaling down deployment", "deployment", dep.GetName())
+			dep.Spec.Replicas = ptr.To[int32](0)
+			if err := r.Update(ctx, dep); err != nil {
+				log.Error(err, "unable to scale down deployment", "deployment", dep.GetName())
+				return r.Requeue, nil
+			}
+		}
+		if dep.Status.Replicas > 0 {
+			running = true
+		}
+	}
+
+	if running {
+		return r.updatePhase(
+			ctx, restore, v1.RestorePhaseScalingDown,
+			"waiting for registry deployments to scale down", log,
+		)
+	}
+
+	var database appsv1.Deployment
+	nsn := types.NamespacedName{
+		Namespace: quay.GetNamespace(),
+		Name:      fmt.Sprintf("%s-quay-database", quay.GetName()),
+	}
+	if err := r.Get(ctx, nsn, &database); err != nil {
+		if errors.IsNotFound(err) {
+			return r.finish(
+				ctx, restore, quay, v1.RestorePhaseFailed,
+				fmt.Sprintf("database deployment %s not found", nsn.Name), log,
+			)
+		}
+		log.Error(err, "unable to retrieve database deployment")
+		return r.Requeue, nil
+	}
+
+	if len(database.Spec.Template.Spec.Containers) == 0 {
+		return r.finish(
+			ctx, restore, quay, v1.RestorePhaseFailed,
+			fmt.Sprintf("database deployment %s has no containers", nsn.Name), log,
+		)
+	}
+	image := database.Spec.Template.Spec.Containers[0].Image
+
+	job := restoreJobFor(quay, restore, image)
+	if err := controllerutil.SetControllerReference(restore, job, r.Scheme); err != nil {
+		log.Error(err, "unable to set restore job owner")
+		return r.Requeue, nil
+	}
+
+	log.Info("creating restore job", "job", job.GetName())
+	if err := r.Create(ctx, job); err != nil && !errors.IsAlreadyExists(err) {
+		log.Error(err, "unable to create restore job")
+		return r.Requeue, nil
+	}
+
+	return r.updatePhase(
+		ctx, restore, v1.RestorePhaseRestoring,
+		fmt.Sprintf("restoring %s", restore.Spec.Backup.Name), log,
+	)
+}
+
+// checkRestoreJob waits for the restore job. Once it succeeds the QuayRegistry is released
+// with its current version reset so the QuayRegistry reconciler runs the upgrade job again.
+func (r *QuayRegistryRestoreReconciler) checkRestoreJob(
+	ctx context.Context,
+	restore *v1.QuayRegistryRestore,
+	quay *v1.QuayRegistry,
+	log logr.Logger,
+) (ctrl.Result, error) {
+	var job batchv1.Job
+	nsn := types.NamespacedName{
+		Namespace: restore.GetNamespace(),
+		Name:      v1.RestoreJobNameFor(restore),
+	}
+	if err := r.Get(ctx, nsn, &job); err != nil {
+		if errors.IsNotFound(err) {
+			return r.finish(
+				ctx, restore, quay, v1.RestorePhaseFailed,
+				fmt.Sprintf("restore job %s not found", nsn.Name), log,
+			)
+		}
+		log.Error(err, "unable to retrieve restore job")
+		return r.Requeue, nil
+	}
+
+	for _, cond := range job.Status.Conditions {
+		if cond.Type == batchv1.JobFailed && cond.Status == corev1.ConditionTrue {
+			return r.finish(
+				ctx, restore, quay, v1.RestorePhaseFailed,
+				fmt.Sprintf("restore job failed: %s", cond.Message), log,
+			)
+		}
+	}
+
+	if job.Status.Succeeded == 0 {
+		return r.Requeue, nil
+	}
+
+	log.Info("database restored, running migrations", "quayregistry", quay.GetName())
+	quay.Status.CurrentVersion = ""
+	quay.Status.Conditions = v1.RemoveCondition(
+		quay.Status.Conditions, v1.ConditionComponentsCreated,
+	)
+	if err := r.Status().Update(ctx, quay); err != nil {
+		log.Error(err, "unable to release QuayRegistry for migrations")
+		return r.Requeue, nil
+	}
+
+	return r.updatePhase(
+		ctx, restore, v1.RestorePhaseMigrating, "running database migrations", log,
+	)
+}
+
+// checkMigrations waits for the QuayRegistry reconciler to run the upgrade job against the
+// restored database.
+func (r *QuayRegistryRestoreReconciler) checkMigrations(
+	ctx context.Context,
+	restore *v1.QuayRegistryRestore,
+	quay *v1.QuayRegistry,
+	log logr.Logger,
+) (ctrl.Result, error) {
+	if quay.Status.CurrentVersion == v1.QuayVersionCurrent {
+		return r.finish(
+			ctx, restore, quay, v1.RestorePhaseCompleted,
+			fmt.Sprintf("database restored from %s", restore.Spec.Backup.Name), log,
+		)
+	}
+
+	created := v1.GetCondition(quay.Status.Conditions, v1.ConditionComponentsCreated)
+	if created != nil && created.Reason == v1.ConditionReasonMigrationsFailed {
+		return r.finish(
+			ctx, restore, quay, v1.RestorePhaseFailed,
+			fmt.Sprintf("database migrations failed: %s", created.Message), log,
+		)
+	}
+	return r.Requeue, nil
+}
+
+// finish scales back up the deployments scaled down for the restore, releases the
+// QuayRegistry if still held and records the outcome of the restore.
+func (r *QuayRegistryRestoreReconciler) finish(
+	ctx context.Context,
+	restore *v1.QuayRegistryRestore,
+	quay *v1.QuayRegistry,
+	phase v1.RestorePhase,
+	msg string,
+	log logr.Logger,
+) (ctrl.Result, error) {
+	if quay != nil {
+		for _, scaled := range restore.Status.ScaledDown {
+			var dep appsv1.Deployment
+			nsn := types.NamespacedName{Namespace: quay.GetNamespace(), Name: scaled.Name}
+			if err := r.Get(ctx, nsn, &dep); err != nil {
+				if errors.IsNotFound(err) {
+					continue
+				}
+				log.Error(err, "unable to retrieve deployment", "deployment", scaled.Name)
+				return r.Requeue, nil
+			}
+
+			// the QuayRegistry reconciler may have scaled it up already.
+			if ptr.Deref(dep.Spec.Replicas, 1) != 0 {
+				continue
+			}
+
+			log.Info("scaling up deployment", "deployment", scaled.Name)
+			dep.Spec.Replicas = ptr.To(scaled.Replicas)
+			if err := r.Update(ctx, &dep); err != nil {
+				log.Error(err, "unable to scale up deployment", "deployment", scaled.Name)
+				return r.Requeue, nil
+			}
+		}
+
+		if heldBy(quay, restore) {
+			quay.Status.Conditions = v1.RemoveCondition(
+				quay.Status.Conditions, v1.ConditionComponentsCreated,
+			)
+			if err := r.Status().Update(ctx, quay); err != nil {
+				log.Error(err, "unable to release QuayRegistry")
+				return r.Requeue, nil
+			}
+		}
+	}
+
+	now := metav1.Now()
+	restore.Status.CompletionTime = &now
+	if _, err := r.updatePhase(ctx, restore, phase, msg, log); err != nil {
+		return r.Requeue, err
+	}
+	return ctrl.Result{}, nil
+}
+
+// updatePhase records the phase and message of the provided QuayRegistryRestore.
+func (r *QuayRegistryRestoreReconciler) updatePhase(
+	ctx context.Context,
+	restore *v1.QuayRegistryRestore,
+	phase v1.RestorePhase,
+	msg string,
+	log logr.Logger,
+) (ctrl.Result, error) {
+	restore.Status.Phase = phase
+	restore.Status.Message = msg
+	if err := r.Status().Update(ctx, restore); err != nil {
+		log.Error(err, "unable to update QuayRegistryRestore status")
+	}
+	return r.Requeue, nil
+}
+
+// SetupWithManager initializes the controller manager.
+func (r *QuayRegistryRestoreReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1.QuayRegistryRestore{}).
+		Owns(&batchv1.Job{}).
+		Complete(r)
+}
+
+// heldBy returns true if the provided QuayRegistry is held by the provided restore.
+func heldBy(quay *v1.QuayRegistry, restore *v1.QuayRegistryRestore) bool {
+	if !v1.RestoreRunning(quay) {
+		return false
+	}
+	created := v1.GetCondition(quay.Status.Conditions, v1.ConditionComponentsCreated)
+	return created.Message == fmt.Sprintf(
+		"restoring database from QuayRegistryRestore %s", restore.GetName(),
+	)
+}
+
+// scaledDeploymentFor returns the replicas recorded for the named deployment, if any.
+func scaledDeploymentFor(restore *v1.QuayRegistryRestore, name string) *v1.ScaledDeployment {
+	for i := range restore.Status.ScaledDown {
+		if restore.Status.ScaledDown[i].Name == name {
+			return &restore.Status.ScaledDown[i]
+		}
+	}
+	return nil
+}
+
+// restoreJobFor returns the Job restoring the dump referenced by the provided restore into
+// the managed database of the provided QuayRegistry, using the image of the database.
+func restoreJobFor(
+	quay *v1.QuayRegistry, restore *v1.QuayRegistryRestore, image string,
+) *batchv1.Job {
+	destination := v1.RestoreDestinationFor(restore)
+
+	env := []corev1.EnvVar{
+		{Name: "BACKUP_FILE", Value: restore.Spec.Backup.Name},
+		{Name: "BACKUP_DESTINATION", Value: string(destination)},
+		{
+			Name: "DATABASE_URI",
+			ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{
+						Name: fmt.Sprintf(
+							"%s-quay-registry-managed-secret-keys", quay.GetName(),
+						),
+					},
+					Key: "DB_URI",
+				},
+			},
+		},
+	}
+
+	var envFrom []corev1.EnvFromSource
+	backups := corev1.VolumeSource{
+		PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+			ClaimName: v1.RestoreVolumeFor(quay, restore),
+			ReadOnly:  true,
+		},
+	}
+	if destination == v1.BackupDestinationObjectStorage {
+		datastore := corev1.LocalObjectReference{
+			Name: fmt.Sprintf("%s-quay-datastore", quay.GetName()),
+		}
+		envFrom = []corev1.EnvFromSource{
+			{ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: datastore}},
+			{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: datastore}},
+		}
+		backups = corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}
+	}
+
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      v1.RestoreJobNameFor(restore),
+			Namespace: restore.GetNamespace(),
+			Labels: map[string]string{
+				"quay-operator/quayregistry":        quay.GetName(),
+				"quay-operator/quayregistryrestore": restore.GetName(),
+			},
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: ptr.To[int32](1),
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyNever,
+					ImagePullSecrets: v1.GetImagePullSecretsOverrideForComponent(
+						quay, v1.ComponentPostgres,
+					),
+					Containers: []corev1.Container{
+						{
+							Name:    "pg-restore",
+							Image:   image,
+							Command: []string{"/bin/bash", "-c", restoreScript},
+							Env:     env,
+							EnvFrom: envFrom,
+							VolumeMounts: []corev1.VolumeMount{
+								{Name: "backups", MountPath: "/backups"},
+								{
+									Name:      "backup-ca",
+									MountPath: "/run/secrets/backup-ca",
+									ReadOnly:  true,
+								},
+								{
+									Name:      "postgresql-ca",
+									MountPath: "/run/secrets/postgresql",
+									ReadOnly:  true,
+								},
+							},
+						},
+					},
+					Volumes: []corev1.Volume{
+						{Name: "backups", VolumeSource: backups},
+						{
+							Name: "backup-ca",
+							VolumeSource: corev1.VolumeSource{
+								ConfigMap: &corev1.ConfigMapVolumeSource{
+									LocalObjectReference: corev1.LocalObjectReference{
+										Name: fmt.Sprintf(
+											"%s-cluster-service-ca", quay.GetName(),
+										),
+									},
+									Optional: ptr.To(true),
+								},
+							},
+						},
+						{
+							Name: "postgresql-ca",
+							VolumeSource: corev1.VolumeSource{
+								Secret: &corev1.SecretVolumeSource{
+									SecretName: fmt.Sprintf(
+										"%s-postgresql-ca", quay.GetName(),
+									),
+									Optional: ptr.To(true),
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,435 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	v1 "github.com/quay/quay-operator/apis/quay/v1"
+)
+
+func newRestoreTestReconciler(t *testing.T, objs ...client.Object) *QuayRegistryRestoreReconciler {
+	t.Helper()
+
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := v1.AddToScheme(scheme); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	cli := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(objs...).
+		WithStatusSubresource(&v1.QuayRegistry{}, &v1.QuayRegistryRestore{}).
+		Build()
+	return &QuayRegistryRestoreReconciler{
+		Client: cli,
+		Log:    logr.Discard(),
+		Scheme: scheme,
+	}
+}
+
+func newRestoreTestDeployment(name string, replicas int32) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ns"},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: ptr.To(replicas),
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Name: name, Image: "quay.io/sclorg/postgresql-13-c9s"},
+					},
+				},
+			},
+		},
+		Status: appsv1.DeploymentStatus{Replicas: replicas},
+	}
+}
+
+func TestQuayRegistryRestoreReconcile(t *testing.T) {
+	ctx := context.Background()
+
+	// the current version is read from the environment, which tests do not set.
+	version := v1.QuayVersionCurrent
+	v1.QuayVersionCurrent = "v3.14.0"
+	defer func() { v1.QuayVersionCurrent = version }()
+
+	quay := &v1.QuayRegistry{
+		ObjectMeta: metav1.ObjectMeta{Name: "registry", Namespace: "ns", UID: "uid"},
+		Spec: v1.QuayRegistrySpec{
+			Components: []v1.Component{
+				{Kind: v1.ComponentPostgres, Managed: true},
+			},
+		},
+		Status: v1.QuayRegistryStatus{CurrentVersion: v1.QuayVersionCurrent},
+	}
+	restore := &v1.QuayRegistryRestore{
+		ObjectMeta: metav1.ObjectMeta{Name: "restore", Namespace: "ns", UID: "restore-uid"},
+		Spec: v1.QuayRegistryRestoreSpec{
+			QuayRegistry: "registry",
+			Backup:       v1.RestoreBackup{Name: "quay-database-20240501T020000Z.dump"},
+		},
+	}
+
+	r := newRestoreTestReconciler(
+		t,
+		quay,
+		restore,
+		newRestoreTestDeployment("registry-quay-app", 2),
+		newRestoreTestDeployment("registry-clair-app", 1),
+		newRestoreTestDeployment("registry-quay-database", 1),
+	)
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "ns", Name: "restore"}}
+	registry := types.NamespacedName{Namespace: "ns", Name: "registry"}
+
+	reconcile := func(expected v1.RestorePhase) *v1.QuayRegistryRestore {
+		t.Helper()
+		if _, err := r.Reconcile(ctx, req); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		var current v1.QuayRegistryRestore
+		if err := r.Get(ctx, req.NamespacedName, &current); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if current.Status.Phase != expected {
+			t.Fatalf(
+				"expected phase %s, received %s: %s",
+				expected, current.Status.Phase, current.Status.Message,
+			)
+		}
+		return &current
+	}
+
+	replicasOf := func(name string) int32 {
+		t.Helper()
+		var dep appsv1.Deployment
+		nsn := types.NamespacedName{Namespace: "ns", Name: name}
+		if err := r.Get(ctx, nsn, &dep); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		return *dep.Spec.Replicas
+	}
+
+	current := reconcile(v1.RestorePhaseScalingDown)
+	if current.Status.StartTime == nil {
+		t.Errorf("expected start time to be set")
+	}
+
+	var reg v1.QuayRegistry
+	if err := r.Get(ctx, registry, &reg); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !v1.RestoreRunning(&reg) {
+		t.Fatalf("expected QuayRegistry to be held by the restore")
+	}
+
+	// replicas are recorded first, deployments are scaled down afterwards.
+	current = reconcile(v1.RestorePhaseScalingDown)
+	expected := []v1.ScaledDeployment{
+		{Name: "registry-quay-app", Replicas: 2},
+		{Name: "registry-clair-app", Replicas: 1},
+	}
+	if len(current.Status.ScaledDown) != len(expected) {
+		t.Fatalf("expected %+v, received %+v", expected, current.Status.ScaledDown)
+	}
+	for i := range expected {
+		if current.Status.ScaledDown[i] != expected[i] {
+			t.Errorf("expected %+v, received %+v", expected[i], current.Status.ScaledDown[i])
+		}
+	}
+
+	reconcile(v1.RestorePhaseScalingDown)
+	for _, name := range []string{"registry-quay-app", "registry-clair-app"} {
+		if replicas := replicasOf(name); replicas != 0 {
+			t.Errorf("expected %s to be scaled down, has %d replicas", name, replicas)
+		}
+
+		var dep appsv1.Deployment
+		nsn := types.NamespacedName{Namespace: "ns", Name: name}
+		if err := r.Get(ctx, nsn, &dep); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		dep.Status.Replicas = 0
+		if err := r.Status().Update(ctx, &dep); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	reconcile(v1.RestorePhaseRestoring)
+
+	var job batchv1.Job
+	jobnsn := types.NamespacedName{Namespace: "ns", Name: "restore-pg-restore"}
+	if err := r.Get(ctx, jobnsn, &job); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !metav1.IsControlledBy(&job, current) {
+		t.Errorf("expected restore job to be owned by the restore")
+	}
+	if image := job.Spec.Template.Spec.Containers[0].Image; image != "quay.io/sclorg/postgresql-13-c9s" {
+		t.Errorf("expected restore job to use the database image, received %s", image)
+	}
+
+	reconcile(v1.RestorePhaseRestoring)
+
+	job.Status.Succeeded = 1
+	if err := r.Status().Update(ctx, &job); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	reconcile(v1.RestorePhaseMigrating)
+	if err := r.Get(ctx, registry, &reg); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if v1.RestoreRunning(&reg) {
+		t.Errorf("expected QuayRegistry to be released for migrations")
+	}
+	if reg.Status.CurrentVersion != "" {
+		t.Errorf("expected current version to be reset, received %s", reg.Status.CurrentVersion)
+	}
+
+	reconcile(v1.RestorePhaseMigrating)
+
+	reg.Status.CurrentVersion = v1.QuayVersionCurrent
+	if err := r.Status().Update(ctx, &reg); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	current = reconcile(v1.RestorePhaseCompleted)
+	if current.Status.CompletionTime == nil {
+		t.Errorf("expected completion time to be set")
+	}
+	if replicas := replicasOf("registry-quay-app"); replicas != 2 {
+		t.Errorf("expected registry-quay-app to be scaled up to 2, has %d replicas", replicas)
+	}
+	if replicas := replicasOf("registry-clair-app"); replicas != 1 {
+		t.Errorf("expected registry-clair-app to be scaled up to 1, has %d replicas", replicas)
+	}
+}
+
+func TestQuayRegistryRestoreReconcileFailure(t *testing.T) {
+	ctx := context.Background()
+
+	// the current version is read from the environment, which tests do not set.
+	version := v1.QuayVersionCurrent
+	v1.QuayVersionCurrent = "v3.14.0"
+	defer func() { v1.QuayVersionCurrent = version }()
+
+	for _, tt := range []struct {
+		name    string
+		quay    *v1.QuayRegistry
+		restore *v1.QuayRegistryRestore
+		objs    []client.Object
+		phase   v1.RestorePhase
+		msg     string
+		held    bool
+	}{
+		{
+			name: "registry not found",
+			restore: &v1.QuayRegistryRestore{
+				ObjectMeta: metav1.ObjectMeta{Name: "restore", Namespace: "ns"},
+				Spec: v1.QuayRegistryRestoreSpec{
+					QuayRegistry: "registry",
+					Backup:       v1.RestoreBackup{Name: "backup.dump"},
+				},
+			},
+			phase: v1.RestorePhaseFailed,
+			msg:   "QuayRegistry registry not found",
+		},
+		{
+			name: "unmanaged postgres",
+			quay: &v1.QuayRegistry{
+				ObjectMeta: metav1.ObjectMeta{Name: "registry", Namespace: "ns"},
+				Spec: v1.QuayRegistrySpec{
+					Components: []v1.Component{
+						{Kind: v1.ComponentPostgres, Managed: false},
+					},
+				},
+			},
+			restore: &v1.QuayRegistryRestore{
+				ObjectMeta: metav1.ObjectMeta{Name: "restore", Namespace: "ns"},
+				Spec: v1.QuayRegistryRestoreSpec{
+					QuayRegistry: "registry",
+					Backup:       v1.RestoreBackup{Name: "backup.dump"},
+				},
+			},
+			phase: v1.RestorePhaseFailed,
+			msg:   "QuayRegistry registry does not have a managed postgres",
+		},
+		{
+			name: "migrations running",
+			quay: &v1.QuayRegistry{
+				ObjectMeta: metav1.ObjectMeta{Name: "registry", Namespace: "ns"},
+				Spec: v1.QuayRegistrySpec{
+					Components: []v1.Component{
+						{Kind: v1.ComponentPostgres, Managed: true},
+					},
+				},
+				Status: v1.QuayRegistryStatus{
+					Conditions: []v1.Condition{
+						{
+							Type:   v1.ConditionComponentsCreated,
+							Status: metav1.ConditionFalse,
+							Reason: v1.ConditionReasonMigrationsInProgress,
+						},
+					},
+				},
+			},
+			restore: &v1.QuayRegistryRestore{
+				ObjectMeta: metav1.ObjectMeta{Name: "restore", Namespace: "ns"},
+				Spec: v1.QuayRegistryRestoreSpec{
+					QuayRegistry: "registry",
+					Backup:       v1.RestoreBackup{Name: "backup.dump"},
+				},
+			},
+			phase: v1.RestorePhasePending,
+			msg:   "waiting for QuayRegistry registry to finish ongoing operations",
+		},
+		{
+			name: "restore job failed",
+			quay: &v1.QuayRegistry{
+				ObjectMeta: metav1.ObjectMeta{Name: "registry", Namespace: "ns"},
+				Spec: v1.QuayRegistrySpec{
+					Components: []v1.Component{
+						{Kind: v1.ComponentPostgres, Managed: true},
+					},
+				},
+				Status: v1.QuayRegistryStatus{
+					Conditions: []v1.Condition{
+						{
+							Type:    v1.ConditionComponentsCreated,
+							Status:  metav1.ConditionFalse,
+							Reason:  v1.ConditionReasonRestoreInProgress,
+							Message: "restoring database from QuayRegistryRestore restore",
+						},
+					},
+				},
+			},
+			restore: &v1.QuayRegistryRestore{
+				ObjectMeta: metav1.ObjectMeta{Name: "restore", Namespace: "ns"},
+				Spec: v1.QuayRegistryRestoreSpec{
+					QuayRegistry: "registry",
+					Backup:       v1.RestoreBackup{Name: "backup.dump"},
+				},
+				Status: v1.QuayRegistryRestoreStatus{
+					Phase: v1.RestorePhaseRestoring,
+					ScaledDown: []v1.ScaledDeployment{
+						{Name: "registry-quay-app", Replicas: 2},
+					},
+				},
+			},
+			objs: []client.Object{
+				newRestoreTestDeployment("registry-quay-app", 0),
+				&batchv1.Job{
+					ObjectMeta: metav1.ObjectMeta{Name: "restore-pg-restore", Namespace: "ns"},
+					Status: batchv1.JobStatus{
+						Conditions: []batchv1.JobCondition{
+							{
+								Type:    batchv1.JobFailed,
+								Status:  corev1.ConditionTrue,
+								Message: "Job has reached the specified backoff limit",
+							},
+						},
+					},
+				},
+			},
+			phase: v1.RestorePhaseFailed,
+			msg:   "restore job failed: Job has reached the specified backoff limit",
+		},
+		{
+			name: "migrations failed",
+			quay: &v1.QuayRegistry{
+				ObjectMeta: metav1.ObjectMeta{Name: "registry", Namespace: "ns"},
+				Spec: v1.QuayRegistrySpec{
+					Components: []v1.Component{
+						{Kind: v1.ComponentPostgres, Managed: true},
+					},
+				},
+				Status: v1.QuayRegistryStatus{
+					Conditions: []v1.Condition{
+						{
+							Type:    v1.ConditionComponentsCreated,
+							Status:  metav1.ConditionFalse,
+							Reason:  v1.ConditionReasonMigrationsFailed,
+							Message: "alembic failed",
+						},
+					},
+				},
+			},
+			restore: &v1.QuayRegistryRestore{
+				ObjectMeta: metav1.ObjectMeta{Name: "restore", Namespace: "ns"},
+				Spec: v1.QuayRegistryRestoreSpec{
+					QuayRegistry: "registry",
+					Backup:       v1.RestoreBackup{Name: "backup.dump"},
+				},
+				Status: v1.QuayRegistryRestoreStatus{Phase: v1.RestorePhaseMigrating},
+			},
+			phase: v1.RestorePhaseFailed,
+			msg:   "database migrations failed: alembic failed",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			objs := append([]client.Object{tt.restore}, tt.objs...)
+			if tt.quay != nil {
+				objs = append(objs, tt.quay)
+			}
+			r := newRestoreTestReconciler(t, objs...)
+
+			req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "ns", Name: "restore"}}
+			if _, err := r.Reconcile(ctx, req); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			var current v1.QuayRegistryRestore
+			if err := r.Get(ctx, req.NamespacedName, &current); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if current.Status.Phase != tt.phase {
+				t.Errorf("expected phase %s, received %s", tt.phase, current.Status.Phase)
+			}
+			if current.Status.Message != tt.msg {
+				t.Errorf("expected message %q, received %q", tt.msg, current.Status.Message)
+			}
+
+			if tt.quay == nil {
+				return
+			}
+
+			var reg v1.QuayRegistry
+			nsn := types.NamespacedName{Namespace: "ns", Name: "registry"}
+			if err := r.Get(ctx, nsn, &reg); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if v1.RestoreRunning(&reg) != tt.held {
+				t.Errorf("expected QuayRegistry held to be %v", tt.held)
+			}
+
+			for _, scaled := range current.Status.ScaledDown {
+				var dep appsv1.Deployment
+				nsn := types.NamespacedName{Namespace: "ns", Name: scaled.Name}
+				if err := r.Get(ctx, nsn, &dep); err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+				if *dep.Spec.Replicas != scaled.Replicas {
+					t.Errorf(
+						"expected %s to be scaled back to %d, has %d replicas",
+						scaled.Name, scaled.Replicas, *dep.Spec.Replicas,
+					)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,318 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	v1 "github.com/quay/quay-operator/apis/quay/v1"
+)
+
+// checkUpgradeRollbackPoint dumps the managed database of the provided QuayRegistry before the
+// migrations of its upgrade run, and records the image of the version of Quay it runs so the
+// upgrade can be rolled back to it. Returns true once the dump has been written and recorded.
+func (r *QuayRegistryReconciler) checkUpgradeRollbackPoint(
+	ctx context.Context, quay *v1.QuayRegistry,
+) (bool, error) {
+	name := v1.UpgradeRollbackNameFor(quay)
+
+	// the dump of a previous upgrade, or of the rolled back upgrade being retried, is taken
+	// again. its jobs are deleted first so they are not mistaken for the ones of this upgrade.
+	if quay.Status.UpgradeRollback != nil {
+		restore := upgradeRollbackRestoreFor(quay, quay.Status.UpgradeRollback)
+		for _, job := range []string{name, v1.RestoreJobNameFor(restore)} {
+			if err := r.Delete(
+				ctx,
+				&batchv1.Job{
+					ObjectMeta: metav1.ObjectMeta{Name: job, Namespace: quay.GetNamespace()},
+				},
+				client.PropagationPolicy(metav1.DeletePropagationBackground),
+			); err != nil && !errors.IsNotFound(err) {
+				return false, fmt.Errorf("unable to delete %s: %w", job, err)
+			}
+		}
+
+		quay.Status.UpgradeRollback = nil
+		return false, r.updateStatus(ctx, quay)
+	}
+
+	var job batchv1.Job
+	if err := r.Get(ctx, types.NamespacedName{
+		Name:      name,
+		Namespace: quay.GetNamespace(),
+	}, &job); err == nil && job.GetDeletionTimestamp() != nil {
+		return false, nil
+	} else if err != nil && !errors.IsNotFound(err) {
+		return false, err
+	}
+
+	var app appsv1.Deployment
+	if err := r.Get(ctx, types.NamespacedName{
+		Name:      fmt.Sprintf("%s-quay-app", quay.GetName()),
+		Namespace: quay.GetNamespace(),
+	}, &app); err != nil {
+		return false, fmt.Errorf("unable to get quay deployment: %w", err)
+	}
+
+	image := quayImageOf(&app, quay.Status.CurrentVersion)
+	if image == "" {
+		return false, fmt.Errorf(
+			"quay deployment does not run version %s anymore", quay.Status.CurrentVersion,
+		)
+	}
+
+	database, err := r.managedDatabaseDeployment(ctx, quay)
+	if err != nil {
+		return false, err
+	}
+
+	done, err := r.checkPreUpgradeDump(
+		ctx,
+		quay,
+		v1.ComponentPostgres,
+		name,
+		database.Spec.Template.Spec.Containers[0].Image,
+		databaseVolumeOf(database),
+	)
+	if err != nil || !done {
+		return false, err
+	}
+
+	quay.Status.UpgradeRollback = &v1.UpgradeRollbackStatus{
+		Version:       quay.Status.CurrentVersion,
+		Image:         image,
+		TargetVersion: v1.QuayVersionCurrent,
+		Backup:        name,
+		Phase:         v1.UpgradeRollbackPhaseReady,
+	}
+	return true, r.updateStatus(ctx, quay)
+}
+
+// startUpgradeRollback holds the provided QuayRegistry for the rollback of its upgrade once
+// its migration job failed. The provided message of the failure is recorded in the rollback.
+func (r *QuayRegistryReconciler) startUpgradeRollback(
+	ctx context.Context, quay *v1.QuayRegistry, msg string, log logr.Logger,
+) (ctrl.Result, error) {
+	rollback := v1.UpgradeRollbackFor(quay)
+	rollback.Phase = v1.UpgradeRollbackPhaseRollingBack
+	rollback.Message = msg
+	rollback.CompletionTime = nil
+
+	log.Info("database migrations failed, rolling back upgrade", "version", rollback.Version)
+	if err := r.updateWithCondition(
+		ctx,
+		quay,
+		v1.ConditionComponentsCreated,
+		metav1.ConditionFalse,
+		v1.ConditionReasonUpgradeRollingBack,
+		fmt.Sprintf(
+			"migrations to quay %s failed, restoring %s", rollback.TargetVersion, rollback.Backup,
+		),
+	); err != nil {
+		log.Error(err, "failed to update `conditions` of `QuayRegistry`")
+	}
+	return r.Requeue, nil
+}
+
+// retryUpgradeRollback restores the database of the provided QuayRegistry again once the user
+// asked for its failed rollback to be retried. The registry is held until then.
+func (r *QuayRegistryReconciler) retryUpgradeRollback(
+	ctx context.Context, quay *v1.QuayRegistry, log logr.Logger,
+) (ctrl.Result, error) {
+	if !v1.UpgradeRollbackRetried(quay) {
+		log.Info("upgrade rollback failed, waiting for it to be retried")
+		return ctrl.Result{RequeueAfter: time.Minute}, nil
+	}
+
+	rollback := v1.UpgradeRollbackFor(quay)
+	restore := upgradeRollbackRestoreFor(quay, rollback)
+	if err := r.Delete(
+		ctx,
+		&batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      v1.RestoreJobNameFor(restore),
+				Namespace: quay.GetNamespace(),
+			},
+		},
+		client.PropagationPolicy(metav1.DeletePropagationBackground),
+	); err != nil && !errors.IsNotFound(err) {
+		log.Error(err, "unable to delete upgrade rollback job")
+		return r.Requeue, nil
+	}
+
+	log.Info("retrying upgrade rollback", "version", rollback.Version)
+	rollback.Phase = v1.UpgradeRollbackPhaseRollingBack
+	rollback.CompletionTime = nil
+	if err := r.updateWithCondition(
+		ctx,
+		quay,
+		v1.ConditionComponentsCreated,
+		metav1.ConditionFalse,
+		v1.ConditionReasonUpgradeRollingBack,
+		fmt.Sprintf("retrying the restore of %s", rollback.Backup),
+	); err != nil {
+		log.Error(err, "failed to update `conditions` of `QuayRegistry`")
+	}
+	return r.Requeue, nil
+}
+
+// checkUpgradeRollback restores the dump taken before the migrations of the failed upgrade of
+// the provided QuayRegistry through a Job. Once restored the registry is released and runs the
+// previous version of Quay again.
+func (r *QuayRegistryReconciler) checkUpgradeRollback(
+	ctx context.Context, quay *v1.QuayRegistry, log logr.Logger,
+) (ctrl.Result, error) {
+	rollback := v1.UpgradeRollbackFor(quay)
+	if rollback == nil {
+		if err := r.updateWithCondition(
+			ctx,
+			quay,
+			v1.ConditionComponentsCreated,
+			metav1.ConditionFalse,
+			v1.ConditionReasonUpgradeRollbackFailed,
+			"upgrade rollback point not found",
+		); err != nil {
+			log.Error(err, "failed to update `conditions` of `QuayRegistry`")
+		}
+		return r.Requeue, nil
+	}
+
+	restore := upgradeRollbackRestoreFor(quay, rollback)
+
+	var job batchv1.Job
+	if err := r.Get(ctx, types.NamespacedName{
+		Name:      v1.RestoreJobNameFor(restore),
+		Namespace: quay.GetNamespace(),
+	}, &job); err != nil {
+		if !errors.IsNotFound(err) {
+			log.Error(err, "unable to retrieve upgrade rollback job")
+			return r.Requeue, nil
+		}
+
+		database, err := r.managedDatabaseDeployment(ctx, quay)
+		if err != nil {
+			return r.finishUpgradeRollback(
+				ctx, quay, v1.UpgradeRollbackPhaseFailed,
+				v1.ConditionReasonUpgradeRollbackFailed,
+				fmt.Sprintf("unable to restore %s: %s", rollback.Backup, err), log,
+			)
+		}
+
+		obj := v1.EnsureOwnerReference(
+			quay, restoreJobFor(quay, restore, database.Spec.Template.Spec.Containers[0].Image),
+		)
+		if err := r.Create(ctx, obj); err != nil {
+			log.Error(err, "unable to create upgrade rollback job")
+		}
+		return r.Requeue, nil
+	}
+
+	// the job of a previous attempt is still being deleted.
+	if job.GetDeletionTimestamp() != nil {
+		return r.Requeue, nil
+	}
+
+	for _, cond := range job.Status.Conditions {
+		if cond.Type == batchv1.JobFailed && cond.Status == corev1.ConditionTrue {
+			return r.finishUpgradeRollback(
+				ctx, quay, v1.UpgradeRollbackPhaseFailed,
+				v1.ConditionReasonUpgradeRollbackFailed,
+				fmt.Sprintf("unable to restore %s: %s", rollback.Backup, cond.Message), log,
+			)
+		}
+	}
+
+	if job.Status.Succeeded == 0 {
+		log.Info("upgrade rollback running, requeueing reconcile...")
+		return r.Requeue, nil
+	}
+
+	log.Info("database restored, running the previous version of quay", "version", rollback.Version)
+	return r.finishUpgradeRollback(
+		ctx, quay, v1.UpgradeRollbackPhaseRolledBack,
+		v1.ConditionReasonUpgradeRolledBack, upgradeRolledBackMessage(rollback), log,
+	)
+}
+
+// finishUpgradeRollback records the outcome of the rollback of the upgrade of the provided
+// QuayRegistry.
+func (r *QuayRegistryReconciler) finishUpgradeRollback(
+	ctx context.Context,
+	quay *v1.QuayRegistry,
+	phase v1.UpgradeRollbackPhase,
+	reason v1.ConditionReason,
+	msg string,
+	log logr.Logger,
+) (ctrl.Result, error) {
+	rollback := v1.UpgradeRollbackFor(quay)
+	rollback.Phase = phase
+	rollback.CompletionTime = ptr.To(metav1.Now())
+	rollback.Generation = quay.GetGeneration()
+
+	if err := r.updateWithCondition(
+		ctx, quay, v1.ConditionComponentsCreated, metav1.ConditionFalse, reason, msg,
+	); err != nil {
+		log.Error(err, "failed to update `conditions` of `QuayRegistry`")
+	}
+	return r.Requeue, nil
+}
+
+// managedDatabaseDeployment returns the deployment of the managed database of the provided
+// QuayRegistry.
+func (r *QuayRegistryReconciler) managedDatabaseDeployment(
+	ctx context.Context, quay *v1.QuayRegistry,
+) (*appsv1.Deployment, error) {
+	var dep appsv1.Deployment
+	if err := r.Get(ctx, types.NamespacedName{
+		Name:      fmt.Sprintf("%s-quay-database", quay.GetName()),
+		Namespace: quay.GetNamespace(),
+	}, &dep); err != nil {
+		return nil, fmt.Errorf("unable to get managed database: %w", err)
+	}
+
+	if len(dep.Spec.Template.Spec.Containers) == 0 {
+		return nil, fmt.Errorf("managed database deployment has no containers")
+	}
+	return &dep, nil
+}
+
+// upgradeRollbackRestoreFor returns the restore of the dump the provided rollback point was
+// taken into. It is never created, the rollback restores the dump the same way a
+// QuayRegistryRestore does.
+func upgradeRollbackRestoreFor(
+	quay *v1.QuayRegistry, rollback *v1.UpgradeRollbackStatus,
+) *v1.QuayRegistryRestore {
+	return &v1.QuayRegistryRestore{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      v1.UpgradeRollbackNameFor(quay),
+			Namespace: quay.GetNamespace(),
+		},
+		Spec: v1.QuayRegistryRestoreSpec{
+			QuayRegistry: quay.GetName(),
+			Backup: v1.RestoreBackup{
+				Name:                  preUpgradeDumpFile,
+				Destination:           v1.BackupDestinationPersistentVolume,
+				PersistentVolumeClaim: rollback.Backup,
+			},
+		},
+	}
+}
+
+// upgradeRolledBackMessage returns the message the registry is marked as rolled back with.
+func upgradeRolledBackMessage(rollback *v1.UpgradeRollbackStatus) string {
+	return fmt.Sprintf(
+		"upgrade to quay %s rolled back to %s, set spec.upgradeRollback.retryVersion to retry: %s",
+		rollback.TargetVersion, rollback.Version, rollback.Message,
+	)
+}
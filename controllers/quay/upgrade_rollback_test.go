@@ -0,0 +1,312 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+
+	v1 "github.com/quay/quay-operator/apis/quay/v1"
+	quaycontext "github.com/quay/quay-operator/pkg/context"
+)
+
+func newUpgradeRollbackTestObjects(t *testing.T) (*v1.QuayRegistry, *appsv1.Deployment, *appsv1.Deployment, *corev1.PersistentVolumeClaim) {
+	t.Helper()
+
+	current := v1.QuayVersionCurrent
+	v1.QuayVersionCurrent = "3.12.0"
+	t.Cleanup(func() { v1.QuayVersionCurrent = current })
+
+	quay, database, pvc := newPreUpgradeTestObjects(v1.PreUpgradeBackupNone)
+	quay.Spec.Components = []v1.Component{{Kind: v1.ComponentPostgres, Managed: true}}
+	quay.Spec.UpgradeRollback = &v1.UpgradeRollbackPolicy{OnMigrationFailure: true}
+	quay.Status.CurrentVersion = "3.11.0"
+
+	app := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "registry-quay-app", Namespace: "ns"},
+		Spec: appsv1.DeploymentSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name:  "quay-app",
+							Image: "quay.io/projectquay/quay:3.11.0",
+							Env:   []corev1.EnvVar{{Name: "QUAY_VERSION", Value: "3.11.0"}},
+						},
+					},
+				},
+			},
+		},
+	}
+	return quay, app, database, pvc
+}
+
+func TestCheckUpgradeRollbackPoint(t *testing.T) {
+	ctx := context.Background()
+	quay, app, database, pvc := newUpgradeRollbackTestObjects(t)
+	r := newPreUpgradeTestReconciler(t, quay, app, database, pvc)
+	if err := v1.EnsureDefaultComponents(quaycontext.NewQuayRegistryContext(), quay); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	done, err := r.checkUpgradeRollbackPoint(ctx, quay)
+	if err != nil || done {
+		t.Fatalf("expected the dump to be started, received %v, %v", done, err)
+	}
+
+	nsn := types.NamespacedName{Name: "registry-quay-database-rollback", Namespace: "ns"}
+	var job batchv1.Job
+	if err := r.Get(ctx, nsn, &job); err != nil {
+		t.Fatalf("expected dump job to be created: %s", err)
+	}
+	if image := job.Spec.Template.Spec.Containers[0].Image; image != "centos/postgresql-10-centos7:latest" {
+		t.Errorf("expected dump taken with the image of the database, received %s", image)
+	}
+
+	job.Status.Succeeded = 1
+	if err := r.Status().Update(ctx, &job); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	done, err = r.checkUpgradeRollbackPoint(ctx, quay)
+	if err != nil || !done {
+		t.Fatalf("expected the dump to be recorded, received %v, %v", done, err)
+	}
+
+	rollback := quay.Status.UpgradeRollback
+	if rollback == nil {
+		t.Fatal("expected rollback point to be recorded")
+	}
+	if rollback.Image != "quay.io/projectquay/quay:3.11.0" || rollback.Version != "3.11.0" {
+		t.Errorf("expected rollback to the running version of quay, received %+v", rollback)
+	}
+	if rollback.TargetVersion != "3.12.0" || rollback.Phase != v1.UpgradeRollbackPhaseReady {
+		t.Errorf("expected rollback point ready for the upgrade, received %+v", rollback)
+	}
+	if v1.UpgradeRollbackPointNeeded(quay) {
+		t.Error("expected no further rollback point to be needed")
+	}
+	if !v1.ComponentIsManaged(quay.Spec.Components, v1.ComponentRedis) {
+		t.Error("expected the defaulted components to be kept after recording the rollback point")
+	}
+}
+
+func TestCheckUpgradeRollbackPointReplacesPrevious(t *testing.T) {
+	ctx := context.Background()
+	quay, app, database, pvc := newUpgradeRollbackTestObjects(t)
+	quay.Status.UpgradeRollback = &v1.UpgradeRollbackStatus{
+		Version:       "3.10.0",
+		TargetVersion: "3.11.0",
+		Backup:        "registry-quay-database-rollback",
+		Phase:         v1.UpgradeRollbackPhaseReady,
+	}
+	previous := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: "registry-quay-database-rollback", Namespace: "ns"},
+		Status:     batchv1.JobStatus{Succeeded: 1},
+	}
+	r := newPreUpgradeTestReconciler(t, quay, app, database, pvc, previous)
+	if err := v1.EnsureDefaultComponents(quaycontext.NewQuayRegistryContext(), quay); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	done, err := r.checkUpgradeRollbackPoint(ctx, quay)
+	if err != nil || done {
+		t.Fatalf("expected the previous rollback point to be replaced, received %v, %v", done, err)
+	}
+	if quay.Status.UpgradeRollback != nil {
+		t.Errorf("expected previous rollback point to be removed, received %+v", quay.Status.UpgradeRollback)
+	}
+	if !v1.ComponentIsManaged(quay.Spec.Components, v1.ComponentRedis) {
+		t.Error("expected the defaulted components to be kept after removing the rollback point")
+	}
+
+	var job batchv1.Job
+	nsn := types.NamespacedName{Name: "registry-quay-database-rollback", Namespace: "ns"}
+	if err := r.Get(ctx, nsn, &job); !errors.IsNotFound(err) {
+		t.Errorf("expected the dump job of the previous upgrade to be deleted, received %v", err)
+	}
+}
+
+func TestCheckUpgradeRollbackPointWithoutPreviousVersion(t *testing.T) {
+	ctx := context.Background()
+	quay, app, database, pvc := newUpgradeRollbackTestObjects(t)
+	app.Spec.Template.Spec.Containers[0].Env[0].Value = "3.12.0"
+	r := newPreUpgradeTestReconciler(t, quay, app, database, pvc)
+
+	if _, err := r.checkUpgradeRollbackPoint(ctx, quay); err == nil {
+		t.Error("expected an error when quay does not run the version upgraded from")
+	}
+}
+
+func TestCheckMigrationStatusStartsRollback(t *testing.T) {
+	ctx := context.Background()
+	quay, _, _, _ := newUpgradeRollbackTestObjects(t)
+	quay.Status.UpgradeRollback = &v1.UpgradeRollbackStatus{
+		Version:       "3.11.0",
+		Image:         "quay.io/projectquay/quay:3.11.0",
+		TargetVersion: "3.12.0",
+		Backup:        "registry-quay-database-rollback",
+		Phase:         v1.UpgradeRollbackPhaseReady,
+	}
+
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: "registry-quay-app-upgrade", Namespace: "ns"},
+		Status: batchv1.JobStatus{
+			Failed: 1,
+			Conditions: []batchv1.JobCondition{
+				{
+					Type:    batchv1.JobFailed,
+					Status:  corev1.ConditionTrue,
+					Message: "Job has reached the specified backoff limit",
+				},
+			},
+		},
+	}
+
+	var lines []string
+	for i := 0; i < 20; i++ {
+		lines = append(lines, fmt.Sprintf("line %d", i))
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "registry-quay-app-upgrade-abcde",
+			Namespace: "ns",
+			Labels:    map[string]string{"job-name": "registry-quay-app-upgrade"},
+		},
+		Status: corev1.PodStatus{
+			ContainerStatuses: []corev1.ContainerStatus{
+				{
+					Name: "quay-app-upgrade",
+					State: corev1.ContainerState{
+						Terminated: &corev1.ContainerStateTerminated{
+							ExitCode: 1,
+							Message:  strings.Join(lines, "\n"),
+						},
+					},
+				},
+			},
+		},
+	}
+
+	r := newPreUpgradeTestReconciler(t, quay, job, pod)
+	r.EventRecorder = record.NewFakeRecorder(10)
+
+	if _, err := r.checkMigrationStatus(ctx, quay, r.Log); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if !v1.UpgradeRollbackRunning(quay) {
+		t.Fatalf("expected the rollback to start, received %+v", quay.Status.Conditions)
+	}
+
+	rollback := quay.Status.UpgradeRollback
+	if rollback.Phase != v1.UpgradeRollbackPhaseRollingBack {
+		t.Errorf("expected rollback to be running, received %s", rollback.Phase)
+	}
+	if !strings.HasPrefix(rollback.Message, "Job has reached the specified backoff limit: ") {
+		t.Errorf("expected job failure to be recorded, received %q", rollback.Message)
+	}
+	if !strings.HasSuffix(rollback.Message, "line 19") || strings.Contains(rollback.Message, "line 9\n") {
+		t.Errorf("expected the tail of the job log to be recorded, received %q", rollback.Message)
+	}
+}
+
+func TestCheckUpgradeRollback(t *testing.T) {
+	for _, tt := range []struct {
+		name       string
+		status     batchv1.JobStatus
+		phase      v1.UpgradeRollbackPhase
+		reason     v1.ConditionReason
+		rolledBack bool
+	}{
+		{
+			name:       "Succeeded",
+			status:     batchv1.JobStatus{Succeeded: 1},
+			phase:      v1.UpgradeRollbackPhaseRolledBack,
+			reason:     v1.ConditionReasonUpgradeRolledBack,
+			rolledBack: true,
+		},
+		{
+			name: "Failed",
+			status: batchv1.JobStatus{
+				Conditions: []batchv1.JobCondition{
+					{Type: batchv1.JobFailed, Status: corev1.ConditionTrue, Message: "restore failed"},
+				},
+			},
+			phase:  v1.UpgradeRollbackPhaseFailed,
+			reason: v1.ConditionReasonUpgradeRollbackFailed,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			quay, _, database, _ := newUpgradeRollbackTestObjects(t)
+			quay.Generation = 3
+			quay.Status.UpgradeRollback = &v1.UpgradeRollbackStatus{
+				Version:       "3.11.0",
+				Image:         "quay.io/projectquay/quay:3.11.0",
+				TargetVersion: "3.12.0",
+				Backup:        "registry-quay-database-rollback",
+				Phase:         v1.UpgradeRollbackPhaseRollingBack,
+				Message:       "migrations failed",
+			}
+			quay.Status.Conditions = []v1.Condition{
+				{
+					Type:   v1.ConditionComponentsCreated,
+					Status: metav1.ConditionFalse,
+					Reason: v1.ConditionReasonUpgradeRollingBack,
+				},
+			}
+
+			r := newPreUpgradeTestReconciler(t, quay, database)
+			r.EventRecorder = record.NewFakeRecorder(10)
+
+			if _, err := r.checkUpgradeRollback(ctx, quay, r.Log); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			var job batchv1.Job
+			nsn := types.NamespacedName{Name: "registry-quay-database-rollback-pg-restore", Namespace: "ns"}
+			if err := r.Get(ctx, nsn, &job); err != nil {
+				t.Fatalf("expected restore job to be created: %s", err)
+			}
+
+			spec := job.Spec.Template.Spec
+			if claim := spec.Volumes[0].PersistentVolumeClaim.ClaimName; claim != "registry-quay-database-rollback" {
+				t.Errorf("expected the dump of the rollback point to be restored, received %s", claim)
+			}
+			if env := spec.Containers[0].Env[0]; env.Value != "pre-upgrade.dump" {
+				t.Errorf("expected the pre-upgrade dump to be restored, received %s", env.Value)
+			}
+
+			job.Status = tt.status
+			if err := r.Status().Update(ctx, &job); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if _, err := r.checkUpgradeRollback(ctx, quay, r.Log); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			rollback := quay.Status.UpgradeRollback
+			if rollback.Phase != tt.phase || rollback.Generation != 3 {
+				t.Errorf("expected rollback %s at generation 3, received %+v", tt.phase, rollback)
+			}
+
+			created := v1.GetCondition(quay.Status.Conditions, v1.ConditionComponentsCreated)
+			if created.Reason != tt.reason {
+				t.Errorf("expected reason %s, received %s", tt.reason, created.Reason)
+			}
+			if (v1.UpgradeRolledBack(quay) != nil) != tt.rolledBack {
+				t.Errorf("expected rolled back to be %v", tt.rolledBack)
+			}
+		})
+	}
+}
@@ -62,6 +62,7 @@ func main() {
 	secureMetrics := false
 	enableLeaderElection := false
 	namespace := ""
+	enableWebhooks := false
 	flag.BoolVar(&enableHTTP2, "enable-http2", enableHTTP2, "If HTTP/2 should be enabled for the metrics and webhook servers.")
 	flag.StringVar(&metricsAddr, "metrics-addr", metricsAddr, "The address the metric endpoint binds to.")
 	flag.BoolVar(&secureMetrics, "metrics-secure", secureMetrics, "If the metrics endpoint should be served securely.")
@@ -69,6 +70,7 @@ func main() {
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
 	flag.StringVar(&namespace, "namespace", namespace, "The Kubernetes namespace that the controller will watch.")
+	flag.BoolVar(&enableWebhooks, "enable-webhooks", enableWebhooks, "If the QuayRegistry admission webhooks should be served.")
 	flag.Parse()
 
 	// if this environment variable is set the operator removes all resource requirements
@@ -166,6 +168,16 @@ func main() {
 		os.Exit(1)
 	}
 
+	if err = (&quaycontroller.QuayRegistryRestoreReconciler{
+		Client:  mgr.GetClient(),
+		Log:     ctrl.Log.WithName("controllers").WithName("QuayRegistryRestore"),
+		Scheme:  mgr.GetScheme(),
+		Requeue: ctrl.Result{RequeueAfter: 10 * time.Second},
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "QuayRegistryRestore")
+		os.Exit(1)
+	}
+
 	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &corev1.Event{}, "involvedObject.uid", func(rawObj client.Object) []string {
 		event, ok := rawObj.(*corev1.Event)
 		if !ok {
@@ -180,6 +192,15 @@ func main() {
 		os.Exit(1)
 	}
 
+	// the webhook server requires a serving certificate, when deployed through OLM this is
+	// provisioned based on the webhook definitions present in the CSV.
+	if enableWebhooks {
+		if err = (&quay.QuayRegistry{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "QuayRegistry")
+			os.Exit(1)
+		}
+	}
+
 	// +kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
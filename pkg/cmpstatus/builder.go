@@ -0,0 +1,106 @@
+package cmpstatus
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	qv1 "github.com/quay/quay-operator/apis/quay/v1"
+)
+
+// Builder checks the health of the builder component.
+type Builder struct {
+	Client client.Client
+}
+
+// Name returns the component name this entity checks for health.
+func (b *Builder) Name() string {
+	return "builder"
+}
+
+// Check verifies the build namespace exists and the token for the builder service account
+// has been issued. Quay can't schedule builds without the token.
+func (b *Builder) Check(ctx context.Context, reg qv1.QuayRegistry) (qv1.Condition, error) {
+	var zero qv1.Condition
+
+	if !qv1.ComponentIsManaged(reg.Spec.Components, qv1.ComponentBuilder) {
+		return qv1.Condition{
+			Type:           qv1.ComponentBuilderReady,
+			Status:         metav1.ConditionTrue,
+			Reason:         qv1.ConditionReasonComponentUnmanaged,
+			Message:        "Builder not managed by the operator",
+			LastUpdateTime: metav1.NewTime(time.Now()),
+		}, nil
+	}
+
+	nsname := qv1.BuilderNamespaceFor(&reg)
+	var ns corev1.Namespace
+	if err := b.Client.Get(ctx, types.NamespacedName{Name: nsname}, &ns); err != nil {
+		if errors.IsNotFound(err) {
+			return qv1.Condition{
+				Type:           qv1.ComponentBuilderReady,
+				Status:         metav1.ConditionFalse,
+				Reason:         qv1.ConditionReasonComponentNotReady,
+				Message:        fmt.Sprintf("Build namespace %s not found", nsname),
+				LastUpdateTime: metav1.NewTime(time.Now()),
+			}, nil
+		}
+		return zero, err
+	}
+
+	if ns.Status.Phase == corev1.NamespaceTerminating {
+		return qv1.Condition{
+			Type:           qv1.ComponentBuilderReady,
+			Status:         metav1.ConditionFalse,
+			Reason:         qv1.ConditionReasonComponentNotReady,
+			Message:        fmt.Sprintf("Build namespace %s is terminating", nsname),
+			LastUpdateTime: metav1.NewTime(time.Now()),
+		}, nil
+	}
+
+	nsn := types.NamespacedName{
+		Namespace: nsname,
+		Name:      qv1.BuilderTokenSecretNameFor(&reg),
+	}
+
+	var secret corev1.Secret
+	if err := b.Client.Get(ctx, nsn, &secret); err != nil {
+		if errors.IsNotFound(err) {
+			return qv1.Condition{
+				Type:           qv1.ComponentBuilderReady,
+				Status:         metav1.ConditionFalse,
+				Reason:         qv1.ConditionReasonComponentNotReady,
+				Message:        fmt.Sprintf("Secret %s not found", nsn.Name),
+				LastUpdateTime: metav1.NewTime(time.Now()),
+			}, nil
+		}
+		return zero, err
+	}
+
+	if len(secret.Data[corev1.ServiceAccountTokenKey]) == 0 {
+		return qv1.Condition{
+			Type:   qv1.ComponentBuilderReady,
+			Status: metav1.ConditionFalse,
+			Reason: qv1.ConditionReasonComponentNotReady,
+			Message: fmt.Sprintf(
+				"Awaiting token for service account %s",
+				qv1.BuilderServiceAccountNameFor(&reg),
+			),
+			LastUpdateTime: metav1.NewTime(time.Now()),
+		}, nil
+	}
+
+	return qv1.Condition{
+		Type:           qv1.ComponentBuilderReady,
+		Status:         metav1.ConditionTrue,
+		Reason:         qv1.ConditionReasonComponentReady,
+		Message:        fmt.Sprintf("Builds scheduled in namespace %s", nsname),
+		LastUpdateTime: metav1.NewTime(time.Now()),
+	}, nil
+}
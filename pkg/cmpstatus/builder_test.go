@@ -0,0 +1,167 @@
+package cmpstatus
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	qv1 "github.com/quay/quay-operator/apis/quay/v1"
+)
+
+func TestBuilderCheck(t *testing.T) {
+	managed := qv1.QuayRegistry{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "registry",
+			Namespace: "ns",
+		},
+		Spec: qv1.QuayRegistrySpec{
+			Components: []qv1.Component{
+				{Kind: qv1.ComponentBuilder, Managed: true},
+			},
+		},
+	}
+
+	namespace := &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "ns-registry-builder",
+		},
+	}
+
+	for _, tt := range []struct {
+		name string
+		quay qv1.QuayRegistry
+		objs []client.Object
+		cond qv1.Condition
+	}{
+		{
+			name: "unmanaged",
+			quay: qv1.QuayRegistry{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "registry",
+					Namespace: "ns",
+				},
+			},
+			cond: qv1.Condition{
+				Type:    qv1.ComponentBuilderReady,
+				Status:  metav1.ConditionTrue,
+				Reason:  qv1.ConditionReasonComponentUnmanaged,
+				Message: "Builder not managed by the operator",
+			},
+		},
+		{
+			name: "namespace not found",
+			quay: managed,
+			cond: qv1.Condition{
+				Type:    qv1.ComponentBuilderReady,
+				Status:  metav1.ConditionFalse,
+				Reason:  qv1.ConditionReasonComponentNotReady,
+				Message: "Build namespace ns-registry-builder not found",
+			},
+		},
+		{
+			name: "namespace terminating",
+			quay: managed,
+			objs: []client.Object{
+				&corev1.Namespace{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "ns-registry-builder",
+					},
+					Status: corev1.NamespaceStatus{
+						Phase: corev1.NamespaceTerminating,
+					},
+				},
+			},
+			cond: qv1.Condition{
+				Type:    qv1.ComponentBuilderReady,
+				Status:  metav1.ConditionFalse,
+				Reason:  qv1.ConditionReasonComponentNotReady,
+				Message: "Build namespace ns-registry-builder is terminating",
+			},
+		},
+		{
+			name: "token secret not found",
+			quay: managed,
+			objs: []client.Object{namespace},
+			cond: qv1.Condition{
+				Type:    qv1.ComponentBuilderReady,
+				Status:  metav1.ConditionFalse,
+				Reason:  qv1.ConditionReasonComponentNotReady,
+				Message: "Secret registry-quay-builder-token not found",
+			},
+		},
+		{
+			name: "token not issued",
+			quay: managed,
+			objs: []client.Object{
+				namespace,
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "registry-quay-builder-token",
+						Namespace: "ns-registry-builder",
+					},
+				},
+			},
+			cond: qv1.Condition{
+				Type:    qv1.ComponentBuilderReady,
+				Status:  metav1.ConditionFalse,
+				Reason:  qv1.ConditionReasonComponentNotReady,
+				Message: "Awaiting token for service account registry-quay-builder",
+			},
+		},
+		{
+			name: "ready",
+			quay: managed,
+			objs: []client.Object{
+				namespace,
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "registry-quay-builder-token",
+						Namespace: "ns-registry-builder",
+					},
+					Data: map[string][]byte{
+						corev1.ServiceAccountTokenKey: []byte("token"),
+					},
+				},
+			},
+			cond: qv1.Condition{
+				Type:    qv1.ComponentBuilderReady,
+				Status:  metav1.ConditionTrue,
+				Reason:  qv1.ConditionReasonComponentReady,
+				Message: "Builds scheduled in namespace ns-registry-builder",
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+
+			scheme := runtime.NewScheme()
+			if err := corev1.AddToScheme(scheme); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			cli := fake.NewClientBuilder().WithObjects(tt.objs...).WithScheme(scheme).Build()
+			builder := Builder{cli}
+
+			cond, err := builder.Check(ctx, tt.quay)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if cond.LastUpdateTime.IsZero() {
+				t.Errorf("unexpected zeroed last update time for condition")
+			}
+
+			cond.LastUpdateTime = metav1.NewTime(time.Time{})
+			if !reflect.DeepEqual(tt.cond, cond) {
+				t.Errorf("expecting %+v, received %+v", tt.cond, cond)
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package cmpstatus
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	qv1 "github.com/quay/quay-operator/apis/quay/v1"
+)
+
+// PostgresBackup checks the scheduled backups of the quay database.
+type PostgresBackup struct {
+	Client client.Client
+}
+
+// Name returns the component name this entity checks for health.
+func (p *PostgresBackup) Name() string {
+	return "postgres-backup"
+}
+
+// Check reports when the quay database was last backed up.
+func (p *PostgresBackup) Check(ctx context.Context, quay qv1.QuayRegistry) (qv1.Condition, error) {
+	return checkDatabaseBackup(
+		ctx, p.Client, quay, qv1.ComponentPostgres, qv1.ComponentPostgresBackupReady,
+	)
+}
+
+// ClairPostgresBackup checks the scheduled backups of the clair database.
+type ClairPostgresBackup struct {
+	Client client.Client
+}
+
+// Name returns the component name this entity checks for health.
+func (c *ClairPostgresBackup) Name() string {
+	return "clairpostgres-backup"
+}
+
+// Check reports when the clair database was last backed up.
+func (c *ClairPostgresBackup) Check(ctx context.Context, quay qv1.QuayRegistry) (qv1.Condition, error) {
+	return checkDatabaseBackup(
+		ctx, c.Client, quay, qv1.ComponentClairPostgres, qv1.ComponentClairPostgresBackupReady,
+	)
+}
+
+// checkDatabaseBackup inspects the CronJob taking backups of the provided database component.
+// A backup is considered failed if the last scheduled run did not succeed and no job is still
+// running. Backups do not affect the availability of the registry.
+func checkDatabaseBackup(
+	ctx context.Context,
+	cli client.Client,
+	quay qv1.QuayRegistry,
+	component qv1.ComponentKind,
+	condType qv1.ConditionType,
+) (qv1.Condition, error) {
+	var zero qv1.Condition
+
+	if qv1.DatabaseBackupFor(&quay, component) == nil {
+		return qv1.Condition{
+			Type:           condType,
+			Status:         metav1.ConditionTrue,
+			Reason:         qv1.ConditionReasonBackupNotConfigured,
+			Message:        fmt.Sprintf("Backups of %s not configured", component),
+			LastUpdateTime: metav1.NewTime(time.Now()),
+		}, nil
+	}
+
+	nsn := types.NamespacedName{
+		Namespace: quay.Namespace,
+		Name:      qv1.DatabaseBackupNameFor(&quay, component),
+	}
+
+	var cj batchv1.CronJob
+	if err := cli.Get(ctx, nsn, &cj); err != nil {
+		if errors.IsNotFound(err) {
+			return qv1.Condition{
+				Type:           condType,
+				Status:         metav1.ConditionFalse,
+				Reason:         qv1.ConditionReasonBackupPending,
+				Message:        fmt.Sprintf("Backup cronjob %s not found", nsn.Name),
+				LastUpdateTime: metav1.NewTime(time.Now()),
+			}, nil
+		}
+		return zero, err
+	}
+
+	if !qv1.Owns(quay, &cj) {
+		return qv1.Condition{
+			Type:           condType,
+			Status:         metav1.ConditionFalse,
+			Reason:         qv1.ConditionReasonBackupPending,
+			Message:        fmt.Sprintf("Backup cronjob %s not owned by QuayRegistry", nsn.Name),
+			LastUpdateTime: metav1.NewTime(time.Now()),
+		}, nil
+	}
+
+	lastSuccess := cj.Status.LastSuccessfulTime
+	lastSchedule := cj.Status.LastScheduleTime
+	if lastSuccess == nil {
+		if lastSchedule != nil && len(cj.Status.Active) == 0 {
+			return qv1.Condition{
+				Type:   condType,
+				Status: metav1.ConditionFalse,
+				Reason: qv1.ConditionReasonBackupFailed,
+				Message: fmt.Sprintf(
+					"Backup scheduled at %s failed",
+					lastSchedule.UTC().Format(time.RFC3339),
+				),
+				LastUpdateTime: metav1.NewTime(time.Now()),
+			}, nil
+		}
+		return qv1.Condition{
+			Type:           condType,
+			Status:         metav1.ConditionFalse,
+			Reason:         qv1.ConditionReasonBackupPending,
+			Message:        "Awaiting first backup",
+			LastUpdateTime: metav1.NewTime(time.Now()),
+		}, nil
+	}
+
+	if lastSchedule != nil && lastSchedule.After(lastSuccess.Time) && len(cj.Status.Active) == 0 {
+		return qv1.Condition{
+			Type:   condType,
+			Status: metav1.ConditionFalse,
+			Reason: qv1.ConditionReasonBackupFailed,
+			Message: fmt.Sprintf(
+				"Backup scheduled at %s failed, last successful backup at %s",
+				lastSchedule.UTC().Format(time.RFC3339),
+				lastSuccess.UTC().Format(time.RFC3339),
+			),
+			LastUpdateTime: metav1.NewTime(time.Now()),
+		}, nil
+	}
+
+	return qv1.Condition{
+		Type:   condType,
+		Status: metav1.ConditionTrue,
+		Reason: qv1.ConditionReasonBackupSucceeded,
+		Message: fmt.Sprintf(
+			"Last successful backup at %s",
+			lastSuccess.UTC().Format(time.RFC3339),
+		),
+		LastUpdateTime: metav1.NewTime(time.Now()),
+	}, nil
+}
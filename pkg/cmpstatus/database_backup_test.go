@@ -0,0 +1,244 @@
+package cmpstatus
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	qv1 "github.com/quay/quay-operator/apis/quay/v1"
+)
+
+func TestPostgresBackupCheck(t *testing.T) {
+	configured := qv1.QuayRegistry{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "registry",
+			Namespace: "ns",
+			UID:       "uid",
+		},
+		Spec: qv1.QuayRegistrySpec{
+			Components: []qv1.Component{
+				{
+					Kind:    qv1.ComponentPostgres,
+					Managed: true,
+					Overrides: &qv1.Override{
+						Backup: &qv1.DatabaseBackup{Schedule: "0 2 * * *"},
+					},
+				},
+			},
+		},
+	}
+
+	owned := metav1.ObjectMeta{
+		Name:      "registry-quay-database-backup",
+		Namespace: "ns",
+		OwnerReferences: []metav1.OwnerReference{
+			{
+				Kind:       "QuayRegistry",
+				Name:       "registry",
+				APIVersion: "quay.redhat.com/v1",
+				UID:        "uid",
+			},
+		},
+	}
+
+	first := metav1.NewTime(time.Date(2024, 5, 1, 2, 0, 0, 0, time.UTC))
+	second := metav1.NewTime(time.Date(2024, 5, 2, 2, 0, 0, 0, time.UTC))
+
+	for _, tt := range []struct {
+		name string
+		quay qv1.QuayRegistry
+		objs []client.Object
+		cond qv1.Condition
+	}{
+		{
+			name: "not configured",
+			quay: qv1.QuayRegistry{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "registry",
+					Namespace: "ns",
+				},
+				Spec: qv1.QuayRegistrySpec{
+					Components: []qv1.Component{
+						{Kind: qv1.ComponentPostgres, Managed: true},
+					},
+				},
+			},
+			cond: qv1.Condition{
+				Type:    qv1.ComponentPostgresBackupReady,
+				Status:  metav1.ConditionTrue,
+				Reason:  qv1.ConditionReasonBackupNotConfigured,
+				Message: "Backups of postgres not configured",
+			},
+		},
+		{
+			name: "cronjob not found",
+			quay: configured,
+			cond: qv1.Condition{
+				Type:    qv1.ComponentPostgresBackupReady,
+				Status:  metav1.ConditionFalse,
+				Reason:  qv1.ConditionReasonBackupPending,
+				Message: "Backup cronjob registry-quay-database-backup not found",
+			},
+		},
+		{
+			name: "cronjob not owned",
+			quay: configured,
+			objs: []client.Object{
+				&batchv1.CronJob{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "registry-quay-database-backup",
+						Namespace: "ns",
+					},
+				},
+			},
+			cond: qv1.Condition{
+				Type:    qv1.ComponentPostgresBackupReady,
+				Status:  metav1.ConditionFalse,
+				Reason:  qv1.ConditionReasonBackupPending,
+				Message: "Backup cronjob registry-quay-database-backup not owned by QuayRegistry",
+			},
+		},
+		{
+			name: "never scheduled",
+			quay: configured,
+			objs: []client.Object{
+				&batchv1.CronJob{ObjectMeta: owned},
+			},
+			cond: qv1.Condition{
+				Type:    qv1.ComponentPostgresBackupReady,
+				Status:  metav1.ConditionFalse,
+				Reason:  qv1.ConditionReasonBackupPending,
+				Message: "Awaiting first backup",
+			},
+		},
+		{
+			name: "first backup running",
+			quay: configured,
+			objs: []client.Object{
+				&batchv1.CronJob{
+					ObjectMeta: owned,
+					Status: batchv1.CronJobStatus{
+						Active:           []corev1.ObjectReference{{Name: "job"}},
+						LastScheduleTime: &first,
+					},
+				},
+			},
+			cond: qv1.Condition{
+				Type:    qv1.ComponentPostgresBackupReady,
+				Status:  metav1.ConditionFalse,
+				Reason:  qv1.ConditionReasonBackupPending,
+				Message: "Awaiting first backup",
+			},
+		},
+		{
+			name: "first backup failed",
+			quay: configured,
+			objs: []client.Object{
+				&batchv1.CronJob{
+					ObjectMeta: owned,
+					Status: batchv1.CronJobStatus{
+						LastScheduleTime: &first,
+					},
+				},
+			},
+			cond: qv1.Condition{
+				Type:    qv1.ComponentPostgresBackupReady,
+				Status:  metav1.ConditionFalse,
+				Reason:  qv1.ConditionReasonBackupFailed,
+				Message: "Backup scheduled at 2024-05-01T02:00:00Z failed",
+			},
+		},
+		{
+			name: "last backup failed",
+			quay: configured,
+			objs: []client.Object{
+				&batchv1.CronJob{
+					ObjectMeta: owned,
+					Status: batchv1.CronJobStatus{
+						LastScheduleTime:   &second,
+						LastSuccessfulTime: &first,
+					},
+				},
+			},
+			cond: qv1.Condition{
+				Type:    qv1.ComponentPostgresBackupReady,
+				Status:  metav1.ConditionFalse,
+				Reason:  qv1.ConditionReasonBackupFailed,
+				Message: "Backup scheduled at 2024-05-02T02:00:00Z failed, last successful backup at 2024-05-01T02:00:00Z",
+			},
+		},
+		{
+			name: "backup running",
+			quay: configured,
+			objs: []client.Object{
+				&batchv1.CronJob{
+					ObjectMeta: owned,
+					Status: batchv1.CronJobStatus{
+						Active:             []corev1.ObjectReference{{Name: "job"}},
+						LastScheduleTime:   &second,
+						LastSuccessfulTime: &first,
+					},
+				},
+			},
+			cond: qv1.Condition{
+				Type:    qv1.ComponentPostgresBackupReady,
+				Status:  metav1.ConditionTrue,
+				Reason:  qv1.ConditionReasonBackupSucceeded,
+				Message: "Last successful backup at 2024-05-01T02:00:00Z",
+			},
+		},
+		{
+			name: "backup succeeded",
+			quay: configured,
+			objs: []client.Object{
+				&batchv1.CronJob{
+					ObjectMeta: owned,
+					Status: batchv1.CronJobStatus{
+						LastScheduleTime:   &second,
+						LastSuccessfulTime: &second,
+					},
+				},
+			},
+			cond: qv1.Condition{
+				Type:    qv1.ComponentPostgresBackupReady,
+				Status:  metav1.ConditionTrue,
+				Reason:  qv1.ConditionReasonBackupSucceeded,
+				Message: "Last successful backup at 2024-05-02T02:00:00Z",
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+
+			scheme := runtime.NewScheme()
+			if err := batchv1.AddToScheme(scheme); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			cli := fake.NewClientBuilder().WithObjects(tt.objs...).WithScheme(scheme).Build()
+			backup := PostgresBackup{cli}
+
+			cond, err := backup.Check(ctx, tt.quay)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if cond.LastUpdateTime.IsZero() {
+				t.Errorf("unexpected zeroed last update time for condition")
+			}
+
+			cond.LastUpdateTime = metav1.NewTime(time.Time{})
+			if !reflect.DeepEqual(tt.cond, cond) {
+				t.Errorf("expecting %+v, received %+v", tt.cond, cond)
+			}
+		})
+	}
+}
@@ -12,6 +12,8 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
@@ -35,6 +37,10 @@ func CheckDatabaseDeploymentAndPVCStatus(
 		componentName = "ClairPostgres"
 	}
 
+	if qv1.DatabaseClusterEnabled(&quay, component) {
+		return checkDatabaseCluster(ctx, cli, quay, component, componentName, componentReadyType)
+	}
+
 	nsn := types.NamespacedName{
 		Namespace: quay.Namespace,
 		Name:      fmt.Sprintf("%s-%s", quay.Name, deploymentNameSuffix),
@@ -151,3 +157,83 @@ func CheckDatabaseDeploymentAndPVCStatus(
 	cond.Type = componentReadyType
 	return cond, nil
 }
+
+// checkDatabaseCluster checks the status of the CloudNativePG cluster running a database. The
+// cluster is ready as long as a primary has been elected and an instance is ready, standbys
+// may be missing while a failover is in progress.
+func checkDatabaseCluster(
+	ctx context.Context,
+	cli client.Client,
+	quay qv1.QuayRegistry,
+	component qv1.ComponentKind,
+	componentName string,
+	componentReadyType qv1.ConditionType,
+) (qv1.Condition, error) {
+	var zero qv1.Condition
+
+	nsn := types.NamespacedName{
+		Namespace: quay.Namespace,
+		Name:      qv1.DatabaseClusterNameFor(&quay, component),
+	}
+
+	var cluster unstructured.Unstructured
+	cluster.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   "postgresql.cnpg.io",
+		Version: "v1",
+		Kind:    "Cluster",
+	})
+	if err := cli.Get(ctx, nsn, &cluster); err != nil {
+		if errors.IsNotFound(err) {
+			return qv1.Condition{
+				Type:           componentReadyType,
+				Status:         metav1.ConditionFalse,
+				Reason:         qv1.ConditionReasonComponentNotReady,
+				Message:        fmt.Sprintf("%s cluster %s not found", componentName, nsn.Name),
+				LastUpdateTime: metav1.NewTime(time.Now()),
+			}, nil
+		}
+		return zero, err
+	}
+
+	if !qv1.Owns(quay, &cluster) {
+		return qv1.Condition{
+			Type:   componentReadyType,
+			Status: metav1.ConditionFalse,
+			Reason: qv1.ConditionReasonComponentNotReady,
+			Message: fmt.Sprintf(
+				"%s cluster %s not owned by QuayRegistry", componentName, nsn.Name,
+			),
+			LastUpdateTime: metav1.NewTime(time.Now()),
+		}, nil
+	}
+
+	instances, _, _ := unstructured.NestedInt64(cluster.Object, "spec", "instances")
+	ready, _, _ := unstructured.NestedInt64(cluster.Object, "status", "readyInstances")
+	phase, _, _ := unstructured.NestedString(cluster.Object, "status", "phase")
+	primary, _, _ := unstructured.NestedString(cluster.Object, "status", "currentPrimary")
+
+	if ready == 0 || primary == "" {
+		msg := fmt.Sprintf("%s cluster %s has no ready instance", componentName, nsn.Name)
+		if phase != "" {
+			msg = fmt.Sprintf("%s: %s", msg, phase)
+		}
+		return qv1.Condition{
+			Type:           componentReadyType,
+			Status:         metav1.ConditionFalse,
+			Reason:         qv1.ConditionReasonComponentNotReady,
+			Message:        msg,
+			LastUpdateTime: metav1.NewTime(time.Now()),
+		}, nil
+	}
+
+	return qv1.Condition{
+		Type:   componentReadyType,
+		Status: metav1.ConditionTrue,
+		Reason: qv1.ConditionReasonComponentReady,
+		Message: fmt.Sprintf(
+			"%s cluster %s available, %d of %d instances ready, primary %s",
+			componentName, nsn.Name, ready, instances, primary,
+		),
+		LastUpdateTime: metav1.NewTime(time.Now()),
+	}, nil
+}
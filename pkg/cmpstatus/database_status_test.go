@@ -10,7 +10,9 @@ import (
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -274,3 +276,138 @@ func TestDatabaseComponentCheck(t *testing.T) {
 		}
 	}
 }
+
+func newTestDatabaseCluster(
+	name string, owned bool, instances, ready int64, primary string,
+) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   "postgresql.cnpg.io",
+		Version: "v1",
+		Kind:    "Cluster",
+	})
+	obj.SetName(name)
+	obj.SetNamespace("quay-namespace")
+	if owned {
+		obj.SetOwnerReferences([]metav1.OwnerReference{
+			{
+				Kind:       "QuayRegistry",
+				Name:       "registry",
+				APIVersion: "quay.redhat.com/v1",
+				UID:        "registry-uid",
+			},
+		})
+	}
+	_ = unstructured.SetNestedField(obj.Object, instances, "spec", "instances")
+	_ = unstructured.SetNestedField(obj.Object, ready, "status", "readyInstances")
+	_ = unstructured.SetNestedField(obj.Object, "Cluster in healthy state", "status", "phase")
+	if primary != "" {
+		_ = unstructured.SetNestedField(obj.Object, primary, "status", "currentPrimary")
+	}
+	return obj
+}
+
+func TestDatabaseClusterCheck(t *testing.T) {
+	backend := qv1.DatabaseBackendCloudNativePG
+	quay := qv1.QuayRegistry{
+		ObjectMeta: metav1.ObjectMeta{Name: "registry", Namespace: "quay-namespace", UID: "registry-uid"},
+		Spec: qv1.QuayRegistrySpec{
+			Components: []qv1.Component{
+				{
+					Kind:      qv1.ComponentPostgres,
+					Managed:   true,
+					Overrides: &qv1.Override{Backend: &backend},
+				},
+			},
+		},
+	}
+
+	for _, tt := range []struct {
+		name string
+		objs []client.Object
+		cond qv1.Condition
+	}{
+		{
+			name: "cluster not found",
+			cond: qv1.Condition{
+				Type:    qv1.ComponentPostgresReady,
+				Status:  metav1.ConditionFalse,
+				Reason:  qv1.ConditionReasonComponentNotReady,
+				Message: "Postgres cluster registry-quay-database not found",
+			},
+		},
+		{
+			name: "cluster not owned",
+			objs: []client.Object{
+				newTestDatabaseCluster("registry-quay-database", false, 3, 3, "registry-quay-database-1"),
+			},
+			cond: qv1.Condition{
+				Type:    qv1.ComponentPostgresReady,
+				Status:  metav1.ConditionFalse,
+				Reason:  qv1.ConditionReasonComponentNotReady,
+				Message: "Postgres cluster registry-quay-database not owned by QuayRegistry",
+			},
+		},
+		{
+			name: "no primary",
+			objs: []client.Object{
+				newTestDatabaseCluster("registry-quay-database", true, 3, 0, ""),
+			},
+			cond: qv1.Condition{
+				Type:    qv1.ComponentPostgresReady,
+				Status:  metav1.ConditionFalse,
+				Reason:  qv1.ConditionReasonComponentNotReady,
+				Message: "Postgres cluster registry-quay-database has no ready instance: Cluster in healthy state",
+			},
+		},
+		{
+			name: "standby missing",
+			objs: []client.Object{
+				newTestDatabaseCluster("registry-quay-database", true, 3, 2, "registry-quay-database-2"),
+			},
+			cond: qv1.Condition{
+				Type:    qv1.ComponentPostgresReady,
+				Status:  metav1.ConditionTrue,
+				Reason:  qv1.ConditionReasonComponentReady,
+				Message: "Postgres cluster registry-quay-database available, 2 of 3 instances ready, primary registry-quay-database-2",
+			},
+		},
+		{
+			name: "all healthy",
+			objs: []client.Object{
+				newTestDatabaseCluster("registry-quay-database", true, 3, 3, "registry-quay-database-1"),
+			},
+			cond: qv1.Condition{
+				Type:    qv1.ComponentPostgresReady,
+				Status:  metav1.ConditionTrue,
+				Reason:  qv1.ConditionReasonComponentReady,
+				Message: "Postgres cluster registry-quay-database available, 3 of 3 instances ready, primary registry-quay-database-1",
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+
+			scheme := runtime.NewScheme()
+			if err := qv1.AddToScheme(scheme); err != nil {
+				t.Fatalf("failed to add quay/v1 to scheme: %s", err)
+			}
+			if err := clientgoscheme.AddToScheme(scheme); err != nil {
+				t.Fatalf("failed to add client-go to scheme: %s", err)
+			}
+
+			cli := fake.NewClientBuilder().WithScheme(scheme).WithObjects(tt.objs...).Build()
+			pg := &Postgres{Client: cli}
+			cond, err := pg.Check(ctx, quay)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			cond.LastUpdateTime = metav1.NewTime(time.Time{})
+			if !reflect.DeepEqual(tt.cond, cond) {
+				t.Errorf("expecting %+v, received %+v", tt.cond, cond)
+			}
+		})
+	}
+}
@@ -30,7 +30,12 @@ func Evaluate(ctx context.Context, c client.Client, q qv1.QuayRegistry) ([]qv1.C
 	for _, component := range []Checker{
 		&HPA{Client: c},
 		&Route{Client: c},
+		&Ingress{Client: c},
+		&Gateway{Client: c},
+		&Builder{Client: c},
 		&Monitoring{Client: c},
+		&PostgresBackup{Client: c},
+		&ClairPostgresBackup{Client: c},
 	} {
 		cond, err := component.Check(ctx, q)
 		if err != nil {
@@ -49,7 +54,7 @@ func Evaluate(ctx context.Context, c client.Client, q qv1.QuayRegistry) ([]qv1.C
 		&ObjectStorage{Client: c},
 		&Clair{Client: c},
 		&ClairPostgres{Client: c},
-		&TLS{Client: c},
+		&TLS{Client: c, ExpiryWarning: TLSExpiryWarningFromEnv()},
 		&Redis{Client: c},
 	} {
 		cond, err := component.Check(ctx, q)
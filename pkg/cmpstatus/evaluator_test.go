@@ -91,12 +91,42 @@ func TestEvaluate(t *testing.T) {
 					Reason:  qv1.ConditionReasonComponentNotReady,
 					Message: "Route not found",
 				},
+				{
+					Type:    qv1.ComponentIngressReady,
+					Status:  metav1.ConditionTrue,
+					Reason:  qv1.ConditionReasonComponentUnmanaged,
+					Message: "Ingress not managed by the operator",
+				},
+				{
+					Type:    qv1.ComponentGatewayReady,
+					Status:  metav1.ConditionTrue,
+					Reason:  qv1.ConditionReasonComponentUnmanaged,
+					Message: "Gateway routes not managed by the operator",
+				},
+				{
+					Type:    qv1.ComponentBuilderReady,
+					Status:  metav1.ConditionTrue,
+					Reason:  qv1.ConditionReasonComponentUnmanaged,
+					Message: "Builder not managed by the operator",
+				},
 				{
 					Type:    qv1.ComponentMonitoringReady,
 					Status:  metav1.ConditionFalse,
 					Reason:  qv1.ConditionReasonComponentNotReady,
 					Message: "PrometheusRule registry-quay-prometheus-rules not found",
 				},
+				{
+					Type:    qv1.ComponentPostgresBackupReady,
+					Status:  metav1.ConditionTrue,
+					Reason:  qv1.ConditionReasonBackupNotConfigured,
+					Message: "Backups of postgres not configured",
+				},
+				{
+					Type:    qv1.ComponentClairPostgresBackupReady,
+					Status:  metav1.ConditionTrue,
+					Reason:  qv1.ConditionReasonBackupNotConfigured,
+					Message: "Backups of clairpostgres not configured",
+				},
 				{
 					Type:    qv1.ComponentPostgresReady,
 					Status:  metav1.ConditionFalse,
@@ -409,12 +439,42 @@ func TestEvaluate(t *testing.T) {
 					Reason:  qv1.ConditionReasonComponentReady,
 					Message: "Route admitted",
 				},
+				{
+					Type:    qv1.ComponentIngressReady,
+					Status:  metav1.ConditionTrue,
+					Reason:  qv1.ConditionReasonComponentUnmanaged,
+					Message: "Ingress not managed by the operator",
+				},
+				{
+					Type:    qv1.ComponentGatewayReady,
+					Status:  metav1.ConditionTrue,
+					Reason:  qv1.ConditionReasonComponentUnmanaged,
+					Message: "Gateway routes not managed by the operator",
+				},
+				{
+					Type:    qv1.ComponentBuilderReady,
+					Status:  metav1.ConditionTrue,
+					Reason:  qv1.ConditionReasonComponentUnmanaged,
+					Message: "Builder not managed by the operator",
+				},
 				{
 					Type:    qv1.ComponentMonitoringReady,
 					Status:  metav1.ConditionTrue,
 					Reason:  qv1.ConditionReasonComponentReady,
 					Message: "ServiceMonitor and PrometheusRules created",
 				},
+				{
+					Type:    qv1.ComponentPostgresBackupReady,
+					Status:  metav1.ConditionTrue,
+					Reason:  qv1.ConditionReasonBackupNotConfigured,
+					Message: "Backups of postgres not configured",
+				},
+				{
+					Type:    qv1.ComponentClairPostgresBackupReady,
+					Status:  metav1.ConditionTrue,
+					Reason:  qv1.ConditionReasonBackupNotConfigured,
+					Message: "Backups of clairpostgres not configured",
+				},
 				{
 					Type:    qv1.ComponentPostgresReady,
 					Status:  metav1.ConditionTrue,
@@ -748,12 +808,42 @@ func TestEvaluate(t *testing.T) {
 					Reason:  qv1.ConditionReasonComponentReady,
 					Message: "Route admitted",
 				},
+				{
+					Type:    qv1.ComponentIngressReady,
+					Status:  metav1.ConditionTrue,
+					Reason:  qv1.ConditionReasonComponentUnmanaged,
+					Message: "Ingress not managed by the operator",
+				},
+				{
+					Type:    qv1.ComponentGatewayReady,
+					Status:  metav1.ConditionTrue,
+					Reason:  qv1.ConditionReasonComponentUnmanaged,
+					Message: "Gateway routes not managed by the operator",
+				},
+				{
+					Type:    qv1.ComponentBuilderReady,
+					Status:  metav1.ConditionTrue,
+					Reason:  qv1.ConditionReasonComponentUnmanaged,
+					Message: "Builder not managed by the operator",
+				},
 				{
 					Type:    qv1.ComponentMonitoringReady,
 					Status:  metav1.ConditionTrue,
 					Reason:  qv1.ConditionReasonComponentReady,
 					Message: "ServiceMonitor and PrometheusRules created",
 				},
+				{
+					Type:    qv1.ComponentPostgresBackupReady,
+					Status:  metav1.ConditionTrue,
+					Reason:  qv1.ConditionReasonBackupNotConfigured,
+					Message: "Backups of postgres not configured",
+				},
+				{
+					Type:    qv1.ComponentClairPostgresBackupReady,
+					Status:  metav1.ConditionTrue,
+					Reason:  qv1.ConditionReasonBackupNotConfigured,
+					Message: "Backups of clairpostgres not configured",
+				},
 				{
 					Type:    qv1.ComponentPostgresReady,
 					Status:  metav1.ConditionTrue,
@@ -1110,12 +1200,42 @@ func TestEvaluate(t *testing.T) {
 					Reason:  qv1.ConditionReasonComponentReady,
 					Message: "Route admitted",
 				},
+				{
+					Type:    qv1.ComponentIngressReady,
+					Status:  metav1.ConditionTrue,
+					Reason:  qv1.ConditionReasonComponentUnmanaged,
+					Message: "Ingress not managed by the operator",
+				},
+				{
+					Type:    qv1.ComponentGatewayReady,
+					Status:  metav1.ConditionTrue,
+					Reason:  qv1.ConditionReasonComponentUnmanaged,
+					Message: "Gateway routes not managed by the operator",
+				},
+				{
+					Type:    qv1.ComponentBuilderReady,
+					Status:  metav1.ConditionTrue,
+					Reason:  qv1.ConditionReasonComponentUnmanaged,
+					Message: "Builder not managed by the operator",
+				},
 				{
 					Type:    qv1.ComponentMonitoringReady,
 					Status:  metav1.ConditionTrue,
 					Reason:  qv1.ConditionReasonComponentReady,
 					Message: "ServiceMonitor and PrometheusRules created",
 				},
+				{
+					Type:    qv1.ComponentPostgresBackupReady,
+					Status:  metav1.ConditionTrue,
+					Reason:  qv1.ConditionReasonBackupNotConfigured,
+					Message: "Backups of postgres not configured",
+				},
+				{
+					Type:    qv1.ComponentClairPostgresBackupReady,
+					Status:  metav1.ConditionTrue,
+					Reason:  qv1.ConditionReasonBackupNotConfigured,
+					Message: "Backups of clairpostgres not configured",
+				},
 				{
 					Type:    qv1.ComponentPostgresReady,
 					Status:  metav1.ConditionTrue,
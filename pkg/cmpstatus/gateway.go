@@ -0,0 +1,153 @@
+package cmpstatus
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	qv1 "github.com/quay/quay-operator/apis/quay/v1"
+)
+
+// Gateway checks the status of the Gateway API routes of a quay registry.
+type Gateway struct {
+	Client client.Client
+}
+
+// Name returns the component name this entity checks for health.
+func (g *Gateway) Name() string {
+	return "gateway"
+}
+
+// Check verifies if the managed route for the quay app has been accepted by its parent
+// Gateway and if its backend references have been resolved. The route kind depends on the
+// TLS termination mode: HTTPRoute when the Gateway terminates TLS (edge) or TLSRoute when quay
+// serves its own certificate (passthrough).
+func (g *Gateway) Check(ctx context.Context, reg qv1.QuayRegistry) (qv1.Condition, error) {
+	var zero qv1.Condition
+
+	if !qv1.ComponentIsManaged(reg.Spec.Components, qv1.ComponentGateway) {
+		return qv1.Condition{
+			Type:           qv1.ComponentGatewayReady,
+			Status:         metav1.ConditionTrue,
+			Reason:         qv1.ConditionReasonComponentUnmanaged,
+			Message:        "Gateway routes not managed by the operator",
+			LastUpdateTime: metav1.NewTime(time.Now()),
+		}, nil
+	}
+
+	gvk := schema.GroupVersionKind{
+		Group:   "gateway.networking.k8s.io",
+		Version: "v1",
+		Kind:    "HTTPRoute",
+	}
+	passthrough := !qv1.ComponentIsManaged(reg.Spec.Components, qv1.ComponentTLS) ||
+		qv1.TLSIssuedByCertManager(&reg)
+	if !passthrough {
+		issued, err := operatorIssuedTLS(ctx, g.Client, reg)
+		if err != nil {
+			return zero, err
+		}
+		passthrough = issued
+	}
+
+	label := "quay-app-httproute"
+	if passthrough {
+		gvk.Version = "v1alpha2"
+		gvk.Kind = "TLSRoute"
+		label = "quay-app-tlsroute"
+	}
+
+	var list unstructured.UnstructuredList
+	list.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+	if err := g.Client.List(ctx, &list, client.InNamespace(reg.Namespace)); err != nil {
+		return zero, err
+	}
+
+	for i := range list.Items {
+		rt := &list.Items[i]
+		if !qv1.Owns(reg, rt) {
+			continue
+		}
+
+		if rt.GetLabels()["quay-component"] != label {
+			continue
+		}
+
+		parents, _, _ := unstructured.NestedSlice(rt.Object, "status", "parents")
+		if len(parents) == 0 {
+			return qv1.Condition{
+				Type:           qv1.ComponentGatewayReady,
+				Status:         metav1.ConditionFalse,
+				Reason:         qv1.ConditionReasonComponentNotReady,
+				Message:        fmt.Sprintf("%s not attached to any Gateway", gvk.Kind),
+				LastUpdateTime: metav1.NewTime(time.Now()),
+			}, nil
+		}
+
+		for _, parent := range parents {
+			pmap, ok := parent.(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			conds, _, _ := unstructured.NestedSlice(pmap, "conditions")
+			for _, ctype := range []string{"Accepted", "ResolvedRefs"} {
+				status, msg := g.routeCondition(conds, ctype)
+				if status == string(metav1.ConditionTrue) {
+					continue
+				}
+
+				return qv1.Condition{
+					Type:   qv1.ComponentGatewayReady,
+					Status: metav1.ConditionFalse,
+					Reason: qv1.ConditionReasonComponentNotReady,
+					Message: fmt.Sprintf(
+						"%s condition %s not true: %s", gvk.Kind, ctype, msg,
+					),
+					LastUpdateTime: metav1.NewTime(time.Now()),
+				}, nil
+			}
+		}
+
+		return qv1.Condition{
+			Type:           qv1.ComponentGatewayReady,
+			Status:         metav1.ConditionTrue,
+			Reason:         qv1.ConditionReasonComponentReady,
+			Message:        fmt.Sprintf("%s accepted by Gateway", gvk.Kind),
+			LastUpdateTime: metav1.NewTime(time.Now()),
+		}, nil
+	}
+
+	return qv1.Condition{
+		Type:           qv1.ComponentGatewayReady,
+		Status:         metav1.ConditionFalse,
+		Reason:         qv1.ConditionReasonComponentNotReady,
+		Message:        fmt.Sprintf("%s not found", gvk.Kind),
+		LastUpdateTime: metav1.NewTime(time.Now()),
+	}, nil
+}
+
+// routeCondition looks for the condition of the provided type among the conditions reported
+// by a route parent. Returns the condition status and message, empty strings if not found.
+func (g *Gateway) routeCondition(conds []interface{}, ctype string) (string, string) {
+	for _, cond := range conds {
+		cmap, ok := cond.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		if cmap["type"] != ctype {
+			continue
+		}
+
+		status, _ := cmap["status"].(string)
+		msg, _ := cmap["message"].(string)
+		return status, msg
+	}
+	return "", "condition not reported"
+}
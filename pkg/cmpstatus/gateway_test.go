@@ -0,0 +1,241 @@
+package cmpstatus
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	qv1 "github.com/quay/quay-operator/apis/quay/v1"
+)
+
+func newUnstructuredGatewayRoute(
+	kind, version, label string, owned bool, conds ...map[string]interface{},
+) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   "gateway.networking.k8s.io",
+		Version: version,
+		Kind:    kind,
+	})
+	obj.SetName("registry-quay")
+	obj.SetLabels(map[string]string{"quay-component": label})
+	if owned {
+		obj.SetOwnerReferences([]metav1.OwnerReference{
+			{
+				Kind:       "QuayRegistry",
+				Name:       "registry",
+				APIVersion: "quay.redhat.com/v1",
+				UID:        "uid",
+			},
+		})
+	}
+	if len(conds) > 0 {
+		var list []interface{}
+		for _, cond := range conds {
+			list = append(list, cond)
+		}
+		parent := map[string]interface{}{"conditions": list}
+		_ = unstructured.SetNestedSlice(obj.Object, []interface{}{parent}, "status", "parents")
+	}
+	return obj
+}
+
+func TestGatewayCheck(t *testing.T) {
+	edge := qv1.QuayRegistry{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "registry",
+			UID:  "uid",
+		},
+		Spec: qv1.QuayRegistrySpec{
+			Components: []qv1.Component{
+				{Kind: qv1.ComponentGateway, Managed: true},
+				{Kind: qv1.ComponentTLS, Managed: true},
+			},
+		},
+	}
+
+	passthrough := *edge.DeepCopy()
+	passthrough.Spec.Components[1].Managed = false
+
+	accepted := map[string]interface{}{"type": "Accepted", "status": "True"}
+	resolved := map[string]interface{}{"type": "ResolvedRefs", "status": "True"}
+
+	for _, tt := range []struct {
+		name string
+		quay qv1.QuayRegistry
+		objs []client.Object
+		cond qv1.Condition
+	}{
+		{
+			name: "unmanaged",
+			quay: qv1.QuayRegistry{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "registry",
+					UID:  "uid",
+				},
+			},
+			cond: qv1.Condition{
+				Type:    qv1.ComponentGatewayReady,
+				Status:  metav1.ConditionTrue,
+				Reason:  qv1.ConditionReasonComponentUnmanaged,
+				Message: "Gateway routes not managed by the operator",
+			},
+		},
+		{
+			name: "not found",
+			quay: edge,
+			objs: []client.Object{
+				newUnstructuredGatewayRoute("HTTPRoute", "v1", "quay-app-httproute", false),
+			},
+			cond: qv1.Condition{
+				Type:    qv1.ComponentGatewayReady,
+				Status:  metav1.ConditionFalse,
+				Reason:  qv1.ConditionReasonComponentNotReady,
+				Message: "HTTPRoute not found",
+			},
+		},
+		{
+			name: "not attached",
+			quay: edge,
+			objs: []client.Object{
+				newUnstructuredGatewayRoute("HTTPRoute", "v1", "quay-app-httproute", true),
+			},
+			cond: qv1.Condition{
+				Type:    qv1.ComponentGatewayReady,
+				Status:  metav1.ConditionFalse,
+				Reason:  qv1.ConditionReasonComponentNotReady,
+				Message: "HTTPRoute not attached to any Gateway",
+			},
+		},
+		{
+			name: "not accepted",
+			quay: edge,
+			objs: []client.Object{
+				newUnstructuredGatewayRoute(
+					"HTTPRoute", "v1", "quay-app-httproute", true,
+					map[string]interface{}{
+						"type":    "Accepted",
+						"status":  "False",
+						"message": "no matching listener",
+					},
+					resolved,
+				),
+			},
+			cond: qv1.Condition{
+				Type:    qv1.ComponentGatewayReady,
+				Status:  metav1.ConditionFalse,
+				Reason:  qv1.ConditionReasonComponentNotReady,
+				Message: "HTTPRoute condition Accepted not true: no matching listener",
+			},
+		},
+		{
+			name: "refs not resolved",
+			quay: edge,
+			objs: []client.Object{
+				newUnstructuredGatewayRoute(
+					"HTTPRoute", "v1", "quay-app-httproute", true, accepted,
+				),
+			},
+			cond: qv1.Condition{
+				Type:    qv1.ComponentGatewayReady,
+				Status:  metav1.ConditionFalse,
+				Reason:  qv1.ConditionReasonComponentNotReady,
+				Message: "HTTPRoute condition ResolvedRefs not true: condition not reported",
+			},
+		},
+		{
+			name: "edge accepted",
+			quay: edge,
+			objs: []client.Object{
+				newUnstructuredGatewayRoute(
+					"HTTPRoute", "v1", "quay-app-httproute", true, accepted, resolved,
+				),
+			},
+			cond: qv1.Condition{
+				Type:    qv1.ComponentGatewayReady,
+				Status:  metav1.ConditionTrue,
+				Reason:  qv1.ConditionReasonComponentReady,
+				Message: "HTTPRoute accepted by Gateway",
+			},
+		},
+		{
+			name: "passthrough accepted",
+			quay: passthrough,
+			objs: []client.Object{
+				newUnstructuredGatewayRoute(
+					"TLSRoute", "v1alpha2", "quay-app-tlsroute", true, accepted, resolved,
+				),
+			},
+			cond: qv1.Condition{
+				Type:    qv1.ComponentGatewayReady,
+				Status:  metav1.ConditionTrue,
+				Reason:  qv1.ConditionReasonComponentReady,
+				Message: "TLSRoute accepted by Gateway",
+			},
+		},
+		{
+			name: "operator issued certificate accepted",
+			quay: edge,
+			objs: []client.Object{
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "registry-quay-operator-tls",
+						OwnerReferences: []metav1.OwnerReference{
+							{
+								Kind:       "QuayRegistry",
+								Name:       "registry",
+								APIVersion: "quay.redhat.com/v1",
+								UID:        "uid",
+							},
+						},
+					},
+				},
+				newUnstructuredGatewayRoute(
+					"TLSRoute", "v1alpha2", "quay-app-tlsroute", true, accepted, resolved,
+				),
+			},
+			cond: qv1.Condition{
+				Type:    qv1.ComponentGatewayReady,
+				Status:  metav1.ConditionTrue,
+				Reason:  qv1.ConditionReasonComponentReady,
+				Message: "TLSRoute accepted by Gateway",
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+
+			scheme := runtime.NewScheme()
+			if err := corev1.AddToScheme(scheme); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			builder := fake.NewClientBuilder()
+			cli := builder.WithObjects(tt.objs...).WithScheme(scheme).Build()
+			gateway := Gateway{cli}
+
+			cond, err := gateway.Check(ctx, tt.quay)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if cond.LastUpdateTime.IsZero() {
+				t.Errorf("unexpected zeroed last update time for condition")
+			}
+
+			cond.LastUpdateTime = metav1.NewTime(time.Time{})
+			if !reflect.DeepEqual(tt.cond, cond) {
+				t.Errorf("expecting %+v, received %+v", tt.cond, cond)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package cmpstatus
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	qv1 "github.com/quay/quay-operator/apis/quay/v1"
+)
+
+// Ingress checks a quay registry ingress status.
+type Ingress struct {
+	Client client.Client
+}
+
+// Name returns the component name this entity checks for health.
+func (i *Ingress) Name() string {
+	return "ingress"
+}
+
+// Check verifies if the managed ingress for a quay registry has been admitted by an ingress
+// controller, i.e. if a load balancer address has been assigned to it. Expects to find the
+// quay app ingress owned by the registry if ingress component is managed.
+func (i *Ingress) Check(ctx context.Context, reg qv1.QuayRegistry) (qv1.Condition, error) {
+	var zero qv1.Condition
+
+	if !qv1.ComponentIsManaged(reg.Spec.Components, qv1.ComponentIngress) {
+		return qv1.Condition{
+			Type:           qv1.ComponentIngressReady,
+			Status:         metav1.ConditionTrue,
+			Reason:         qv1.ConditionReasonComponentUnmanaged,
+			Message:        "Ingress not managed by the operator",
+			LastUpdateTime: metav1.NewTime(time.Now()),
+		}, nil
+	}
+
+	var list networkingv1.IngressList
+	if err := i.Client.List(ctx, &list, client.InNamespace(reg.Namespace)); err != nil {
+		return zero, err
+	}
+
+	for _, ing := range list.Items {
+		if !qv1.Owns(reg, &ing) {
+			continue
+		}
+
+		if ing.Labels["quay-component"] != "quay-app-ingress" {
+			continue
+		}
+
+		var address string
+		for _, lb := range ing.Status.LoadBalancer.Ingress {
+			if lb.IP != "" {
+				address = lb.IP
+			} else {
+				address = lb.Hostname
+			}
+			if address != "" {
+				break
+			}
+		}
+
+		if address == "" {
+			return qv1.Condition{
+				Type:           qv1.ComponentIngressReady,
+				Status:         metav1.ConditionFalse,
+				Reason:         qv1.ConditionReasonComponentNotReady,
+				Message:        "Ingress not admitted, no load balancer address assigned",
+				LastUpdateTime: metav1.NewTime(time.Now()),
+			}, nil
+		}
+
+		return qv1.Condition{
+			Type:           qv1.ComponentIngressReady,
+			Status:         metav1.ConditionTrue,
+			Reason:         qv1.ConditionReasonComponentReady,
+			Message:        fmt.Sprintf("Ingress admitted, load balancer address %s", address),
+			LastUpdateTime: metav1.NewTime(time.Now()),
+		}, nil
+	}
+
+	return qv1.Condition{
+		Type:           qv1.ComponentIngressReady,
+		Status:         metav1.ConditionFalse,
+		Reason:         qv1.ConditionReasonComponentNotReady,
+		Message:        "Ingress not found",
+		LastUpdateTime: metav1.NewTime(time.Now()),
+	}, nil
+}
@@ -0,0 +1,188 @@
+package cmpstatus
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	qv1 "github.com/quay/quay-operator/apis/quay/v1"
+)
+
+func TestIngressCheck(t *testing.T) {
+	quay := qv1.QuayRegistry{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "registry",
+			UID:  "uid",
+		},
+		Spec: qv1.QuayRegistrySpec{
+			Components: []qv1.Component{
+				{
+					Kind:    qv1.ComponentIngress,
+					Managed: true,
+				},
+			},
+		},
+	}
+
+	owned := metav1.ObjectMeta{
+		Name: "registry-quay",
+		Labels: map[string]string{
+			"quay-component": "quay-app-ingress",
+		},
+		OwnerReferences: []metav1.OwnerReference{
+			{
+				Kind:       "QuayRegistry",
+				Name:       "registry",
+				APIVersion: "quay.redhat.com/v1",
+				UID:        "uid",
+			},
+		},
+	}
+
+	for _, tt := range []struct {
+		name string
+		quay qv1.QuayRegistry
+		objs []client.Object
+		cond qv1.Condition
+	}{
+		{
+			name: "unmanaged",
+			quay: qv1.QuayRegistry{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "registry",
+					UID:  "uid",
+				},
+			},
+			cond: qv1.Condition{
+				Type:    qv1.ComponentIngressReady,
+				Status:  metav1.ConditionTrue,
+				Reason:  qv1.ConditionReasonComponentUnmanaged,
+				Message: "Ingress not managed by the operator",
+			},
+		},
+		{
+			name: "managed but not found",
+			quay: quay,
+			cond: qv1.Condition{
+				Type:    qv1.ComponentIngressReady,
+				Status:  metav1.ConditionFalse,
+				Reason:  qv1.ConditionReasonComponentNotReady,
+				Message: "Ingress not found",
+			},
+		},
+		{
+			name: "managed but not owned",
+			quay: quay,
+			objs: []client.Object{
+				&networkingv1.Ingress{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "some-ingress",
+						Labels: map[string]string{
+							"quay-component": "quay-app-ingress",
+						},
+					},
+				},
+			},
+			cond: qv1.Condition{
+				Type:    qv1.ComponentIngressReady,
+				Status:  metav1.ConditionFalse,
+				Reason:  qv1.ConditionReasonComponentNotReady,
+				Message: "Ingress not found",
+			},
+		},
+		{
+			name: "no load balancer address",
+			quay: quay,
+			objs: []client.Object{
+				&networkingv1.Ingress{
+					ObjectMeta: owned,
+				},
+			},
+			cond: qv1.Condition{
+				Type:    qv1.ComponentIngressReady,
+				Status:  metav1.ConditionFalse,
+				Reason:  qv1.ConditionReasonComponentNotReady,
+				Message: "Ingress not admitted, no load balancer address assigned",
+			},
+		},
+		{
+			name: "admitted with ip",
+			quay: quay,
+			objs: []client.Object{
+				&networkingv1.Ingress{
+					ObjectMeta: owned,
+					Status: networkingv1.IngressStatus{
+						LoadBalancer: networkingv1.IngressLoadBalancerStatus{
+							Ingress: []networkingv1.IngressLoadBalancerIngress{
+								{IP: "10.0.0.1"},
+							},
+						},
+					},
+				},
+			},
+			cond: qv1.Condition{
+				Type:    qv1.ComponentIngressReady,
+				Status:  metav1.ConditionTrue,
+				Reason:  qv1.ConditionReasonComponentReady,
+				Message: "Ingress admitted, load balancer address 10.0.0.1",
+			},
+		},
+		{
+			name: "admitted with hostname",
+			quay: quay,
+			objs: []client.Object{
+				&networkingv1.Ingress{
+					ObjectMeta: owned,
+					Status: networkingv1.IngressStatus{
+						LoadBalancer: networkingv1.IngressLoadBalancerStatus{
+							Ingress: []networkingv1.IngressLoadBalancerIngress{
+								{Hostname: "lb.example.com"},
+							},
+						},
+					},
+				},
+			},
+			cond: qv1.Condition{
+				Type:    qv1.ComponentIngressReady,
+				Status:  metav1.ConditionTrue,
+				Reason:  qv1.ConditionReasonComponentReady,
+				Message: "Ingress admitted, load balancer address lb.example.com",
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+
+			scheme := runtime.NewScheme()
+			if err := networkingv1.AddToScheme(scheme); err != nil {
+				t.Fatalf("unexpected error adding networking to scheme: %s", err)
+			}
+
+			builder := fake.NewClientBuilder()
+			cli := builder.WithObjects(tt.objs...).WithScheme(scheme).Build()
+			ingress := Ingress{cli}
+
+			cond, err := ingress.Check(ctx, tt.quay)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if cond.LastUpdateTime.IsZero() {
+				t.Errorf("unexpected zeroed last update time for condition")
+			}
+
+			cond.LastUpdateTime = metav1.NewTime(time.Time{})
+			if !reflect.DeepEqual(tt.cond, cond) {
+				t.Errorf("expecting %+v, received %+v", tt.cond, cond)
+			}
+		})
+	}
+}
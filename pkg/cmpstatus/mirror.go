@@ -71,15 +71,21 @@ func (m *Mirror) Check(ctx context.Context, reg qv1.QuayRegistry) (qv1.Condition
 
 	// users are able to override the number of replicas. if they do override it to zero
 	// we expect zero replicas to be running.
+	// the mirror is also scaled down while the registry is under maintenance.
 	replicas := qv1.GetReplicasOverrideForComponent(&reg, qv1.ComponentMirror)
 	scaleddown := replicas != nil && *replicas == 0
-	if scaleddown {
+	if scaleddown || qv1.MaintenanceEnabled(&reg) {
 		if dep.Status.AvailableReplicas == 0 {
+			message := "Mirror manually scaled down"
+			if qv1.MaintenanceEnabled(&reg) {
+				message = "Mirror paused for maintenance"
+			}
+
 			return qv1.Condition{
 				Type:           qv1.ComponentMirrorReady,
 				Reason:         qv1.ConditionReasonComponentReady,
 				Status:         metav1.ConditionTrue,
-				Message:        "Mirror manually scaled down",
+				Message:        message,
 				LastUpdateTime: metav1.NewTime(time.Now()),
 			}, nil
 		}
@@ -197,6 +197,47 @@ func TestMirrorCheck(t *testing.T) {
 				Message: "Mirror manually scaled down",
 			},
 		},
+		{
+			name: "deployment paused for maintenance",
+			quay: qv1.QuayRegistry{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "registry",
+					UID:  "uid",
+				},
+				Spec: qv1.QuayRegistrySpec{
+					Components: []qv1.Component{
+						{
+							Kind:    qv1.ComponentMirror,
+							Managed: true,
+						},
+					},
+					Maintenance: &qv1.Maintenance{
+						Mode: qv1.MaintenanceModeReadOnly,
+					},
+				},
+			},
+			objs: []client.Object{
+				&appsv1.Deployment{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "registry-quay-mirror",
+						OwnerReferences: []metav1.OwnerReference{
+							{
+								Kind:       "QuayRegistry",
+								Name:       "registry",
+								APIVersion: "quay.redhat.com/v1",
+								UID:        "uid",
+							},
+						},
+					},
+				},
+			},
+			cond: qv1.Condition{
+				Type:    qv1.ComponentMirrorReady,
+				Status:  metav1.ConditionTrue,
+				Reason:  qv1.ConditionReasonComponentReady,
+				Message: "Mirror paused for maintenance",
+			},
+		},
 	} {
 		t.Run(tt.name, func(t *testing.T) {
 			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
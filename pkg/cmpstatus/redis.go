@@ -3,8 +3,10 @@ package cmpstatus
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/go-redis/redis/v8"
 	appsv1 "k8s.io/api/apps/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -14,10 +16,29 @@ import (
 	qv1 "github.com/quay/quay-operator/apis/quay/v1"
 )
 
+// redisSentinelMaster is the name the sentinels of a highly available redis monitor the
+// master under.
+const redisSentinelMaster = "quay"
+
+// sentinelStatus is the state of the redis master as reported by a sentinel.
+type sentinelStatus struct {
+	// master is the address of the current master.
+	master string
+	// flags are the flags the sentinel set on the master, e.g. "master,o_down".
+	flags string
+	// quorum is nil if enough sentinels are reachable to authorize a failover.
+	quorum error
+}
+
+// sentinelProbe queries the sentinel listening on addr for the provided master.
+type sentinelProbe func(ctx context.Context, addr, master string) (sentinelStatus, error)
+
 // Redis checks a quay registry Redis component status.
 type Redis struct {
 	Client client.Client
 	deploy deploy
+	// probe queries the sentinels of a highly available redis, defaults to probeSentinel.
+	probe sentinelProbe
 }
 
 // Name returns the component name this entity checks for health.
@@ -45,6 +66,10 @@ func (r *Redis) Check(ctx context.Context, reg qv1.QuayRegistry) (qv1.Condition,
 		Name:      fmt.Sprintf("%s-quay-redis", reg.Name),
 	}
 
+	if qv1.RedisHighAvailabilityEnabled(&reg) {
+		return r.checkHighAvailability(ctx, reg, nsn)
+	}
+
 	var dep appsv1.Deployment
 	if err := r.Client.Get(ctx, nsn, &dep); err != nil {
 		if errors.IsNotFound(err) {
@@ -73,3 +98,132 @@ func (r *Redis) Check(ctx context.Context, reg qv1.QuayRegistry) (qv1.Condition,
 	cond.Type = qv1.ComponentRedisReady
 	return cond, nil
 }
+
+// checkHighAvailability verifies the redis statefulset has enough replicas ready for the
+// sentinels to reach a quorum, and that the sentinels agree on a healthy master.
+func (r *Redis) checkHighAvailability(
+	ctx context.Context, reg qv1.QuayRegistry, nsn types.NamespacedName,
+) (qv1.Condition, error) {
+	var zero qv1.Condition
+
+	var sts appsv1.StatefulSet
+	if err := r.Client.Get(ctx, nsn, &sts); err != nil {
+		if errors.IsNotFound(err) {
+			return qv1.Condition{
+				Type:           qv1.ComponentRedisReady,
+				Status:         metav1.ConditionFalse,
+				Reason:         qv1.ConditionReasonComponentNotReady,
+				Message:        "Redis statefulset not found",
+				LastUpdateTime: metav1.NewTime(time.Now()),
+			}, nil
+		}
+		return zero, err
+	}
+
+	if !qv1.Owns(reg, &sts) {
+		return qv1.Condition{
+			Type:           qv1.ComponentRedisReady,
+			Status:         metav1.ConditionFalse,
+			Reason:         qv1.ConditionReasonComponentNotReady,
+			Message:        "Redis statefulset not owned by QuayRegistry",
+			LastUpdateTime: metav1.NewTime(time.Now()),
+		}, nil
+	}
+
+	replicas := qv1.RedisReplicasFor(&reg)
+	quorum := qv1.RedisSentinelQuorumFor(&reg)
+	if sts.Status.ReadyReplicas < quorum {
+		return qv1.Condition{
+			Type:   qv1.ComponentRedisReady,
+			Status: metav1.ConditionFalse,
+			Reason: qv1.ConditionReasonComponentNotReady,
+			Message: fmt.Sprintf(
+				"StatefulSet %s has %d of %d replicas ready, sentinel quorum requires %d",
+				sts.Name, sts.Status.ReadyReplicas, replicas, quorum,
+			),
+			LastUpdateTime: metav1.NewTime(time.Now()),
+		}, nil
+	}
+
+	probe := r.probe
+	if probe == nil {
+		probe = probeSentinel
+	}
+
+	addr := fmt.Sprintf("%s-sentinel.%s.svc:26379", nsn.Name, nsn.Namespace)
+	status, err := probe(ctx, addr, redisSentinelMaster)
+	if err != nil {
+		return qv1.Condition{
+			Type:           qv1.ComponentRedisReady,
+			Status:         metav1.ConditionFalse,
+			Reason:         qv1.ConditionReasonComponentNotReady,
+			Message:        fmt.Sprintf("Unable to query redis sentinels: %s", err),
+			LastUpdateTime: metav1.NewTime(time.Now()),
+		}, nil
+	}
+
+	for _, flag := range strings.Split(status.flags, ",") {
+		if flag != "s_down" && flag != "o_down" {
+			continue
+		}
+		return qv1.Condition{
+			Type:           qv1.ComponentRedisReady,
+			Status:         metav1.ConditionFalse,
+			Reason:         qv1.ConditionReasonComponentNotReady,
+			Message:        fmt.Sprintf("Redis master %s is down", status.master),
+			LastUpdateTime: metav1.NewTime(time.Now()),
+		}, nil
+	}
+
+	if status.quorum != nil {
+		return qv1.Condition{
+			Type:           qv1.ComponentRedisReady,
+			Status:         metav1.ConditionFalse,
+			Reason:         qv1.ConditionReasonComponentNotReady,
+			Message:        fmt.Sprintf("Sentinel quorum not reachable: %s", status.quorum),
+			LastUpdateTime: metav1.NewTime(time.Now()),
+		}, nil
+	}
+
+	return qv1.Condition{
+		Type:   qv1.ComponentRedisReady,
+		Status: metav1.ConditionTrue,
+		Reason: qv1.ConditionReasonComponentReady,
+		Message: fmt.Sprintf(
+			"Redis master %s healthy, %d of %d replicas ready",
+			status.master, sts.Status.ReadyReplicas, replicas,
+		),
+		LastUpdateTime: metav1.NewTime(time.Now()),
+	}, nil
+}
+
+// probeSentinel asks the sentinel listening on addr for the state of the provided master and
+// whether the sentinels can reach a quorum.
+func probeSentinel(ctx context.Context, addr, master string) (sentinelStatus, error) {
+	var zero sentinelStatus
+
+	sentinel := redis.NewSentinelClient(
+		&redis.Options{
+			Addr:        addr,
+			DialTimeout: 5 * time.Second,
+			ReadTimeout: 5 * time.Second,
+		},
+	)
+	defer sentinel.Close()
+
+	info, err := sentinel.Master(ctx, master).Result()
+	if err != nil {
+		return zero, err
+	}
+
+	status := sentinelStatus{
+		master: fmt.Sprintf("%s:%s", info["ip"], info["port"]),
+		flags:  info["flags"],
+	}
+
+	// CKQUORUM replies with an error when the quorum can't be reached.
+	if _, err := sentinel.CkQuorum(ctx, master).Result(); err != nil {
+		status.quorum = err
+	}
+	return status, nil
+}
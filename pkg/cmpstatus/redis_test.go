@@ -2,6 +2,7 @@ package cmpstatus
 
 import (
 	"context"
+	"fmt"
 	"reflect"
 	"testing"
 	"time"
@@ -9,6 +10,7 @@ import (
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
@@ -172,3 +174,176 @@ func TestRedisCheck(t *testing.T) {
 		})
 	}
 }
+
+func TestRedisCheckHighAvailability(t *testing.T) {
+	quay := qv1.QuayRegistry{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "registry",
+			Namespace: "ns",
+			UID:       "uid",
+		},
+		Spec: qv1.QuayRegistrySpec{
+			Components: []qv1.Component{
+				{
+					Kind:    qv1.ComponentRedis,
+					Managed: true,
+					Overrides: &qv1.Override{
+						HighAvailability: ptr.To(true),
+					},
+				},
+			},
+		},
+	}
+
+	statefulset := func(ready int32) *appsv1.StatefulSet {
+		return &appsv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "registry-quay-redis",
+				Namespace: "ns",
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						Kind:       "QuayRegistry",
+						Name:       "registry",
+						APIVersion: "quay.redhat.com/v1",
+						UID:        "uid",
+					},
+				},
+			},
+			Status: appsv1.StatefulSetStatus{
+				ReadyReplicas: ready,
+			},
+		}
+	}
+
+	for _, tt := range []struct {
+		name   string
+		objs   []client.Object
+		status sentinelStatus
+		err    error
+		cond   qv1.Condition
+	}{
+		{
+			name: "statefulset not found",
+			cond: qv1.Condition{
+				Type:    qv1.ComponentRedisReady,
+				Status:  metav1.ConditionFalse,
+				Reason:  qv1.ConditionReasonComponentNotReady,
+				Message: "Redis statefulset not found",
+			},
+		},
+		{
+			name: "statefulset not owned",
+			objs: []client.Object{
+				&appsv1.StatefulSet{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "registry-quay-redis",
+						Namespace: "ns",
+					},
+				},
+			},
+			cond: qv1.Condition{
+				Type:    qv1.ComponentRedisReady,
+				Status:  metav1.ConditionFalse,
+				Reason:  qv1.ConditionReasonComponentNotReady,
+				Message: "Redis statefulset not owned by QuayRegistry",
+			},
+		},
+		{
+			name: "not enough replicas for quorum",
+			objs: []client.Object{statefulset(1)},
+			cond: qv1.Condition{
+				Type:    qv1.ComponentRedisReady,
+				Status:  metav1.ConditionFalse,
+				Reason:  qv1.ConditionReasonComponentNotReady,
+				Message: "StatefulSet registry-quay-redis has 1 of 3 replicas ready, sentinel quorum requires 2",
+			},
+		},
+		{
+			name: "sentinels unreachable",
+			objs: []client.Object{statefulset(3)},
+			err:  fmt.Errorf("connection refused"),
+			cond: qv1.Condition{
+				Type:    qv1.ComponentRedisReady,
+				Status:  metav1.ConditionFalse,
+				Reason:  qv1.ConditionReasonComponentNotReady,
+				Message: "Unable to query redis sentinels: connection refused",
+			},
+		},
+		{
+			name: "master down",
+			objs: []client.Object{statefulset(2)},
+			status: sentinelStatus{
+				master: "registry-quay-redis-0.registry-quay-redis-headless:6379",
+				flags:  "master,s_down,o_down",
+			},
+			cond: qv1.Condition{
+				Type:    qv1.ComponentRedisReady,
+				Status:  metav1.ConditionFalse,
+				Reason:  qv1.ConditionReasonComponentNotReady,
+				Message: "Redis master registry-quay-redis-0.registry-quay-redis-headless:6379 is down",
+			},
+		},
+		{
+			name: "quorum not reachable",
+			objs: []client.Object{statefulset(2)},
+			status: sentinelStatus{
+				master: "registry-quay-redis-1.registry-quay-redis-headless:6379",
+				flags:  "master",
+				quorum: fmt.Errorf("NOQUORUM 1 usable Sentinels"),
+			},
+			cond: qv1.Condition{
+				Type:    qv1.ComponentRedisReady,
+				Status:  metav1.ConditionFalse,
+				Reason:  qv1.ConditionReasonComponentNotReady,
+				Message: "Sentinel quorum not reachable: NOQUORUM 1 usable Sentinels",
+			},
+		},
+		{
+			name: "healthy",
+			objs: []client.Object{statefulset(3)},
+			status: sentinelStatus{
+				master: "registry-quay-redis-1.registry-quay-redis-headless:6379",
+				flags:  "master",
+			},
+			cond: qv1.Condition{
+				Type:    qv1.ComponentRedisReady,
+				Status:  metav1.ConditionTrue,
+				Reason:  qv1.ConditionReasonComponentReady,
+				Message: "Redis master registry-quay-redis-1.registry-quay-redis-headless:6379 healthy, 3 of 3 replicas ready",
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+
+			cli := fake.NewClientBuilder().WithObjects(tt.objs...).Build()
+			redis := Redis{
+				Client: cli,
+				probe: func(_ context.Context, addr, master string) (sentinelStatus, error) {
+					if addr != "registry-quay-redis-sentinel.ns.svc:26379" {
+						t.Errorf("unexpected sentinel address %s", addr)
+					}
+					if master != "quay" {
+						t.Errorf("unexpected master name %s", master)
+					}
+					return tt.status, tt.err
+				},
+			}
+
+			cond, err := redis.Check(ctx, quay)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if cond.LastUpdateTime.IsZero() {
+				t.Errorf("unexpected zeroed last update time for condition")
+			}
+
+			cond.LastUpdateTime = metav1.NewTime(time.Time{})
+			if !reflect.DeepEqual(tt.cond, cond) {
+				t.Errorf("expecting %+v, received %+v", tt.cond, cond)
+			}
+		})
+	}
+}
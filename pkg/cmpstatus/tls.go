@@ -2,11 +2,16 @@ package cmpstatus
 
 import (
 	"context"
+	"fmt"
+	"os"
+	"strconv"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
@@ -14,9 +19,31 @@ import (
 	quaytls "github.com/quay/quay-operator/pkg/tls"
 )
 
+// DefaultTLSExpiryWarningDays is how many days before its expiration a certificate is reported
+// as expiring, unless overridden through the TLS_EXPIRY_WARNING_DAYS environment variable.
+const DefaultTLSExpiryWarningDays = 30
+
+// tlsExpiryWarningEnv is the environment variable the expiry warning window is read from.
+const tlsExpiryWarningEnv = "TLS_EXPIRY_WARNING_DAYS"
+
+// TLSExpiryWarningFromEnv returns how long before its expiration a certificate is reported as
+// expiring, as set in days through TLS_EXPIRY_WARNING_DAYS. Unset or invalid values fall back
+// to DefaultTLSExpiryWarningDays.
+func TLSExpiryWarningFromEnv() time.Duration {
+	days := DefaultTLSExpiryWarningDays
+	if val, ok := os.LookupEnv(tlsExpiryWarningEnv); ok {
+		if parsed, err := strconv.Atoi(val); err == nil && parsed >= 0 {
+			days = parsed
+		}
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
 // TLS checks a quay registry TLS status.
 type TLS struct {
 	Client client.Client
+	// ExpiryWarning is how long before its expiration a certificate is reported as expiring.
+	ExpiryWarning time.Duration
 }
 
 // Name returns the component name this entity checks for health.
@@ -26,8 +53,51 @@ func (t *TLS) Name() string {
 
 // Check verifies the status for a TLS component. If TLS is managed we expect not to find an entry
 // for ssl keys in the config bundle secret while if TLS is unmanaged we do expect to find this
-// entry.
+// entry. When the certificate is issued by cert-manager the readiness of the Certificate is
+// reported instead. Once the component is deemed ready the expiration of the served certificate
+// chain is verified, certificates expiring within ExpiryWarning are reported with a warning
+// reason while expired certificates render the component not ready.
 func (t *TLS) Check(ctx context.Context, reg qv1.QuayRegistry) (qv1.Condition, error) {
+	cond, err := t.check(ctx, reg)
+	if err != nil || cond.Status != metav1.ConditionTrue {
+		return cond, err
+	}
+
+	notAfter, err := CertificateNotAfter(ctx, t.Client, reg)
+	if err != nil {
+		return qv1.Condition{}, err
+	}
+
+	// certificates we can't see (e.g. the cluster wildcard certs) can't be verified.
+	if notAfter == nil {
+		return cond, nil
+	}
+
+	remaining := time.Until(*notAfter)
+	expiry := notAfter.UTC().Format(time.RFC3339)
+	switch {
+	case remaining <= 0:
+		return qv1.Condition{
+			Type:           qv1.ComponentTLSReady,
+			Status:         metav1.ConditionFalse,
+			Reason:         qv1.ConditionReasonComponentNotReady,
+			Message:        fmt.Sprintf("Certificate expired at %s", expiry),
+			LastUpdateTime: metav1.NewTime(time.Now()),
+		}, nil
+	case remaining <= t.ExpiryWarning:
+		return qv1.Condition{
+			Type:           qv1.ComponentTLSReady,
+			Status:         metav1.ConditionTrue,
+			Reason:         qv1.ConditionReasonCertificateExpiring,
+			Message:        fmt.Sprintf("%s, certificate expires at %s", cond.Message, expiry),
+			LastUpdateTime: metav1.NewTime(time.Now()),
+		}, nil
+	}
+	return cond, nil
+}
+
+// check verifies the TLS material is in place for the TLS component, regardless of its expiry.
+func (t *TLS) check(ctx context.Context, reg qv1.QuayRegistry) (qv1.Condition, error) {
 	var zero qv1.Condition
 
 	if reg.Spec.ConfigBundleSecret == "" {
@@ -108,6 +178,20 @@ func (t *TLS) Check(ctx context.Context, reg qv1.QuayRegistry) (qv1.Condition, e
 	_, hasCRT := secret.Data["ssl.cert"]
 	_, hasKey := secret.Data["ssl.key"]
 
+	// cert-manager mode: the certificate is requested from the issuer referenced by the user.
+	if qv1.TLSIssuedByCertManager(&reg) {
+		if hasCRT || hasKey {
+			return qv1.Condition{
+				Type:           qv1.ComponentTLSReady,
+				Status:         metav1.ConditionFalse,
+				Reason:         qv1.ConditionReasonComponentNotReady,
+				Message:        "tls component issuerRef and certs in configBundleSecret are mutually exclusive",
+				LastUpdateTime: metav1.NewTime(time.Now()),
+			}, nil
+		}
+		return t.checkCertificate(ctx, reg)
+	}
+
 	// if tls is managed we do not expect to find entries for ssl.key and ssl.cert in the
 	// config bundle secret.
 	if qv1.ComponentIsManaged(reg.Spec.Components, qv1.ComponentTLS) {
@@ -121,6 +205,20 @@ func (t *TLS) Check(ctx context.Context, reg qv1.QuayRegistry) (qv1.Condition, e
 			}, nil
 		}
 
+		issued, err := operatorIssuedTLS(ctx, t.Client, reg)
+		if err != nil {
+			return zero, err
+		}
+		if issued {
+			return qv1.Condition{
+				Type:           qv1.ComponentTLSReady,
+				Status:         metav1.ConditionTrue,
+				Reason:         qv1.ConditionReasonComponentReady,
+				Message:        "Using certificate issued by the operator CA",
+				LastUpdateTime: metav1.NewTime(time.Now()),
+			}, nil
+		}
+
 		return qv1.Condition{
 			Type:           qv1.ComponentTLSReady,
 			Status:         metav1.ConditionTrue,
@@ -148,3 +246,152 @@ func (t *TLS) Check(ctx context.Context, reg qv1.QuayRegistry) (qv1.Condition, e
 		LastUpdateTime: metav1.NewTime(time.Now()),
 	}, nil
 }
+
+// checkCertificate inspects the Ready condition of the cert-manager Certificate rendered for
+// the quay registry. Issuance failures reported by cert-manager are surfaced in the returned
+// condition message.
+func (t *TLS) checkCertificate(ctx context.Context, reg qv1.QuayRegistry) (qv1.Condition, error) {
+	var zero qv1.Condition
+
+	cert := &unstructured.Unstructured{}
+	cert.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   "cert-manager.io",
+		Version: "v1",
+		Kind:    "Certificate",
+	})
+
+	nsn := types.NamespacedName{
+		Namespace: reg.Namespace,
+		Name:      qv1.CertManagerTLSSecretName(&reg),
+	}
+	if err := t.Client.Get(ctx, nsn, cert); err != nil {
+		if errors.IsNotFound(err) {
+			return qv1.Condition{
+				Type:           qv1.ComponentTLSReady,
+				Status:         metav1.ConditionFalse,
+				Reason:         qv1.ConditionReasonComponentNotReady,
+				Message:        "Certificate not found",
+				LastUpdateTime: metav1.NewTime(time.Now()),
+			}, nil
+		}
+		return zero, err
+	}
+
+	conds, _, _ := unstructured.NestedSlice(cert.Object, "status", "conditions")
+	for _, c := range conds {
+		cond, ok := c.(map[string]interface{})
+		if !ok || cond["type"] != "Ready" {
+			continue
+		}
+
+		if cond["status"] != string(metav1.ConditionTrue) {
+			return qv1.Condition{
+				Type:           qv1.ComponentTLSReady,
+				Status:         metav1.ConditionFalse,
+				Reason:         qv1.ConditionReasonComponentNotReady,
+				Message:        fmt.Sprintf("Certificate not ready: %v", cond["message"]),
+				LastUpdateTime: metav1.NewTime(time.Now()),
+			}, nil
+		}
+
+		return qv1.Condition{
+			Type:           qv1.ComponentTLSReady,
+			Status:         metav1.ConditionTrue,
+			Reason:         qv1.ConditionReasonComponentReady,
+			Message:        "Using certificate issued by cert-manager",
+			LastUpdateTime: metav1.NewTime(time.Now()),
+		}, nil
+	}
+
+	return qv1.Condition{
+		Type:           qv1.ComponentTLSReady,
+		Status:         metav1.ConditionFalse,
+		Reason:         qv1.ConditionReasonComponentNotReady,
+		Message:        "Certificate issuance pending",
+		LastUpdateTime: metav1.NewTime(time.Now()),
+	}, nil
+}
+
+// operatorIssuedTLS returns true if the certificate served by quay has been issued by the
+// operator from its own CA. This happens when TLS is managed on clusters without the Route
+// API, in which case the operator persists the CA and the certificate in a managed secret.
+func operatorIssuedTLS(ctx context.Context, cli client.Client, reg qv1.QuayRegistry) (bool, error) {
+	if !qv1.ComponentIsManaged(reg.Spec.Components, qv1.ComponentTLS) ||
+		qv1.TLSIssuedByCertManager(&reg) {
+		return false, nil
+	}
+
+	nsn := types.NamespacedName{
+		Namespace: reg.Namespace,
+		Name:      qv1.OperatorTLSSecretNameFor(&reg),
+	}
+
+	var secret corev1.Secret
+	if err := cli.Get(ctx, nsn, &secret); err != nil {
+		if errors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return qv1.Owns(reg, &secret), nil
+}
+
+// CertificateNotAfter returns the expiration time of the certificate chain served for the quay
+// registry endpoint. The chain is read from wherever the certificate is provided: the external
+// secret referenced by the TLS component, the secret populated by cert-manager, the secret
+// managed by the operator or the config bundle. Returns nil if the chain is not available, for
+// instance when the cluster wildcard certs are in use or the chain can't be parsed.
+func CertificateNotAfter(ctx context.Context, cli client.Client, reg qv1.QuayRegistry) (*time.Time, error) {
+	chain, err := servingCertificate(ctx, cli, reg)
+	if err != nil || chain == nil {
+		return nil, err
+	}
+
+	notAfter, err := quaytls.NotAfter(chain)
+	if err != nil {
+		return nil, nil
+	}
+	return &notAfter, nil
+}
+
+// servingCertificate returns the PEM encoded certificate chain served for the quay registry
+// endpoint, nil is returned if the chain is not available.
+func servingCertificate(ctx context.Context, cli client.Client, reg qv1.QuayRegistry) ([]byte, error) {
+	if reg.Spec.ConfigBundleSecret == "" {
+		return nil, nil
+	}
+
+	var name, key string
+	switch {
+	case qv1.GetTLSSecretRef(reg.Spec.Components) != nil:
+		name = qv1.GetTLSSecretRef(reg.Spec.Components).Name
+		key = corev1.TLSCertKey
+	case qv1.TLSIssuedByCertManager(&reg):
+		name = qv1.CertManagerTLSSecretName(&reg)
+		key = corev1.TLSCertKey
+	case qv1.ComponentIsManaged(reg.Spec.Components, qv1.ComponentTLS):
+		issued, err := operatorIssuedTLS(ctx, cli, reg)
+		if err != nil || !issued {
+			return nil, err
+		}
+		name = qv1.OperatorTLSSecretNameFor(&reg)
+		key = corev1.TLSCertKey
+	default:
+		name = reg.Spec.ConfigBundleSecret
+		key = "ssl.cert"
+	}
+
+	nsn := types.NamespacedName{
+		Namespace: reg.Namespace,
+		Name:      name,
+	}
+
+	var secret corev1.Secret
+	if err := cli.Get(ctx, nsn, &secret); err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return secret.Data[key], nil
+}
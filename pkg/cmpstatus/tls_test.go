@@ -8,13 +8,74 @@ import (
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
 	qv1 "github.com/quay/quay-operator/apis/quay/v1"
+	quaytls "github.com/quay/quay-operator/pkg/tls"
 )
 
+func newCertificate(conds ...map[string]interface{}) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   "cert-manager.io",
+		Version: "v1",
+		Kind:    "Certificate",
+	})
+	obj.SetName("registry-quay-app-tls")
+	if len(conds) > 0 {
+		var list []interface{}
+		for _, cond := range conds {
+			list = append(list, cond)
+		}
+		_ = unstructured.SetNestedSlice(obj.Object, list, "status", "conditions")
+	}
+	return obj
+}
+
+// issueCert returns a certificate issued at the provided time, along with its formatted
+// expiration time.
+func issueCert(t *testing.T, issuedAt time.Time) ([]byte, string) {
+	ca, cakey, err := quaytls.GenerateCA("test-ca", issuedAt)
+	if err != nil {
+		t.Fatalf("unexpected error generating CA: %s", err)
+	}
+
+	cert, _, err := quaytls.GenerateCert(ca, cakey, []string{"registry.company.com"}, issuedAt)
+	if err != nil {
+		t.Fatalf("unexpected error generating certificate: %s", err)
+	}
+
+	notAfter, err := quaytls.NotAfter(cert)
+	if err != nil {
+		t.Fatalf("unexpected error parsing certificate: %s", err)
+	}
+	return cert, notAfter.UTC().Format(time.RFC3339)
+}
+
 func TestTLSCheck(t *testing.T) {
+	expiringCert, expiringAt := issueCert(t, time.Now().Add(-quaytls.CertValidity+10*24*time.Hour))
+	expiredCert, expiredAt := issueCert(t, time.Now().Add(-quaytls.CertValidity-time.Hour))
+	validCert, _ := issueCert(t, time.Now())
+
+	certManagerTLS := qv1.Component{
+		Kind:    qv1.ComponentTLS,
+		Managed: true,
+		Overrides: &qv1.Override{
+			IssuerRef: &qv1.CertificateIssuerReference{Name: "letsencrypt"},
+		},
+	}
+	configBundle := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "config-bundle",
+		},
+		Data: map[string][]byte{
+			"foo": []byte(""),
+		},
+	}
+
 	for _, tt := range []struct {
 		name string
 		quay qv1.QuayRegistry
@@ -172,6 +233,115 @@ func TestTLSCheck(t *testing.T) {
 				Message: "Config bundle contains certs",
 			},
 		},
+		{
+			name: "unmanaged tls with valid certs",
+			quay: qv1.QuayRegistry{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "registry",
+					UID:  "uid",
+				},
+				Spec: qv1.QuayRegistrySpec{
+					ConfigBundleSecret: "config-bundle",
+					Components: []qv1.Component{
+						{
+							Kind:    qv1.ComponentTLS,
+							Managed: false,
+						},
+					},
+				},
+			},
+			objs: []client.Object{
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "config-bundle",
+					},
+					Data: map[string][]byte{
+						"ssl.key":  []byte(""),
+						"ssl.cert": validCert,
+					},
+				},
+			},
+			cond: qv1.Condition{
+				Type:    qv1.ComponentTLSReady,
+				Status:  metav1.ConditionTrue,
+				Reason:  qv1.ConditionReasonComponentReady,
+				Message: "Config bundle contains certs",
+			},
+		},
+		{
+			name: "unmanaged tls with expiring certs",
+			quay: qv1.QuayRegistry{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "registry",
+					UID:  "uid",
+				},
+				Spec: qv1.QuayRegistrySpec{
+					ConfigBundleSecret: "config-bundle",
+					Components: []qv1.Component{
+						{
+							Kind:    qv1.ComponentTLS,
+							Managed: false,
+						},
+					},
+				},
+			},
+			objs: []client.Object{
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "config-bundle",
+					},
+					Data: map[string][]byte{
+						"ssl.key":  []byte(""),
+						"ssl.cert": expiringCert,
+					},
+				},
+			},
+			cond: qv1.Condition{
+				Type:    qv1.ComponentTLSReady,
+				Status:  metav1.ConditionTrue,
+				Reason:  qv1.ConditionReasonCertificateExpiring,
+				Message: "Config bundle contains certs, certificate expires at " + expiringAt,
+			},
+		},
+		{
+			name: "secretRef with expired external secret",
+			quay: qv1.QuayRegistry{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "registry",
+					UID:  "uid",
+				},
+				Spec: qv1.QuayRegistrySpec{
+					ConfigBundleSecret: "config-bundle",
+					Components: []qv1.Component{
+						{Kind: qv1.ComponentTLS, Managed: false, SecretRef: &corev1.LocalObjectReference{Name: "my-tls"}},
+					},
+				},
+			},
+			objs: []client.Object{
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "config-bundle",
+					},
+					Data: map[string][]byte{},
+				},
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "my-tls",
+					},
+					Type: corev1.SecretTypeTLS,
+					Data: map[string][]byte{
+						"tls.crt": expiredCert,
+						"tls.key": []byte("key-data"),
+					},
+				},
+			},
+			cond: qv1.Condition{
+				Type:    qv1.ComponentTLSReady,
+				Status:  metav1.ConditionFalse,
+				Reason:  qv1.ConditionReasonComponentNotReady,
+				Message: "Certificate expired at " + expiredAt,
+			},
+		},
 		{
 			name: "secretRef with ssl.cert in config bundle",
 			quay: qv1.QuayRegistry{
@@ -374,13 +544,184 @@ func TestTLSCheck(t *testing.T) {
 				Message: "TLS unmanaged but config bundle does not contain certs",
 			},
 		},
+		{
+			name: "managed tls issued by the operator",
+			quay: qv1.QuayRegistry{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "registry",
+					UID:  "uid",
+				},
+				Spec: qv1.QuayRegistrySpec{
+					ConfigBundleSecret: "config-bundle",
+					Components: []qv1.Component{
+						{
+							Kind:    qv1.ComponentTLS,
+							Managed: true,
+						},
+					},
+				},
+			},
+			objs: []client.Object{
+				configBundle,
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "registry-quay-operator-tls",
+						OwnerReferences: []metav1.OwnerReference{
+							{
+								Kind:       "QuayRegistry",
+								Name:       "registry",
+								APIVersion: "quay.redhat.com/v1",
+								UID:        "uid",
+							},
+						},
+					},
+				},
+			},
+			cond: qv1.Condition{
+				Type:    qv1.ComponentTLSReady,
+				Status:  metav1.ConditionTrue,
+				Reason:  qv1.ConditionReasonComponentReady,
+				Message: "Using certificate issued by the operator CA",
+			},
+		},
+		{
+			name: "cert-manager certificate not found",
+			quay: qv1.QuayRegistry{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "registry",
+					UID:  "uid",
+				},
+				Spec: qv1.QuayRegistrySpec{
+					ConfigBundleSecret: "config-bundle",
+					Components:         []qv1.Component{certManagerTLS},
+				},
+			},
+			objs: []client.Object{configBundle},
+			cond: qv1.Condition{
+				Type:    qv1.ComponentTLSReady,
+				Status:  metav1.ConditionFalse,
+				Reason:  qv1.ConditionReasonComponentNotReady,
+				Message: "Certificate not found",
+			},
+		},
+		{
+			name: "cert-manager certificate issuance pending",
+			quay: qv1.QuayRegistry{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "registry",
+					UID:  "uid",
+				},
+				Spec: qv1.QuayRegistrySpec{
+					ConfigBundleSecret: "config-bundle",
+					Components:         []qv1.Component{certManagerTLS},
+				},
+			},
+			objs: []client.Object{configBundle, newCertificate()},
+			cond: qv1.Condition{
+				Type:    qv1.ComponentTLSReady,
+				Status:  metav1.ConditionFalse,
+				Reason:  qv1.ConditionReasonComponentNotReady,
+				Message: "Certificate issuance pending",
+			},
+		},
+		{
+			name: "cert-manager certificate issuance failed",
+			quay: qv1.QuayRegistry{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "registry",
+					UID:  "uid",
+				},
+				Spec: qv1.QuayRegistrySpec{
+					ConfigBundleSecret: "config-bundle",
+					Components:         []qv1.Component{certManagerTLS},
+				},
+			},
+			objs: []client.Object{
+				configBundle,
+				newCertificate(
+					map[string]interface{}{
+						"type":    "Ready",
+						"status":  "False",
+						"message": `Issuer "letsencrypt" not found`,
+					},
+				),
+			},
+			cond: qv1.Condition{
+				Type:    qv1.ComponentTLSReady,
+				Status:  metav1.ConditionFalse,
+				Reason:  qv1.ConditionReasonComponentNotReady,
+				Message: `Certificate not ready: Issuer "letsencrypt" not found`,
+			},
+		},
+		{
+			name: "cert-manager certificate ready",
+			quay: qv1.QuayRegistry{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "registry",
+					UID:  "uid",
+				},
+				Spec: qv1.QuayRegistrySpec{
+					ConfigBundleSecret: "config-bundle",
+					Components:         []qv1.Component{certManagerTLS},
+				},
+			},
+			objs: []client.Object{
+				configBundle,
+				newCertificate(
+					map[string]interface{}{
+						"type":   "Issuing",
+						"status": "False",
+					},
+					map[string]interface{}{
+						"type":   "Ready",
+						"status": "True",
+					},
+				),
+			},
+			cond: qv1.Condition{
+				Type:    qv1.ComponentTLSReady,
+				Status:  metav1.ConditionTrue,
+				Reason:  qv1.ConditionReasonComponentReady,
+				Message: "Using certificate issued by cert-manager",
+			},
+		},
+		{
+			name: "cert-manager with certs in config bundle",
+			quay: qv1.QuayRegistry{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "registry",
+					UID:  "uid",
+				},
+				Spec: qv1.QuayRegistrySpec{
+					ConfigBundleSecret: "config-bundle",
+					Components:         []qv1.Component{certManagerTLS},
+				},
+			},
+			objs: []client.Object{
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "config-bundle",
+					},
+					Data: map[string][]byte{
+						"ssl.cert": []byte("cert"),
+						"ssl.key":  []byte("key"),
+					},
+				},
+			},
+			cond: qv1.Condition{
+				Type:    qv1.ComponentTLSReady,
+				Status:  metav1.ConditionFalse,
+				Reason:  qv1.ConditionReasonComponentNotReady,
+				Message: "tls component issuerRef and certs in configBundleSecret are mutually exclusive",
+			},
+		},
 	} {
 		t.Run(tt.name, func(t *testing.T) {
 			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 			defer cancel()
 
 			cli := fake.NewClientBuilder().WithObjects(tt.objs...).Build()
-			tls := TLS{cli}
+			tls := TLS{Client: cli, ExpiryWarning: TLSExpiryWarningFromEnv()}
 
 			cond, err := tls.Check(ctx, tt.quay)
 			if err != nil {
@@ -398,3 +739,80 @@ func TestTLSCheck(t *testing.T) {
 		})
 	}
 }
+
+func TestTLSCheckExpiryWarning(t *testing.T) {
+	// the certificate expires in ten days.
+	cert, expiresAt := issueCert(t, time.Now().Add(-quaytls.CertValidity+10*24*time.Hour))
+
+	quay := qv1.QuayRegistry{
+		ObjectMeta: metav1.ObjectMeta{Name: "registry", UID: "uid"},
+		Spec: qv1.QuayRegistrySpec{
+			ConfigBundleSecret: "config-bundle",
+			Components:         []qv1.Component{{Kind: qv1.ComponentTLS, Managed: false}},
+		},
+	}
+	bundle := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "config-bundle"},
+		Data: map[string][]byte{
+			"ssl.key":  []byte(""),
+			"ssl.cert": cert,
+		},
+	}
+
+	for _, tt := range []struct {
+		name   string
+		days   string
+		reason qv1.ConditionReason
+		msg    string
+	}{
+		{
+			name:   "WithinWindow",
+			days:   "11",
+			reason: qv1.ConditionReasonCertificateExpiring,
+			msg:    "Config bundle contains certs, certificate expires at " + expiresAt,
+		},
+		{
+			name:   "OutsideWindow",
+			days:   "9",
+			reason: qv1.ConditionReasonComponentReady,
+			msg:    "Config bundle contains certs",
+		},
+		{
+			name:   "Default",
+			reason: qv1.ConditionReasonCertificateExpiring,
+			msg:    "Config bundle contains certs, certificate expires at " + expiresAt,
+		},
+		{
+			name:   "Invalid",
+			days:   "soon",
+			reason: qv1.ConditionReasonCertificateExpiring,
+			msg:    "Config bundle contains certs, certificate expires at " + expiresAt,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.days != "" {
+				t.Setenv("TLS_EXPIRY_WARNING_DAYS", tt.days)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+
+			cli := fake.NewClientBuilder().WithObjects(bundle.DeepCopy()).Build()
+			tls := TLS{Client: cli, ExpiryWarning: TLSExpiryWarningFromEnv()}
+
+			cond, err := tls.Check(ctx, quay)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if cond.Status != metav1.ConditionTrue {
+				t.Errorf("expected the component to be ready, received %+v", cond)
+			}
+			if cond.Reason != tt.reason {
+				t.Errorf("expected reason %s, received %s", tt.reason, cond.Reason)
+			}
+			if cond.Message != tt.msg {
+				t.Errorf("expected message %q, received %q", tt.msg, cond.Message)
+			}
+		})
+	}
+}
@@ -18,6 +18,10 @@ type QuayRegistryContext struct {
 	TLSKey              []byte
 	TLSSecretHash       string
 
+	// Operator issued TLS, used when the Route API is not available
+	OperatorCACert []byte
+	OperatorCAKey  []byte
+
 	// TLS Security Profile (from OpenShift APIServer)
 	SSLProtocols string // e.g. "TLSv1.2 TLSv1.3" (nginx format)
 	SSLCiphers   string // e.g. "ECDHE-RSA-AES128-GCM-SHA256:..." (OpenSSL format)
@@ -33,6 +37,18 @@ type QuayRegistryContext struct {
 	// Monitoring
 	SupportsMonitoring bool
 
+	// Gateway API
+	SupportsGatewayAPI bool
+
+	// cert-manager
+	SupportsCertManager bool
+
+	// CloudNativePG, runs the managed databases when selected through the backend override
+	SupportsCloudNativePG bool
+
+	// Builds, the token is issued for the builder service account in the build namespace
+	BuilderServiceAccountToken string
+
 	// Secret Keys
 	DatabaseSecretKey string
 	SecretKey         string
@@ -44,6 +60,18 @@ type QuayRegistryContext struct {
 	NeedsPgUpgrade      bool
 	NeedsClairPgUpgrade bool
 
+	// Database TLS, issued by the operator when enabled through the tls override
+	DatabaseCACert          []byte
+	DatabaseCAKey           []byte
+	DatabaseServerCert      []byte
+	DatabaseServerKey       []byte
+	DatabaseClientCert      []byte
+	DatabaseClientKey       []byte
+	ClairDatabaseServerCert []byte
+	ClairDatabaseServerKey  []byte
+	ClairDatabaseClientCert []byte
+	ClairDatabaseClientKey  []byte
+
 	// Clair Database
 	ClairDatabaseInitialized bool
 	ClairDbUser              string
@@ -53,6 +81,15 @@ type QuayRegistryContext struct {
 
 	// Clair integration
 	SecurityScannerV4PSK string
+
+	// Read-only upgrade, the previous version of Quay keeps serving pulls while the
+	// migrations run once it has been rolled into read-only mode
+	PreviousQuayImage string
+	QuayReadOnly      bool
+
+	// Upgrade rollback, the previous version of Quay runs against the database restored
+	// after the migrations of its upgrade failed
+	QuayRolledBack bool
 }
 
 // NewQuayRegistryContext returns a fresh context for reconciling a `QuayRegistry`.
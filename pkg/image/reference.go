@@ -0,0 +1,78 @@
+// Package image parses container image references, e.g. mirror.local:5000/quay/quay:3.12
+// or quay.io/projectquay/quay@sha256:<hex>, following the grammar of the distribution
+// project.
+package image
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// nameMaxLength is the maximum length of the name of a repository, domain included.
+const nameMaxLength = 255
+
+var (
+	// domainComponent is a component of a domain, e.g. "quay" in quay.io.
+	domainComponent = `(?:[a-zA-Z0-9]|[a-zA-Z0-9][a-zA-Z0-9-]*[a-zA-Z0-9])`
+	// domain is the host the image is pulled from, with an optional port. IPv6 addresses are
+	// enclosed in brackets.
+	domain = `(?:` + domainComponent + `(?:\.` + domainComponent + `)*|\[[a-fA-F0-9:]+\])(?::[0-9]+)?`
+	// pathComponent is a lowercase component of the path of a repository.
+	pathComponent = `[a-z0-9]+(?:(?:[._]|__|[-]+)[a-z0-9]+)*`
+	// name is the name of a repository, its first component is only a domain when followed
+	// by other components.
+	name = `(?:` + domain + `/)?` + pathComponent + `(?:/` + pathComponent + `)*`
+	// tag identifies an image within a repository.
+	tag = `[\w][\w.-]{0,127}`
+	// digest is the content addressable identifier of an image, e.g. sha256:<hex>.
+	digest = `[A-Za-z][A-Za-z0-9]*(?:[-_+.][A-Za-z][A-Za-z0-9]*)*:[0-9a-fA-F]{32,}`
+
+	referenceRegexp = regexp.MustCompile(
+		`^(` + name + `)(?::(` + tag + `))?(?:@(` + digest + `))?$`,
+	)
+)
+
+// Reference is a parsed image reference. Tag and Digest are empty when the reference does
+// not hold them.
+type Reference struct {
+	Name   string
+	Tag    string
+	Digest string
+}
+
+// ParseReference parses the provided image reference. Names are not normalized, an image
+// without domain is returned as is.
+func ParseReference(ref string) (Reference, error) {
+	if ref == "" {
+		return Reference{}, fmt.Errorf("image reference is empty")
+	}
+
+	match := referenceRegexp.FindStringSubmatch(ref)
+	if match == nil {
+		if strings.ToLower(ref) != ref && referenceRegexp.MatchString(strings.ToLower(ref)) {
+			return Reference{}, fmt.Errorf("repository name must be lowercase: %s", ref)
+		}
+		return Reference{}, fmt.Errorf("invalid image reference: %s", ref)
+	}
+
+	if len(match[1]) > nameMaxLength {
+		return Reference{}, fmt.Errorf(
+			"repository name must not be longer than %d characters: %s", nameMaxLength, ref,
+		)
+	}
+
+	return Reference{Name: match[1], Tag: match[2], Digest: match[3]}, nil
+}
+
+// String returns the reference in its canonical form, name[:tag][@digest].
+func (r Reference) String() string {
+	ref := r.Name
+	if r.Tag != "" {
+		ref += ":" + r.Tag
+	}
+	if r.Digest != "" {
+		ref += "@" + r.Digest
+	}
+	return ref
+}
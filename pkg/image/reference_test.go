@@ -0,0 +1,108 @@
+package image
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseReference(t *testing.T) {
+	digest := "sha256:" + strings.Repeat("a1", 32)
+
+	for _, tt := range []struct {
+		name     string
+		ref      string
+		expected Reference
+		err      bool
+	}{
+		{
+			name:     "Tag",
+			ref:      "quay.io/projectquay/quay:3.12.0",
+			expected: Reference{Name: "quay.io/projectquay/quay", Tag: "3.12.0"},
+		},
+		{
+			name:     "Digest",
+			ref:      "quay.io/projectquay/quay@" + digest,
+			expected: Reference{Name: "quay.io/projectquay/quay", Digest: digest},
+		},
+		{
+			name:     "TagAndDigest",
+			ref:      "quay.io/projectquay/quay:3.12.0@" + digest,
+			expected: Reference{Name: "quay.io/projectquay/quay", Tag: "3.12.0", Digest: digest},
+		},
+		{
+			name:     "DomainWithPort",
+			ref:      "mirror.local:5000/quay:3.x",
+			expected: Reference{Name: "mirror.local:5000/quay", Tag: "3.x"},
+		},
+		{
+			name:     "DomainWithPortAndDigest",
+			ref:      "mirror.local:5000/projectquay/quay@" + digest,
+			expected: Reference{Name: "mirror.local:5000/projectquay/quay", Digest: digest},
+		},
+		{
+			name:     "IPv6Domain",
+			ref:      "[fd00::1]:5000/quay:hotfix",
+			expected: Reference{Name: "[fd00::1]:5000/quay", Tag: "hotfix"},
+		},
+		{
+			name:     "WithoutDomain",
+			ref:      "quay:latest",
+			expected: Reference{Name: "quay", Tag: "latest"},
+		},
+		{
+			name:     "WithoutTag",
+			ref:      "quay.io/projectquay/quay",
+			expected: Reference{Name: "quay.io/projectquay/quay"},
+		},
+		{
+			name: "Empty",
+			ref:  "",
+			err:  true,
+		},
+		{
+			name: "Uppercase",
+			ref:  "quay.io/ProjectQuay/quay:3.12.0",
+			err:  true,
+		},
+		{
+			name: "ShortDigest",
+			ref:  "quay.io/projectquay/quay@sha256:abc",
+			err:  true,
+		},
+		{
+			name: "EmptyTag",
+			ref:  "quay.io/projectquay/quay:",
+			err:  true,
+		},
+		{
+			name: "Spaces",
+			ref:  "quay.io/projectquay/quay: 3.12.0",
+			err:  true,
+		},
+		{
+			name: "NameTooLong",
+			ref:  "quay.io/" + strings.Repeat("a", 250) + ":latest",
+			err:  true,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			ref, err := ParseReference(tt.ref)
+			if tt.err {
+				if err == nil {
+					t.Errorf("expected an error, received %+v", ref)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if ref != tt.expected {
+				t.Errorf("expected %+v, received %+v", tt.expected, ref)
+			}
+			if ref.String() != tt.ref {
+				t.Errorf("expected %s, received %s", tt.ref, ref.String())
+			}
+		})
+	}
+}
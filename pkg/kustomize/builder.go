@@ -0,0 +1,121 @@
+package kustomize
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/quay/quay/config-tool/pkg/lib/shared"
+
+	v1 "github.com/quay/quay-operator/apis/quay/v1"
+	quaycontext "github.com/quay/quay-operator/pkg/context"
+)
+
+const (
+	defaultBuilderImage = "quay.io/projectquay/quay-builder:latest"
+	// serviceAccountCAPath is where the CA of the cluster API server is mounted in the
+	// quay pods, builds are scheduled in the same cluster.
+	serviceAccountCAPath = "/var/run/secrets/kubernetes.io/serviceaccount/ca.crt"
+)
+
+// BuildManagerFieldGroup holds the config fields rendered for the managed builder component,
+// the config-tool library does not provide a field group for the build manager.
+type BuildManagerFieldGroup struct {
+	FeatureBuildSupport bool          `json:"FEATURE_BUILD_SUPPORT"`
+	BuildmanHostname    string        `json:"BUILDMAN_HOSTNAME,omitempty"`
+	BuildManager        []interface{} `json:"BUILD_MANAGER,omitempty"`
+}
+
+// Fields returns the config fields in this field group.
+func (fg *BuildManagerFieldGroup) Fields() []string {
+	return []string{"FEATURE_BUILD_SUPPORT", "BUILDMAN_HOSTNAME", "BUILD_MANAGER"}
+}
+
+// Validate is a no-op, the fields are generated by the operator.
+func (fg *BuildManagerFieldGroup) Validate(opts shared.Options) []shared.ValidationError {
+	return nil
+}
+
+// BuildManagerConfig is the configuration of the ephemeral build manager.
+type BuildManagerConfig struct {
+	AllowedWorkerCount int                   `json:"ALLOWED_WORKER_COUNT"`
+	OrchestratorPrefix string                `json:"ORCHESTRATOR_PREFIX"`
+	Orchestrator       BuildOrchestrator     `json:"ORCHESTRATOR"`
+	Executors          []BuildExecutorConfig `json:"EXECUTORS"`
+}
+
+// BuildOrchestrator points the build manager to the redis used to coordinate builds.
+type BuildOrchestrator struct {
+	RedisHost                   string `json:"REDIS_HOST"`
+	RedisSSL                    bool   `json:"REDIS_SSL"`
+	RedisSkipKeyspaceEventSetup bool   `json:"REDIS_SKIP_KEYSPACE_EVENT_SETUP"`
+}
+
+// BuildExecutorConfig configures the executor scheduling the builds as jobs in the build
+// namespace.
+type BuildExecutorConfig struct {
+	Executor               string `json:"EXECUTOR"`
+	Name                   string `json:"NAME"`
+	BuilderNamespace       string `json:"BUILDER_NAMESPACE"`
+	BuilderContainerImage  string `json:"BUILDER_CONTAINER_IMAGE"`
+	K8sAPIServer           string `json:"K8S_API_SERVER"`
+	K8sAPITLSCA            string `json:"K8S_API_TLS_CA"`
+	KubernetesDistribution string `json:"KUBERNETES_DISTRIBUTION"`
+	ServiceAccountName     string `json:"SERVICE_ACCOUNT_NAME"`
+	ServiceAccountToken    string `json:"SERVICE_ACCOUNT_TOKEN"`
+	SetupTime              int    `json:"SETUP_TIME"`
+	MinimumRetryThreshold  int    `json:"MINIMUM_RETRY_THRESHOLD"`
+}
+
+// builderImage returns the image used by the build jobs.
+func builderImage() string {
+	if image := os.Getenv(componentImagePrefix + "BUILDER"); image != "" {
+		return image
+	}
+	return defaultBuilderImage
+}
+
+// buildManagerFieldGroupFor returns the build manager config for the provided QuayRegistry.
+// Builds remain disabled until the token for the builder service account has been issued.
+func buildManagerFieldGroupFor(
+	ctx *quaycontext.QuayRegistryContext, quay *v1.QuayRegistry,
+) *BuildManagerFieldGroup {
+	fieldGroup := &BuildManagerFieldGroup{
+		BuildmanHostname: ctx.BuildManagerHostname,
+	}
+	if ctx.BuilderServiceAccountToken == "" {
+		return fieldGroup
+	}
+
+	distribution := "k8s"
+	if ctx.SupportsRoutes {
+		distribution = "openshift"
+	}
+
+	fieldGroup.FeatureBuildSupport = true
+	fieldGroup.BuildManager = []interface{}{
+		"ephemeral",
+		BuildManagerConfig{
+			AllowedWorkerCount: 1,
+			OrchestratorPrefix: "buildman/production/",
+			Orchestrator: BuildOrchestrator{
+				RedisHost: fmt.Sprintf("%s-quay-redis", quay.GetName()),
+			},
+			Executors: []BuildExecutorConfig{
+				{
+					Executor:               "kubernetesPodman",
+					Name:                   distribution,
+					BuilderNamespace:       v1.BuilderNamespaceFor(quay),
+					BuilderContainerImage:  builderImage(),
+					K8sAPIServer:           "kubernetes.default.svc",
+					K8sAPITLSCA:            serviceAccountCAPath,
+					KubernetesDistribution: distribution,
+					ServiceAccountName:     v1.BuilderServiceAccountNameFor(quay),
+					ServiceAccountToken:    ctx.BuilderServiceAccountToken,
+					SetupTime:              180,
+					MinimumRetryThreshold:  0,
+				},
+			},
+		},
+	}
+	return fieldGroup
+}
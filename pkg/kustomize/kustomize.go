@@ -8,6 +8,7 @@ import (
 	"runtime"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/go-logr/logr"
 	route "github.com/openshift/api/route/v1"
@@ -16,6 +17,8 @@ import (
 	autoscaling "k8s.io/api/autoscaling/v2"
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
 	rbac "k8s.io/api/rbac/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -29,6 +32,7 @@ import (
 
 	v1 "github.com/quay/quay-operator/apis/quay/v1"
 	quaycontext "github.com/quay/quay-operator/pkg/context"
+	"github.com/quay/quay-operator/pkg/image"
 	"github.com/quay/quay-operator/pkg/middleware"
 )
 
@@ -37,6 +41,7 @@ const (
 
 	proxyConfigPrefix                 = "quay-proxy-config"
 	configSecretPrefix                = "quay-config-secret"
+	readOnlyConfigSecretPrefix        = "quay-config-secret-readonly"
 	registryHostnameAnnotation        = "quay-registry-hostname"
 	buildManagerHostnameAnnotation    = "quay-buildmanager-hostname"
 	operatorServiceEndpointAnnotation = "quay-operator-service-endpoint"
@@ -46,9 +51,10 @@ const (
 	componentImagePrefix = "RELATED_IMAGE_COMPONENT_"
 )
 
-// ComponentImageFor checks for an environment variable indicating which component container image
-// to use. If set, returns a Kustomize image override for the given component.
-func ComponentImageFor(component v1.ComponentKind) (types.Image, error) {
+// ComponentImageFor returns a Kustomize image override for the provided component of the
+// provided QuayRegistry. The image set through the overrides of the component takes
+// precedence over the one set through the environment variable of the component.
+func ComponentImageFor(quay *v1.QuayRegistry, component v1.ComponentKind) (types.Image, error) {
 	envVarFor := map[v1.ComponentKind]string{
 		v1.ComponentQuay:          componentImagePrefix + "QUAY",
 		v1.ComponentClair:         componentImagePrefix + "CLAIR",
@@ -60,84 +66,92 @@ func ComponentImageFor(component v1.ComponentKind) (types.Image, error) {
 		v1.ComponentQuay:          "quay.io/projectquay/quay",
 		v1.ComponentClair:         "quay.io/projectquay/clair",
 		v1.ComponentRedis:         "quay.io/sclorg/redis-7-c9s",
-		v1.ComponentPostgres:      "quay.io/sclorg/postgresql-13-c9s",
-		v1.ComponentClairPostgres: "quay.io/sclorg/postgresql-15-c9s",
+		v1.ComponentPostgres:      v1.TargetPostgresVersionFor(v1.ComponentPostgres).Image,
+		v1.ComponentClairPostgres: v1.TargetPostgresVersionFor(v1.ComponentClairPostgres).Image,
 	}
 
-	imageOverride := types.Image{
-		Name: defaultImagesFor[component],
+	reference := v1.GetImageOverrideForComponent(quay, component)
+	if reference == "" {
+		reference = os.Getenv(envVarFor[component])
 	}
+	return imageOverrideFor(defaultImagesFor[component], reference)
+}
 
-	image := os.Getenv(envVarFor[component])
-	if image == "" {
-		return imageOverride, nil
+// PostgresImageFor returns the reference of the image running the provided version of Postgres
+// for the provided database component of the provided QuayRegistry. The image of the version
+// the component runs can be overridden through the overrides of the component or the
+// RELATED_IMAGE_COMPONENT_<COMPONENT> environment variable, the one of the version before it
+// through the RELATED_IMAGE_COMPONENT_<COMPONENT>_PREVIOUS environment variable.
+func PostgresImageFor(
+	quay *v1.QuayRegistry, component v1.ComponentKind, version v1.PostgresVersion,
+) (string, error) {
+	env := componentImagePrefix + strings.ToUpper(string(component))
+	path := v1.PostgresUpgradePathFor(component)
+
+	var image string
+	if version.Major == v1.TargetPostgresVersionFor(component).Major {
+		image = v1.GetImageOverrideForComponent(quay, component)
+		if image == "" {
+			image = os.Getenv(env)
+		}
+	} else if len(path) > 1 && version.Major == path[len(path)-2].Major {
+		image = os.Getenv(env + "_PREVIOUS")
 	}
 
-	if len(strings.Split(image, "@")) == 2 {
-		imageOverride.NewName = strings.Split(image, "@")[0]
-		imageOverride.Digest = strings.Split(image, "@")[1]
-	} else if len(strings.Split(image, ":")) == 2 {
-		imageOverride.NewName = strings.Split(image, ":")[0]
-		imageOverride.NewTag = strings.Split(image, ":")[1]
-	} else {
-		return types.Image{}, fmt.Errorf(
-			"image override must be reference by tag or digest: %s", image,
-		)
+	override, err := imageOverrideFor(version.Image, image)
+	if err != nil {
+		return "", err
 	}
-
-	return imageOverride, nil
+	return imageReference(override), nil
 }
 
-// ComponentImageFor checks for an environment variable indicating which component container image
-// to use. If set, returns a Kustomize image override for the given component.
-func postgresUpgradeImage() (types.Image, error) {
+// imageOverrideFor returns a Kustomize image override replacing the image with the provided
+// name by the provided reference, which must be by tag or digest. Nothing is replaced if the
+// reference is empty.
+func imageOverrideFor(name, reference string) (types.Image, error) {
 	imageOverride := types.Image{
-		Name: "centos/postgresql-10-centos7",
+		Name: name,
 	}
 
-	image := os.Getenv("RELATED_IMAGE_COMPONENT_POSTGRES_PREVIOUS")
-	if image == "" {
+	if reference == "" {
 		return imageOverride, nil
 	}
 
-	if len(strings.Split(image, "@")) == 2 {
-		imageOverride.NewName = strings.Split(image, "@")[0]
-		imageOverride.Digest = strings.Split(image, "@")[1]
-	} else if len(strings.Split(image, ":")) == 2 {
-		imageOverride.NewName = strings.Split(image, ":")[0]
-		imageOverride.NewTag = strings.Split(image, ":")[1]
-	} else {
+	ref, err := image.ParseReference(reference)
+	if err != nil {
+		return types.Image{}, fmt.Errorf("invalid image override: %w", err)
+	}
+
+	if ref.Tag == "" && ref.Digest == "" {
 		return types.Image{}, fmt.Errorf(
-			"image override must be reference by tag or digest: %s", image,
+			"image override must be reference by tag or digest: %s", reference,
 		)
 	}
+
+	// images referenced by both are pulled by digest.
+	imageOverride.NewName = ref.Name
+	imageOverride.NewTag = ref.Tag
+	imageOverride.Digest = ref.Digest
 	return imageOverride, nil
 }
 
-func clairpostgresUpgradeImage() (types.Image, error) {
-	imageOverride := types.Image{
-		Name: "quay.io/sclorg/postgresql-13-c9s",
+// imageReference returns the reference of the image resulting from the provided override,
+// images without a tag or digest are pulled by the latest tag.
+func imageReference(image types.Image) string {
+	name := image.Name
+	if image.NewName != "" {
+		name = image.NewName
 	}
 
-	image := os.Getenv("RELATED_IMAGE_COMPONENT_CLAIRPOSTGRES_PREVIOUS")
-
-	if image == "" {
-		return imageOverride, nil
+	if image.Digest != "" {
+		return name + "@" + image.Digest
 	}
 
-	if len(strings.Split(image, "@")) == 2 {
-		imageOverride.NewName = strings.Split(image, "@")[0]
-		imageOverride.Digest = strings.Split(image, "@")[1]
-	} else if len(strings.Split(image, ":")) == 2 {
-		imageOverride.NewName = strings.Split(image, ":")[0]
-		imageOverride.NewTag = strings.Split(image, ":")[1]
-	} else {
-		return types.Image{}, fmt.Errorf(
-			"image override must be reference by tag or digest: %s", image,
-		)
+	tag := image.NewTag
+	if tag == "" {
+		tag = "latest"
 	}
-
-	return imageOverride, nil
+	return name + ":" + tag
 }
 
 func kustomizeDir() string {
@@ -205,12 +219,18 @@ func ModelFor(gvk schema.GroupVersionKind) client.Object {
 		return &corev1.PersistentVolumeClaim{}
 	case schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}.String():
 		return &apps.Deployment{}
+	case schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "StatefulSet"}.String():
+		return &apps.StatefulSet{}
+	case schema.GroupVersionKind{Group: "policy", Version: "v1", Kind: "PodDisruptionBudget"}.String():
+		return &policyv1.PodDisruptionBudget{}
 	case schema.GroupVersionKind{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "Role"}.String():
 		return &rbac.Role{}
 	case schema.GroupVersionKind{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "RoleBinding"}.String():
 		return &rbac.RoleBinding{}
 	case schema.GroupVersionKind{Group: "route.openshift.io", Version: "v1", Kind: "Route"}.String():
 		return &route.Route{}
+	case schema.GroupVersionKind{Group: "networking.k8s.io", Version: "v1", Kind: "Ingress"}.String():
+		return &networkingv1.Ingress{}
 	case schema.GroupVersionKind{Group: "objectbucket.io", Version: "v1alpha1", Kind: "ObjectBucketClaim"}.String():
 		obj := &unstructured.Unstructured{}
 		obj.SetGroupVersionKind(schema.GroupVersionKind{
@@ -223,6 +243,8 @@ func ModelFor(gvk schema.GroupVersionKind) client.Object {
 		return &autoscaling.HorizontalPodAutoscaler{}
 	case schema.GroupVersionKind{Group: "batch", Version: "v1", Kind: "Job"}.String():
 		return &batchv1.Job{}
+	case schema.GroupVersionKind{Group: "batch", Version: "v1", Kind: "CronJob"}.String():
+		return &batchv1.CronJob{}
 	case schema.GroupVersionKind{Group: "monitoring.coreos.com", Version: "v1", Kind: "ServiceMonitor"}.String():
 		obj := &unstructured.Unstructured{}
 		obj.SetGroupVersionKind(schema.GroupVersionKind{
@@ -239,6 +261,14 @@ func ModelFor(gvk schema.GroupVersionKind) client.Object {
 			Kind:    "PrometheusRule",
 		})
 		return obj
+	case schema.GroupVersionKind{Group: "gateway.networking.k8s.io", Version: "v1", Kind: "HTTPRoute"}.String(),
+		schema.GroupVersionKind{Group: "gateway.networking.k8s.io", Version: "v1", Kind: "GRPCRoute"}.String(),
+		schema.GroupVersionKind{Group: "gateway.networking.k8s.io", Version: "v1alpha2", Kind: "TLSRoute"}.String(),
+		schema.GroupVersionKind{Group: "cert-manager.io", Version: "v1", Kind: "Certificate"}.String(),
+		schema.GroupVersionKind{Group: "postgresql.cnpg.io", Version: "v1", Kind: "Cluster"}.String():
+		obj := &unstructured.Unstructured{}
+		obj.SetGroupVersionKind(gvk)
+		return obj
 	default:
 		panic(fmt.Sprintf("Missing model for GVK %s", gvk.String()))
 	}
@@ -480,8 +510,74 @@ func KustomizationFor(
 		},
 	}
 
+	// the CA and the certificate issued by the operator must be stable across runs, we store
+	// them (and re-read them) from a dedicated secret. the CA is also published in a
+	// configmap so clients can trust it.
+	generatedConfigMaps := []types.ConfigMapArgs{}
+	if v1.TLSIssuedByOperator(ctx, quay) {
+		generatedSecrets = append(
+			generatedSecrets,
+			types.SecretArgs{
+				GeneratorArgs: types.GeneratorArgs{
+					Name: v1.OperatorTLSSecretName,
+					Options: &types.GeneratorOptions{
+						DisableNameSuffixHash: true,
+					},
+					KvPairSources: types.KvPairSources{
+						LiteralSources: []string{
+							"ca.crt=" + string(ctx.OperatorCACert),
+							"ca.key=" + string(ctx.OperatorCAKey),
+							"tls.crt=" + string(ctx.TLSCert),
+							"tls.key=" + string(ctx.TLSKey),
+						},
+					},
+				},
+				Type: string(corev1.SecretTypeTLS),
+			},
+		)
+
+		generatedConfigMaps = append(
+			generatedConfigMaps,
+			types.ConfigMapArgs{
+				GeneratorArgs: types.GeneratorArgs{
+					Name: v1.OperatorCAConfigMapName,
+					Options: &types.GeneratorOptions{
+						DisableNameSuffixHash: true,
+					},
+					KvPairSources: types.KvPairSources{
+						LiteralSources: []string{
+							"ca.crt=" + string(ctx.OperatorCACert),
+						},
+					},
+				},
+			},
+		)
+	}
+
+	// with the read-only upgrade strategy the previous version of Quay is rolled into
+	// read-only mode, through a variant of its config, before the migrations start. the
+	// same variant is run while the registry is under maintenance. a registry whose upgrade
+	// was rolled back runs the previous version with its regular config.
+	readonly := (ctx.PreviousQuayImage != "" && !ctx.QuayRolledBack) || v1.MaintenanceEnabled(quay)
+	if readonly {
+		generatedSecrets = append(
+			generatedSecrets,
+			types.SecretArgs{
+				GeneratorArgs: types.GeneratorArgs{
+					Name: readOnlyConfigSecretPrefix,
+					KvPairSources: types.KvPairSources{
+						FileSources: configFiles,
+					},
+				},
+			},
+		)
+	}
+
 	componentPaths := []string{}
-	if overlay == upgradeOverlayDir() {
+	if readonly {
+		componentPaths = append(componentPaths, "../components/readonly")
+	}
+	if overlay == upgradeOverlayDir() || ctx.QuayReadOnly {
 		// only include the upgrade job when object storage is either unmanaged
 		// or already initialized. when managed object storage is pending (OBC
 		// not yet bound), we skip the job to avoid creating it with incomplete
@@ -489,7 +585,7 @@ func KustomizationFor(
 		// after the OBC credentials become available.
 		osmanaged := v1.ComponentIsManaged(quay.Spec.Components, v1.ComponentObjectStorage)
 		if !osmanaged || ctx.ObjectStorageInitialized {
-			componentPaths = []string{"../components/job"}
+			componentPaths = append(componentPaths, "../components/job")
 		}
 	}
 	for _, component := range quay.Spec.Components {
@@ -530,6 +626,21 @@ func KustomizationFor(
 		)
 	}
 
+	// tls for the managed databases is enabled through components patching the
+	// deployments, they must come after the components rendering them.
+	generatedSecrets = append(generatedSecrets, databaseTLSSecretsFor(ctx, quay)...)
+	componentPaths = append(componentPaths, databaseTLSComponentsFor(quay)...)
+
+	// the highly available redis replaces the deployment rendered by the redis component.
+	if v1.RedisHighAvailabilityEnabled(quay) {
+		componentPaths = append(componentPaths, "../components/redis/ha")
+	}
+
+	// cloudnativepg clusters replace the deployments rendered by the database components.
+	componentPaths = append(componentPaths, databaseClusterComponentsFor(quay)...)
+	componentPaths = append(componentPaths, databaseBackupComponentsFor(quay)...)
+	componentPaths = append(componentPaths, databaseSnapshotComponentsFor(quay)...)
+
 	if ctx.NeedsPgUpgrade {
 		componentPaths = append(componentPaths, "../components/pgupgrade")
 	}
@@ -546,7 +657,7 @@ func KustomizationFor(
 	for _, component := range append(
 		quay.Spec.Components, v1.Component{Kind: "quay", Managed: true},
 	) {
-		image, err := ComponentImageFor(component.Kind)
+		image, err := ComponentImageFor(quay, component.Kind)
 		if err != nil {
 			return nil, err
 		}
@@ -555,33 +666,18 @@ func KustomizationFor(
 		}
 	}
 
-	if ctx.NeedsPgUpgrade {
-		pgImage, err := postgresUpgradeImage()
-		if err != nil {
-			return nil, err
-		}
-		images = append(images, pgImage)
-	}
-
-	if ctx.NeedsClairPgUpgrade {
-		clairPgImage, err := clairpostgresUpgradeImage()
-		if err != nil {
-			return nil, err
-		}
-		images = append(images, clairPgImage)
-	}
-
 	return &types.Kustomization{
 		TypeMeta: types.TypeMeta{
 			APIVersion: types.KustomizationVersion,
 			Kind:       types.KustomizationKind,
 		},
-		Namespace:       quay.GetNamespace(),
-		NamePrefix:      quay.GetName() + "-",
-		Resources:       []string{"../base"},
-		Images:          images,
-		Components:      componentPaths,
-		SecretGenerator: generatedSecrets,
+		Namespace:          quay.GetNamespace(),
+		NamePrefix:         quay.GetName() + "-",
+		Resources:          []string{"../base"},
+		Images:             images,
+		Components:         componentPaths,
+		SecretGenerator:    generatedSecrets,
+		ConfigMapGenerator: generatedConfigMaps,
 		CommonLabels: map[string]string{
 			QuayRegistryNameLabel: quay.GetName(),
 		},
@@ -606,6 +702,153 @@ func KustomizationFor(
 	}, nil
 }
 
+// databaseTLSSecretsFor returns the secrets holding the certificates issued for the managed
+// databases with TLS enabled. The CA and all the certificates are persisted in a dedicated
+// secret so they remain stable across runs, each party only mounts the certificates it needs.
+func databaseTLSSecretsFor(
+	ctx *quaycontext.QuayRegistryContext, quay *v1.QuayRegistry,
+) []types.SecretArgs {
+	pgtls := v1.DatabaseTLSEnabled(quay, v1.ComponentPostgres)
+	clairtls := v1.DatabaseTLSEnabled(quay, v1.ComponentClairPostgres)
+	if !pgtls && !clairtls {
+		return nil
+	}
+
+	secretFor := func(name string, sources ...string) types.SecretArgs {
+		return types.SecretArgs{
+			GeneratorArgs: types.GeneratorArgs{
+				Name: name,
+				KvPairSources: types.KvPairSources{
+					LiteralSources: sources,
+				},
+			},
+		}
+	}
+
+	cacrt := string(ctx.DatabaseCACert)
+	store := secretFor(
+		v1.DatabaseTLSSecretName,
+		"ca.crt="+cacrt,
+		"ca.key="+string(ctx.DatabaseCAKey),
+		"postgres.crt="+string(ctx.DatabaseServerCert),
+		"postgres.key="+string(ctx.DatabaseServerKey),
+		"postgres-client.crt="+string(ctx.DatabaseClientCert),
+		"postgres-client.key="+string(ctx.DatabaseClientKey),
+		"clair-postgres.crt="+string(ctx.ClairDatabaseServerCert),
+		"clair-postgres.key="+string(ctx.ClairDatabaseServerKey),
+		"clair-postgres-client.crt="+string(ctx.ClairDatabaseClientCert),
+		"clair-postgres-client.key="+string(ctx.ClairDatabaseClientKey),
+	)
+	store.Options = &types.GeneratorOptions{DisableNameSuffixHash: true}
+	secrets := []types.SecretArgs{store}
+
+	if pgtls {
+		secrets = append(
+			secrets,
+			secretFor("postgresql-ca", "root.crt="+cacrt),
+			secretFor(
+				"postgresql-client-certs",
+				"postgresql.crt="+string(ctx.DatabaseClientCert),
+				"postgresql.key="+string(ctx.DatabaseClientKey),
+			),
+			secretFor(
+				"postgres-server-tls",
+				"ca.crt="+cacrt,
+				"tls.crt="+string(ctx.DatabaseServerCert),
+				"tls.key="+string(ctx.DatabaseServerKey),
+			),
+		)
+	}
+
+	if clairtls {
+		secrets = append(
+			secrets,
+			secretFor(
+				"clair-postgres-server-tls",
+				"ca.crt="+cacrt,
+				"tls.crt="+string(ctx.ClairDatabaseServerCert),
+				"tls.key="+string(ctx.ClairDatabaseServerKey),
+			),
+		)
+	}
+
+	if clairtls && v1.ComponentIsManaged(quay.Spec.Components, v1.ComponentClair) {
+		secrets = append(
+			secrets,
+			secretFor(
+				"clair-postgres-client-certs",
+				"ca.crt="+cacrt,
+				"tls.crt="+string(ctx.ClairDatabaseClientCert),
+				"tls.key="+string(ctx.ClairDatabaseClientKey),
+			),
+		)
+	}
+	return secrets
+}
+
+// databaseClusterComponentsFor returns the paths of the components replacing the managed
+// databases with CloudNativePG clusters when selected through the backend override.
+func databaseClusterComponentsFor(quay *v1.QuayRegistry) []string {
+	var paths []string
+	if v1.DatabaseClusterEnabled(quay, v1.ComponentPostgres) {
+		paths = append(paths, "../components/postgres/cloudnativepg")
+	}
+	if v1.DatabaseClusterEnabled(quay, v1.ComponentClairPostgres) {
+		paths = append(paths, "../components/clairpostgres/cloudnativepg")
+	}
+	return paths
+}
+
+// databaseBackupComponentsFor returns the paths of the components rendering the CronJobs that
+// take logical backups of the managed databases when configured through the backup override.
+func databaseBackupComponentsFor(quay *v1.QuayRegistry) []string {
+	var paths []string
+	if v1.DatabaseBackupFor(quay, v1.ComponentPostgres) != nil {
+		paths = append(paths, "../components/backup/postgres")
+	}
+	if v1.DatabaseBackupFor(quay, v1.ComponentClairPostgres) != nil {
+		paths = append(paths, "../components/backup/clairpostgres")
+	}
+	if len(paths) == 0 {
+		return nil
+	}
+	return append([]string{"../components/backup"}, paths...)
+}
+
+// databaseSnapshotComponentsFor returns the paths of the components rendering the CronJobs
+// that take VolumeSnapshots of the volumes of the managed databases when configured through the
+// snapshot override.
+func databaseSnapshotComponentsFor(quay *v1.QuayRegistry) []string {
+	var paths []string
+	if v1.DatabaseSnapshotFor(quay, v1.ComponentPostgres) != nil {
+		paths = append(paths, "../components/snapshot/postgres")
+	}
+	if v1.DatabaseSnapshotFor(quay, v1.ComponentClairPostgres) != nil {
+		paths = append(paths, "../components/snapshot/clairpostgres")
+	}
+	if len(paths) == 0 {
+		return nil
+	}
+	return append([]string{"../components/snapshot"}, paths...)
+}
+
+// databaseTLSComponentsFor returns the paths of the components enabling TLS for the managed
+// databases, and for their clients, when TLS is enabled through the tls override.
+func databaseTLSComponentsFor(quay *v1.QuayRegistry) []string {
+	var paths []string
+	if v1.DatabaseTLSEnabled(quay, v1.ComponentPostgres) {
+		paths = append(paths, "../components/postgres/tls")
+	}
+
+	if v1.DatabaseTLSEnabled(quay, v1.ComponentClairPostgres) {
+		paths = append(paths, "../components/clairpostgres/tls")
+		if v1.ComponentIsManaged(quay.Spec.Components, v1.ComponentClair) {
+			paths = append(paths, "../components/clair/postgres-tls")
+		}
+	}
+	return paths
+}
+
 // Inflate takes a `QuayRegistry` object and returns a set of Kubernetes objects representing a
 // Quay deployment.
 func Inflate(
@@ -654,9 +897,15 @@ func Inflate(
 	parsedUserConfig["SECRET_KEY"] = ctx.SecretKey
 
 	var dbCfgHasChanged bool
+	dbURIProvided := false
 	if dbURI, ok := parsedUserConfig["DB_URI"].(string); ok && len(dbURI) > 0 {
 		dbCfgHasChanged = parsedUserConfig["DB_URI"] != ctx.DbUri
+		dbURIProvided = true
 		ctx.DbUri = dbURI
+	} else if v1.DatabaseClusterEnabled(quay, v1.ComponentPostgres) {
+		// the uri is read from the application secret of the cloudnativepg cluster, it
+		// remains empty until the cluster has been bootstrapped.
+		log.Info("managed `DB_URI` provided by cloudnativepg")
 	} else if v1.ComponentIsManaged(quay.Spec.Components, v1.ComponentPostgres) && len(ctx.DbUri) == 0 {
 		dbCfgHasChanged = true
 		log.Info("managed `DB_URI` not found in config, generating new one")
@@ -672,6 +921,20 @@ func Inflate(
 			"postgresql://%s:%s@%s:%s/%s", user, password, host, port, name,
 		)
 	}
+
+	// the managed database uri is kept in sync with the tls override of the postgres
+	// component, user provided uris are left untouched.
+	pgmanaged := v1.ComponentIsManaged(quay.Spec.Components, v1.ComponentPostgres)
+	pgcluster := v1.DatabaseClusterEnabled(quay, v1.ComponentPostgres)
+	if !dbURIProvided && pgmanaged && !pgcluster {
+		dbURI, err := databaseURIWithTLS(
+			ctx.DbUri, v1.DatabaseTLSEnabled(quay, v1.ComponentPostgres),
+		)
+		if err != nil {
+			return nil, err
+		}
+		ctx.DbUri = dbURI
+	}
 	parsedUserConfig["DB_URI"] = ctx.DbUri
 
 	for field, value := range BaseQuayConfig() {
@@ -709,6 +972,18 @@ func Inflate(
 		componentConfigFiles[index] = encode(fieldGroup)
 	}
 
+	if v1.TLSIssuedByOperator(ctx, quay) {
+		log.Info("Ensuring operator issued TLS cert/key pair for Quay app")
+		if err := EnsureOperatorTLS(ctx, quay, time.Now()); err != nil {
+			return nil, err
+		}
+	}
+
+	log.Info("Ensuring TLS certificates for the managed databases")
+	if err := EnsureDatabaseTLS(ctx, quay, time.Now()); err != nil {
+		return nil, err
+	}
+
 	log.Info("Ensuring TLS cert/key pair for Quay app")
 	tlsCert, tlsKey, err := EnsureTLSFor(ctx, quay)
 	if err != nil {
@@ -717,13 +992,23 @@ func Inflate(
 	ctx.TLSCert = tlsCert
 	ctx.TLSKey = tlsKey
 
+	// the previous version of Quay is only kept serving when the registry is upgraded, a
+	// new database is migrated with quay scaled down.
+	if dbCfgHasChanged {
+		ctx.PreviousQuayImage = ""
+		ctx.QuayReadOnly = false
+		ctx.QuayRolledBack = false
+	}
+
 	var overlay string
-	if quay.Status.CurrentVersion != v1.QuayVersionCurrent || dbCfgHasChanged {
+	upgrading := quay.Status.CurrentVersion != v1.QuayVersionCurrent || dbCfgHasChanged
+	if upgrading && ctx.PreviousQuayImage == "" {
 		// we render the upgrade overlay directory only if the operator version or the
 		// database configuration has changed. this scales down quay and runs a job to
-		// migrate the database.
+		// migrate the database. with the read-only upgrade strategy quay is not scaled
+		// down, the readonly component keeps it serving instead.
 		overlay = upgradeOverlayDir()
-	} else if !v1.ComponentIsManaged(quay.Spec.Components, v1.ComponentTLS) {
+	} else if v1.QuayServesTLS(ctx, quay) {
 		overlay = unmanagedTLSOverlayDir()
 	} else {
 		overlay = overlayDir()
@@ -740,7 +1025,15 @@ func Inflate(
 	}
 
 	for index, resource := range resources {
-		obj, err := middleware.Process(quay, ctx, resource, skipres)
+		obj, err := processPostgresUpgrade(quay, resource)
+		if err != nil {
+			return nil, err
+		} else if obj == nil {
+			resources[index] = nil
+			continue
+		}
+
+		obj, err = middleware.Process(quay, ctx, obj, skipres)
 		if err != nil {
 			return nil, err
 		}
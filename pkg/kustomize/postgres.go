@@ -0,0 +1,73 @@
+package kustomize
+
+import (
+	"fmt"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	v1 "github.com/quay/quay-operator/apis/quay/v1"
+)
+
+// postgresUpgradeObjects maps the objects rendered for an upgrade of a managed database, by
+// their name without the registry prefix, to the database component they upgrade.
+var postgresUpgradeObjects = map[string]v1.ComponentKind{
+	"quay-database-old":      v1.ComponentPostgres,
+	"quay-postgres-next":     v1.ComponentPostgres,
+	"quay-postgres-upgrade":  v1.ComponentPostgres,
+	"clair-postgres-old":     v1.ComponentClairPostgres,
+	"clair-postgres-next":    v1.ComponentClairPostgres,
+	"clair-postgres-upgrade": v1.ComponentClairPostgres,
+}
+
+// processPostgresUpgrade points the objects rendered for an upgrade of a managed database to
+// the step of its upgrade path the database is going through: the old deployment runs the
+// version upgraded from on its volume and the upgrade job writes the version upgraded to.
+// The volume of the version upgraded to is only rendered when it is not the one the database
+// runs at the end of the path, returns nil in that case.
+func processPostgresUpgrade(quay *v1.QuayRegistry, obj client.Object) (client.Object, error) {
+	kind, ok := postgresUpgradeObjects[strings.TrimPrefix(obj.GetName(), quay.GetName()+"-")]
+	if !ok {
+		return obj, nil
+	}
+	from, to, volume := v1.PostgresUpgradeVersionsFor(quay, kind)
+
+	switch o := obj.(type) {
+	case *appsv1.Deployment:
+		image, err := PostgresImageFor(quay, kind, from)
+		if err != nil {
+			return nil, err
+		}
+		setPostgresUpgradePodSpec(&o.Spec.Template.Spec, image, volume)
+	case *batchv1.Job:
+		image, err := PostgresImageFor(quay, kind, to)
+		if err != nil {
+			return nil, err
+		}
+		setPostgresUpgradePodSpec(&o.Spec.Template.Spec, image, v1.PostgresVolumeNameFor(quay, to))
+	case *corev1.PersistentVolumeClaim:
+		if to.Major == v1.TargetPostgresVersionFor(kind).Major {
+			return nil, nil
+		}
+		o.SetName(v1.PostgresVolumeNameFor(quay, to))
+	default:
+		return nil, fmt.Errorf("unexpected postgres upgrade object %s", obj.GetName())
+	}
+	return obj, nil
+}
+
+// setPostgresUpgradePodSpec sets the image of the database container, and the volume holding
+// the data, of the provided pod spec.
+func setPostgresUpgradePodSpec(spec *corev1.PodSpec, image, volume string) {
+	if len(spec.Containers) > 0 {
+		spec.Containers[0].Image = image
+	}
+	for i := range spec.Volumes {
+		if spec.Volumes[i].PersistentVolumeClaim != nil {
+			spec.Volumes[i].PersistentVolumeClaim.ClaimName = volume
+		}
+	}
+}
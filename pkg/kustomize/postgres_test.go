@@ -0,0 +1,221 @@
+package kustomize
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	v1 "github.com/quay/quay-operator/apis/quay/v1"
+)
+
+func TestPostgresImageFor(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		component v1.ComponentKind
+		major     int32
+		env       map[string]string
+		override  string
+		expected  string
+		expectErr bool
+	}{
+		{
+			name:      "DefaultTarget",
+			component: v1.ComponentClairPostgres,
+			major:     15,
+			expected:  "quay.io/sclorg/postgresql-15-c9s:latest",
+		},
+		{
+			name:      "DefaultIntermediate",
+			component: v1.ComponentClairPostgres,
+			major:     10,
+			env: map[string]string{
+				"RELATED_IMAGE_COMPONENT_CLAIRPOSTGRES_PREVIOUS": "registry.example.com/postgres:13",
+			},
+			expected: "centos/postgresql-10-centos7:latest",
+		},
+		{
+			name:      "OverriddenTarget",
+			component: v1.ComponentPostgres,
+			major:     13,
+			env: map[string]string{
+				"RELATED_IMAGE_COMPONENT_POSTGRES": "registry.example.com/postgres@sha256:71b24684d64da46f960682cc4216222a7e4ed8b1a31dd5a865b3e71afdea20d2",
+			},
+			expected: "registry.example.com/postgres@sha256:71b24684d64da46f960682cc4216222a7e4ed8b1a31dd5a865b3e71afdea20d2",
+		},
+		{
+			name:      "OverriddenTargetThroughComponent",
+			component: v1.ComponentPostgres,
+			major:     13,
+			env: map[string]string{
+				"RELATED_IMAGE_COMPONENT_POSTGRES": "registry.example.com/postgres:13",
+			},
+			override: "mirror.local:5000/postgres:13-hotfix",
+			expected: "mirror.local:5000/postgres:13-hotfix",
+		},
+		{
+			name:      "ComponentOverrideIgnoredForPrevious",
+			component: v1.ComponentPostgres,
+			major:     10,
+			override:  "mirror.local:5000/postgres:13-hotfix",
+			expected:  "centos/postgresql-10-centos7:latest",
+		},
+		{
+			name:      "OverriddenPrevious",
+			component: v1.ComponentClairPostgres,
+			major:     13,
+			env: map[string]string{
+				"RELATED_IMAGE_COMPONENT_CLAIRPOSTGRES_PREVIOUS": "registry.example.com/postgres:13",
+			},
+			expected: "registry.example.com/postgres:13",
+		},
+		{
+			name:      "InvalidOverride",
+			component: v1.ComponentPostgres,
+			major:     10,
+			env: map[string]string{
+				"RELATED_IMAGE_COMPONENT_POSTGRES_PREVIOUS": "postgres",
+			},
+			expectErr: true,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+
+			version, ok := v1.PostgresVersionFor(tt.component, tt.major)
+			assert.True(t, ok)
+
+			quay := &v1.QuayRegistry{}
+			if tt.override != "" {
+				quay.Spec.Components = []v1.Component{
+					{
+						Kind:      tt.component,
+						Managed:   true,
+						Overrides: &v1.Override{Image: tt.override},
+					},
+				}
+			}
+
+			image, err := PostgresImageFor(quay, tt.component, version)
+			if tt.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, image)
+		})
+	}
+}
+
+func postgresUpgradeTestObjects(prefix string) []client.Object {
+	podSpec := func(claim string) corev1.PodSpec {
+		return corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "postgres", Image: "postgres"}},
+			Volumes: []corev1.Volume{
+				{
+					Name: "postgres-data",
+					VolumeSource: corev1.VolumeSource{
+						PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+							ClaimName: claim,
+						},
+					},
+				},
+			},
+		}
+	}
+
+	return []client.Object{
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: prefix + "-old"},
+			Spec: appsv1.DeploymentSpec{
+				Template: corev1.PodTemplateSpec{Spec: podSpec("old")},
+			},
+		},
+		&batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{Name: prefix + "-upgrade"},
+			Spec: batchv1.JobSpec{
+				Template: corev1.PodTemplateSpec{Spec: podSpec("new")},
+			},
+		},
+		&corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: prefix + "-next"},
+		},
+	}
+}
+
+func TestProcessPostgresUpgrade(t *testing.T) {
+	t.Run("FirstStep", func(t *testing.T) {
+		quay := quayRegistry("test")
+		quay.Status.PostgresUpgrades = []v1.PostgresUpgradeStep{
+			{
+				Component: v1.ComponentClairPostgres,
+				From:      10,
+				To:        13,
+				Phase:     v1.PostgresUpgradePhaseRunning,
+			},
+		}
+
+		objs := postgresUpgradeTestObjects("test-clair-postgres")
+		for i, obj := range objs {
+			processed, err := processPostgresUpgrade(quay, obj)
+			assert.NoError(t, err)
+			objs[i] = processed
+		}
+
+		old := objs[0].(*appsv1.Deployment).Spec.Template.Spec
+		assert.Equal(t, "centos/postgresql-10-centos7:latest", old.Containers[0].Image)
+		assert.Equal(t, "test-clair-postgres", old.Volumes[0].PersistentVolumeClaim.ClaimName)
+
+		job := objs[1].(*batchv1.Job).Spec.Template.Spec
+		assert.Equal(t, "quay.io/sclorg/postgresql-13-c9s:latest", job.Containers[0].Image)
+		assert.Equal(t, "test-clair-postgres-13", job.Volumes[0].PersistentVolumeClaim.ClaimName)
+
+		assert.NotNil(t, objs[2])
+		assert.Equal(t, "test-clair-postgres-13", objs[2].GetName())
+	})
+
+	t.Run("LastStep", func(t *testing.T) {
+		quay := quayRegistry("test")
+		quay.Status.DatabaseVersions = []v1.DatabaseVersion{
+			{
+				Component:             v1.ComponentClairPostgres,
+				Version:               13,
+				PersistentVolumeClaim: "test-clair-postgres-13",
+			},
+		}
+
+		objs := postgresUpgradeTestObjects("test-clair-postgres")
+		for i, obj := range objs {
+			processed, err := processPostgresUpgrade(quay, obj)
+			assert.NoError(t, err)
+			objs[i] = processed
+		}
+
+		old := objs[0].(*appsv1.Deployment).Spec.Template.Spec
+		assert.Equal(t, "quay.io/sclorg/postgresql-13-c9s:latest", old.Containers[0].Image)
+		assert.Equal(t, "test-clair-postgres-13", old.Volumes[0].PersistentVolumeClaim.ClaimName)
+
+		job := objs[1].(*batchv1.Job).Spec.Template.Spec
+		assert.Equal(t, "quay.io/sclorg/postgresql-15-c9s:latest", job.Containers[0].Image)
+		assert.Equal(t, "test-clair-postgres-15", job.Volumes[0].PersistentVolumeClaim.ClaimName)
+
+		assert.Nil(t, objs[2])
+	})
+
+	t.Run("OtherObject", func(t *testing.T) {
+		quay := quayRegistry("test")
+		obj := &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-quay-postgres-13"},
+		}
+
+		processed, err := processPostgresUpgrade(quay, obj)
+		assert.NoError(t, err)
+		assert.Equal(t, obj, processed)
+	})
+}
@@ -0,0 +1,70 @@
+package kustomize
+
+import (
+	"fmt"
+
+	"github.com/quay/quay/config-tool/pkg/lib/shared"
+
+	v1 "github.com/quay/quay-operator/apis/quay/v1"
+)
+
+const (
+	// redisSentinelMasterName is the name the sentinels monitor the redis master under, it
+	// must match the name used by the scripts of the redis/ha component.
+	redisSentinelMasterName = "quay"
+	redisSentinelPort       = 26379
+)
+
+// RedisSentinelFieldGroup holds the redis config fields rendered when the managed redis is
+// highly available. The config-tool library does not support sentinel.
+type RedisSentinelFieldGroup struct {
+	BuildlogsRedis  *RedisSentinelConfig `json:"BUILDLOGS_REDIS"`
+	UserEventsRedis *RedisSentinelConfig `json:"USER_EVENTS_REDIS"`
+}
+
+// Fields returns the config fields in this field group.
+func (fg *RedisSentinelFieldGroup) Fields() []string {
+	return []string{"BUILDLOGS_REDIS", "USER_EVENTS_REDIS"}
+}
+
+// Validate is a no-op, the fields are generated by the operator.
+func (fg *RedisSentinelFieldGroup) Validate(opts shared.Options) []shared.ValidationError {
+	return nil
+}
+
+// RedisSentinelConfig points Quay to the sentinels tracking the current redis master.
+type RedisSentinelConfig struct {
+	SentinelHosts  []string `json:"sentinel_hosts"`
+	SentinelMaster string   `json:"sentinel_master"`
+}
+
+// RedisSentinelHostsFor returns the addresses of the sentinels, one per redis replica, of the
+// provided QuayRegistry.
+func RedisSentinelHostsFor(quay *v1.QuayRegistry) []string {
+	statefulset := fmt.Sprintf("%s-quay-redis", quay.GetName())
+
+	var hosts []string
+	for i := int32(0); i < v1.RedisReplicasFor(quay); i++ {
+		hosts = append(
+			hosts,
+			fmt.Sprintf(
+				"%s-%d.%s-headless:%d", statefulset, i, statefulset, redisSentinelPort,
+			),
+		)
+	}
+	return hosts
+}
+
+// redisSentinelFieldGroupFor returns the redis config for a highly available managed redis.
+func redisSentinelFieldGroupFor(quay *v1.QuayRegistry) *RedisSentinelFieldGroup {
+	config := func() *RedisSentinelConfig {
+		return &RedisSentinelConfig{
+			SentinelHosts:  RedisSentinelHostsFor(quay),
+			SentinelMaster: redisSentinelMasterName,
+		}
+	}
+	return &RedisSentinelFieldGroup{
+		BuildlogsRedis:  config(),
+		UserEventsRedis: config(),
+	}
+}
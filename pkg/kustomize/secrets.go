@@ -6,7 +6,9 @@ import (
 	"fmt"
 	"net/url"
 	"os"
+	"path"
 	"strings"
+	"time"
 
 	"github.com/go-logr/logr"
 	"github.com/quay/clair/config"
@@ -21,10 +23,14 @@ import (
 
 	v1 "github.com/quay/quay-operator/apis/quay/v1"
 	quaycontext "github.com/quay/quay-operator/pkg/context"
+	quaytls "github.com/quay/quay-operator/pkg/tls"
 )
 
 const (
 	secretKeyLength = 80
+	// databaseCertsDir is where the CA and client certificates used to connect to the managed
+	// databases are mounted, in the quay and in the clair pods.
+	databaseCertsDir = "/run/secrets/postgresql"
 )
 
 // FieldGroupFor generates and returns the correct config field group for the given component.
@@ -62,6 +68,10 @@ func FieldGroupFor(
 		return fieldGroup, nil
 
 	case v1.ComponentRedis:
+		if v1.RedisHighAvailabilityEnabled(quay) {
+			return redisSentinelFieldGroupFor(quay), nil
+		}
+
 		fieldGroup, err := redis.NewRedisFieldGroup(map[string]interface{}{})
 		if err != nil {
 			return nil, err
@@ -127,6 +137,26 @@ func FieldGroupFor(
 		}
 		return fieldGroup, nil
 
+	case v1.ComponentIngress, v1.ComponentGateway:
+		// ingresses and gateway routes can't be rendered without a hostname, unlike
+		// routes there is no cluster wide domain we can fall back to.
+		if ctx.ServerHostname == "" {
+			return nil, fmt.Errorf(
+				"cannot configure managed %s, `SERVER_HOSTNAME` is not set", component,
+			)
+		}
+
+		terminateExternally := len(ctx.TLSCert) == 0 && len(ctx.TLSKey) == 0
+		fieldGroup := &hostsettings.HostSettingsFieldGroup{
+			ExternalTlsTermination: terminateExternally,
+			PreferredUrlScheme:     "https",
+			ServerHostname:         ctx.ServerHostname,
+		}
+		return fieldGroup, nil
+
+	case v1.ComponentBuilder:
+		return buildManagerFieldGroupFor(ctx, quay), nil
+
 	case v1.ComponentMirror:
 		fieldGroup := &repomirror.RepoMirrorFieldGroup{
 			FeatureRepoMirror:   true,
@@ -181,6 +211,175 @@ func BaseQuayConfig() map[string]interface{} {
 	}
 }
 
+// EnsureOperatorTLS makes sure the context holds a CA and a serving certificate, issued by the
+// operator, for the quay and build manager hostnames. Both are generated when missing and are
+// renewed before they expire, the serving certificate is reissued whenever the CA changes.
+func EnsureOperatorTLS(
+	ctx *quaycontext.QuayRegistryContext, quay *v1.QuayRegistry, now time.Time,
+) error {
+	if ctx.ServerHostname == "" {
+		return fmt.Errorf("cannot issue TLS certificate, `SERVER_HOSTNAME` is not set")
+	}
+
+	hosts := []string{strings.Split(ctx.ServerHostname, ":")[0]}
+	if ctx.BuildManagerHostname != "" {
+		hosts = append(hosts, strings.Split(ctx.BuildManagerHostname, ":")[0])
+	}
+
+	if quaytls.NeedsRenewal(ctx.OperatorCACert, nil, nil, quaytls.CARenewBefore, now) {
+		cacert, cakey, err := quaytls.GenerateCA(v1.OperatorCAConfigMapNameFor(quay), now)
+		if err != nil {
+			return err
+		}
+		ctx.OperatorCACert = cacert
+		ctx.OperatorCAKey = cakey
+	}
+
+	if quaytls.NeedsRenewal(
+		ctx.TLSCert, ctx.OperatorCACert, hosts, quaytls.CertRenewBefore, now,
+	) {
+		cert, key, err := quaytls.GenerateCert(
+			ctx.OperatorCACert, ctx.OperatorCAKey, hosts, now,
+		)
+		if err != nil {
+			return err
+		}
+		ctx.TLSCert = cert
+		ctx.TLSKey = key
+	}
+
+	ctx.TLSSecretHash = quaytls.Hash(ctx.TLSCert, ctx.TLSKey)
+	return nil
+}
+
+// EnsureDatabaseTLS populates the provided QuayRegistryContext with a CA and with the server
+// and client certificates for the managed databases with TLS enabled. Certificates are issued
+// if missing, about to expire or not valid for the database service anymore. The CA is also
+// regenerated before it expires, reissuing all certificates with it.
+func EnsureDatabaseTLS(
+	ctx *quaycontext.QuayRegistryContext, quay *v1.QuayRegistry, now time.Time,
+) error {
+	pgtls := v1.DatabaseTLSEnabled(quay, v1.ComponentPostgres)
+	clairtls := v1.DatabaseTLSEnabled(quay, v1.ComponentClairPostgres)
+	if !pgtls && !clairtls {
+		return nil
+	}
+
+	if quaytls.NeedsRenewal(ctx.DatabaseCACert, nil, nil, quaytls.CARenewBefore, now) {
+		cacert, cakey, err := quaytls.GenerateCA(quay.GetName()+"-postgres-ca", now)
+		if err != nil {
+			return err
+		}
+		ctx.DatabaseCACert = cacert
+		ctx.DatabaseCAKey = cakey
+	}
+
+	if pgtls {
+		hosts := databaseHostsFor(quay, quay.GetName()+"-quay-database")
+		if err := ensureDatabaseCert(
+			ctx, &ctx.DatabaseServerCert, &ctx.DatabaseServerKey, hosts, now,
+		); err != nil {
+			return err
+		}
+
+		if err := ensureDatabaseClientCert(
+			ctx, &ctx.DatabaseClientCert, &ctx.DatabaseClientKey, quay.GetName()+"-quay-app", now,
+		); err != nil {
+			return err
+		}
+	}
+
+	if clairtls {
+		hosts := databaseHostsFor(quay, quay.GetName()+"-clair-postgres")
+		if err := ensureDatabaseCert(
+			ctx, &ctx.ClairDatabaseServerCert, &ctx.ClairDatabaseServerKey, hosts, now,
+		); err != nil {
+			return err
+		}
+
+		if err := ensureDatabaseClientCert(
+			ctx, &ctx.ClairDatabaseClientCert, &ctx.ClairDatabaseClientKey, quay.GetName()+"-clair-app", now,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// databaseURIWithTLS returns the provided database URI configured to verify the server
+// certificate, against the CA mounted in the quay pods, if tls is true. If tls is false the
+// TLS parameters are removed from the URI instead.
+func databaseURIWithTLS(uri string, tls bool) (string, error) {
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		return "", fmt.Errorf("unable to parse database uri: %w", err)
+	}
+
+	query := parsed.Query()
+	if tls {
+		query.Set("sslmode", "verify-full")
+		query.Set("sslrootcert", path.Join(databaseCertsDir, "root.crt"))
+	} else {
+		query.Del("sslmode")
+		query.Del("sslrootcert")
+	}
+	parsed.RawQuery = query.Encode()
+	return parsed.String(), nil
+}
+
+// databaseHostsFor returns the names through which the provided database service is reached
+// from within the cluster.
+func databaseHostsFor(quay *v1.QuayRegistry, service string) []string {
+	hosts := []string{service}
+	if ns := quay.GetNamespace(); ns != "" {
+		hosts = append(
+			hosts,
+			strings.Join([]string{service, ns}, "."),
+			strings.Join([]string{service, ns, "svc"}, "."),
+			strings.Join([]string{service, ns, "svc", "cluster", "local"}, "."),
+		)
+	}
+	return hosts
+}
+
+// ensureDatabaseCert reissues the provided database server certificate, signed by the database
+// CA, if it needs to be renewed.
+func ensureDatabaseCert(
+	ctx *quaycontext.QuayRegistryContext, cert, key *[]byte, hosts []string, now time.Time,
+) error {
+	if !quaytls.NeedsRenewal(*cert, ctx.DatabaseCACert, hosts, quaytls.CertRenewBefore, now) {
+		return nil
+	}
+
+	newcert, newkey, err := quaytls.GenerateCert(ctx.DatabaseCACert, ctx.DatabaseCAKey, hosts, now)
+	if err != nil {
+		return err
+	}
+	*cert = newcert
+	*key = newkey
+	return nil
+}
+
+// ensureDatabaseClientCert reissues the provided database client certificate, signed by the
+// database CA, if it needs to be renewed.
+func ensureDatabaseClientCert(
+	ctx *quaycontext.QuayRegistryContext, cert, key *[]byte, commonName string, now time.Time,
+) error {
+	if !quaytls.NeedsRenewal(*cert, ctx.DatabaseCACert, nil, quaytls.CertRenewBefore, now) {
+		return nil
+	}
+
+	newcert, newkey, err := quaytls.GenerateClientCert(
+		ctx.DatabaseCACert, ctx.DatabaseCAKey, commonName, now,
+	)
+	if err != nil {
+		return err
+	}
+	*cert = newcert
+	*key = newkey
+	return nil
+}
+
 // EnsureTLSFor checks if given TLS cert/key pair are valid for the Quay registry to use for
 // secure communication with clients.
 func EnsureTLSFor(
@@ -269,9 +468,14 @@ func ContainsComponentConfig(
 	case v1.ComponentMirror:
 		fields = (&repomirror.RepoMirrorFieldGroup{}).Fields()
 
-	case v1.ComponentRoute:
+	case v1.ComponentRoute, v1.ComponentIngress, v1.ComponentGateway:
 		fields = (&hostsettings.HostSettingsFieldGroup{}).Fields()
 
+	case v1.ComponentBuilder:
+		// a build manager hostname may be provided along with the managed builder,
+		// only the executors config is managed by the operator.
+		fields = []string{"BUILD_MANAGER"}
+
 	case v1.ComponentMonitoring:
 		return false, nil
 
@@ -309,6 +513,11 @@ func ContainsComponentConfig(
 func componentConfigFilesFor(log logr.Logger, qctx *quaycontext.QuayRegistryContext, component v1.ComponentKind, quay *v1.QuayRegistry, configFiles map[string][]byte) (map[string][]byte, error) {
 	switch component {
 	case v1.ComponentPostgres:
+		// cloudnativepg generates the credentials of the cluster it runs.
+		if v1.DatabaseClusterEnabled(quay, v1.ComponentPostgres) {
+			return nil, nil
+		}
+
 		dbConfig, ok := configFiles["postgres.config.yaml"]
 		if !ok {
 			return nil, fmt.Errorf("cannot generate managed component config file for `postgres` if `postgres.config.yaml` is missing")
@@ -380,6 +589,10 @@ func componentConfigFilesFor(log logr.Logger, qctx *quaycontext.QuayRegistryCont
 
 		return cfgFiles, nil
 	case v1.ComponentClairPostgres:
+		if v1.DatabaseClusterEnabled(quay, v1.ComponentClairPostgres) {
+			return nil, nil
+		}
+
 		return map[string][]byte{
 			"database-username":      []byte(qctx.ClairDbUser),
 			"database-password":      []byte(qctx.ClairDbPassword),
@@ -409,6 +622,23 @@ func clairConfigFor(log logr.Logger, qctx *quaycontext.QuayRegistryContext, quay
 	user := qctx.ClairDbUser
 	password := qctx.ClairDbPassword
 	dbConn := fmt.Sprintf("host=%s port=5432 dbname=%s user=%s password=%s sslmode=disable pool_max_conns=%d", host, dbname, user, password, poolsize)
+	if v1.DatabaseClusterEnabled(quay, v1.ComponentClairPostgres) {
+		// cloudnativepg serves the primary through the read-write service and enables tls
+		// with its own certificates.
+		dbConn = fmt.Sprintf(
+			"host=%s-rw port=5432 dbname=%s user=%s password=%s sslmode=require pool_max_conns=%d",
+			host, dbname, user, password, poolsize,
+		)
+	} else if v1.DatabaseTLSEnabled(quay, v1.ComponentClairPostgres) {
+		dbConn = fmt.Sprintf(
+			"host=%s port=5432 dbname=%s user=%s password=%s sslmode=verify-full sslrootcert=%s sslcert=%s sslkey=%s pool_max_conns=%d",
+			host, dbname, user, password,
+			path.Join(databaseCertsDir, "ca.crt"),
+			path.Join(databaseCertsDir, "tls.crt"),
+			path.Join(databaseCertsDir, "tls.key"),
+			poolsize,
+		)
+	}
 
 	psk, err := base64.StdEncoding.DecodeString(preSharedKey)
 	if err != nil {
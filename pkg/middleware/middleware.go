@@ -2,6 +2,8 @@ package middleware
 
 import (
 	"fmt"
+	"slices"
+	"strconv"
 	"strings"
 
 	route "github.com/openshift/api/route/v1"
@@ -10,10 +12,17 @@ import (
 	autoscalingv2 "k8s.io/api/autoscaling/v2"
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
 	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/yaml"
 
@@ -22,9 +31,51 @@ import (
 
 const (
 	configSecretPrefix    = "quay-config-secret"
+	readOnlyConfigPrefix  = "quay-config-secret-readonly"
+	readOnlyRegistryState = "readonly"
 	fieldGroupsAnnotation = "quay-managed-fieldgroups"
+	gatewayGroup          = "gateway.networking.k8s.io"
+	certManagerGroup      = "cert-manager.io"
+	cloudNativePGGroup    = "postgresql.cnpg.io"
 )
 
+// databaseBackupComponents maps the labels of the objects rendered by the backup component to
+// the database component they back up.
+var databaseBackupComponents = map[string]v1.ComponentKind{
+	"postgres-backup":       v1.ComponentPostgres,
+	"clair-postgres-backup": v1.ComponentClairPostgres,
+}
+
+// databaseSnapshotComponents maps the labels of the CronJobs rendered by the snapshot component
+// to the database component whose volume they snapshot.
+var databaseSnapshotComponents = map[string]v1.ComponentKind{
+	"postgres-snapshot":       v1.ComponentPostgres,
+	"clair-postgres-snapshot": v1.ComponentClairPostgres,
+}
+
+// imageComponents maps the quay-component of the workloads to the component whose image they
+// run, the mirror workers and the upgrade jobs run the image of another component.
+var imageComponents = map[string]v1.ComponentKind{
+	"quay":                   v1.ComponentQuay,
+	"mirror":                 v1.ComponentQuay,
+	"quay-app-upgrade":       v1.ComponentQuay,
+	"clair":                  v1.ComponentClair,
+	"redis":                  v1.ComponentRedis,
+	"postgres":               v1.ComponentPostgres,
+	"quay-postgres-upgrade":  v1.ComponentPostgres,
+	"clair-postgres":         v1.ComponentClairPostgres,
+	"clair-postgres-old":     v1.ComponentClairPostgres,
+	"clair-postgres-upgrade": v1.ComponentClairPostgres,
+}
+
+// disruptionBudgetComponents maps the quay-component of the PodDisruptionBudgets sized by
+// the Operator to the component whose pods they cover.
+var disruptionBudgetComponents = map[string]v1.ComponentKind{
+	"quay":   v1.ComponentQuay,
+	"clair":  v1.ComponentClair,
+	"mirror": v1.ComponentMirror,
+}
+
 // Process applies any additional middleware steps to a managed k8s object that cannot be
 // accomplished using the Kustomize toolchain. if skipres is set all resource requests are
 // trimmed from the objects thus deploying quay with a much smaller footprint.
@@ -77,9 +128,22 @@ func Process(quay *v1.QuayRegistry, qctx *quaycontext.QuayRegistryContext, obj c
 			}
 		}
 
+		// the read-only variant of the config is run by quay while it is upgraded with
+		// the read-only strategy or while the registry is under maintenance.
+		if strings.Contains(objectMeta.GetName(), readOnlyConfigPrefix+"-") {
+			if err := setRegistryState(configBundleSecret, readOnlyRegistryState); err != nil {
+				return nil, err
+			}
+		}
+
 		return configBundleSecret, nil
 	}
 
+	// objects rendered by the builder component live in the build namespace.
+	if labels.Set(objectMeta.GetAnnotations()).Get("quay-component") == "builder" {
+		return processBuilderObject(quay, obj), nil
+	}
+
 	// we need to remove
 	// all unused annotations from postgres deployment to avoid its redeployment.
 	if dep, ok := obj.(*appsv1.Deployment); ok {
@@ -95,20 +159,47 @@ func Process(quay *v1.QuayRegistry, qctx *quaycontext.QuayRegistryContext, obj c
 				}
 			}
 
-			// Add additional default environment variables to Quay deployment
+			// Add additional default environment variables to Quay deployment, while it
+			// is upgraded with the read-only strategy, or once its upgrade has been rolled
+			// back, it keeps running the previous version.
 			if kind == v1.ComponentQuay {
-				oenv := corev1.EnvVar{Name: "QUAY_VERSION", Value: string(v1.QuayVersionCurrent)}
+				version := v1.QuayVersionCurrent
+				if qctx.PreviousQuayImage != "" {
+					version = quay.Status.CurrentVersion
+				}
+
+				oenv := corev1.EnvVar{Name: "QUAY_VERSION", Value: string(version)}
 				for i := range dep.Spec.Template.Spec.Containers {
 					ref := &dep.Spec.Template.Spec.Containers[i]
 					UpsertContainerEnv(ref, oenv)
+					if qctx.PreviousQuayImage != "" && ref.Name == "quay-app" {
+						ref.Image = qctx.PreviousQuayImage
+					}
 				}
 			}
 		}
 
+		// once an upgrade has been rolled back the mirror workers run the previous version
+		// of Quay along with the registry.
+		if qctx.QuayRolledBack && kind == v1.ComponentMirror {
+			for i := range dep.Spec.Template.Spec.InitContainers {
+				dep.Spec.Template.Spec.InitContainers[i].Image = qctx.PreviousQuayImage
+			}
+			for i := range dep.Spec.Template.Spec.Containers {
+				dep.Spec.Template.Spec.Containers[i].Image = qctx.PreviousQuayImage
+			}
+		}
+
 		if oaff := v1.GetAffinityForComponent(quay, kind); oaff != nil {
 			dep.Spec.Template.Spec.Affinity = oaff
 		}
 
+		setSchedulingOverrides(quay, &dep.Spec.Template.Spec, kind, dep.Spec.Selector)
+
+		setImagePullSecrets(
+			quay, &dep.Spec.Template.Spec, labels.Set(objectMeta.GetAnnotations()).Get("quay-component"),
+		)
+
 		// Add annotations to track the hash of the cluster service CA. This is to ensure that we redeploy when the cluster service CA changes.
 		dep.Annotations[v1.ClusterServiceCAName] = qctx.ClusterServiceCAHash
 		dep.Annotations[v1.ClusterTrustedCAName] = qctx.ClusterTrustedCAHash
@@ -123,6 +214,14 @@ func Process(quay *v1.QuayRegistry, qctx *quaycontext.QuayRegistryContext, obj c
 			delete(dep.Spec.Template.Annotations, v1.TLSSecretHashAnnotation)
 		}
 
+		// the mirror workers are paused while the registry is under maintenance, and while
+		// it is upgraded with the read-only strategy as they would write to the database
+		// being migrated. the autoscaler does not scale a deployment up from zero replicas.
+		readonlyUpgrade := qctx.PreviousQuayImage != "" && !qctx.QuayRolledBack
+		if (v1.MaintenanceEnabled(quay) || readonlyUpgrade) && strings.HasSuffix(dep.Name, "quay-mirror") {
+			dep.Spec.Replicas = ptr.To[int32](0)
+		}
+
 		// here we do an attempt to setting the default or overwriten number of replicas
 		// for clair, quay and mirror. we can't do that if horizontal pod autoscaler is
 		// in managed state as we would be stomping in the values defined by the hpa
@@ -233,20 +332,51 @@ func Process(quay *v1.QuayRegistry, qctx *quaycontext.QuayRegistryContext, obj c
 		return dep, nil
 	}
 
+	// statefulsets are only rendered for the highly available redis.
+	if sts, ok := obj.(*appsv1.StatefulSet); ok {
+		return processRedisStatefulSet(quay, sts, skipres), nil
+	}
+
+	if cj, ok := obj.(*batchv1.CronJob); ok {
+		if kind, ok := databaseSnapshotComponents[quayComponentLabel]; ok {
+			return processDatabaseSnapshot(quay, cj, kind, skipres), nil
+		}
+		return processDatabaseBackup(quay, cj, quayComponentLabel, skipres), nil
+	}
+
 	// If the current object is a PVC, check for volume override
 	if pvc, ok := obj.(*corev1.PersistentVolumeClaim); ok {
 		var volumeSizeOverride *resource.Quantity
 		var storageClassNameOverride *string
 
+		// backups written to the object storage do not need a volume.
+		if kind, ok := databaseBackupComponents[quayComponentLabel]; ok {
+			dest := v1.DatabaseBackupDestinationFor(quay, kind)
+			if dest == v1.BackupDestinationObjectStorage {
+				return nil, nil
+			}
+		}
+
+		// backup volumes, and the volumes of intermediate upgrade steps, are sized as the
+		// volume of the database they hold the data of.
 		switch quayComponentLabel {
-		case "postgres":
+		case "postgres", "postgres-backup", "postgres-next":
 			volumeSizeOverride = v1.GetVolumeSizeOverrideForComponent(quay, v1.ComponentPostgres)
 			storageClassNameOverride = v1.GetStorageClassNameOverrideForComponent(quay, v1.ComponentPostgres)
-		case "clair-postgres":
+		case "clair-postgres", "clair-postgres-backup", "clair-postgres-next":
 			volumeSizeOverride = v1.GetVolumeSizeOverrideForComponent(quay, v1.ComponentClairPostgres)
 			storageClassNameOverride = v1.GetStorageClassNameOverrideForComponent(quay, v1.ComponentClairPostgres)
 		}
 
+		// database volumes may be cloned from a snapshot, the reconciler keeps the data
+		// source of volumes that already exist.
+		switch quayComponentLabel {
+		case "postgres":
+			pvc.Spec.DataSource = v1.GetDataSourceOverrideForComponent(quay, v1.ComponentPostgres)
+		case "clair-postgres":
+			pvc.Spec.DataSource = v1.GetDataSourceOverrideForComponent(quay, v1.ComponentClairPostgres)
+		}
+
 		// If volume override was provided
 		if volumeSizeOverride != nil {
 			// Ensure that volume size is not being reduced
@@ -272,6 +402,10 @@ func Process(quay *v1.QuayRegistry, qctx *quaycontext.QuayRegistryContext, obj c
 		return pvc, nil
 	}
 
+	if pdb, ok := obj.(*policyv1.PodDisruptionBudget); ok {
+		return processPodDisruptionBudget(quay, pdb), nil
+	}
+
 	if _, ok := obj.(*autoscalingv2.HorizontalPodAutoscaler); ok {
 		componentMap := map[string]v1.ComponentKind{
 			"mirror": v1.ComponentMirror,
@@ -307,6 +441,28 @@ func Process(quay *v1.QuayRegistry, qctx *quaycontext.QuayRegistryContext, obj c
 				job.Spec.Template.Spec.Containers[i].Resources = noresources
 			}
 		}
+
+		setImagePullSecrets(quay, &job.Spec.Template.Spec, quayComponentLabel)
+
+		if ojob := v1.GetJobOverride(quay, quayComponentLabel); ojob != nil {
+			processJobOverride(job, ojob)
+		}
+	}
+
+	if ing, ok := obj.(*networkingv1.Ingress); ok {
+		return processIngress(quay, qctx, ing, quayComponentLabel), nil
+	}
+
+	if u, ok := obj.(*unstructured.Unstructured); ok && u.GroupVersionKind().Group == gatewayGroup {
+		return processGatewayRoute(quay, qctx, u, quayComponentLabel)
+	}
+
+	if u, ok := obj.(*unstructured.Unstructured); ok && u.GroupVersionKind().Group == certManagerGroup {
+		return processCertificate(quay, qctx, u)
+	}
+
+	if u, ok := obj.(*unstructured.Unstructured); ok && u.GroupVersionKind().Group == cloudNativePGGroup {
+		return processDatabaseCluster(quay, u, quayComponentLabel, skipres)
 	}
 
 	rt, ok := obj.(*route.Route)
@@ -335,8 +491,9 @@ func Process(quay *v1.QuayRegistry, qctx *quaycontext.QuayRegistryContext, obj c
 		}
 	}
 
-	// if we are managing TLS we can simply return the original route as no change is needed.
-	if v1.ComponentIsManaged(quay.Spec.Components, v1.ComponentTLS) {
+	// if the router terminates TLS we can simply return the original route as no change is
+	// needed.
+	if !v1.QuayServesTLS(qctx, quay) {
 		return obj, nil
 	}
 
@@ -359,6 +516,708 @@ func Process(quay *v1.QuayRegistry, qctx *quaycontext.QuayRegistryContext, obj c
 	return rt, nil
 }
 
+// processDatabaseBackup applies the backup override of a database component to the CronJob
+// taking its backups. Backups written to the object storage read the bucket location and
+// credentials from the objects created for the ObjectBucketClaim.
+func processDatabaseBackup(
+	quay *v1.QuayRegistry, cj *batchv1.CronJob, label string, skipres bool,
+) client.Object {
+	kind, ok := databaseBackupComponents[label]
+	if !ok {
+		return cj
+	}
+
+	backup := v1.DatabaseBackupFor(quay, kind)
+	if backup == nil {
+		return nil
+	}
+	cj.Spec.Schedule = backup.Schedule
+
+	dest := v1.DatabaseBackupDestinationFor(quay, kind)
+	retention := v1.DatabaseBackupRetentionFor(quay, kind)
+	podspec := &cj.Spec.JobTemplate.Spec.Template.Spec
+	for i := range podspec.Containers {
+		ref := &podspec.Containers[i]
+		UpsertContainerEnv(ref, corev1.EnvVar{Name: "BACKUP_DESTINATION", Value: string(dest)})
+		UpsertContainerEnv(ref, corev1.EnvVar{Name: "BACKUP_RETENTION", Value: fmt.Sprint(retention)})
+		if kind == v1.ComponentClairPostgres {
+			UpsertContainerEnv(
+				ref, corev1.EnvVar{Name: "PGHOST", Value: quay.GetName() + "-clair-postgres"},
+			)
+		}
+
+		if dest == v1.BackupDestinationObjectStorage {
+			datastore := quay.GetName() + "-quay-datastore"
+			ref.EnvFrom = append(
+				ref.EnvFrom,
+				corev1.EnvFromSource{
+					ConfigMapRef: &corev1.ConfigMapEnvSource{
+						LocalObjectReference: corev1.LocalObjectReference{Name: datastore},
+					},
+				},
+				corev1.EnvFromSource{
+					SecretRef: &corev1.SecretEnvSource{
+						LocalObjectReference: corev1.LocalObjectReference{Name: datastore},
+					},
+				},
+			)
+		}
+
+		if skipres {
+			ref.Resources = corev1.ResourceRequirements{}
+		}
+	}
+
+	// dumps are only staged locally before being uploaded to the bucket.
+	if dest == v1.BackupDestinationObjectStorage {
+		for i := range podspec.Volumes {
+			if podspec.Volumes[i].Name != "backups" {
+				continue
+			}
+			podspec.Volumes[i].VolumeSource = corev1.VolumeSource{
+				EmptyDir: &corev1.EmptyDirVolumeSource{},
+			}
+		}
+	}
+	return cj
+}
+
+// processDatabaseSnapshot applies the snapshot override of a database component to the CronJob
+// taking snapshots of its volume. Without a schedule the CronJob is suspended and only runs
+// when a Job is created from it.
+func processDatabaseSnapshot(
+	quay *v1.QuayRegistry, cj *batchv1.CronJob, kind v1.ComponentKind, skipres bool,
+) client.Object {
+	snapshot := v1.DatabaseSnapshotFor(quay, kind)
+	if snapshot == nil {
+		return nil
+	}
+
+	if snapshot.Schedule != "" {
+		cj.Spec.Schedule = snapshot.Schedule
+	} else {
+		cj.Spec.Suspend = ptr.To(true)
+	}
+
+	host := quay.GetName() + "-quay-database"
+	if kind == v1.ComponentClairPostgres {
+		host = quay.GetName() + "-clair-postgres"
+	}
+
+	retention := v1.DatabaseSnapshotRetentionFor(quay, kind)
+	podspec := &cj.Spec.JobTemplate.Spec.Template.Spec
+	for i := range podspec.Containers {
+		ref := &podspec.Containers[i]
+		for _, env := range []corev1.EnvVar{
+			{Name: "SNAPSHOT_NAME", Value: v1.DatabaseSnapshotNameFor(quay, kind)},
+			{Name: "SNAPSHOT_RETENTION", Value: fmt.Sprint(retention)},
+			{Name: "QUAY_REGISTRY", Value: quay.GetName()},
+			{Name: "PERSISTENT_VOLUME_CLAIM", Value: v1.DatabaseVolumeNameFor(quay, kind)},
+			{Name: "PGHOST", Value: host},
+		} {
+			UpsertContainerEnv(ref, env)
+		}
+
+		if snapshot.VolumeSnapshotClassName != "" {
+			UpsertContainerEnv(
+				ref,
+				corev1.EnvVar{
+					Name:  "VOLUME_SNAPSHOT_CLASS",
+					Value: snapshot.VolumeSnapshotClassName,
+				},
+			)
+		}
+
+		if skipres {
+			ref.Resources = corev1.ResourceRequirements{}
+		}
+	}
+	return cj
+}
+
+// setImagePullSecrets adds the pull secrets of the component whose image is run by the
+// workload with the provided quay-component to the provided pod spec.
+func setImagePullSecrets(quay *v1.QuayRegistry, spec *corev1.PodSpec, component string) {
+	kind, ok := imageComponents[component]
+	if !ok {
+		return
+	}
+
+	for _, secret := range v1.GetImagePullSecretsOverrideForComponent(quay, kind) {
+		if !slices.Contains(spec.ImagePullSecrets, secret) {
+			spec.ImagePullSecrets = append(spec.ImagePullSecrets, secret)
+		}
+	}
+}
+
+// setSchedulingOverrides applies the tolerations, node selector, topology spread constraints
+// and priority class set for the provided component to the provided pod spec. Constraints
+// without a label selector are given the provided selector of the pods of the component.
+func setSchedulingOverrides(
+	quay *v1.QuayRegistry, spec *corev1.PodSpec, kind v1.ComponentKind, selector *metav1.LabelSelector,
+) {
+	if otolerations := v1.GetTolerationsOverrideForComponent(quay, kind); otolerations != nil {
+		spec.Tolerations = otolerations
+	}
+
+	if oselector := v1.GetNodeSelectorOverrideForComponent(quay, kind); oselector != nil {
+		spec.NodeSelector = oselector
+	}
+
+	if oconstraints := v1.GetTopologySpreadConstraintsOverrideForComponent(quay, kind); oconstraints != nil {
+		spec.TopologySpreadConstraints = topologySpreadConstraintsFor(oconstraints, selector)
+	}
+
+	if opriority := v1.GetPriorityClassNameOverrideForComponent(quay, kind); opriority != nil {
+		spec.PriorityClassName = *opriority
+	}
+}
+
+// topologySpreadConstraintsFor returns a copy of the provided constraints, the ones without a
+// label selector are given the provided one.
+func topologySpreadConstraintsFor(
+	constraints []corev1.TopologySpreadConstraint, selector *metav1.LabelSelector,
+) []corev1.TopologySpreadConstraint {
+	result := make([]corev1.TopologySpreadConstraint, 0, len(constraints))
+	for _, constraint := range constraints {
+		constraint := *constraint.DeepCopy()
+		if constraint.LabelSelector == nil && selector != nil {
+			constraint.LabelSelector = selector.DeepCopy()
+		}
+		result = append(result, constraint)
+	}
+	return result
+}
+
+// processPodDisruptionBudget sizes the PodDisruptionBudget of the quay, clair and mirror
+// components from the number of replicas they run with. Returns nil if the component runs
+// less than two replicas, a budget would then block node drains. Other budgets, e.g. the one
+// of the highly available redis, are returned as is.
+func processPodDisruptionBudget(
+	quay *v1.QuayRegistry, pdb *policyv1.PodDisruptionBudget,
+) client.Object {
+	kind, ok := disruptionBudgetComponents[labels.Set(pdb.GetAnnotations()).Get("quay-component")]
+	if !ok {
+		return pdb
+	}
+
+	budget := v1.PodDisruptionBudgetFor(quay, kind)
+	if budget == nil {
+		return nil
+	}
+
+	pdb.Spec.MinAvailable = nil
+	pdb.Spec.MaxUnavailable = nil
+	if budget.MinAvailable != nil {
+		pdb.Spec.MinAvailable = ptr.To(*budget.MinAvailable)
+	}
+	if budget.MaxUnavailable != nil {
+		pdb.Spec.MaxUnavailable = ptr.To(*budget.MaxUnavailable)
+	}
+	return pdb
+}
+
+// processJobOverride applies the overrides set by the user for an upgrade Job, they take
+// precedence over the ones borrowed from the quay component.
+func processJobOverride(job *batchv1.Job, ojob *v1.JobOverride) {
+	podspec := &job.Spec.Template.Spec
+	if ojob.Resources != nil {
+		for i := range podspec.Containers {
+			podspec.Containers[i].Resources.Requests = ojob.Resources.Requests
+			podspec.Containers[i].Resources.Limits = ojob.Resources.Limits
+		}
+	}
+
+	if ojob.NodeSelector != nil {
+		podspec.NodeSelector = ojob.NodeSelector
+	}
+
+	if ojob.Affinity != nil {
+		podspec.Affinity = ojob.Affinity
+	}
+
+	if ojob.Tolerations != nil {
+		podspec.Tolerations = ojob.Tolerations
+	}
+
+	if ojob.ActiveDeadlineSeconds != nil {
+		job.Spec.ActiveDeadlineSeconds = ojob.ActiveDeadlineSeconds
+	}
+
+	if ojob.BackoffLimit != nil {
+		job.Spec.BackoffLimit = ojob.BackoffLimit
+	}
+
+	if ojob.TTLSecondsAfterFinished != nil {
+		job.Spec.TTLSecondsAfterFinished = ojob.TTLSecondsAfterFinished
+	}
+}
+
+// processBuilderObject moves an object rendered by the builder component to the build
+// namespace, references to the builder service account are updated accordingly.
+func processBuilderObject(quay *v1.QuayRegistry, obj client.Object) client.Object {
+	namespace := v1.BuilderNamespaceFor(quay)
+	obj.SetNamespace(namespace)
+
+	switch o := obj.(type) {
+	case *rbacv1.RoleBinding:
+		for i := range o.Subjects {
+			o.Subjects[i].Namespace = namespace
+		}
+	case *corev1.Secret:
+		// kustomize does not prefix names referenced from annotations.
+		o.Annotations[corev1.ServiceAccountNameKey] = v1.BuilderServiceAccountNameFor(quay)
+	}
+	return obj
+}
+
+// processRedisStatefulSet applies the redis overrides to the StatefulSet rendered when redis
+// is highly available. The sentinel quorum follows the number of replicas.
+func processRedisStatefulSet(
+	quay *v1.QuayRegistry, sts *appsv1.StatefulSet, skipres bool,
+) *appsv1.StatefulSet {
+	replicas := v1.RedisReplicasFor(quay)
+	sts.Spec.Replicas = &replicas
+
+	quorum := corev1.EnvVar{
+		Name:  "SENTINEL_QUORUM",
+		Value: strconv.Itoa(int(v1.RedisSentinelQuorumFor(quay))),
+	}
+	for i := range sts.Spec.Template.Spec.Containers {
+		ref := &sts.Spec.Template.Spec.Containers[i]
+		for _, oenv := range v1.GetEnvOverrideForComponent(quay, v1.ComponentRedis) {
+			UpsertContainerEnv(ref, oenv)
+		}
+		if ref.Name == "redis-sentinel" {
+			UpsertContainerEnv(ref, quorum)
+		}
+		if skipres {
+			ref.Resources = corev1.ResourceRequirements{}
+		}
+	}
+
+	if oresources := v1.GetResourceOverridesForComponent(quay, v1.ComponentRedis); oresources != nil {
+		ref := &sts.Spec.Template.Spec.Containers[0]
+		ref.Resources.Requests = oresources.Requests
+		ref.Resources.Limits = oresources.Limits
+	}
+
+	setImagePullSecrets(quay, &sts.Spec.Template.Spec, "redis")
+	setSchedulingOverrides(quay, &sts.Spec.Template.Spec, v1.ComponentRedis, sts.Spec.Selector)
+
+	if olabels := v1.GetLabelsOverrideForComponent(quay, v1.ComponentRedis); olabels != nil {
+		if sts.Labels == nil {
+			sts.Labels = map[string]string{}
+		}
+		if sts.Spec.Template.Labels == nil {
+			sts.Spec.Template.Labels = map[string]string{}
+		}
+		for key, value := range olabels {
+			if v1.ExceptionLabel(key) {
+				continue
+			}
+			sts.Labels[key] = value
+			sts.Spec.Template.Labels[key] = value
+		}
+	}
+
+	if oannot := v1.GetAnnotationsOverrideForComponent(quay, v1.ComponentRedis); oannot != nil {
+		if sts.Annotations == nil {
+			sts.Annotations = map[string]string{}
+		}
+		if sts.Spec.Template.Annotations == nil {
+			sts.Spec.Template.Annotations = map[string]string{}
+		}
+		for key, value := range oannot {
+			sts.Annotations[key] = value
+			sts.Spec.Template.Annotations[key] = value
+		}
+	}
+	return sts
+}
+
+// processIngress sets the hosts and the TLS configuration for the quay app and builder
+// ingresses and applies the user provided overrides. Returns nil if the ingress must not
+// be rendered.
+func processIngress(
+	quay *v1.QuayRegistry,
+	qctx *quaycontext.QuayRegistryContext,
+	ing *networkingv1.Ingress,
+	quayComponentLabel string,
+) client.Object {
+	host := qctx.ServerHostname
+	if quayComponentLabel == "quay-builder-ingress" {
+		// without a build manager hostname builds are not exposed.
+		if qctx.BuildManagerHostname == "" {
+			return nil
+		}
+		host = strings.Split(qctx.BuildManagerHostname, ":")[0]
+	}
+
+	for i := range ing.Spec.Rules {
+		ing.Spec.Rules[i].Host = host
+	}
+
+	tls := networkingv1.IngressTLS{Hosts: []string{host}}
+	if ref := v1.GetTLSSecretRef(quay.Spec.Components); ref != nil {
+		tls.SecretName = ref.Name
+	} else if v1.TLSIssuedByCertManager(quay) {
+		tls.SecretName = v1.CertManagerTLSSecretName(quay)
+	} else if v1.TLSIssuedByOperator(qctx, quay) {
+		tls.SecretName = v1.OperatorTLSSecretNameFor(quay)
+	}
+	ing.Spec.TLS = []networkingv1.IngressTLS{tls}
+
+	if class := v1.GetIngressClassNameOverrideForComponent(quay, v1.ComponentIngress); class != nil {
+		ing.Spec.IngressClassName = class
+	}
+
+	if ing.Annotations == nil {
+		ing.Annotations = map[string]string{}
+	}
+
+	// if quay serves its own certificate traffic between the ingress controller and the pods
+	// is encrypted as well.
+	if v1.QuayServesTLS(qctx, quay) {
+		switch quayComponentLabel {
+		case "quay-app-ingress":
+			ing.Annotations["nginx.ingress.kubernetes.io/backend-protocol"] = "HTTPS"
+			for _, rule := range ing.Spec.Rules {
+				if rule.HTTP == nil {
+					continue
+				}
+				for i := range rule.HTTP.Paths {
+					if svc := rule.HTTP.Paths[i].Backend.Service; svc != nil {
+						svc.Port.Name = "https"
+					}
+				}
+			}
+		case "quay-builder-ingress":
+			ing.Annotations["nginx.ingress.kubernetes.io/backend-protocol"] = "GRPCS"
+		}
+	}
+
+	if olabels := v1.GetLabelsOverrideForComponent(quay, v1.ComponentIngress); olabels != nil {
+		if ing.Labels == nil {
+			ing.Labels = map[string]string{}
+		}
+		for key, value := range olabels {
+			if v1.ExceptionLabel(key) {
+				continue
+			}
+			ing.Labels[key] = value
+		}
+	}
+
+	// annotation overrides are applied last so users can replace our defaults, e.g. when
+	// using an ingress controller other than ingress-nginx.
+	for key, value := range v1.GetAnnotationsOverrideForComponent(quay, v1.ComponentIngress) {
+		ing.Annotations[key] = value
+	}
+
+	return ing
+}
+
+// processGatewayRoute attaches the Gateway API routes to the Gateway referenced by the user
+// and sets their hostnames. If quay serves its own certificate only the TLSRoutes (passthrough)
+// are kept, otherwise the Gateway terminates TLS (edge) and the TLSRoutes are dropped. Returns nil if the route must not be rendered.
+func processGatewayRoute(
+	quay *v1.QuayRegistry,
+	qctx *quaycontext.QuayRegistryContext,
+	rt *unstructured.Unstructured,
+	quayComponentLabel string,
+) (client.Object, error) {
+	passthrough := v1.QuayServesTLS(qctx, quay)
+
+	host := qctx.ServerHostname
+	switch quayComponentLabel {
+	case "quay-app-httproute":
+		if passthrough {
+			return nil, nil
+		}
+	case "quay-app-tlsroute":
+		if !passthrough {
+			return nil, nil
+		}
+	case "quay-builder-grpcroute", "quay-builder-tlsroute":
+		usetls := quayComponentLabel == "quay-builder-tlsroute"
+		if qctx.BuildManagerHostname == "" || usetls != passthrough {
+			return nil, nil
+		}
+		host = strings.Split(qctx.BuildManagerHostname, ":")[0]
+	default:
+		return rt, nil
+	}
+
+	if err := unstructured.SetNestedStringSlice(
+		rt.Object, []string{host}, "spec", "hostnames",
+	); err != nil {
+		return nil, err
+	}
+
+	if pref := v1.GetParentRefOverrideForComponent(quay, v1.ComponentGateway); pref != nil {
+		parent := map[string]interface{}{
+			"name": pref.Name,
+		}
+		if pref.Namespace != "" {
+			parent["namespace"] = pref.Namespace
+		}
+		if pref.SectionName != "" {
+			parent["sectionName"] = pref.SectionName
+		}
+
+		if err := unstructured.SetNestedSlice(
+			rt.Object, []interface{}{parent}, "spec", "parentRefs",
+		); err != nil {
+			return nil, err
+		}
+	}
+
+	if olabels := v1.GetLabelsOverrideForComponent(quay, v1.ComponentGateway); olabels != nil {
+		rlabels := rt.GetLabels()
+		if rlabels == nil {
+			rlabels = map[string]string{}
+		}
+		for key, value := range olabels {
+			if v1.ExceptionLabel(key) {
+				continue
+			}
+			rlabels[key] = value
+		}
+		rt.SetLabels(rlabels)
+	}
+
+	if oannot := v1.GetAnnotationsOverrideForComponent(quay, v1.ComponentGateway); oannot != nil {
+		rannot := rt.GetAnnotations()
+		if rannot == nil {
+			rannot = map[string]string{}
+		}
+		for key, value := range oannot {
+			rannot[key] = value
+		}
+		rt.SetAnnotations(rannot)
+	}
+
+	return rt, nil
+}
+
+// processCertificate points the cert-manager Certificate to the issuer referenced by the user
+// and requests it for the quay and builder hostnames. Returns nil if TLS is not issued by
+// cert-manager.
+func processCertificate(
+	quay *v1.QuayRegistry,
+	qctx *quaycontext.QuayRegistryContext,
+	cert *unstructured.Unstructured,
+) (client.Object, error) {
+	if !v1.TLSIssuedByCertManager(quay) {
+		return nil, nil
+	}
+	iref := v1.GetIssuerRefOverrideForComponent(quay, v1.ComponentTLS)
+
+	hosts := []string{qctx.ServerHostname}
+	if qctx.BuildManagerHostname != "" {
+		hosts = append(hosts, strings.Split(qctx.BuildManagerHostname, ":")[0])
+	}
+
+	if err := unstructured.SetNestedStringSlice(
+		cert.Object, hosts, "spec", "dnsNames",
+	); err != nil {
+		return nil, err
+	}
+
+	if err := unstructured.SetNestedField(
+		cert.Object, v1.CertManagerTLSSecretName(quay), "spec", "secretName",
+	); err != nil {
+		return nil, err
+	}
+
+	issuer := map[string]interface{}{
+		"name": iref.Name,
+		"kind": "Issuer",
+	}
+	if iref.Kind != "" {
+		issuer["kind"] = iref.Kind
+	}
+	if iref.Group != "" {
+		issuer["group"] = iref.Group
+	}
+
+	if err := unstructured.SetNestedMap(cert.Object, issuer, "spec", "issuerRef"); err != nil {
+		return nil, err
+	}
+
+	if olabels := v1.GetLabelsOverrideForComponent(quay, v1.ComponentTLS); olabels != nil {
+		clabels := cert.GetLabels()
+		if clabels == nil {
+			clabels = map[string]string{}
+		}
+		for key, value := range olabels {
+			if v1.ExceptionLabel(key) {
+				continue
+			}
+			clabels[key] = value
+		}
+		cert.SetLabels(clabels)
+	}
+
+	if oannot := v1.GetAnnotationsOverrideForComponent(quay, v1.ComponentTLS); oannot != nil {
+		cannot := cert.GetAnnotations()
+		if cannot == nil {
+			cannot = map[string]string{}
+		}
+		for key, value := range oannot {
+			cannot[key] = value
+		}
+		cert.SetAnnotations(cannot)
+	}
+
+	return cert, nil
+}
+
+// processDatabaseCluster applies the overrides of the database component to the CloudNativePG
+// cluster running it.
+func processDatabaseCluster(
+	quay *v1.QuayRegistry,
+	cluster *unstructured.Unstructured,
+	quayComponentLabel string,
+	skipres bool,
+) (client.Object, error) {
+	component := v1.ComponentPostgres
+	if quayComponentLabel == "clair-postgres" {
+		component = v1.ComponentClairPostgres
+	}
+
+	if err := unstructured.SetNestedField(
+		cluster.Object, int64(v1.DatabaseInstancesFor(quay, component)), "spec", "instances",
+	); err != nil {
+		return nil, err
+	}
+
+	if size := v1.GetVolumeSizeOverrideForComponent(quay, component); size != nil {
+		if err := unstructured.SetNestedField(
+			cluster.Object, size.String(), "spec", "storage", "size",
+		); err != nil {
+			return nil, err
+		}
+	}
+
+	if class := v1.GetStorageClassNameOverrideForComponent(quay, component); class != nil {
+		if err := unstructured.SetNestedField(
+			cluster.Object, *class, "spec", "storage", "storageClass",
+		); err != nil {
+			return nil, err
+		}
+	}
+
+	if skipres {
+		unstructured.RemoveNestedField(cluster.Object, "spec", "resources")
+	} else if ores := v1.GetResourceOverridesForComponent(quay, component); ores != nil {
+		res := corev1.ResourceRequirements{
+			Limits:   ores.Limits,
+			Requests: ores.Requests,
+		}
+
+		ures, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&res)
+		if err != nil {
+			return nil, err
+		}
+		if err := unstructured.SetNestedMap(cluster.Object, ures, "spec", "resources"); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := setDatabaseClusterScheduling(quay, cluster, component); err != nil {
+		return nil, err
+	}
+
+	if olabels := v1.GetLabelsOverrideForComponent(quay, component); olabels != nil {
+		clabels := cluster.GetLabels()
+		if clabels == nil {
+			clabels = map[string]string{}
+		}
+		for key, value := range olabels {
+			if v1.ExceptionLabel(key) {
+				continue
+			}
+			clabels[key] = value
+		}
+		cluster.SetLabels(clabels)
+	}
+
+	if oannot := v1.GetAnnotationsOverrideForComponent(quay, component); oannot != nil {
+		cannot := cluster.GetAnnotations()
+		if cannot == nil {
+			cannot = map[string]string{}
+		}
+		for key, value := range oannot {
+			cannot[key] = value
+		}
+		cluster.SetAnnotations(cannot)
+	}
+
+	return cluster, nil
+}
+
+// setDatabaseClusterScheduling applies the scheduling overrides of the provided database
+// component to the CloudNativePG cluster running it. Topology spread constraints without a
+// label selector select the instances of the cluster.
+func setDatabaseClusterScheduling(
+	quay *v1.QuayRegistry, cluster *unstructured.Unstructured, component v1.ComponentKind,
+) error {
+	if otolerations := v1.GetTolerationsOverrideForComponent(quay, component); otolerations != nil {
+		tolerations := make([]interface{}, 0, len(otolerations))
+		for i := range otolerations {
+			toleration, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&otolerations[i])
+			if err != nil {
+				return err
+			}
+			tolerations = append(tolerations, toleration)
+		}
+		if err := unstructured.SetNestedSlice(
+			cluster.Object, tolerations, "spec", "affinity", "tolerations",
+		); err != nil {
+			return err
+		}
+	}
+
+	if oselector := v1.GetNodeSelectorOverrideForComponent(quay, component); oselector != nil {
+		if err := unstructured.SetNestedStringMap(
+			cluster.Object, oselector, "spec", "affinity", "nodeSelector",
+		); err != nil {
+			return err
+		}
+	}
+
+	if oconstraints := v1.GetTopologySpreadConstraintsOverrideForComponent(quay, component); oconstraints != nil {
+		selector := &metav1.LabelSelector{
+			MatchLabels: map[string]string{"cnpg.io/cluster": cluster.GetName()},
+		}
+
+		var constraints []interface{}
+		for _, constraint := range topologySpreadConstraintsFor(oconstraints, selector) {
+			uconstraint, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&constraint)
+			if err != nil {
+				return err
+			}
+			constraints = append(constraints, uconstraint)
+		}
+		if err := unstructured.SetNestedSlice(
+			cluster.Object, constraints, "spec", "topologySpreadConstraints",
+		); err != nil {
+			return err
+		}
+	}
+
+	if opriority := v1.GetPriorityClassNameOverrideForComponent(quay, component); opriority != nil {
+		if err := unstructured.SetNestedField(
+			cluster.Object, *opriority, "spec", "priorityClassName",
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // UpsertContainerEnv updates or inserts an environment variable into provided container.
 func UpsertContainerEnv(container *corev1.Container, newv corev1.EnvVar) {
 	for i, origv := range container.Env {
@@ -411,6 +1270,27 @@ func FlattenSecret(configBundle *corev1.Secret) (*corev1.Secret, error) {
 	return flattenedSecret, nil
 }
 
+// setRegistryState sets the state Quay runs in, as REGISTRY_STATE, in the config of the
+// provided flattened config bundle secret.
+func setRegistryState(configBundle *corev1.Secret, state string) error {
+	var config map[string]interface{}
+	if err := yaml.Unmarshal(configBundle.Data["config.yaml"], &config); err != nil {
+		return err
+	}
+	if config == nil {
+		config = map[string]interface{}{}
+	}
+	config["REGISTRY_STATE"] = state
+
+	configYAML, err := yaml.Marshal(config)
+	if err != nil {
+		return err
+	}
+
+	configBundle.Data["config.yaml"] = configYAML
+	return nil
+}
+
 const clairEphemeralVolumeName = "indexer-layer-storage"
 
 func applyClairEphemeralVolumeOverrides(quay *v1.QuayRegistry, dep *appsv1.Deployment) {
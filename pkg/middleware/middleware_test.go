@@ -11,8 +11,11 @@ import (
 	autoscalingv2 "k8s.io/api/autoscaling/v2"
 	batchv1k8s "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/utils/ptr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -309,7 +312,9 @@ func TestProcess(t *testing.T) {
 	for _, test := range processTests {
 
 		t.Run(test.name, func(t *testing.T) {
+			// routes are only rendered on clusters supporting them.
 			quayContext := quaycontext.NewQuayRegistryContext()
+			quayContext.SupportsRoutes = true
 			processedObj, err := Process(test.quay, quayContext, test.obj, false)
 			if test.expectedError != nil {
 				assert.Error(err, test.name)
@@ -468,6 +473,448 @@ func TestProcessClairEphemeralVolumeOverrides(t *testing.T) {
 	}
 }
 
+func newIngress(component string, port string) *networkingv1.Ingress {
+	return &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels:      map[string]string{"quay-component": component},
+			Annotations: map[string]string{"quay-component": "ingress"},
+		},
+		Spec: networkingv1.IngressSpec{
+			Rules: []networkingv1.IngressRule{
+				{
+					IngressRuleValue: networkingv1.IngressRuleValue{
+						HTTP: &networkingv1.HTTPIngressRuleValue{
+							Paths: []networkingv1.HTTPIngressPath{
+								{
+									Path: "/",
+									Backend: networkingv1.IngressBackend{
+										Service: &networkingv1.IngressServiceBackend{
+											Name: "quay-app",
+											Port: networkingv1.ServiceBackendPort{Name: port},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestProcessIngress(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		quay     *v1.QuayRegistry
+		qctx     *quaycontext.QuayRegistryContext
+		obj      *networkingv1.Ingress
+		expected func() *networkingv1.Ingress
+	}{
+		{
+			name: "AppTLSManaged",
+			quay: &v1.QuayRegistry{
+				Spec: v1.QuayRegistrySpec{
+					Components: []v1.Component{
+						{Kind: "ingress", Managed: true},
+						{Kind: "tls", Managed: true},
+					},
+				},
+			},
+			qctx: &quaycontext.QuayRegistryContext{
+				SupportsRoutes: true,
+				ServerHostname: "quay.example.com",
+			},
+			obj: newIngress("quay-app-ingress", "http"),
+			expected: func() *networkingv1.Ingress {
+				ing := newIngress("quay-app-ingress", "http")
+				ing.Spec.Rules[0].Host = "quay.example.com"
+				ing.Spec.TLS = []networkingv1.IngressTLS{
+					{Hosts: []string{"quay.example.com"}},
+				}
+				return ing
+			},
+		},
+		{
+			name: "AppTLSIssuedByOperator",
+			quay: &v1.QuayRegistry{
+				ObjectMeta: metav1.ObjectMeta{Name: "registry"},
+				Spec: v1.QuayRegistrySpec{
+					Components: []v1.Component{
+						{Kind: "ingress", Managed: true},
+						{Kind: "tls", Managed: true},
+					},
+				},
+			},
+			qctx: &quaycontext.QuayRegistryContext{ServerHostname: "quay.example.com"},
+			obj:  newIngress("quay-app-ingress", "http"),
+			expected: func() *networkingv1.Ingress {
+				ing := newIngress("quay-app-ingress", "https")
+				ing.Annotations["nginx.ingress.kubernetes.io/backend-protocol"] = "HTTPS"
+				ing.Spec.Rules[0].Host = "quay.example.com"
+				ing.Spec.TLS = []networkingv1.IngressTLS{
+					{Hosts: []string{"quay.example.com"}, SecretName: "registry-quay-operator-tls"},
+				}
+				return ing
+			},
+		},
+		{
+			name: "AppTLSUnmanaged",
+			quay: &v1.QuayRegistry{
+				Spec: v1.QuayRegistrySpec{
+					Components: []v1.Component{
+						{Kind: "ingress", Managed: true},
+						{
+							Kind:      "tls",
+							Managed:   false,
+							SecretRef: &corev1.LocalObjectReference{Name: "my-tls"},
+						},
+					},
+				},
+			},
+			qctx: &quaycontext.QuayRegistryContext{ServerHostname: "quay.example.com"},
+			obj:  newIngress("quay-app-ingress", "http"),
+			expected: func() *networkingv1.Ingress {
+				ing := newIngress("quay-app-ingress", "https")
+				ing.Annotations["nginx.ingress.kubernetes.io/backend-protocol"] = "HTTPS"
+				ing.Spec.Rules[0].Host = "quay.example.com"
+				ing.Spec.TLS = []networkingv1.IngressTLS{
+					{Hosts: []string{"quay.example.com"}, SecretName: "my-tls"},
+				}
+				return ing
+			},
+		},
+		{
+			name: "AppOverrides",
+			quay: &v1.QuayRegistry{
+				Spec: v1.QuayRegistrySpec{
+					Components: []v1.Component{
+						{
+							Kind:    "ingress",
+							Managed: true,
+							Overrides: &v1.Override{
+								IngressClassName: ptr.To("traefik"),
+								Labels: map[string]string{
+									"quay-component": "invalid",
+									"custom-label":   "my-value",
+								},
+								Annotations: map[string]string{
+									"quay-component": "overridden",
+								},
+							},
+						},
+						{Kind: "tls", Managed: true},
+					},
+				},
+			},
+			qctx: &quaycontext.QuayRegistryContext{
+				SupportsRoutes: true,
+				ServerHostname: "quay.example.com",
+			},
+			obj: newIngress("quay-app-ingress", "http"),
+			expected: func() *networkingv1.Ingress {
+				ing := newIngress("quay-app-ingress", "http")
+				ing.Labels["custom-label"] = "my-value"
+				ing.Annotations["quay-component"] = "overridden"
+				ing.Spec.IngressClassName = ptr.To("traefik")
+				ing.Spec.Rules[0].Host = "quay.example.com"
+				ing.Spec.TLS = []networkingv1.IngressTLS{
+					{Hosts: []string{"quay.example.com"}},
+				}
+				return ing
+			},
+		},
+		{
+			name: "BuilderWithoutHostname",
+			quay: &v1.QuayRegistry{
+				Spec: v1.QuayRegistrySpec{
+					Components: []v1.Component{
+						{Kind: "ingress", Managed: true},
+					},
+				},
+			},
+			qctx:     &quaycontext.QuayRegistryContext{ServerHostname: "quay.example.com"},
+			obj:      newIngress("quay-builder-ingress", "grpc"),
+			expected: func() *networkingv1.Ingress { return nil },
+		},
+		{
+			name: "BuilderTLSUnmanaged",
+			quay: &v1.QuayRegistry{
+				Spec: v1.QuayRegistrySpec{
+					Components: []v1.Component{
+						{Kind: "ingress", Managed: true},
+						{Kind: "tls", Managed: false},
+					},
+				},
+			},
+			qctx: &quaycontext.QuayRegistryContext{
+				ServerHostname:       "quay.example.com",
+				BuildManagerHostname: "builds.example.com:443",
+			},
+			obj: newIngress("quay-builder-ingress", "grpc"),
+			expected: func() *networkingv1.Ingress {
+				ing := newIngress("quay-builder-ingress", "grpc")
+				ing.Annotations["nginx.ingress.kubernetes.io/backend-protocol"] = "GRPCS"
+				ing.Spec.Rules[0].Host = "builds.example.com"
+				ing.Spec.TLS = []networkingv1.IngressTLS{
+					{Hosts: []string{"builds.example.com"}},
+				}
+				return ing
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Process(tt.quay, tt.qctx, tt.obj, false)
+			assert.NoError(t, err)
+
+			expected := tt.expected()
+			if expected == nil {
+				assert.Nil(t, result)
+				return
+			}
+			assert.Equal(t, expected, result)
+		})
+	}
+}
+
+func newGatewayRoute(kind, component string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion("gateway.networking.k8s.io/v1")
+	obj.SetKind(kind)
+	obj.SetLabels(map[string]string{"quay-component": component})
+	return obj
+}
+
+func TestProcessGatewayRoute(t *testing.T) {
+	gateway := v1.Component{
+		Kind:    "gateway",
+		Managed: true,
+		Overrides: &v1.Override{
+			ParentRef: &v1.GatewayParentReference{
+				Name:        "gw",
+				Namespace:   "infra",
+				SectionName: "https",
+			},
+			Annotations: map[string]string{"foo": "bar"},
+		},
+	}
+
+	for _, tt := range []struct {
+		name     string
+		tls      bool
+		noroutes bool
+		obj      *unstructured.Unstructured
+		expected func() *unstructured.Unstructured
+	}{
+		{
+			name: "EdgeHTTPRoute",
+			tls:  true,
+			obj:  newGatewayRoute("HTTPRoute", "quay-app-httproute"),
+			expected: func() *unstructured.Unstructured {
+				obj := newGatewayRoute("HTTPRoute", "quay-app-httproute")
+				obj.SetAnnotations(map[string]string{"foo": "bar"})
+				obj.Object["spec"] = map[string]interface{}{
+					"hostnames": []interface{}{"quay.example.com"},
+					"parentRefs": []interface{}{
+						map[string]interface{}{
+							"name":        "gw",
+							"namespace":   "infra",
+							"sectionName": "https",
+						},
+					},
+				}
+				return obj
+			},
+		},
+		{
+			name:     "EdgeTLSRoute",
+			tls:      true,
+			obj:      newGatewayRoute("TLSRoute", "quay-app-tlsroute"),
+			expected: func() *unstructured.Unstructured { return nil },
+		},
+		{
+			name:     "OperatorIssuedTLSRoute",
+			tls:      true,
+			noroutes: true,
+			obj:      newGatewayRoute("TLSRoute", "quay-app-tlsroute"),
+			expected: func() *unstructured.Unstructured {
+				obj := newGatewayRoute("TLSRoute", "quay-app-tlsroute")
+				obj.SetAnnotations(map[string]string{"foo": "bar"})
+				obj.Object["spec"] = map[string]interface{}{
+					"hostnames": []interface{}{"quay.example.com"},
+					"parentRefs": []interface{}{
+						map[string]interface{}{
+							"name":        "gw",
+							"namespace":   "infra",
+							"sectionName": "https",
+						},
+					},
+				}
+				return obj
+			},
+		},
+		{
+			name:     "PassthroughHTTPRoute",
+			obj:      newGatewayRoute("HTTPRoute", "quay-app-httproute"),
+			expected: func() *unstructured.Unstructured { return nil },
+		},
+		{
+			name:     "PassthroughGRPCRoute",
+			obj:      newGatewayRoute("GRPCRoute", "quay-builder-grpcroute"),
+			expected: func() *unstructured.Unstructured { return nil },
+		},
+		{
+			name: "PassthroughBuilderTLSRoute",
+			obj:  newGatewayRoute("TLSRoute", "quay-builder-tlsroute"),
+			expected: func() *unstructured.Unstructured {
+				obj := newGatewayRoute("TLSRoute", "quay-builder-tlsroute")
+				obj.SetAnnotations(map[string]string{"foo": "bar"})
+				obj.Object["spec"] = map[string]interface{}{
+					"hostnames": []interface{}{"builds.example.com"},
+					"parentRefs": []interface{}{
+						map[string]interface{}{
+							"name":        "gw",
+							"namespace":   "infra",
+							"sectionName": "https",
+						},
+					},
+				}
+				return obj
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			quay := &v1.QuayRegistry{
+				Spec: v1.QuayRegistrySpec{
+					Components: []v1.Component{
+						gateway,
+						{Kind: "tls", Managed: tt.tls},
+					},
+				},
+			}
+
+			qctx := &quaycontext.QuayRegistryContext{
+				SupportsRoutes:       !tt.noroutes,
+				ServerHostname:       "quay.example.com",
+				BuildManagerHostname: "builds.example.com:443",
+			}
+
+			result, err := Process(quay, qctx, tt.obj, false)
+			assert.NoError(t, err)
+
+			expected := tt.expected()
+			if expected == nil {
+				assert.Nil(t, result)
+				return
+			}
+			assert.Equal(t, expected, result)
+		})
+	}
+}
+
+func newCertificate() *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion("cert-manager.io/v1")
+	obj.SetKind("Certificate")
+	obj.SetName("registry-quay-app-tls")
+	obj.SetLabels(map[string]string{"quay-component": "quay-app-certificate"})
+	return obj
+}
+
+func TestProcessCertificate(t *testing.T) {
+	qctx := &quaycontext.QuayRegistryContext{
+		ServerHostname:       "quay.example.com",
+		BuildManagerHostname: "builds.example.com:443",
+	}
+
+	for _, tt := range []struct {
+		name     string
+		tls      v1.Component
+		expected func() *unstructured.Unstructured
+	}{
+		{
+			name:     "WithoutIssuerRef",
+			tls:      v1.Component{Kind: "tls", Managed: true},
+			expected: func() *unstructured.Unstructured { return nil },
+		},
+		{
+			name: "Issuer",
+			tls: v1.Component{
+				Kind:    "tls",
+				Managed: true,
+				Overrides: &v1.Override{
+					IssuerRef: &v1.CertificateIssuerReference{Name: "ca-issuer"},
+				},
+			},
+			expected: func() *unstructured.Unstructured {
+				obj := newCertificate()
+				obj.Object["spec"] = map[string]interface{}{
+					"dnsNames":   []interface{}{"quay.example.com", "builds.example.com"},
+					"secretName": "registry-quay-app-tls",
+					"issuerRef": map[string]interface{}{
+						"name": "ca-issuer",
+						"kind": "Issuer",
+					},
+				}
+				return obj
+			},
+		},
+		{
+			name: "ClusterIssuerWithOverrides",
+			tls: v1.Component{
+				Kind:    "tls",
+				Managed: true,
+				Overrides: &v1.Override{
+					IssuerRef: &v1.CertificateIssuerReference{
+						Name:  "letsencrypt",
+						Kind:  "ClusterIssuer",
+						Group: "cert-manager.io",
+					},
+					Labels:      map[string]string{"team": "registry"},
+					Annotations: map[string]string{"foo": "bar"},
+				},
+			},
+			expected: func() *unstructured.Unstructured {
+				obj := newCertificate()
+				obj.SetLabels(map[string]string{
+					"quay-component": "quay-app-certificate",
+					"team":           "registry",
+				})
+				obj.SetAnnotations(map[string]string{"foo": "bar"})
+				obj.Object["spec"] = map[string]interface{}{
+					"dnsNames":   []interface{}{"quay.example.com", "builds.example.com"},
+					"secretName": "registry-quay-app-tls",
+					"issuerRef": map[string]interface{}{
+						"name":  "letsencrypt",
+						"kind":  "ClusterIssuer",
+						"group": "cert-manager.io",
+					},
+				}
+				return obj
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			quay := &v1.QuayRegistry{
+				ObjectMeta: metav1.ObjectMeta{Name: "registry"},
+				Spec: v1.QuayRegistrySpec{
+					Components: []v1.Component{tt.tls},
+				},
+			}
+
+			result, err := Process(quay, qctx, newCertificate(), false)
+			assert.NoError(t, err)
+
+			expected := tt.expected()
+			if expected == nil {
+				assert.Nil(t, result)
+				return
+			}
+			assert.Equal(t, expected, result)
+		})
+	}
+}
+
 func TestHPAWithUnmanagedMirrorAndClair(t *testing.T) {
 	quayRegistry := &v1.QuayRegistry{
 		Spec: v1.QuayRegistrySpec{
@@ -866,3 +1313,786 @@ func TestProcessJobSecurityContextOverride(t *testing.T) {
 		})
 	}
 }
+
+func TestProcessJobOverride(t *testing.T) {
+	resources := &v1.Resources{
+		Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("16Gi")},
+		Limits:   corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("32Gi")},
+	}
+	tolerations := []corev1.Toleration{
+		{Key: "dedicated", Operator: corev1.TolerationOpEqual, Value: "migrations"},
+	}
+
+	quay := &v1.QuayRegistry{
+		Spec: v1.QuayRegistrySpec{
+			Components: []v1.Component{
+				{
+					Kind:    v1.ComponentQuay,
+					Managed: true,
+					Overrides: &v1.Override{
+						Env: []corev1.EnvVar{{Name: "DEBUGLOG", Value: "true"}},
+					},
+				},
+			},
+			JobOverrides: []v1.JobOverride{
+				{
+					Name:                    "quay-app-upgrade",
+					Resources:               resources,
+					NodeSelector:            map[string]string{"node-role": "migrations"},
+					Tolerations:             tolerations,
+					ActiveDeadlineSeconds:   ptr.To[int64](7200),
+					BackoffLimit:            ptr.To[int32](2),
+					TTLSecondsAfterFinished: ptr.To[int32](86400),
+				},
+			},
+		},
+	}
+
+	jobFor := func(component string) *batchv1k8s.Job {
+		return &batchv1k8s.Job{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   "test-" + component,
+				Labels: map[string]string{"quay-component": component},
+			},
+			Spec: batchv1k8s.JobSpec{
+				BackoffLimit: ptr.To[int32](50),
+				Template: corev1.PodTemplateSpec{
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{
+							{
+								Name: component,
+								Resources: corev1.ResourceRequirements{
+									Requests: corev1.ResourceList{
+										corev1.ResourceMemory: resource.MustParse("2Gi"),
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	for _, skipres := range []bool{false, true} {
+		t.Run(fmt.Sprintf("Overridden/skipres=%v", skipres), func(t *testing.T) {
+			qctx := quaycontext.NewQuayRegistryContext()
+			result, err := Process(quay, qctx, jobFor("quay-app-upgrade"), skipres)
+			assert.NoError(t, err)
+
+			job := result.(*batchv1k8s.Job)
+			podspec := job.Spec.Template.Spec
+			assert.Equal(t, resources.Requests, podspec.Containers[0].Resources.Requests)
+			assert.Equal(t, resources.Limits, podspec.Containers[0].Resources.Limits)
+			assert.Equal(t, map[string]string{"node-role": "migrations"}, podspec.NodeSelector)
+			assert.Equal(t, tolerations, podspec.Tolerations)
+			assert.Equal(t, ptr.To[int64](7200), job.Spec.ActiveDeadlineSeconds)
+			assert.Equal(t, ptr.To[int32](2), job.Spec.BackoffLimit)
+			assert.Equal(t, ptr.To[int32](86400), job.Spec.TTLSecondsAfterFinished)
+			assert.Contains(t, podspec.Containers[0].Env, corev1.EnvVar{Name: "DEBUGLOG", Value: "true"})
+		})
+	}
+
+	t.Run("NotOverridden", func(t *testing.T) {
+		qctx := quaycontext.NewQuayRegistryContext()
+		result, err := Process(quay, qctx, jobFor("quay-postgres-upgrade"), false)
+		assert.NoError(t, err)
+
+		job := result.(*batchv1k8s.Job)
+		podspec := job.Spec.Template.Spec
+		assert.Equal(t, resource.MustParse("2Gi"), podspec.Containers[0].Resources.Requests[corev1.ResourceMemory])
+		assert.Nil(t, podspec.NodeSelector)
+		assert.Nil(t, job.Spec.ActiveDeadlineSeconds)
+		assert.Equal(t, ptr.To[int32](50), job.Spec.BackoffLimit)
+	})
+}
+
+func TestProcessImagePullSecrets(t *testing.T) {
+	quaySecrets := []corev1.LocalObjectReference{{Name: "quay-pull-secret"}}
+	postgresSecrets := []corev1.LocalObjectReference{{Name: "postgres-pull-secret"}}
+	quay := &v1.QuayRegistry{
+		Spec: v1.QuayRegistrySpec{
+			Components: []v1.Component{
+				{Kind: v1.ComponentQuay, Managed: true, Overrides: &v1.Override{ImagePullSecrets: quaySecrets}},
+				{Kind: v1.ComponentPostgres, Managed: true, Overrides: &v1.Override{ImagePullSecrets: postgresSecrets}},
+				{Kind: v1.ComponentClairPostgres, Managed: true},
+			},
+		},
+	}
+
+	for _, tt := range []struct {
+		component string
+		existing  []corev1.LocalObjectReference
+		expected  []corev1.LocalObjectReference
+	}{
+		{
+			component: "quay-app-upgrade",
+			existing:  quaySecrets,
+			expected:  quaySecrets,
+		},
+		{
+			component: "quay-postgres-upgrade",
+			expected:  postgresSecrets,
+		},
+		{
+			component: "clair-postgres-upgrade",
+		},
+	} {
+		t.Run(tt.component, func(t *testing.T) {
+			job := &batchv1k8s.Job{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:   "test-" + tt.component,
+					Labels: map[string]string{"quay-component": tt.component},
+				},
+				Spec: batchv1k8s.JobSpec{
+					Template: corev1.PodTemplateSpec{
+						Spec: corev1.PodSpec{
+							ImagePullSecrets: tt.existing,
+							Containers:       []corev1.Container{{Name: tt.component}},
+						},
+					},
+				},
+			}
+
+			qctx := quaycontext.NewQuayRegistryContext()
+			result, err := Process(quay, qctx, job, false)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, result.(*batchv1k8s.Job).Spec.Template.Spec.ImagePullSecrets)
+		})
+	}
+}
+
+func TestProcessRedisStatefulSet(t *testing.T) {
+	assert := assert.New(t)
+
+	quay := &v1.QuayRegistry{
+		Spec: v1.QuayRegistrySpec{
+			Components: []v1.Component{
+				{
+					Kind:    v1.ComponentRedis,
+					Managed: true,
+					Overrides: &v1.Override{
+						HighAvailability: ptr.To(true),
+						Replicas:         ptr.To[int32](4),
+						Env:              []corev1.EnvVar{{Name: "FOO", Value: "bar"}},
+						Labels:           map[string]string{"team": "registry"},
+					},
+				},
+			},
+		},
+	}
+
+	sts := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "registry-quay-redis",
+			Labels: map[string]string{"quay-component": "redis"},
+		},
+		Spec: appsv1.StatefulSetSpec{
+			Replicas: ptr.To[int32](3),
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Name: "redis-master"},
+						{
+							Name: "redis-sentinel",
+							Env:  []corev1.EnvVar{{Name: "SENTINEL_QUORUM", Value: "2"}},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	obj, err := Process(quay, quaycontext.NewQuayRegistryContext(), sts, false)
+	assert.Nil(err)
+
+	processed := obj.(*appsv1.StatefulSet)
+	assert.Equal(int32(4), *processed.Spec.Replicas)
+	assert.Equal("registry", processed.Spec.Template.Labels["team"])
+
+	containers := processed.Spec.Template.Spec.Containers
+	assert.Equal([]corev1.EnvVar{{Name: "FOO", Value: "bar"}}, containers[0].Env)
+	assert.Equal(
+		[]corev1.EnvVar{
+			{Name: "SENTINEL_QUORUM", Value: "3"},
+			{Name: "FOO", Value: "bar"},
+		},
+		containers[1].Env,
+	)
+}
+
+func TestProcessDatabaseCluster(t *testing.T) {
+	assert := assert.New(t)
+
+	quay := &v1.QuayRegistry{
+		Spec: v1.QuayRegistrySpec{
+			Components: []v1.Component{
+				{
+					Kind:    v1.ComponentClairPostgres,
+					Managed: true,
+					Overrides: &v1.Override{
+						Backend:          ptr.To(v1.DatabaseBackendCloudNativePG),
+						Replicas:         ptr.To[int32](5),
+						VolumeSize:       ptr.To(resource.MustParse("100Gi")),
+						StorageClassName: ptr.To("fast"),
+						Resources: &v1.Resources{
+							Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("4Gi")},
+						},
+						Labels: map[string]string{"team": "registry"},
+					},
+				},
+			},
+		},
+	}
+
+	cluster := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "postgresql.cnpg.io/v1",
+			"kind":       "Cluster",
+			"metadata": map[string]interface{}{
+				"name":   "registry-clair-postgres",
+				"labels": map[string]interface{}{"quay-component": "clair-postgres"},
+			},
+			"spec": map[string]interface{}{
+				"instances": int64(3),
+				"storage":   map[string]interface{}{"size": "50Gi"},
+			},
+		},
+	}
+
+	obj, err := Process(quay, quaycontext.NewQuayRegistryContext(), cluster, false)
+	assert.Nil(err)
+
+	processed := obj.(*unstructured.Unstructured)
+	instances, _, _ := unstructured.NestedInt64(processed.Object, "spec", "instances")
+	assert.Equal(int64(5), instances)
+	size, _, _ := unstructured.NestedString(processed.Object, "spec", "storage", "size")
+	assert.Equal("100Gi", size)
+	class, _, _ := unstructured.NestedString(processed.Object, "spec", "storage", "storageClass")
+	assert.Equal("fast", class)
+	memory, _, _ := unstructured.NestedString(processed.Object, "spec", "resources", "limits", "memory")
+	assert.Equal("4Gi", memory)
+	assert.Equal("registry", processed.GetLabels()["team"])
+
+	obj, err = Process(quay, quaycontext.NewQuayRegistryContext(), processed, true)
+	assert.Nil(err)
+	_, found, _ := unstructured.NestedMap(obj.(*unstructured.Unstructured).Object, "spec", "resources")
+	assert.False(found)
+}
+
+func TestProcessSchedulingOverrides(t *testing.T) {
+	assert := assert.New(t)
+
+	tolerations := []corev1.Toleration{
+		{
+			Key:      "node-role.kubernetes.io/infra",
+			Operator: corev1.TolerationOpExists,
+			Effect:   corev1.TaintEffectNoSchedule,
+		},
+	}
+	nodeSelector := map[string]string{"node-role.kubernetes.io/infra": ""}
+	constraints := []corev1.TopologySpreadConstraint{
+		{
+			MaxSkew:           1,
+			TopologyKey:       "topology.kubernetes.io/zone",
+			WhenUnsatisfiable: corev1.ScheduleAnyway,
+		},
+	}
+	overrides := &v1.Override{
+		Tolerations:               tolerations,
+		NodeSelector:              nodeSelector,
+		TopologySpreadConstraints: constraints,
+		PriorityClassName:         ptr.To("registry-critical"),
+	}
+
+	quay := &v1.QuayRegistry{
+		Spec: v1.QuayRegistrySpec{
+			Components: []v1.Component{
+				{Kind: v1.ComponentPostgres, Managed: true, Overrides: overrides},
+				{Kind: v1.ComponentRedis, Managed: true, Overrides: overrides},
+				{
+					Kind:    v1.ComponentClairPostgres,
+					Managed: true,
+					Overrides: &v1.Override{
+						Backend:                   ptr.To(v1.DatabaseBackendCloudNativePG),
+						Tolerations:               tolerations,
+						NodeSelector:              nodeSelector,
+						TopologySpreadConstraints: constraints,
+						PriorityClassName:         ptr.To("registry-critical"),
+					},
+				},
+			},
+		},
+	}
+
+	selector := &metav1.LabelSelector{MatchLabels: map[string]string{"quay-component": "postgres"}}
+	dep := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "registry-quay-database",
+			Annotations: map[string]string{"quay-component": "postgres"},
+		},
+		Spec: appsv1.DeploymentSpec{
+			Selector: selector,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{}},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "postgres"}},
+				},
+			},
+		},
+	}
+
+	obj, err := Process(quay, quaycontext.NewQuayRegistryContext(), dep, false)
+	assert.Nil(err)
+
+	podspec := obj.(*appsv1.Deployment).Spec.Template.Spec
+	assert.Equal(tolerations, podspec.Tolerations)
+	assert.Equal(nodeSelector, podspec.NodeSelector)
+	assert.Equal("registry-critical", podspec.PriorityClassName)
+	assert.Len(podspec.TopologySpreadConstraints, 1)
+	assert.Equal(selector, podspec.TopologySpreadConstraints[0].LabelSelector)
+	assert.Nil(constraints[0].LabelSelector, "overrides must not be modified")
+
+	sts := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "registry-quay-redis"},
+		Spec: appsv1.StatefulSetSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"quay-component": "redis"}},
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "redis"}, {Name: "redis-sentinel"}},
+				},
+			},
+		},
+	}
+
+	obj, err = Process(quay, quaycontext.NewQuayRegistryContext(), sts, false)
+	assert.Nil(err)
+
+	podspec = obj.(*appsv1.StatefulSet).Spec.Template.Spec
+	assert.Equal(tolerations, podspec.Tolerations)
+	assert.Equal(nodeSelector, podspec.NodeSelector)
+	assert.Equal("registry-critical", podspec.PriorityClassName)
+	assert.Equal(sts.Spec.Selector, podspec.TopologySpreadConstraints[0].LabelSelector)
+
+	cluster := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "postgresql.cnpg.io/v1",
+			"kind":       "Cluster",
+			"metadata": map[string]interface{}{
+				"name":   "registry-clair-postgres",
+				"labels": map[string]interface{}{"quay-component": "clair-postgres"},
+			},
+			"spec": map[string]interface{}{
+				"instances": int64(3),
+			},
+		},
+	}
+
+	obj, err = Process(quay, quaycontext.NewQuayRegistryContext(), cluster, false)
+	assert.Nil(err)
+
+	processed := obj.(*unstructured.Unstructured)
+	utolerations, _, _ := unstructured.NestedSlice(processed.Object, "spec", "affinity", "tolerations")
+	assert.Equal(
+		[]interface{}{
+			map[string]interface{}{
+				"key":      "node-role.kubernetes.io/infra",
+				"operator": "Exists",
+				"effect":   "NoSchedule",
+			},
+		},
+		utolerations,
+	)
+	uselector, _, _ := unstructured.NestedStringMap(processed.Object, "spec", "affinity", "nodeSelector")
+	assert.Equal(nodeSelector, uselector)
+	priority, _, _ := unstructured.NestedString(processed.Object, "spec", "priorityClassName")
+	assert.Equal("registry-critical", priority)
+
+	uconstraints, _, _ := unstructured.NestedSlice(processed.Object, "spec", "topologySpreadConstraints")
+	assert.Len(uconstraints, 1)
+	cluster0, _, _ := unstructured.NestedStringMap(
+		uconstraints[0].(map[string]interface{}), "labelSelector", "matchLabels",
+	)
+	assert.Equal(map[string]string{"cnpg.io/cluster": "registry-clair-postgres"}, cluster0)
+}
+
+func TestProcessDatabaseBackup(t *testing.T) {
+	quayWith := func(backup *v1.DatabaseBackup) *v1.QuayRegistry {
+		return &v1.QuayRegistry{
+			ObjectMeta: metav1.ObjectMeta{Name: "registry"},
+			Spec: v1.QuayRegistrySpec{
+				Components: []v1.Component{
+					{
+						Kind:    v1.ComponentClairPostgres,
+						Managed: true,
+						Overrides: &v1.Override{
+							Backup:     backup,
+							VolumeSize: ptr.To(resource.MustParse("80Gi")),
+						},
+					},
+				},
+			},
+		}
+	}
+
+	cronjob := func() *batchv1k8s.CronJob {
+		return &batchv1k8s.CronJob{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   "registry-clair-postgres-backup",
+				Labels: map[string]string{"quay-component": "clair-postgres-backup"},
+			},
+			Spec: batchv1k8s.CronJobSpec{
+				Schedule: "0 0 * * *",
+				JobTemplate: batchv1k8s.JobTemplateSpec{
+					Spec: batchv1k8s.JobSpec{
+						Template: corev1.PodTemplateSpec{
+							Spec: corev1.PodSpec{
+								Volumes: []corev1.Volume{
+									{
+										Name: "backups",
+										VolumeSource: corev1.VolumeSource{
+											PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+												ClaimName: "registry-clair-postgres-backup",
+											},
+										},
+									},
+								},
+								Containers: []corev1.Container{
+									{
+										Name: "backup",
+										Env: []corev1.EnvVar{
+											{Name: "BACKUP_DESTINATION", Value: "persistentvolume"},
+											{Name: "BACKUP_RETENTION", Value: "7"},
+											{Name: "PGHOST", Value: "clair-postgres"},
+										},
+										Resources: corev1.ResourceRequirements{
+											Requests: corev1.ResourceList{
+												corev1.ResourceCPU: resource.MustParse("100m"),
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	volume := func() *corev1.PersistentVolumeClaim {
+		return &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   "registry-clair-postgres-backup",
+				Labels: map[string]string{"quay-component": "clair-postgres-backup"},
+			},
+			Spec: corev1.PersistentVolumeClaimSpec{
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{
+						corev1.ResourceStorage: resource.MustParse("50Gi"),
+					},
+				},
+			},
+		}
+	}
+
+	t.Run("persistent volume", func(t *testing.T) {
+		assert := assert.New(t)
+		quay := quayWith(&v1.DatabaseBackup{Schedule: "@weekly", Retention: ptr.To[int32](4)})
+
+		obj, err := Process(quay, quaycontext.NewQuayRegistryContext(), cronjob(), true)
+		assert.Nil(err)
+		cj := obj.(*batchv1k8s.CronJob)
+		assert.Equal("@weekly", cj.Spec.Schedule)
+
+		podspec := cj.Spec.JobTemplate.Spec.Template.Spec
+		assert.NotNil(podspec.Volumes[0].PersistentVolumeClaim)
+		assert.Empty(podspec.Containers[0].EnvFrom)
+		assert.Empty(podspec.Containers[0].Resources.Requests)
+		assert.Equal(
+			[]corev1.EnvVar{
+				{Name: "BACKUP_DESTINATION", Value: "persistentvolume"},
+				{Name: "BACKUP_RETENTION", Value: "4"},
+				{Name: "PGHOST", Value: "registry-clair-postgres"},
+			},
+			podspec.Containers[0].Env,
+		)
+
+		obj, err = Process(quay, quaycontext.NewQuayRegistryContext(), volume(), false)
+		assert.Nil(err)
+		pvc := obj.(*corev1.PersistentVolumeClaim)
+		assert.Equal(resource.MustParse("80Gi"), pvc.Spec.Resources.Requests[corev1.ResourceStorage])
+	})
+
+	t.Run("object storage", func(t *testing.T) {
+		assert := assert.New(t)
+		quay := quayWith(&v1.DatabaseBackup{
+			Schedule:    "0 3 * * *",
+			Destination: v1.BackupDestinationObjectStorage,
+		})
+
+		obj, err := Process(quay, quaycontext.NewQuayRegistryContext(), cronjob(), false)
+		assert.Nil(err)
+		cj := obj.(*batchv1k8s.CronJob)
+
+		podspec := cj.Spec.JobTemplate.Spec.Template.Spec
+		assert.Nil(podspec.Volumes[0].PersistentVolumeClaim)
+		assert.NotNil(podspec.Volumes[0].EmptyDir)
+		assert.Equal(
+			[]corev1.EnvFromSource{
+				{
+					ConfigMapRef: &corev1.ConfigMapEnvSource{
+						LocalObjectReference: corev1.LocalObjectReference{
+							Name: "registry-quay-datastore",
+						},
+					},
+				},
+				{
+					SecretRef: &corev1.SecretEnvSource{
+						LocalObjectReference: corev1.LocalObjectReference{
+							Name: "registry-quay-datastore",
+						},
+					},
+				},
+			},
+			podspec.Containers[0].EnvFrom,
+		)
+		assert.Equal("objectstorage", podspec.Containers[0].Env[0].Value)
+		assert.NotEmpty(podspec.Containers[0].Resources.Requests)
+
+		obj, err = Process(quay, quaycontext.NewQuayRegistryContext(), volume(), false)
+		assert.Nil(err)
+		assert.Nil(obj)
+	})
+}
+
+func TestProcessDatabaseSnapshot(t *testing.T) {
+	quayWith := func(overrides *v1.Override) *v1.QuayRegistry {
+		return &v1.QuayRegistry{
+			ObjectMeta: metav1.ObjectMeta{Name: "registry"},
+			Spec: v1.QuayRegistrySpec{
+				Components: []v1.Component{
+					{Kind: v1.ComponentClairPostgres, Managed: true, Overrides: overrides},
+				},
+			},
+		}
+	}
+
+	cronjob := func() *batchv1k8s.CronJob {
+		return &batchv1k8s.CronJob{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   "registry-clair-postgres-snapshot",
+				Labels: map[string]string{"quay-component": "clair-postgres-snapshot"},
+			},
+			Spec: batchv1k8s.CronJobSpec{
+				Schedule: "0 0 * * *",
+				JobTemplate: batchv1k8s.JobTemplateSpec{
+					Spec: batchv1k8s.JobSpec{
+						Template: corev1.PodTemplateSpec{
+							Spec: corev1.PodSpec{
+								Containers: []corev1.Container{
+									{
+										Name: "snapshot",
+										Env: []corev1.EnvVar{
+											{Name: "SNAPSHOT_COMPONENT", Value: "clair-postgres-snapshot"},
+											{Name: "SNAPSHOT_NAME", Value: "clair-postgres-snapshot"},
+											{Name: "SNAPSHOT_RETENTION", Value: "7"},
+											{Name: "QUAY_REGISTRY", Value: "quay"},
+											{Name: "PERSISTENT_VOLUME_CLAIM", Value: "clair-postgres-15"},
+											{Name: "PGHOST", Value: "clair-postgres"},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	t.Run("scheduled", func(t *testing.T) {
+		assert := assert.New(t)
+		quay := quayWith(&v1.Override{
+			Snapshot: &v1.DatabaseSnapshot{
+				Schedule:                "@daily",
+				Retention:               ptr.To[int32](2),
+				VolumeSnapshotClassName: "csi-snapclass",
+			},
+		})
+
+		obj, err := Process(quay, quaycontext.NewQuayRegistryContext(), cronjob(), false)
+		assert.Nil(err)
+		cj := obj.(*batchv1k8s.CronJob)
+		assert.Equal("@daily", cj.Spec.Schedule)
+		assert.Nil(cj.Spec.Suspend)
+		assert.Equal(
+			[]corev1.EnvVar{
+				{Name: "SNAPSHOT_COMPONENT", Value: "clair-postgres-snapshot"},
+				{Name: "SNAPSHOT_NAME", Value: "registry-clair-postgres-snapshot"},
+				{Name: "SNAPSHOT_RETENTION", Value: "2"},
+				{Name: "QUAY_REGISTRY", Value: "registry"},
+				{Name: "PERSISTENT_VOLUME_CLAIM", Value: "registry-clair-postgres-15"},
+				{Name: "PGHOST", Value: "registry-clair-postgres"},
+				{Name: "VOLUME_SNAPSHOT_CLASS", Value: "csi-snapclass"},
+			},
+			cj.Spec.JobTemplate.Spec.Template.Spec.Containers[0].Env,
+		)
+	})
+
+	t.Run("on demand", func(t *testing.T) {
+		assert := assert.New(t)
+		quay := quayWith(&v1.Override{Snapshot: &v1.DatabaseSnapshot{}})
+
+		obj, err := Process(quay, quaycontext.NewQuayRegistryContext(), cronjob(), false)
+		assert.Nil(err)
+		cj := obj.(*batchv1k8s.CronJob)
+		assert.Equal("0 0 * * *", cj.Spec.Schedule)
+		assert.Equal(ptr.To(true), cj.Spec.Suspend)
+	})
+
+	t.Run("not configured", func(t *testing.T) {
+		assert := assert.New(t)
+		quay := quayWith(nil)
+
+		obj, err := Process(quay, quaycontext.NewQuayRegistryContext(), cronjob(), false)
+		assert.Nil(err)
+		assert.Nil(obj)
+	})
+
+	t.Run("data source", func(t *testing.T) {
+		assert := assert.New(t)
+		quay := quayWith(&v1.Override{
+			DataSource: &corev1.TypedLocalObjectReference{
+				Kind: "VolumeSnapshot",
+				Name: "production-clair",
+			},
+		})
+
+		volume := &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   "registry-clair-postgres-15",
+				Labels: map[string]string{"quay-component": "clair-postgres"},
+			},
+		}
+		obj, err := Process(quay, quaycontext.NewQuayRegistryContext(), volume, false)
+		assert.Nil(err)
+		assert.Equal(
+			&corev1.TypedLocalObjectReference{
+				APIGroup: ptr.To(v1.VolumeSnapshotGroup),
+				Kind:     "VolumeSnapshot",
+				Name:     "production-clair",
+			},
+			obj.(*corev1.PersistentVolumeClaim).Spec.DataSource,
+		)
+	})
+}
+
+func TestProcessPodDisruptionBudget(t *testing.T) {
+	pdbFor := func(name, component string) *policyv1.PodDisruptionBudget {
+		return &policyv1.PodDisruptionBudget{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Annotations: map[string]string{"quay-component": component},
+			},
+			Spec: policyv1.PodDisruptionBudgetSpec{MinAvailable: ptr.To(intstr.FromInt32(1))},
+		}
+	}
+
+	for _, tt := range []struct {
+		name           string
+		components     []v1.Component
+		pdb            *policyv1.PodDisruptionBudget
+		minAvailable   *intstr.IntOrString
+		maxUnavailable *intstr.IntOrString
+		omitted        bool
+	}{
+		{
+			name:         "ManagedHPA",
+			components:   []v1.Component{{Kind: v1.ComponentHPA, Managed: true}},
+			pdb:          pdbFor("registry-quay-app", "quay"),
+			minAvailable: ptr.To(intstr.FromInt32(1)),
+		},
+		{
+			name: "ReplicasOverride",
+			components: []v1.Component{
+				{Kind: v1.ComponentHPA, Managed: false},
+				{Kind: v1.ComponentClair, Managed: true, Overrides: &v1.Override{Replicas: ptr.To[int32](5)}},
+			},
+			pdb:          pdbFor("registry-clair-app", "clair"),
+			minAvailable: ptr.To(intstr.FromInt32(4)),
+		},
+		{
+			name: "BudgetOverride",
+			components: []v1.Component{
+				{Kind: v1.ComponentHPA, Managed: true},
+				{
+					Kind:    v1.ComponentMirror,
+					Managed: true,
+					Overrides: &v1.Override{
+						PodDisruptionBudget: &v1.DisruptionBudget{
+							MaxUnavailable: ptr.To(intstr.FromString("50%")),
+						},
+					},
+				},
+			},
+			pdb:            pdbFor("registry-quay-mirror", "mirror"),
+			maxUnavailable: ptr.To(intstr.FromString("50%")),
+		},
+		{
+			name: "SingleReplica",
+			components: []v1.Component{
+				{Kind: v1.ComponentHPA, Managed: false},
+				{Kind: v1.ComponentQuay, Managed: true, Overrides: &v1.Override{Replicas: ptr.To[int32](1)}},
+			},
+			pdb:     pdbFor("registry-quay-app", "quay"),
+			omitted: true,
+		},
+		{
+			name: "ScaledDown",
+			components: []v1.Component{
+				{Kind: v1.ComponentHPA, Managed: true},
+				{Kind: v1.ComponentQuay, Managed: true, Overrides: &v1.Override{Replicas: ptr.To[int32](0)}},
+			},
+			pdb:     pdbFor("registry-quay-app", "quay"),
+			omitted: true,
+		},
+		{
+			name:       "UnmanagedMirror",
+			components: []v1.Component{{Kind: v1.ComponentMirror, Managed: false}},
+			pdb:        pdbFor("registry-quay-mirror", "mirror"),
+			omitted:    true,
+		},
+		{
+			name:         "Redis",
+			components:   []v1.Component{{Kind: v1.ComponentRedis, Managed: true}},
+			pdb:          pdbFor("registry-quay-redis", "redis"),
+			minAvailable: ptr.To(intstr.FromInt32(1)),
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			quay := &v1.QuayRegistry{
+				ObjectMeta: metav1.ObjectMeta{Name: "registry"},
+				Spec:       v1.QuayRegistrySpec{Components: tt.components},
+			}
+
+			obj, err := Process(quay, &quaycontext.QuayRegistryContext{}, tt.pdb, false)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if tt.omitted {
+				if obj != nil {
+					t.Errorf("expected pod disruption budget to be left out, received %+v", obj)
+				}
+				return
+			}
+
+			pdb, ok := obj.(*policyv1.PodDisruptionBudget)
+			if !ok {
+				t.Fatalf("expected a pod disruption budget, received %T", obj)
+			}
+			assert.Equal(t, tt.minAvailable, pdb.Spec.MinAvailable)
+			assert.Equal(t, tt.maxUnavailable, pdb.Spec.MaxUnavailable)
+		})
+	}
+}
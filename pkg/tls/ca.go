@@ -0,0 +1,221 @@
+package tls
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"slices"
+	"time"
+)
+
+const (
+	// CAValidity is the validity period of the CAs generated by the operator.
+	CAValidity = 10 * 365 * 24 * time.Hour
+	// CARenewBefore is how long before its expiration a CA is regenerated.
+	CARenewBefore = 365 * 24 * time.Hour
+	// CertValidity is the validity period of the serving certificates issued by the operator.
+	CertValidity = 365 * 24 * time.Hour
+	// CertRenewBefore is how long before its expiration a serving certificate is reissued.
+	CertRenewBefore = 30 * 24 * time.Hour
+)
+
+// GenerateCA creates a new self signed CA with the provided common name. Returns the PEM
+// encoded certificate and private key.
+func GenerateCA(commonName string, now time.Time) (cert, key []byte, err error) {
+	pkey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to generate CA key: %w", err)
+	}
+
+	serial, err := serialNumber()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(CAValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &pkey.PublicKey, pkey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to create CA certificate: %w", err)
+	}
+	return encodeCert(der), encodeKey(pkey), nil
+}
+
+// GenerateCert issues a serving certificate for the provided hosts signed by the provided CA.
+// Hosts may be either DNS names or IP addresses. Returns the PEM encoded certificate and
+// private key.
+func GenerateCert(caCert, caKey []byte, hosts []string, now time.Time) (cert, key []byte, err error) {
+	if len(hosts) == 0 {
+		return nil, nil, fmt.Errorf("no hosts provided for certificate")
+	}
+
+	tmpl := &x509.Certificate{
+		Subject:     pkix.Name{CommonName: hosts[0]},
+		NotBefore:   now.Add(-time.Hour),
+		NotAfter:    now.Add(CertValidity),
+		KeyUsage:    x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			tmpl.IPAddresses = append(tmpl.IPAddresses, ip)
+			continue
+		}
+		tmpl.DNSNames = append(tmpl.DNSNames, host)
+	}
+	return issue(caCert, caKey, tmpl)
+}
+
+// GenerateClientCert issues a client certificate with the provided common name signed by the
+// provided CA. Returns the PEM encoded certificate and private key.
+func GenerateClientCert(caCert, caKey []byte, commonName string, now time.Time) (cert, key []byte, err error) {
+	tmpl := &x509.Certificate{
+		Subject:     pkix.Name{CommonName: commonName},
+		NotBefore:   now.Add(-time.Hour),
+		NotAfter:    now.Add(CertValidity),
+		KeyUsage:    x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	return issue(caCert, caKey, tmpl)
+}
+
+// issue signs a certificate from the provided template with the provided CA. A new private key
+// is generated for the certificate.
+func issue(caCert, caKey []byte, tmpl *x509.Certificate) (cert, key []byte, err error) {
+	ca, err := parseCert(caCert)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to parse CA certificate: %w", err)
+	}
+
+	block, _ := pem.Decode(caKey)
+	if block == nil {
+		return nil, nil, fmt.Errorf("unable to decode CA key")
+	}
+	signer, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to parse CA key: %w", err)
+	}
+
+	pkey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to generate certificate key: %w", err)
+	}
+
+	if tmpl.SerialNumber, err = serialNumber(); err != nil {
+		return nil, nil, err
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca, &pkey.PublicKey, signer)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to create certificate: %w", err)
+	}
+	return encodeCert(der), encodeKey(pkey), nil
+}
+
+// NeedsRenewal returns true if the provided PEM encoded certificate can't be parsed, expires
+// within renewBefore, is not valid for all the provided hosts or, if a CA is provided, was not
+// signed by it.
+func NeedsRenewal(cert, ca []byte, hosts []string, renewBefore time.Duration, now time.Time) bool {
+	crt, err := parseCert(cert)
+	if err != nil {
+		return true
+	}
+
+	if now.Add(renewBefore).After(crt.NotAfter) {
+		return true
+	}
+
+	for _, host := range hosts {
+		if err := crt.VerifyHostname(host); err != nil {
+			return true
+		}
+	}
+
+	if ca == nil {
+		return false
+	}
+
+	issuer, err := parseCert(ca)
+	if err != nil {
+		return true
+	}
+	return crt.CheckSignatureFrom(issuer) != nil
+}
+
+// NotAfter returns the earliest expiration time among the certificates in the provided PEM
+// encoded chain. Blocks other than certificates are ignored.
+func NotAfter(chain []byte) (time.Time, error) {
+	var notAfter time.Time
+	for {
+		var block *pem.Block
+		block, chain = pem.Decode(chain)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		crt, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("unable to parse certificate: %w", err)
+		}
+		if notAfter.IsZero() || crt.NotAfter.Before(notAfter) {
+			notAfter = crt.NotAfter
+		}
+	}
+
+	if notAfter.IsZero() {
+		return time.Time{}, fmt.Errorf("no certificate found")
+	}
+	return notAfter, nil
+}
+
+// Hash returns a short hash of the provided certificate and key. It is used to annotate the
+// deployments consuming them so a rollout happens whenever they change.
+func Hash(cert, key []byte) string {
+	hash := sha256.Sum256(slices.Concat(cert, key))
+	hashStr := hex.EncodeToString(hash[:])
+	return hashStr[len(hashStr)-8:]
+}
+
+func parseCert(cert []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(cert)
+	if block == nil {
+		return nil, fmt.Errorf("unable to decode certificate")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+func serialNumber() (*big.Int, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, fmt.Errorf("unable to generate serial number: %w", err)
+	}
+	return serial, nil
+}
+
+func encodeCert(der []byte) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func encodeKey(key *rsa.PrivateKey) []byte {
+	return pem.EncodeToMemory(
+		&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)},
+	)
+}
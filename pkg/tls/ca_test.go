@@ -0,0 +1,307 @@
+package tls
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"slices"
+	"testing"
+	"time"
+)
+
+func newTestCA(t *testing.T, now time.Time) ([]byte, []byte) {
+	t.Helper()
+
+	ca, key, err := GenerateCA("test-ca", now)
+	if err != nil {
+		t.Fatalf("unexpected error generating CA: %s", err)
+	}
+	return ca, key
+}
+
+func parseTestCert(t *testing.T, cert []byte) *x509.Certificate {
+	t.Helper()
+
+	crt, err := parseCert(cert)
+	if err != nil {
+		t.Fatalf("unexpected error parsing certificate: %s", err)
+	}
+	return crt
+}
+
+func TestGenerateCert(t *testing.T) {
+	now := time.Now()
+	ca, cakey := newTestCA(t, now)
+
+	cert, key, err := GenerateCert(ca, cakey, []string{"registry.company.com", "10.0.0.1"}, now)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if block, _ := pem.Decode(key); block == nil || block.Type != "RSA PRIVATE KEY" {
+		t.Errorf("expected a PEM encoded private key, received %q", key)
+	}
+
+	crt := parseTestCert(t, cert)
+	if !slices.Equal(crt.DNSNames, []string{"registry.company.com"}) {
+		t.Errorf("expected DNS names to be set, received %v", crt.DNSNames)
+	}
+	if len(crt.IPAddresses) != 1 || crt.IPAddresses[0].String() != "10.0.0.1" {
+		t.Errorf("expected IP addresses to be set, received %v", crt.IPAddresses)
+	}
+	if !slices.Equal(crt.ExtKeyUsage, []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}) {
+		t.Errorf("expected a serving certificate, received %v", crt.ExtKeyUsage)
+	}
+	if err := crt.CheckSignatureFrom(parseTestCert(t, ca)); err != nil {
+		t.Errorf("expected certificate to be signed by the CA: %s", err)
+	}
+
+	if _, _, err := GenerateCert(ca, cakey, nil, now); err == nil {
+		t.Error("expected an error issuing a certificate without hosts")
+	}
+}
+
+func TestGenerateClientCert(t *testing.T) {
+	now := time.Now()
+	ca, cakey := newTestCA(t, now)
+
+	cert, _, err := GenerateClientCert(ca, cakey, "quay", now)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	crt := parseTestCert(t, cert)
+	if crt.Subject.CommonName != "quay" {
+		t.Errorf("expected common name quay, received %s", crt.Subject.CommonName)
+	}
+	if !slices.Equal(crt.ExtKeyUsage, []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}) {
+		t.Errorf("expected a client certificate, received %v", crt.ExtKeyUsage)
+	}
+	if err := crt.CheckSignatureFrom(parseTestCert(t, ca)); err != nil {
+		t.Errorf("expected certificate to be signed by the CA: %s", err)
+	}
+}
+
+func TestGenerateCertMalformedCA(t *testing.T) {
+	now := time.Now()
+	ca, cakey := newTestCA(t, now)
+	hosts := []string{"registry.company.com"}
+
+	for _, tt := range []struct {
+		name  string
+		ca    []byte
+		cakey []byte
+	}{
+		{name: "NotPEM", ca: []byte("not a certificate"), cakey: cakey},
+		{
+			name:  "InvalidCertificate",
+			ca:    pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("garbage")}),
+			cakey: cakey,
+		},
+		{name: "KeyNotPEM", ca: ca, cakey: []byte("not a key")},
+		{
+			name:  "InvalidKey",
+			ca:    ca,
+			cakey: pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: []byte("garbage")}),
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := GenerateCert(tt.ca, tt.cakey, hosts, now); err == nil {
+				t.Error("expected an error issuing a certificate")
+			}
+			if _, _, err := GenerateClientCert(tt.ca, tt.cakey, "quay", now); err == nil {
+				t.Error("expected an error issuing a client certificate")
+			}
+		})
+	}
+}
+
+func TestNeedsRenewal(t *testing.T) {
+	now := time.Now()
+	ca, cakey := newTestCA(t, now)
+	otherCA, _ := newTestCA(t, now)
+	hosts := []string{"registry.company.com"}
+
+	cert, _, err := GenerateCert(ca, cakey, hosts, now)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	notAfter := parseTestCert(t, cert).NotAfter
+
+	for _, tt := range []struct {
+		name     string
+		cert     []byte
+		ca       []byte
+		hosts    []string
+		now      time.Time
+		expected bool
+	}{
+		{
+			name:     "Valid",
+			cert:     cert,
+			ca:       ca,
+			hosts:    hosts,
+			now:      now,
+			expected: false,
+		},
+		{
+			name:     "AtRenewalThreshold",
+			cert:     cert,
+			ca:       ca,
+			hosts:    hosts,
+			now:      notAfter.Add(-CertRenewBefore),
+			expected: false,
+		},
+		{
+			name:     "PastRenewalThreshold",
+			cert:     cert,
+			ca:       ca,
+			hosts:    hosts,
+			now:      notAfter.Add(-CertRenewBefore + time.Second),
+			expected: true,
+		},
+		{
+			name:     "Expired",
+			cert:     cert,
+			ca:       ca,
+			hosts:    hosts,
+			now:      notAfter.Add(time.Second),
+			expected: true,
+		},
+		{
+			name:     "HostMismatch",
+			cert:     cert,
+			ca:       ca,
+			hosts:    []string{"registry.company.com", "quay.company.com"},
+			now:      now,
+			expected: true,
+		},
+		{
+			name:     "SignedByAnotherCA",
+			cert:     cert,
+			ca:       otherCA,
+			hosts:    hosts,
+			now:      now,
+			expected: true,
+		},
+		{
+			name:     "WithoutCA",
+			cert:     cert,
+			hosts:    hosts,
+			now:      now,
+			expected: false,
+		},
+		{
+			name:     "MalformedCA",
+			cert:     cert,
+			ca:       []byte("not a certificate"),
+			hosts:    hosts,
+			now:      now,
+			expected: true,
+		},
+		{
+			name:     "MalformedCertificate",
+			cert:     []byte("not a certificate"),
+			ca:       ca,
+			hosts:    hosts,
+			now:      now,
+			expected: true,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			renew := NeedsRenewal(tt.cert, tt.ca, tt.hosts, CertRenewBefore, tt.now)
+			if renew != tt.expected {
+				t.Errorf("expected renewal to be %v, received %v", tt.expected, renew)
+			}
+		})
+	}
+}
+
+func TestNotAfter(t *testing.T) {
+	now := time.Now()
+	ca, cakey := newTestCA(t, now)
+
+	// the certificate expires long before the CA that signed it.
+	cert, key, err := GenerateCert(ca, cakey, []string{"registry.company.com"}, now)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	certNotAfter := parseTestCert(t, cert).NotAfter
+	caNotAfter := parseTestCert(t, ca).NotAfter
+
+	for _, tt := range []struct {
+		name     string
+		chain    []byte
+		expected time.Time
+		err      bool
+	}{
+		{
+			name:     "Certificate",
+			chain:    cert,
+			expected: certNotAfter,
+		},
+		{
+			name:     "ChainCertificateFirst",
+			chain:    slices.Concat(cert, ca),
+			expected: certNotAfter,
+		},
+		{
+			name:     "ChainCAFirst",
+			chain:    slices.Concat(ca, cert),
+			expected: certNotAfter,
+		},
+		{
+			name:     "KeyIgnored",
+			chain:    slices.Concat(key, ca),
+			expected: caNotAfter,
+		},
+		{
+			name:  "NotPEM",
+			chain: []byte("not a certificate"),
+			err:   true,
+		},
+		{
+			name:  "OnlyKey",
+			chain: key,
+			err:   true,
+		},
+		{
+			name:  "InvalidCertificate",
+			chain: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("garbage")}),
+			err:   true,
+		},
+		{
+			name:  "Empty",
+			chain: nil,
+			err:   true,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			notAfter, err := NotAfter(tt.chain)
+			if tt.err {
+				if err == nil {
+					t.Errorf("expected an error, received %s", notAfter)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if !notAfter.Equal(tt.expected) {
+				t.Errorf("expected %s, received %s", tt.expected, notAfter)
+			}
+		})
+	}
+}
+
+func TestHash(t *testing.T) {
+	hash := Hash([]byte("cert"), []byte("key"))
+	if len(hash) != 8 {
+		t.Errorf("expected a hash of 8 characters, received %q", hash)
+	}
+	if again := Hash([]byte("cert"), []byte("key")); again != hash {
+		t.Errorf("expected hash to be stable, received %q and %q", hash, again)
+	}
+	if other := Hash([]byte("cert"), []byte("other")); other == hash {
+		t.Errorf("expected hash to change along with the key, received %q", other)
+	}
+}